@@ -37,7 +37,19 @@ func main() {
 	log := logger.New()
 
 	handler := gateway.NewHandler(cfg.BookingServiceURL, cfg.PaymentServiceURL, cfg.AggregateTargetURL, cfg.RateLimitPerMinute)
-	proxy, err := gateway.NewProxyEngine(cfg, log)
+	serviceTokens, err := middleware.LoadServiceSigner("%s", cfg.ServiceSigningKeyFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load service signing key", zap.Error(err))
+	}
+	jwks := middleware.NewJWKSCache(cfg.AuthServiceURL+middleware.JWKSPath, cfg.JWKSRefreshInterval)
+	jwks.Start(ctx)
+	verifier := middleware.NewVerifier(cfg.JWTSecret, jwks)
+	revocations := middleware.NewRevocationList(
+		middleware.HTTPRevocationSource(cfg.AuthServiceURL, serviceTokens),
+		cfg.RevocationRefreshInterval,
+	)
+	revocations.Start(ctx)
+	proxy, err := gateway.NewProxyEngine(cfg, log, verifier, revocations)
 	if err != nil {
 		log.Fatal("failed to initialize proxy engine", zap.Error(err))
 	}
@@ -66,7 +78,7 @@ func main() {
 	r.Get("/healthz", proxy.Healthz)
 	r.Mount("/gateway/auth", http.StripPrefix("/gateway/auth", authProxy))
 	r.Group(func(router chi.Router) {
-		router.Use(middleware.JWT(cfg.JWTSecret))
+		router.Use(middleware.Authenticate(verifier, revocations))
 		router.Mount("/gateway", handler.Routes())
 	})
 	r.Mount("/", proxy)
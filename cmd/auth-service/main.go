@@ -5,17 +5,22 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	authdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
 	authhttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/http"
+	authnotification "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/notification"
+	authpersonaldata "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/personaldata"
 	authrepo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/repository"
 	authtoken "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/token"
 	authuc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/auth"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/config"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/database"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/logger"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/server"
 )
 
@@ -36,9 +41,48 @@ func main() {
 	}
 
 	repo := authrepo.NewGormRepository(db)
-	issuer := authtoken.NewJWTIssuer(cfg.JWTSecret)
-	service := authuc.NewService(repo, issuer)
-	handler := authhttp.NewHandler(service, cfg.JWTSecret)
+	var keys *authtoken.KeyRing
+	if cfg.JWTSigningKeyFile != "" {
+		keys, err = authtoken.LoadKeyRing(cfg.JWTSigningKeyFile, cfg.JWTVerifyKeyFiles)
+	} else {
+		log.Warn("JWT_SIGNING_KEY_FILE not set; signing tokens with an ephemeral key")
+		keys, err = authtoken.GenerateKeyRing()
+	}
+	if err != nil {
+		log.Fatal("failed to load signing keys", zap.Error(err))
+	}
+	if err := keys.LoadServiceKeys(cfg.ServiceVerifyKeys); err != nil {
+		log.Fatal("failed to load service keys", zap.Error(err))
+	}
+	serviceTokens, err := keys.ServiceSigner("auth-service")
+	if err != nil {
+		log.Fatal("failed to create service signer", zap.Error(err))
+	}
+	issuer := authtoken.NewJWTIssuer(keys)
+	notifier := authnotification.NewHTTPNotifier(cfg.NotificationURL, cfg.AppURL, serviceTokens)
+	lockout := authdomain.LockoutPolicy{
+		MaxAttempts:     cfg.LoginMaxAttempts,
+		LockoutDuration: cfg.LoginLockoutDuration,
+		BaseDelay:       cfg.LoginBaseDelay,
+		MaxDelay:        cfg.LoginMaxDelay,
+		IPMaxAttempts:   cfg.LoginIPMaxAttempts,
+	}
+	personal := authpersonaldata.NewHTTPSource(cfg.BookingServiceURL, cfg.PaymentServiceURL, cfg.NotificationURL, serviceTokens)
+	service := authuc.NewService(repo, issuer, notifier, personal, lockout)
+	// auth-service reads revocations from its own store; logouts handled here apply immediately.
+	revocations := middleware.NewRevocationList(func(ctx context.Context) (map[string]time.Time, error) {
+		tokens, err := service.Revocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		revoked := make(map[string]time.Time, len(tokens))
+		for _, t := range tokens {
+			revoked[t.ID.String()] = t.ExpiresAt
+		}
+		return revoked, nil
+	}, cfg.RevocationRefreshInterval)
+	revocations.Start(ctx)
+	handler := authhttp.NewHandler(service, middleware.NewVerifier(cfg.JWTSecret, keys), revocations, keys, cfg.TrustedProxies)
 
 	r := chi.NewRouter()
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
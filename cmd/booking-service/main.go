@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	bookingdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
 	bookinghttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/http"
 	bookingnotification "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/notification"
 	bookingpayment "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/payment"
@@ -41,6 +43,9 @@ func main() {
 	if err := hotelrepo.AutoMigrate(db); err != nil {
 		log.Fatal("failed to run hotel migrations", zap.Error(err))
 	}
+	if err := database.MigrateIdempotency(db); err != nil {
+		log.Fatal("failed to run idempotency migrations", zap.Error(err))
+	}
 
 	repoFactory := bookingrepo.NewGormFactory(db)
 	repo, err := repoFactory.CreateBookingRepository(bookingrepo.TypeGorm)
@@ -49,10 +54,24 @@ func main() {
 	}
 
 	hRepo := hotelrepo.NewGormRepository(db)
-	paymentClient := bookingpayment.NewHTTPGateway(cfg.PaymentServiceURL)
+	serviceTokens, err := middleware.LoadServiceSigner("%s", cfg.ServiceSigningKeyFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load service signing key", zap.Error(err))
+	}
+	paymentClient := bookingpayment.NewHTTPGateway(cfg.PaymentServiceURL, serviceTokens)
 	notifier := bookingnotification.NewHTTPGateway(cfg.NotificationURL)
 	service := bookinguc.NewService(repo, hRepo, paymentClient, notifier)
 	handler := bookinghttp.NewHandler(service)
+	jwks := middleware.NewJWKSCache(cfg.AuthServiceURL+middleware.JWKSPath, cfg.JWKSRefreshInterval)
+	jwks.Start(ctx)
+	verifier := middleware.NewVerifier(cfg.JWTSecret, jwks)
+	revocations := middleware.NewRevocationList(
+		middleware.HTTPRevocationSource(cfg.AuthServiceURL, serviceTokens),
+		cfg.RevocationRefreshInterval,
+	)
+	revocations.Start(ctx)
+	idempotencyStore := database.NewIdempotencyStore(db)
+	idempotencyStore.StartCleanup(ctx, time.Hour)
 
 	r := chi.NewRouter()
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -60,9 +79,15 @@ func main() {
 		_, _ = w.Write([]byte("ok"))
 	})
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.JWT(cfg.JWTSecret))
+		r.Use(middleware.Authenticate(verifier, revocations))
+		r.Use(middleware.Idempotency(idempotencyStore))
 		r.Mount("/", handler.Routes())
 	})
+	// Service-to-service routes, e.g. payment confirmations from payment-service.
+	r.Route("/internal", func(r chi.Router) {
+		r.Use(middleware.Authenticate(verifier, revocations, middleware.RoleService))
+		r.Mount("/", handler.InternalRoutes())
+	})
 
 	srv := server.New(cfg.HTTPPort, r, log)
 	srv.Start()
@@ -74,8 +99,36 @@ func main() {
 	}
 	defer scheduler.Stop()
 
+	expiryScheduler := bookingworker.NewPaymentExpiryScheduler(service, cfg.BookingHoldWindow, log)
+	if err := expiryScheduler.Start(); err != nil {
+		log.Fatal("failed to start payment expiry scheduler", zap.Error(err))
+	}
+	defer expiryScheduler.Stop()
+
+	noShowScheduler := bookingworker.NewNoShowScheduler(service, bookingdomain.NoShowPolicy{ChargeNights: cfg.NoShowChargeNights}, log)
+	if err := noShowScheduler.Start(); err != nil {
+		log.Fatal("failed to start no-show scheduler", zap.Error(err))
+	}
+	defer noShowScheduler.Stop()
+
+	outboxRelay := bookingworker.NewOutboxRelayScheduler(service, log)
+	if err := outboxRelay.Start(); err != nil {
+		log.Fatal("failed to start outbox relay", zap.Error(err))
+	}
+	defer outboxRelay.Stop()
+
+	sagaRecovery := bookingworker.NewSagaRecoveryScheduler(service, log)
+	if err := sagaRecovery.Start(); err != nil {
+		log.Fatal("failed to start saga recovery", zap.Error(err))
+	}
+	defer sagaRecovery.Stop()
+
 	<-ctx.Done()
 	log.Info("Shutting down gracefully...")
 	scheduler.Stop()
+	expiryScheduler.Stop()
+	noShowScheduler.Stop()
+	outboxRelay.Stop()
+	sagaRecovery.Stop()
 	_ = srv.Stop(context.Background())
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/pkg/config"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/database"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/logger"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/server"
 )
 
@@ -36,7 +37,19 @@ func main() {
 
 	repo := hotelrepo.NewGormRepository(db)
 	service := hoteluc.NewService(repo)
-	handler := hotelhttp.NewHandler(service, cfg.JWTSecret)
+	serviceTokens, err := middleware.LoadServiceSigner("%s", cfg.ServiceSigningKeyFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load service signing key", zap.Error(err))
+	}
+	jwks := middleware.NewJWKSCache(cfg.AuthServiceURL+middleware.JWKSPath, cfg.JWKSRefreshInterval)
+	jwks.Start(ctx)
+	verifier := middleware.NewVerifier(cfg.JWTSecret, jwks)
+	revocations := middleware.NewRevocationList(
+		middleware.HTTPRevocationSource(cfg.AuthServiceURL, serviceTokens),
+		cfg.RevocationRefreshInterval,
+	)
+	revocations.Start(ctx)
+	handler := hotelhttp.NewHandler(service, verifier, revocations)
 
 	r := chi.NewRouter()
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -5,14 +5,19 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
 	dispatcher "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/dispatcher"
 	notificationhttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/http"
+	notificationrepo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/repository"
+	notificationworker "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/worker"
 	notificationuc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/config"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/database"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/logger"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/server"
@@ -25,14 +30,36 @@ func main() {
 	cfg := config.Load()
 	log := logger.New()
 
+	db, err := database.NewGormPostgres(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("failed to connect to postgres", zap.Error(err))
+	}
+
+	if err := notificationrepo.AutoMigrate(db); err != nil {
+		log.Fatal("failed to run migrations", zap.Error(err))
+	}
+
 	var dispatch domain.Dispatcher
 	if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
 		dispatch = dispatcher.NewEmailDispatcher(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
 	} else {
 		dispatch = dispatcher.NewLoggerDispatcher(log)
 	}
-	service := notificationuc.NewService(dispatch)
+	repo := notificationrepo.NewGormRepository(db)
+	service := notificationuc.NewService(repo, dispatch)
 	handler := notificationhttp.NewHandler(service)
+	serviceTokens, err := middleware.LoadServiceSigner("%s", cfg.ServiceSigningKeyFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load service signing key", zap.Error(err))
+	}
+	jwks := middleware.NewJWKSCache(cfg.AuthServiceURL+middleware.JWKSPath, cfg.JWKSRefreshInterval)
+	jwks.Start(ctx)
+	verifier := middleware.NewVerifier(cfg.JWTSecret, jwks)
+	revocations := middleware.NewRevocationList(
+		middleware.HTTPRevocationSource(cfg.AuthServiceURL, serviceTokens),
+		cfg.RevocationRefreshInterval,
+	)
+	revocations.Start(ctx)
 
 	r := chi.NewRouter()
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -40,13 +67,23 @@ func main() {
 		_, _ = w.Write([]byte("ok"))
 	})
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.JWT(cfg.JWTSecret, "admin"))
+		// auth-service sends account emails with a service token.
+		r.Use(middleware.Authenticate(verifier, revocations, "admin", middleware.RoleService))
+		r.Use(middleware.AllowServices("auth-service"))
 		r.Mount("/", handler.Routes())
 	})
 
 	srv := server.New(cfg.HTTPPort, r, log)
 	srv.Start()
 
+	workers := notificationworker.NewPool(service, cfg.NotificationWorkers, cfg.NotificationPollInterval, log)
+	workers.Start()
+
 	<-ctx.Done()
+	// Stop accepting requests first, then let the workers finish the
+	// notifications they are delivering.
 	_ = srv.Stop(context.Background())
+	drainCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_ = workers.Stop(drainCtx)
 }
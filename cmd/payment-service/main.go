@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -37,6 +38,9 @@ func main() {
 	if err := paymentrepo.AutoMigrate(db); err != nil {
 		log.Fatal("failed to run migrations", zap.Error(err))
 	}
+	if err := database.MigrateIdempotency(db); err != nil {
+		log.Fatal("failed to run idempotency migrations", zap.Error(err))
+	}
 
 	repo := paymentrepo.NewGormRepository(db)
 	var provider paymentdomain.Provider
@@ -51,16 +55,40 @@ func main() {
 	} else {
 		provider = paymentprovider.NewXenditMockProvider(cfg.PaymentProviderKey)
 	}
-	statusClient := paymentbooking.NewHTTPStatusClient(cfg.BookingServiceURL)
+	serviceTokens, err := middleware.LoadServiceSigner("%s", cfg.ServiceSigningKeyFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatal("failed to load service signing key", zap.Error(err))
+	}
+	statusClient := paymentbooking.NewHTTPStatusClient(cfg.BookingServiceURL, serviceTokens)
 	service := paymentuc.NewService(repo, provider, statusClient)
 	handler := paymenthttp.NewHandler(service)
+	jwks := middleware.NewJWKSCache(cfg.AuthServiceURL+middleware.JWKSPath, cfg.JWKSRefreshInterval)
+	jwks.Start(ctx)
+	verifier := middleware.NewVerifier(cfg.JWTSecret, jwks)
+	revocations := middleware.NewRevocationList(
+		middleware.HTTPRevocationSource(cfg.AuthServiceURL, serviceTokens),
+		cfg.RevocationRefreshInterval,
+	)
+	revocations.Start(ctx)
+	idempotencyStore := database.NewIdempotencyStore(db)
+	idempotencyStore.StartCleanup(ctx, time.Hour)
 
+	// Booking lifecycle calls come from booking-service only.
+	bookingOnly := middleware.AllowServices("booking-service")
 	api := chi.NewRouter()
-	api.Use(middleware.JWT(cfg.JWTSecret))
+	api.Use(middleware.Authenticate(verifier, revocations))
+	// Retried payment and refund requests carrying an Idempotency-Key replay the first response.
+	api.Use(middleware.Idempotency(idempotencyStore))
 	api.Post("/payments", handler.CreatePayment)
 	api.Get("/payments/{id}", handler.GetPayment)
 	api.Get("/payments/by-booking/{booking_id}", handler.GetByBooking)
 	api.Post("/payments/refund", handler.Refund)
+	api.With(middleware.Authenticate(verifier, revocations, "admin", middleware.RoleService), bookingOnly).
+		Post("/payments/by-booking/{booking_id}/expire", handler.ExpireByBooking)
+	api.With(middleware.Authenticate(verifier, revocations, "admin", middleware.RoleService), bookingOnly).
+		Post("/payments/by-booking/{booking_id}/refund", handler.RefundByBooking)
+	api.With(middleware.Authenticate(verifier, revocations, "admin", middleware.RoleService), bookingOnly).
+		Post("/payments/by-booking/{booking_id}/adjustments/void", handler.VoidAdjustments)
 
 	r := chi.NewRouter()
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/domain"
+)
+
+// LockoutPolicy throttles failed logins. Zero fields disable the matching
+// protection.
+type LockoutPolicy struct {
+	// MaxAttempts consecutive failures lock an account for LockoutDuration.
+	MaxAttempts     int
+	LockoutDuration time.Duration
+	// BaseDelay is the wait enforced after the first failure; it doubles
+	// with every further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// IPMaxAttempts failures from one address, across any accounts, block
+	// that address for LockoutDuration.
+	IPMaxAttempts int
+}
+
+// Delay returns how long to wait after the given number of consecutive
+// failures before the next attempt is checked.
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// LoginAttempts is the failed-login state of an account.
+type LoginAttempts struct {
+	Failures      int
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+}
+
+// RetryAfter returns how long the account must wait at now before its next
+// attempt is checked, or zero when it may try now.
+func (a LoginAttempts) RetryAfter(p LockoutPolicy, now time.Time) time.Duration {
+	if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+	if a.LastFailureAt == nil {
+		return 0
+	}
+	if wait := a.LastFailureAt.Add(p.Delay(a.Failures)).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Locked reports whether the account is locked at now.
+func (a LoginAttempts) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// LoginAttemptStore persists failed-login state on users.
+type LoginAttemptStore interface {
+	// RecordLoginFailure counts a failed login at the given time and returns
+	// the number of consecutive failures.
+	RecordLoginFailure(ctx context.Context, id uuid.UUID, at time.Time) (int, error)
+	// LockUser locks the account until the given time and restarts its count.
+	LockUser(ctx context.Context, id uuid.UUID, until time.Time) error
+	// ClearLoginFailures resets the count and lifts any lock.
+	ClearLoginFailures(ctx context.Context, id uuid.UUID) error
+}
+
+// Audit event types.
+const (
+	EventTypeUserLocked   = "user.locked"
+	EventTypeUserUnlocked = "user.unlocked"
+)
+
+// UserLocked is raised when repeated failed logins lock an account.
+type UserLocked struct {
+	domain.BaseEvent
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Failures int       `json:"failures"`
+	Until    time.Time `json:"locked_until"`
+	IP       string    `json:"ip,omitempty"`
+}
+
+func NewUserLocked(user User, failures int, until time.Time, ip string) UserLocked {
+	return UserLocked{
+		BaseEvent: domain.NewBaseEvent(user.ID, EventTypeUserLocked),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Failures:  failures,
+		Until:     until,
+		IP:        ip,
+	}
+}
+
+// UserUnlocked is raised when an admin lifts a lock.
+type UserUnlocked struct {
+	domain.BaseEvent
+	UserID  uuid.UUID `json:"user_id"`
+	AdminID uuid.UUID `json:"admin_id"`
+}
+
+func NewUserUnlocked(userID, adminID uuid.UUID) UserUnlocked {
+	return UserUnlocked{
+		BaseEvent: domain.NewBaseEvent(userID, EventTypeUserUnlocked),
+		UserID:    userID,
+		AdminID:   adminID,
+	}
+}
+
+// AuditLog records security-relevant account events.
+type AuditLog interface {
+	RecordEvent(ctx context.Context, event domain.DomainEvent) error
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TOTPPeriod is the time step of RFC 6238 codes.
+	TOTPPeriod = 30 * time.Second
+	// totpDigits is the length of a code.
+	totpDigits = 6
+	// totpSkew is how many steps before or after now a code is still accepted,
+	// to allow for clock drift between server and authenticator.
+	totpSkew = 1
+
+	// RecoveryCodeCount is how many recovery codes an enrollment issues.
+	RecoveryCodeCount = 10
+	// MFAIssuer names the account in authenticator apps.
+	MFAIssuer = "Hotel Booking"
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFA is a user's TOTP enrollment. Secret is set on setup; the enrollment
+// only takes effect once a code is verified and EnabledAt is set.
+type MFA struct {
+	Secret    string
+	EnabledAt *time.Time
+	// LastStep is the time step of the last accepted code, so a code cannot
+	// be replayed within its validity window.
+	LastStep int64
+}
+
+// Enabled reports whether logins require a second factor.
+func (m MFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// NewTOTPSecret returns a random 160-bit secret in base32, the form
+// authenticator apps expect.
+func NewTOTPSecret() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+// TOTPStep returns the RFC 6238 time step containing t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode computes the code of secret for step (RFC 4226 with HMAC-SHA1).
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// MatchTOTP returns the step whose code equals code, looking up to totpSkew
+// steps around now.
+func MatchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI returns the otpauth:// URI authenticator apps enroll from.
+func TOTPURI(account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", MFAIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	label := url.PathEscape(MFAIssuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// RecoveryCode stands in for a TOTP code once, for users who lost their
+// authenticator. Like one-time tokens, only the hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Hash      string
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// NewRecoveryCodes issues RecoveryCodeCount codes for userID and returns them
+// with the codes to show the user.
+func NewRecoveryCodes(userID uuid.UUID, now time.Time) ([]RecoveryCode, []string, error) {
+	codes := make([]RecoveryCode, 0, RecoveryCodeCount)
+	plain := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+		code := encoded[:4] + "-" + encoded[4:]
+		codes = append(codes, RecoveryCode{ID: uuid.New(), UserID: userID, Hash: HashRecoveryCode(code), CreatedAt: now})
+		plain = append(plain, code)
+	}
+	return codes, plain, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code, ignoring case
+// and separators.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashOneTimeSecret(normalized)
+}
+
+// MFAStore persists TOTP enrollments and recovery codes.
+type MFAStore interface {
+	// SetMFASecret stores a pending secret, replacing any unfinished setup.
+	SetMFASecret(ctx context.Context, userID uuid.UUID, secret string) error
+	// EnableMFA turns on the pending enrollment, recording step as used, and
+	// replaces the user's recovery codes.
+	EnableMFA(ctx context.Context, userID uuid.UUID, at time.Time, step int64, codes []RecoveryCode) error
+	// UseTOTPStep records step as the last accepted one. It returns false
+	// when a code of that step or a later one was already accepted.
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// UseRecoveryCode spends the unused code with hash. It returns false when
+	// the user has no such unused code.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error)
+}
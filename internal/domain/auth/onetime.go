@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPurpose names what a one-time token may be redeemed for.
+type TokenPurpose string
+
+const (
+	PurposePasswordReset     TokenPurpose = "password_reset"
+	PurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+const (
+	// PasswordResetTTL is how long a password reset link stays valid.
+	PasswordResetTTL = time.Hour
+	// EmailVerificationTTL is how long an email verification link stays valid.
+	EmailVerificationTTL = 48 * time.Hour
+)
+
+// OneTimeToken is the server-side record of a secret mailed to a user. Only
+// the SHA-256 hash of the secret is stored, so a leaked table cannot be used
+// to reset passwords, and the token is spent on first use.
+type OneTimeToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   TokenPurpose
+	Hash      string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// NewOneTimeToken issues a token of purpose for userID and returns it with
+// the secret to deliver to the user.
+func NewOneTimeToken(userID uuid.UUID, purpose TokenPurpose, now time.Time) (OneTimeToken, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return OneTimeToken{}, "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+	ttl := PasswordResetTTL
+	if purpose == PurposeEmailVerification {
+		ttl = EmailVerificationTTL
+	}
+	return OneTimeToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		Hash:      HashOneTimeSecret(secret),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, secret, nil
+}
+
+// HashOneTimeSecret returns the stored form of a one-time token secret.
+func HashOneTimeSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// OneTimeTokenStore persists one-time tokens.
+type OneTimeTokenStore interface {
+	CreateOneTimeToken(ctx context.Context, t OneTimeToken) error
+	// ConsumeOneTimeToken marks the unused, unexpired token of purpose with
+	// the given hash used at the given time and returns it. It fails with
+	// not_found when no such token exists.
+	ConsumeOneTimeToken(ctx context.Context, purpose TokenPurpose, hash string, at time.Time) (OneTimeToken, error)
+}
+
+// AccountNotifier delivers one-time token secrets to users.
+type AccountNotifier interface {
+	SendPasswordReset(ctx context.Context, email, secret string) error
+	SendEmailVerification(ctx context.Context, email, secret string) error
+}
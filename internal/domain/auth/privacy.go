@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/domain"
+)
+
+// ErasedEmail replaces the email address of an erased user. It stays unique
+// and cannot receive mail.
+func ErasedEmail(id uuid.UUID) string {
+	return "erased-" + id.String() + "@erased.invalid"
+}
+
+// PersonalData is what other services hold about a user, each record in the
+// owning service's API representation.
+type PersonalData struct {
+	Bookings      []json.RawMessage
+	Payments      []json.RawMessage
+	Notifications []json.RawMessage
+}
+
+// PersonalDataSource reaches the personal data other services hold.
+type PersonalDataSource interface {
+	// Collect gathers the user's bookings, their payments and the
+	// notifications sent to email.
+	Collect(ctx context.Context, userID uuid.UUID, email string) (PersonalData, error)
+	// Erase removes personal data held elsewhere. Bookings and payments are
+	// financial records and are kept; they refer to the user only by ID,
+	// which no longer leads to personal data once the user is erased.
+	Erase(ctx context.Context, userID uuid.UUID, email string) error
+}
+
+// ErasureStore anonymizes a user in place.
+type ErasureStore interface {
+	// EraseUser replaces the email, clears the password, profile and second
+	// factor, deletes one-time tokens, recovery codes and staff roles,
+	// revokes refresh tokens and strips addresses from audit events.
+	EraseUser(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// Audit event types of self-service account changes.
+const (
+	EventTypePasswordChanged = "user.password_changed"
+	EventTypeUserErased      = "user.erased"
+)
+
+// AccountChanged is raised when a user changes their password or erases
+// their account. It carries no personal data.
+type AccountChanged struct {
+	domain.BaseEvent
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func NewPasswordChanged(userID uuid.UUID) AccountChanged {
+	return AccountChanged{BaseEvent: domain.NewBaseEvent(userID, EventTypePasswordChanged), UserID: userID}
+}
+
+func NewUserErased(userID uuid.UUID) AccountChanged {
+	return AccountChanged{BaseEvent: domain.NewBaseEvent(userID, EventTypeUserErased), UserID: userID}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenTTL is how long a refresh token can be exchanged for new tokens.
+const RefreshTokenTTL = 24 * time.Hour
+
+// RefreshToken is the server-side record of an issued refresh token. Every
+// refresh rotates it for a new token of the same family; tokens of a family
+// descend from one login.
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	// MFA records that the login starting the family passed a second
+	// factor, so tokens refreshed from it keep saying so.
+	MFA bool
+}
+
+// NewRefreshToken issues a token of family for userID; a nil family starts a new one.
+func NewRefreshToken(userID, family uuid.UUID, mfa bool, now time.Time) RefreshToken {
+	if family == uuid.Nil {
+		family = uuid.New()
+	}
+	return RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  family,
+		UserID:    userID,
+		ExpiresAt: now.Add(RefreshTokenTTL),
+		CreatedAt: now,
+		MFA:       mfa,
+	}
+}
+
+// Active reports whether the token may still be exchanged at now.
+func (t RefreshToken) Active(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RefreshTokenStore persists refresh tokens.
+type RefreshTokenStore interface {
+	CreateRefreshToken(ctx context.Context, t RefreshToken) error
+	FindRefreshToken(ctx context.Context, id uuid.UUID) (RefreshToken, error)
+	// UseRefreshToken marks an unused, unrevoked token used at the given time.
+	// It returns false when the token was already used or revoked.
+	UseRefreshToken(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// RevokeFamily revokes every token of a family.
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error
+	// RevokeUserRefreshTokens revokes every token issued to a user.
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
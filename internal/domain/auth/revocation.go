@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevokedToken is an access token withdrawn before its expiry, identified by
+// its jti. The record is only relevant until the token would have expired.
+type RevokedToken struct {
+	ID        uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// RevocationStore persists revoked access tokens.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, t RevokedToken) error
+	// ListRevoked returns revoked tokens that are still unexpired at now.
+	ListRevoked(ctx context.Context, now time.Time) ([]RevokedToken, error)
+}
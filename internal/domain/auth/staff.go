@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/domain"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// HotelRole grants a user a staff role at one hotel, on top of their
+// platform role. Access tokens carry the grants so services can check
+// permissions against the hotel of the resource being acted on.
+type HotelRole struct {
+	UserID    uuid.UUID
+	HotelID   uuid.UUID
+	Role      valueobject.Role
+	GrantedBy uuid.UUID
+	CreatedAt time.Time
+}
+
+// HotelRoleStore persists staff role grants.
+type HotelRoleStore interface {
+	// AssignHotelRole stores a grant; granting the same role twice is a conflict.
+	AssignHotelRole(ctx context.Context, grant HotelRole) error
+	// RemoveHotelRole deletes a grant and reports whether it existed.
+	RemoveHotelRole(ctx context.Context, userID, hotelID uuid.UUID, role valueobject.Role) (bool, error)
+	ListHotelRoles(ctx context.Context, userID uuid.UUID) ([]HotelRole, error)
+}
+
+// Audit event types of staff role changes.
+const (
+	EventTypeHotelRoleAssigned = "user.hotel_role_assigned"
+	EventTypeHotelRoleRemoved  = "user.hotel_role_removed"
+)
+
+// HotelRoleChanged is raised when an admin assigns or removes a staff role.
+type HotelRoleChanged struct {
+	domain.BaseEvent
+	UserID  uuid.UUID        `json:"user_id"`
+	HotelID uuid.UUID        `json:"hotel_id"`
+	Role    valueobject.Role `json:"role"`
+	AdminID uuid.UUID        `json:"admin_id"`
+}
+
+func NewHotelRoleAssigned(grant HotelRole) HotelRoleChanged {
+	return newHotelRoleChanged(EventTypeHotelRoleAssigned, grant.UserID, grant.HotelID, grant.Role, grant.GrantedBy)
+}
+
+func NewHotelRoleRemoved(userID, hotelID uuid.UUID, role valueobject.Role, adminID uuid.UUID) HotelRoleChanged {
+	return newHotelRoleChanged(EventTypeHotelRoleRemoved, userID, hotelID, role, adminID)
+}
+
+func newHotelRoleChanged(eventType string, userID, hotelID uuid.UUID, role valueobject.Role, adminID uuid.UUID) HotelRoleChanged {
+	return HotelRoleChanged{
+		BaseEvent: domain.NewBaseEvent(userID, eventType),
+		UserID:    userID,
+		HotelID:   hotelID,
+		Role:      role,
+		AdminID:   adminID,
+	}
+}
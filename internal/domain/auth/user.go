@@ -16,6 +16,37 @@ type User struct {
 	Password  string
 	Role      string
 	CreatedAt time.Time
+	// EmailVerifiedAt is set once the user proves they own Email.
+	EmailVerifiedAt *time.Time
+	Attempts        LoginAttempts
+	MFA             MFA
+	// HotelRoles are loaded when tokens are issued, not by UserRepository.
+	HotelRoles []HotelRole
+	Profile    Profile
+	// ErasedAt is set once the user's personal data was erased.
+	ErasedAt *time.Time
+}
+
+// Profile holds the guest details a user maintains themselves.
+type Profile struct {
+	Name   string
+	Phone  string
+	Locale string
+	// Preferences are free-form guest preferences such as "bed": "twin".
+	Preferences map[string]string
+}
+
+// Profile limits keep preferences a handful of short settings.
+const (
+	MaxNameLength      = 100
+	MaxPreferences     = 20
+	MaxPreferenceKey   = 64
+	MaxPreferenceValue = 256
+)
+
+// EmailVerified reports whether the user confirmed their email address.
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 // UserRepository persists users.
@@ -24,9 +55,35 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (User, error)
 	List(ctx context.Context, opts query.Options) ([]User, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, hash string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error
+	UpdateProfile(ctx context.Context, id uuid.UUID, profile Profile) error
+	LoginAttemptStore
+}
+
+// Repository persists users, their refresh tokens, revoked access tokens,
+// one-time tokens, MFA enrollments, staff role grants and audit events, and
+// erases users.
+type Repository interface {
+	UserRepository
+	RefreshTokenStore
+	RevocationStore
+	OneTimeTokenStore
+	MFAStore
+	HotelRoleStore
+	ErasureStore
+	AuditLog
 }
 
 // TokenIssuer issues JWT tokens.
 type TokenIssuer interface {
-	Generate(ctx context.Context, user User) (access, refresh string, err error)
+	// Generate issues an access token for user and a refresh token carrying session.
+	Generate(ctx context.Context, user User, session RefreshToken) (access, refresh string, err error)
+	// ParseRefresh validates a refresh token and returns the ID of the session it carries.
+	ParseRefresh(ctx context.Context, token string) (uuid.UUID, error)
+	// IssueMFAChallenge issues a short-lived token proving user passed the
+	// password step, to be exchanged with a second factor.
+	IssueMFAChallenge(ctx context.Context, user User) (string, error)
+	// ParseMFAChallenge validates a challenge token and returns its user ID.
+	ParseMFAChallenge(ctx context.Context, token string) (uuid.UUID, error)
 }
@@ -0,0 +1,52 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// RoomAssignment records the physical room a checked-in booking occupies.
+type RoomAssignment struct {
+	ID           uuid.UUID
+	BookingID    uuid.UUID
+	RoomID       uuid.UUID
+	RoomNumber   string
+	CheckedInAt  time.Time
+	CheckedOutAt *time.Time
+}
+
+// Active reports whether the guest still occupies the room.
+func (a RoomAssignment) Active() bool {
+	return a.CheckedOutAt == nil
+}
+
+// RoomCandidate is a sellable room of the booked type with no active guest.
+type RoomCandidate struct {
+	ID     uuid.UUID
+	Number string
+}
+
+// RoomPicker chooses the room to assign from the free candidates.
+type RoomPicker func(free []RoomCandidate) (RoomCandidate, error)
+
+// PickRoom returns a picker for the requested room number, or for the first
+// free room when no number is requested.
+func PickRoom(requested string) RoomPicker {
+	return func(free []RoomCandidate) (RoomCandidate, error) {
+		if len(free) == 0 {
+			return RoomCandidate{}, pkgErrors.New("conflict", "no free room available for check-in")
+		}
+		if requested == "" {
+			return free[0], nil
+		}
+		for _, room := range free {
+			if room.Number == requested {
+				return room, nil
+			}
+		}
+		return RoomCandidate{}, pkgErrors.New("conflict", "requested room is not free for this booking")
+	}
+}
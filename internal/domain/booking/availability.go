@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// Inventory exposes the room inventory facts needed to evaluate availability.
+type Inventory interface {
+	CountBookableRooms(ctx context.Context, roomTypeID uuid.UUID) (int, error)
+	FindOverlapping(ctx context.Context, roomTypeID uuid.UUID, stay valueobject.DateRange) ([]Booking, error)
+}
+
+// ReservationCheck validates a reservation against a locked inventory view.
+type ReservationCheck func(ctx context.Context, inv Inventory) error
+
+// AvailabilityService evaluates room type availability (pure domain logic).
+type AvailabilityService struct{}
+
+// NewAvailabilityService creates a new AvailabilityService.
+func NewAvailabilityService() *AvailabilityService {
+	return &AvailabilityService{}
+}
+
+// PeakOccupancy returns the highest number of rooms held on any night of the stay.
+func (s *AvailabilityService) PeakOccupancy(stay valueobject.DateRange, bookings []Booking) int {
+	var holding []valueobject.DateRange
+	for _, b := range bookings {
+		if !b.HoldsInventory() {
+			continue
+		}
+		if other := b.Stay(); stay.Overlaps(other) {
+			holding = append(holding, other)
+		}
+	}
+
+	peak := 0
+	for night := stay.Start; night.Before(stay.End); night = night.Add(24 * time.Hour) {
+		occupied := 0
+		for _, other := range holding {
+			if other.Contains(night) {
+				occupied++
+			}
+		}
+		if occupied > peak {
+			peak = occupied
+		}
+	}
+	return peak
+}
+
+// EnsureAvailable rejects the stay when every bookable room is already held on some night.
+func (s *AvailabilityService) EnsureAvailable(totalRooms int, stay valueobject.DateRange, bookings []Booking) error {
+	if totalRooms <= 0 {
+		return pkgErrors.New("conflict", "no rooms available for this room type")
+	}
+	if s.PeakOccupancy(stay, bookings) >= totalRooms {
+		return pkgErrors.New("conflict", "room type fully booked for requested dates")
+	}
+	return nil
+}
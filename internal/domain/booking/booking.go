@@ -9,6 +9,7 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/pkg/domain"
 	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 const (
@@ -17,6 +18,8 @@ const (
 	StatusCancelled      = "cancelled"
 	StatusCheckedIn      = "checked_in"
 	StatusCompleted      = "completed"
+	StatusExpired        = "expired"
+	StatusNoShow         = "no_show"
 )
 
 // Booking aggregate.
@@ -31,6 +34,8 @@ type Booking struct {
 	TotalPrice  float64
 	TotalNights int
 	CreatedAt   time.Time
+	// ReservationID links the booking to a multi-room reservation (uuid.Nil when standalone).
+	ReservationID uuid.UUID
 
 	// events stores domain events raised by this aggregate
 	events []domain.DomainEvent
@@ -46,16 +51,26 @@ func (b *Booking) Confirm() error {
 	return nil
 }
 
-// Cancel transitions booking to cancelled state.
-func (b *Booking) Cancel(reason string) error {
-	if b.Status == StatusConfirmed || b.Status == StatusCheckedIn || b.Status == StatusCompleted {
-		return pkgErrors.New("bad_request", "cannot cancel booking after confirmation")
+// Cancel transitions booking to cancelled state. Confirmed bookings settle
+// according to quote, computed by CancellationService from the rate's policy.
+func (b *Booking) Cancel(reason string, quote CancellationQuote) error {
+	if b.Status == StatusCheckedIn || b.Status == StatusCompleted {
+		return pkgErrors.New("bad_request", "cannot cancel booking after check-in")
 	}
 	if b.Status == StatusCancelled {
 		return pkgErrors.New("bad_request", "booking already cancelled")
 	}
+	if b.Status == StatusExpired {
+		return pkgErrors.New("bad_request", "booking already expired")
+	}
+	if b.Status == StatusNoShow {
+		return pkgErrors.New("bad_request", "booking already marked as no-show")
+	}
+	if b.Status != StatusConfirmed {
+		quote = CancellationQuote{}
+	}
 	b.Status = StatusCancelled
-	b.RecordEvent(NewBookingCancelled(b.ID, reason))
+	b.RecordEvent(NewBookingCancelled(b.ID, reason, quote))
 	return nil
 }
 
@@ -79,6 +94,42 @@ func (b *Booking) Complete() error {
 	return nil
 }
 
+// Expire releases an unpaid booking whose payment hold has lapsed.
+func (b *Booking) Expire() error {
+	if b.Status != StatusPendingPayment {
+		return pkgErrors.New("bad_request", "only pending payment bookings can expire")
+	}
+	b.Status = StatusExpired
+	b.RecordEvent(NewBookingExpired(b.ID, b.CreatedAt))
+	return nil
+}
+
+// MarkNoShow closes a confirmed booking whose guest never checked in,
+// settling it according to charge.
+func (b *Booking) MarkNoShow(charge CancellationQuote) error {
+	if b.Status != StatusConfirmed {
+		return pkgErrors.New("bad_request", "only confirmed bookings can be marked as no-show")
+	}
+	b.Status = StatusNoShow
+	b.RecordEvent(NewBookingNoShow(b.ID, b.CheckIn, charge))
+	return nil
+}
+
+// Stay returns the booked nights as a date range.
+func (b Booking) Stay() valueobject.DateRange {
+	return valueobject.DateRange{Start: b.CheckIn, End: b.CheckOut}
+}
+
+// HoldsInventory reports whether the booking still occupies a room.
+func (b Booking) HoldsInventory() bool {
+	for _, status := range valueobject.ReleasedBookingStatuses {
+		if b.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
 // Events returns the domain events raised by this aggregate.
 func (b *Booking) Events() []domain.DomainEvent {
 	return b.events
@@ -98,6 +149,17 @@ type BookingReader interface {
 	FindByID(ctx context.Context, id uuid.UUID) (Booking, error)
 	List(ctx context.Context, opts query.Options) ([]Booking, error)
 	FindByUserID(ctx context.Context, userID uuid.UUID) ([]Booking, error)
+	// FindPendingBefore returns pending_payment bookings created before cutoff.
+	FindPendingBefore(ctx context.Context, cutoff time.Time) ([]Booking, error)
+	// FindNoShows returns confirmed bookings whose check-in is before cutoff.
+	FindNoShows(ctx context.Context, cutoff time.Time) ([]Booking, error)
+	// FindDueForCheckout returns up to limit checked_in bookings whose check-out
+	// is before the given time, ordered by ID and starting after the after ID.
+	FindDueForCheckout(ctx context.Context, before time.Time, after uuid.UUID, limit int) ([]Booking, error)
+	FindReservation(ctx context.Context, id uuid.UUID) (Reservation, error)
+	// Search returns the page of bookings matching f and the total match count.
+	Search(ctx context.Context, f Filter) (SearchResult, error)
+	FindAssignment(ctx context.Context, bookingID uuid.UUID) (RoomAssignment, error)
 }
 
 // BookingWriter handles commands (CQRS Write Side).
@@ -105,17 +167,45 @@ type BookingWriter interface {
 	Create(ctx context.Context, b Booking) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
 	Save(ctx context.Context, b Booking) error
+	// Transition saves the new status of b only while the stored booking is
+	// still in from, and fails with conflict otherwise, so concurrent runs
+	// cannot apply the same transition twice.
+	Transition(ctx context.Context, b Booking, from string) error
+	// Reserve runs check against a locked inventory view and creates b only when it passes.
+	Reserve(ctx context.Context, b Booking, check ReservationCheck) error
+	// Amend runs check against a locked inventory view and saves b only when it passes.
+	Amend(ctx context.Context, b Booking, check ReservationCheck) error
+	// ReserveGroup runs check against a locked view of every room type of r and
+	// creates the reservation with all its lines only when it passes.
+	ReserveGroup(ctx context.Context, r Reservation, check ReservationCheck) error
+	// AssignRoom saves b and assigns the free room chosen by pick, marking it occupied.
+	AssignRoom(ctx context.Context, b Booking, pick RoomPicker) (RoomAssignment, error)
+	// ReleaseRoom completes b and closes its active assignment, freeing the room.
+	// It fails with conflict when b is no longer checked in.
+	ReleaseRoom(ctx context.Context, b Booking) error
 }
 
 // Repository handles persistence (combines Read and Write).
 type Repository interface {
 	BookingReader
 	BookingWriter
+	OutboxStore
+	SagaStore
 }
 
 // PaymentGateway used by booking service.
 type PaymentGateway interface {
 	Initiate(ctx context.Context, bookingID uuid.UUID, amount float64) (PaymentResult, error)
+	// Expire marks the pending payment of a booking as failed.
+	Expire(ctx context.Context, bookingID uuid.UUID) error
+	// RequestAdditional opens an adjustment payment collecting amount for a booking.
+	RequestAdditional(ctx context.Context, bookingID uuid.UUID, amount float64) (PaymentResult, error)
+	// Refund returns up to amount of what was paid for a booking; the result
+	// carries the amount actually refunded.
+	Refund(ctx context.Context, bookingID uuid.UUID, amount float64, reason string) (RefundResult, error)
+	// VoidAdjustments fails the unpaid adjustment payments of a booking and
+	// returns their total.
+	VoidAdjustments(ctx context.Context, bookingID uuid.UUID) (float64, error)
 }
 
 // NotificationGateway for events.
@@ -130,3 +220,11 @@ type PaymentResult struct {
 	Provider   string
 	PaymentURL string
 }
+
+// RefundResult carries minimal refund data.
+type RefundResult struct {
+	PaymentID uuid.UUID
+	Amount    float64
+	Status    string
+	Reference string
+}
@@ -0,0 +1,48 @@
+package booking
+
+import (
+	"math"
+	"time"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// CancellationQuote splits the price of a cancelled booking into the penalty
+// kept by the hotel and the amount refunded to the guest.
+type CancellationQuote struct {
+	Penalty float64
+	Refund  float64
+}
+
+// CancellationService evaluates cancellation policies (pure domain logic).
+type CancellationService struct{}
+
+// NewCancellationService creates a new CancellationService.
+func NewCancellationService() *CancellationService {
+	return &CancellationService{}
+}
+
+// Quote prices cancelling b at the given moment under policy. Unpaid bookings
+// carry no penalty; paid ones are refunded in full while still inside the free
+// cancellation window and charged the policy penalty afterwards.
+func (s *CancellationService) Quote(policy valueobject.CancellationPolicy, b Booking, at time.Time) CancellationQuote {
+	if b.Status != StatusConfirmed {
+		return CancellationQuote{}
+	}
+	if policy.NonRefundable {
+		return CancellationQuote{Penalty: b.TotalPrice}
+	}
+	if daysBefore(at, b.CheckIn) >= policy.FreeCancellationDays {
+		return CancellationQuote{Refund: b.TotalPrice}
+	}
+
+	penalty := math.Round(b.TotalPrice*policy.PenaltyPercent) / 100
+	return CancellationQuote{Penalty: penalty, Refund: b.TotalPrice - penalty}
+}
+
+// daysBefore counts whole calendar days from at until checkIn (negative once check-in has passed).
+func daysBefore(at, checkIn time.Time) int {
+	from := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(checkIn.Year(), checkIn.Month(), checkIn.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -1,6 +1,8 @@
 package booking
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/ftryyln/hotel-booking-microservices/pkg/domain"
@@ -13,6 +15,15 @@ const (
 	EventTypeBookingCancelled = "booking.cancelled"
 	EventTypeBookingCheckedIn = "booking.checked_in"
 	EventTypeBookingCompleted = "booking.completed"
+	EventTypeBookingExpired   = "booking.expired"
+	EventTypeBookingModified  = "booking.modified"
+	EventTypeBookingNoShow    = "booking.no_show"
+
+	EventTypeReservationCreated = "reservation.created"
+
+	// EventTypeRefundRequested is a command for the payment service rather
+	// than a notification; the outbox relay retries it until it succeeds.
+	EventTypeRefundRequested = "payment.refund_requested"
 )
 
 // BookingCreated event is raised when a new booking is created.
@@ -54,16 +65,20 @@ func NewBookingConfirmed(bookingID uuid.UUID) BookingConfirmed {
 // BookingCancelled event is raised when a booking is cancelled.
 type BookingCancelled struct {
 	domain.BaseEvent
-	BookingID uuid.UUID
-	Reason    string
+	BookingID    uuid.UUID
+	Reason       string
+	Penalty      float64
+	RefundAmount float64
 }
 
 // NewBookingCancelled creates a new BookingCancelled event.
-func NewBookingCancelled(bookingID uuid.UUID, reason string) BookingCancelled {
+func NewBookingCancelled(bookingID uuid.UUID, reason string, quote CancellationQuote) BookingCancelled {
 	return BookingCancelled{
-		BaseEvent: domain.NewBaseEvent(bookingID, EventTypeBookingCancelled),
-		BookingID: bookingID,
-		Reason:    reason,
+		BaseEvent:    domain.NewBaseEvent(bookingID, EventTypeBookingCancelled),
+		BookingID:    bookingID,
+		Reason:       reason,
+		Penalty:      quote.Penalty,
+		RefundAmount: quote.Refund,
 	}
 }
 
@@ -94,3 +109,101 @@ func NewBookingCompleted(bookingID uuid.UUID) BookingCompleted {
 		BookingID: bookingID,
 	}
 }
+
+// BookingExpired event is raised when an unpaid booking exceeds its payment hold.
+type BookingExpired struct {
+	domain.BaseEvent
+	BookingID uuid.UUID
+	HeldSince time.Time
+}
+
+// NewBookingExpired creates a new BookingExpired event.
+func NewBookingExpired(bookingID uuid.UUID, heldSince time.Time) BookingExpired {
+	return BookingExpired{
+		BaseEvent: domain.NewBaseEvent(bookingID, EventTypeBookingExpired),
+		BookingID: bookingID,
+		HeldSince: heldSince,
+	}
+}
+
+// BookingModified event is raised when a confirmed booking's terms change.
+type BookingModified struct {
+	domain.BaseEvent
+	BookingID  uuid.UUID
+	Before     Terms
+	After      Terms
+	PriceDelta float64
+}
+
+// NewBookingModified creates a new BookingModified event.
+func NewBookingModified(bookingID uuid.UUID, before, after Terms) BookingModified {
+	return BookingModified{
+		BaseEvent:  domain.NewBaseEvent(bookingID, EventTypeBookingModified),
+		BookingID:  bookingID,
+		Before:     before,
+		After:      after,
+		PriceDelta: after.TotalPrice - before.TotalPrice,
+	}
+}
+
+// BookingNoShow event is raised when a guest never arrives for a confirmed booking.
+type BookingNoShow struct {
+	domain.BaseEvent
+	BookingID    uuid.UUID
+	CheckIn      time.Time
+	Charge       float64
+	RefundAmount float64
+}
+
+// NewBookingNoShow creates a new BookingNoShow event.
+func NewBookingNoShow(bookingID uuid.UUID, checkIn time.Time, charge CancellationQuote) BookingNoShow {
+	return BookingNoShow{
+		BaseEvent:    domain.NewBaseEvent(bookingID, EventTypeBookingNoShow),
+		BookingID:    bookingID,
+		CheckIn:      checkIn,
+		Charge:       charge.Penalty,
+		RefundAmount: charge.Refund,
+	}
+}
+
+// ReservationCreated event is raised when several rooms are booked together.
+type ReservationCreated struct {
+	domain.BaseEvent
+	ReservationID uuid.UUID
+	UserID        uuid.UUID
+	Rooms         int
+	TotalPrice    float64
+}
+
+// NewReservationCreated creates a new ReservationCreated event.
+func NewReservationCreated(reservationID, userID uuid.UUID, rooms int, totalPrice float64) ReservationCreated {
+	return ReservationCreated{
+		BaseEvent:     domain.NewBaseEvent(reservationID, EventTypeReservationCreated),
+		ReservationID: reservationID,
+		UserID:        userID,
+		Rooms:         rooms,
+		TotalPrice:    totalPrice,
+	}
+}
+
+// RefundRequested asks the payment service to refund part of a booking's
+// payment. PaymentBookingID is the booking the payment is recorded under,
+// which differs from BookingID for reservation lines.
+type RefundRequested struct {
+	domain.BaseEvent
+	BookingID        uuid.UUID
+	PaymentBookingID uuid.UUID
+	Amount           float64
+	Reason           string
+}
+
+// NewRefundRequested creates a new RefundRequested event.
+func NewRefundRequested(bookingID, paymentBookingID uuid.UUID, amount float64, reason string) RefundRequested {
+	return RefundRequested{
+		BaseEvent:        domain.NewBaseEvent(bookingID, EventTypeRefundRequested),
+		BookingID:        bookingID,
+		PaymentBookingID: paymentBookingID,
+		Amount:           amount,
+		Reason:           reason,
+	}
+}
@@ -0,0 +1,37 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+)
+
+// SortableFields lists the booking fields a search can be ordered by.
+var SortableFields = []string{"check_in", "check_out", "created_at", "total_price", "status"}
+
+// Filter narrows a booking search. Zero values leave a criterion unset;
+// ranges are inclusive of From and exclusive of To.
+type Filter struct {
+	UserID     uuid.UUID
+	Statuses   []string
+	HotelID    uuid.UUID
+	RoomTypeID uuid.UUID
+
+	CheckInFrom  time.Time
+	CheckInTo    time.Time
+	CheckOutFrom time.Time
+	CheckOutTo   time.Time
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+
+	Sort []query.Sort
+	Page query.Options
+}
+
+// SearchResult is one page of bookings plus the total number of matches.
+type SearchResult struct {
+	Bookings []Booking
+	Total    int64
+}
@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// Terms captures the priced, amendable part of a booking.
+type Terms struct {
+	RoomTypeID  uuid.UUID
+	CheckIn     time.Time
+	CheckOut    time.Time
+	Guests      int
+	TotalNights int
+	TotalPrice  float64
+}
+
+// Stay returns the terms' nights as a date range.
+func (t Terms) Stay() valueobject.DateRange {
+	return valueobject.DateRange{Start: t.CheckIn, End: t.CheckOut}
+}
+
+// SameStay reports whether both terms hold the same inventory and guest count.
+func (t Terms) SameStay(other Terms) bool {
+	return t.RoomTypeID == other.RoomTypeID &&
+		t.CheckIn.Equal(other.CheckIn) &&
+		t.CheckOut.Equal(other.CheckOut) &&
+		t.Guests == other.Guests
+}
+
+// Terms returns the booking's current terms.
+func (b Booking) Terms() Terms {
+	return Terms{
+		RoomTypeID:  b.RoomTypeID,
+		CheckIn:     b.CheckIn,
+		CheckOut:    b.CheckOut,
+		Guests:      b.Guests,
+		TotalNights: b.TotalNights,
+		TotalPrice:  b.TotalPrice,
+	}
+}
+
+// Modify replaces the booking terms with repriced ones. Only confirmed
+// bookings can be amended; unpaid bookings should be cancelled and rebooked.
+func (b *Booking) Modify(after Terms) error {
+	if b.Status != StatusConfirmed {
+		return pkgErrors.New("bad_request", "only confirmed bookings can be modified")
+	}
+	before := b.Terms()
+	if before.SameStay(after) {
+		return pkgErrors.New("bad_request", "no changes requested")
+	}
+
+	b.RoomTypeID = after.RoomTypeID
+	b.CheckIn = after.CheckIn
+	b.CheckOut = after.CheckOut
+	b.Guests = after.Guests
+	b.TotalNights = after.TotalNights
+	b.TotalPrice = after.TotalPrice
+	b.RecordEvent(NewBookingModified(b.ID, before, after))
+	return nil
+}
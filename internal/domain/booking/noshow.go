@@ -0,0 +1,24 @@
+package booking
+
+import "math"
+
+// NoShowPolicy decides how much of a no-show booking is kept.
+type NoShowPolicy struct {
+	// ChargeNights is the number of nights charged; a negative value charges the whole stay.
+	ChargeNights int
+}
+
+// Quote splits the booking price into the no-show charge and the refund of
+// the nights that are released.
+func (p NoShowPolicy) Quote(b Booking) CancellationQuote {
+	nights := b.TotalNights
+	if nights <= 0 {
+		nights = b.Stay().Nights()
+	}
+	if p.ChargeNights < 0 || nights <= 0 || p.ChargeNights >= nights {
+		return CancellationQuote{Penalty: b.TotalPrice}
+	}
+
+	charge := math.Round(b.TotalPrice*float64(p.ChargeNights)/float64(nights)*100) / 100
+	return CancellationQuote{Penalty: charge, Refund: b.TotalPrice - charge}
+}
@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+)
+
+// Outbox delivery states.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+// MaxOutboxAttempts is how many deliveries are tried before a message is
+// parked as failed and left for an operator to replay.
+const MaxOutboxAttempts = 8
+
+// OutboxMessage is a domain event stored in the same transaction as the
+// booking change that raised it, waiting to be relayed to notifications.
+type OutboxMessage struct {
+	ID            uuid.UUID
+	AggregateID   uuid.UUID
+	EventType     string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+// OutboxRetryDelay returns how long to wait after the given number of failed
+// attempts: 30s doubling per attempt, capped at one hour.
+func OutboxRetryDelay(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// OutboxStore tracks delivery of events written by the booking repository.
+// Every BookingWriter method stores the pending events of the bookings it
+// persists in the same transaction.
+type OutboxStore interface {
+	// ClaimOutbox leases up to limit pending messages due at now so that
+	// concurrent relays do not deliver the same message.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, at time.Time) error
+	// MarkAttemptFailed records a failed delivery; the message is retried at
+	// next, or parked as failed when giveUp is set.
+	MarkAttemptFailed(ctx context.Context, id uuid.UUID, reason string, next time.Time, giveUp bool) error
+	ListOutbox(ctx context.Context, status string, opts query.Options) ([]OutboxMessage, error)
+	FindOutbox(ctx context.Context, id uuid.UUID) (OutboxMessage, error)
+	// ReplayOutbox puts a message back to pending for immediate delivery.
+	ReplayOutbox(ctx context.Context, id uuid.UUID) error
+}
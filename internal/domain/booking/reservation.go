@@ -0,0 +1,58 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// MaxReservationLines caps how many rooms one reservation can hold.
+const MaxReservationLines = 10
+
+// Reservation groups several booking lines made together under one payment.
+// The lead line carries the combined payment; every line keeps its own
+// lifecycle so rooms can be cancelled individually.
+type Reservation struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	LeadBookingID uuid.UUID
+	Lines         []Booking
+	CreatedAt     time.Time
+}
+
+// NewReservation groups lines into a reservation; the first line leads.
+func NewReservation(userID uuid.UUID, lines []Booking) (Reservation, error) {
+	if len(lines) == 0 {
+		return Reservation{}, pkgErrors.New("bad_request", "reservation needs at least one room")
+	}
+	if len(lines) > MaxReservationLines {
+		return Reservation{}, pkgErrors.New("bad_request", "too many rooms in one reservation")
+	}
+
+	r := Reservation{
+		ID:            uuid.New(),
+		UserID:        userID,
+		LeadBookingID: lines[0].ID,
+		Lines:         make([]Booking, len(lines)),
+		CreatedAt:     time.Now(),
+	}
+	for i, line := range lines {
+		line.ReservationID = r.ID
+		line.UserID = userID
+		r.Lines[i] = line
+	}
+	return r, nil
+}
+
+// TotalPrice sums the price of every line still holding inventory.
+func (r Reservation) TotalPrice() float64 {
+	total := 0.0
+	for _, line := range r.Lines {
+		if line.HoldsInventory() {
+			total += line.TotalPrice
+		}
+	}
+	return total
+}
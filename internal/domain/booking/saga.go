@@ -0,0 +1,113 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Steps of the booking creation saga, in order. Reserving inventory and
+// creating the booking commit in one transaction, so they form one step.
+const (
+	SagaStepStarted          = "started"
+	SagaStepReserved         = "reserved"
+	SagaStepPaymentInitiated = "payment_initiated"
+	SagaStepNotified         = "notified"
+)
+
+var sagaSteps = []string{SagaStepStarted, SagaStepReserved, SagaStepPaymentInitiated, SagaStepNotified}
+
+// Saga states.
+const (
+	SagaRunning      = "running"
+	SagaCompleted    = "completed"
+	SagaCompensating = "compensating"
+	SagaCompensated  = "compensated"
+)
+
+// Saga tracks the creation of a booking, or of every line of a reservation,
+// across inventory, payment and notification so that a failed or interrupted
+// creation can be compensated instead of leaving an orphan booking behind.
+type Saga struct {
+	ID            uuid.UUID
+	BookingID     uuid.UUID // booking, or lead line, the payment is opened on
+	ReservationID uuid.UUID
+	Amount        float64
+	Step          string
+	Status        string
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// NewCreationSaga starts a saga creating bookingID, optionally as the lead of reservationID.
+func NewCreationSaga(bookingID, reservationID uuid.UUID, amount float64) Saga {
+	now := time.Now()
+	return Saga{
+		ID:            uuid.New(),
+		BookingID:     bookingID,
+		ReservationID: reservationID,
+		Amount:        amount,
+		Step:          SagaStepStarted,
+		Status:        SagaRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Advance records that step has completed.
+func (s *Saga) Advance(step string) {
+	s.Step = step
+	if step == SagaStepNotified {
+		s.Status = SagaCompleted
+	}
+}
+
+// Reached reports whether step has completed.
+func (s Saga) Reached(step string) bool {
+	return stepIndex(s.Step) >= stepIndex(step)
+}
+
+// RecordFailure notes why the next step failed. The saga stays at its last
+// completed step, so recovery retries from there.
+func (s *Saga) RecordFailure(reason string) {
+	s.LastError = reason
+}
+
+// Compensate switches the saga to undoing its completed steps.
+func (s *Saga) Compensate(reason string) {
+	s.Status = SagaCompensating
+	if reason != "" {
+		s.LastError = reason
+	}
+}
+
+// Compensated records that every completed step has been undone.
+func (s *Saga) Compensated() {
+	s.Status = SagaCompensated
+}
+
+// Finished reports whether the saga has nothing left to do.
+func (s Saga) Finished() bool {
+	return s.Status == SagaCompleted || s.Status == SagaCompensated
+}
+
+func stepIndex(step string) int {
+	for i, candidate := range sagaSteps {
+		if candidate == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// SagaStore persists saga state so an interrupted saga can be resumed.
+type SagaStore interface {
+	// SaveSaga stores s together with the pending events of publish in one transaction.
+	SaveSaga(ctx context.Context, s Saga, publish ...Booking) error
+	FindSaga(ctx context.Context, id uuid.UUID) (Saga, error)
+	// ClaimStaleSagas returns up to limit unfinished sagas without progress
+	// since before, marking them updated at now so concurrent recoveries skip them.
+	ClaimStaleSagas(ctx context.Context, before, now time.Time, limit int) ([]Saga, error)
+}
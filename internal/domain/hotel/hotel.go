@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // Hotel entity.
@@ -16,6 +17,8 @@ type Hotel struct {
 	Description string
 	Address     string
 	CreatedAt   time.Time
+	// CancellationPolicy is the hotel-wide default; nil falls back to the platform default.
+	CancellationPolicy *valueobject.CancellationPolicy
 }
 
 // RoomType entity.
@@ -26,6 +29,20 @@ type RoomType struct {
 	Capacity  int
 	BasePrice float64
 	Amenities string
+	// CancellationPolicy overrides the hotel policy for this rate when set.
+	CancellationPolicy *valueobject.CancellationPolicy
+}
+
+// EffectiveCancellationPolicy resolves the policy for a room type of hotel h:
+// the room type override first, then the hotel policy, then the default.
+func (rt RoomType) EffectiveCancellationPolicy(h Hotel) valueobject.CancellationPolicy {
+	if rt.CancellationPolicy != nil {
+		return *rt.CancellationPolicy
+	}
+	if h.CancellationPolicy != nil {
+		return *h.CancellationPolicy
+	}
+	return valueobject.DefaultCancellationPolicy
 }
 
 // Room entity.
@@ -36,8 +53,32 @@ type Room struct {
 	Status     string
 }
 
+// NightlyInventory is the read model of one room type's inventory on one night.
+type NightlyInventory struct {
+	RoomTypeID  uuid.UUID
+	Night       time.Time
+	TotalRooms  int
+	OutOfOrder  int
+	BookedRooms int
+}
+
+// Remaining returns rooms still sellable for the night.
+func (n NightlyInventory) Remaining() int {
+	remaining := n.TotalRooms - n.OutOfOrder - n.BookedRooms
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// AvailabilityReader builds per-night inventory from rooms and bookings.
+type AvailabilityReader interface {
+	NightlyInventory(ctx context.Context, roomTypeIDs []uuid.UUID, stay valueobject.DateRange) ([]NightlyInventory, error)
+}
+
 // Repository contract.
 type Repository interface {
+	AvailabilityReader
 	CreateHotel(ctx context.Context, h Hotel) error
 	ListHotels(ctx context.Context, opts query.Options) ([]Hotel, error)
 	GetHotel(ctx context.Context, id uuid.UUID) (Hotel, error)
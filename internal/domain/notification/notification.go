@@ -3,6 +3,8 @@ package notification
 import (
 	"context"
 	"time"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
 // Dispatcher sends notifications.
@@ -10,11 +12,116 @@ type Dispatcher interface {
 	Dispatch(ctx context.Context, target, message string) error
 }
 
-// Notification represents stored notification metadata.
+// Delivery statuses of a notification. A failed notification is retried at
+// NextAttemptAt; a dead-lettered one waits for an operator to retry it.
+const (
+	StatusQueued     = "queued"
+	StatusSent       = "sent"
+	StatusFailed     = "failed"
+	StatusDeadLetter = "dead_letter"
+)
+
+// Statuses lists every delivery status.
+var Statuses = []string{StatusQueued, StatusSent, StatusFailed, StatusDeadLetter}
+
+// MaxAttempts is how many deliveries are tried before a notification is
+// dead-lettered.
+const MaxAttempts = 5
+
+// Notification represents stored notification metadata and its delivery state.
 type Notification struct {
-	ID        string
-	Type      string
-	Target    string
-	Message   string
-	CreatedAt time.Time
+	ID            string
+	Type          string
+	Target        string
+	Message       string
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// RetryDelay returns how long to wait after the given number of failed
+// attempts: 10s doubling per attempt, capped at 30 minutes.
+func RetryDelay(attempts int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempts && delay < 30*time.Minute; i++ {
+		delay *= 2
+	}
+	if delay > 30*time.Minute {
+		delay = 30 * time.Minute
+	}
+	return delay
+}
+
+// MarkSent records a successful delivery attempt.
+func (n *Notification) MarkSent(at time.Time) {
+	n.Status = StatusSent
+	n.Attempts++
+	n.LastError = ""
+	n.SentAt = &at
+	n.UpdatedAt = at
+}
+
+// MarkFailed records a failed delivery attempt and its cause, scheduling a
+// retry with backoff or dead-lettering the notification after MaxAttempts.
+func (n *Notification) MarkFailed(cause error, at time.Time) {
+	n.Attempts++
+	n.LastError = cause.Error()
+	n.UpdatedAt = at
+	if n.Attempts >= MaxAttempts {
+		n.Status = StatusDeadLetter
+		return
+	}
+	n.Status = StatusFailed
+	n.NextAttemptAt = at.Add(RetryDelay(n.Attempts))
+}
+
+// Requeue puts a dead-lettered notification back in the queue for immediate
+// delivery with a fresh attempt budget. The last error is kept for reference.
+func (n *Notification) Requeue(at time.Time) {
+	n.Status = StatusQueued
+	n.Attempts = 0
+	n.NextAttemptAt = at
+	n.UpdatedAt = at
+}
+
+// Erased replaces the target and message of notifications whose recipient
+// asked for their personal data to be erased.
+const Erased = "[erased]"
+
+// Filter narrows a notification search. Zero values leave a criterion unset;
+// the created range is inclusive of From and exclusive of To.
+type Filter struct {
+	Type        string
+	Target      string
+	Status      string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	Page query.Options
+}
+
+// SearchResult is one page of notifications, newest first, plus the total
+// number of matches.
+type SearchResult struct {
+	Notifications []Notification
+	Total         int64
+}
+
+// Repository persists notifications and their delivery state.
+type Repository interface {
+	Create(ctx context.Context, n Notification) error
+	// UpdateDelivery stores the status, attempts, last error and timestamps of n.
+	UpdateDelivery(ctx context.Context, n Notification) error
+	// ClaimDue leases up to limit queued or failed notifications due at now
+	// so that concurrent workers do not deliver the same one.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Notification, error)
+	FindByID(ctx context.Context, id string) (Notification, error)
+	Search(ctx context.Context, f Filter) (SearchResult, error)
+	// EraseTarget redacts every notification sent to target and returns how
+	// many were changed. Undelivered ones are dead-lettered, not sent.
+	EraseTarget(ctx context.Context, target string) (int64, error)
 }
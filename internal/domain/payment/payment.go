@@ -13,18 +13,37 @@ const (
 	StatusFailed  = "failed"
 )
 
+const (
+	// KindBooking is the payment that settles a booking and drives its status.
+	KindBooking = "booking"
+	// KindAdjustment is a supplementary charge, e.g. after a booking modification.
+	KindAdjustment = "adjustment"
+)
+
 // Payment aggregates payment state.
 type Payment struct {
-	ID         uuid.UUID
-	BookingID  uuid.UUID
-	Amount     float64
-	Currency   string
-	Status     string
-	Provider   string
-	PaymentURL string
-	WebhookPayload  string
+	ID        uuid.UUID
+	BookingID uuid.UUID
+	Amount    float64
+	Currency  string
+	Status    string
+	Kind      string
+	// Refunded is the running total returned to the customer so far.
+	Refunded         float64
+	Provider         string
+	PaymentURL       string
+	WebhookPayload   string
 	WebhookSignature string
-	CreatedAt  time.Time
+	CreatedAt        time.Time
+}
+
+// Refundable is what can still be returned from the payment: the collected
+// amount less earlier refunds. Unpaid payments have nothing to refund.
+func (p Payment) Refundable() float64 {
+	if p.Status != StatusPaid || p.Refunded >= p.Amount {
+		return 0
+	}
+	return p.Amount - p.Refunded
 }
 
 // Provider integrates external gateway.
@@ -39,7 +58,16 @@ type Repository interface {
 	Create(ctx context.Context, p Payment) error
 	FindByID(ctx context.Context, id uuid.UUID) (Payment, error)
 	FindByBookingID(ctx context.Context, bookingID uuid.UUID) (Payment, error)
+	// ListByBooking returns the booking payment and adjustments of a booking, oldest first.
+	ListByBooking(ctx context.Context, bookingID uuid.UUID) ([]Payment, error)
 	UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentURL, rawPayload, signature string) error
+	// TransitionStatus moves a payment from one status to another and
+	// reports false when it was no longer in from.
+	TransitionStatus(ctx context.Context, id uuid.UUID, from, to string) (bool, error)
+	// AddRefunded adds amount to the refunded total of a paid payment. It
+	// reports false, leaving the total unchanged, when the result would
+	// exceed the payment amount or drop below zero.
+	AddRefunded(ctx context.Context, id uuid.UUID, amount float64) (bool, error)
 }
 
 // BookingStatusUpdater notifies booking service.
@@ -2,8 +2,12 @@ package authhttp
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -19,35 +23,67 @@ import (
 
 // Handler wires HTTP routes to auth service.
 type Handler struct {
-	service   *uc.Service
-	jwtSecret string
+	service  *uc.Service
+	verifier *middleware.Verifier
+	revoked  *middleware.RevocationList
+	keys     KeyPublisher
+	proxies  trustedProxies
 }
 
-func NewHandler(service *uc.Service, jwtSecret string) *Handler {
-	return &Handler{service: service, jwtSecret: jwtSecret}
+// KeyPublisher exposes the public keys tokens are verified with.
+type KeyPublisher interface {
+	JWKS() middleware.JWKSet
+}
+
+// NewHandler builds the auth handler. trustedProxies lists the addresses,
+// CIDR ranges or host names of the proxies (the gateway) whose
+// X-Forwarded-For header is believed; see clientIP.
+func NewHandler(service *uc.Service, verifier *middleware.Verifier, revoked *middleware.RevocationList, keys KeyPublisher, trustedProxies []string) *Handler {
+	return &Handler{service: service, verifier: verifier, revoked: revoked, keys: keys, proxies: parseTrustedProxies(trustedProxies)}
 }
 
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Post("/register", h.register)
 	r.Post("/login", h.login)
-	r.Get("/me/{id}", h.me)
+	r.Post("/refresh", h.refresh)
+	r.Post("/password/forgot", h.forgotPassword)
+	r.Post("/password/reset", h.resetPassword)
+	r.Post("/verify-email", h.verifyEmail)
+	r.Post("/mfa/challenge", h.mfaChallenge)
+	r.Get("/.well-known/jwks.json", h.jwks)
+	r.With(middleware.Authenticate(h.verifier, h.revoked, middleware.RoleService)).
+		Get("/revocations", h.revocations)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.JWT(h.jwtSecret))
+		r.Use(middleware.Authenticate(h.verifier, h.revoked))
+		r.Post("/logout", h.logout)
+		r.Patch("/me", h.updateProfile)
+		r.Delete("/me", h.eraseAccount)
+		r.Post("/me/password", h.changePassword)
+		r.Get("/me/export", h.exportData)
+		r.Get("/me/{id}", h.me)
+		r.Post("/verify-email/resend", h.resendVerification)
+		r.Post("/mfa/setup", h.mfaSetup)
+		r.Post("/mfa/verify", h.mfaVerify)
 		r.Get("/users", h.listUsers)
 		r.Get("/users/{id}", h.getUser)
+		r.Post("/users/{id}/unlock", h.unlockUser)
+		r.Get("/users/{id}/hotel-roles", h.listHotelRoles)
+		r.Post("/users/{id}/hotel-roles", h.assignHotelRole)
+		r.Delete("/users/{id}/hotel-roles/{hotel_id}/{role}", h.removeHotelRole)
 	})
 	return r
 }
 
 // @Summary Register user
-// @Description Create a new user with customer or admin role.
+// @Description Create a new customer account. Any other role is rejected; staff and admin rights are granted by an admin.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body dto.RegisterRequest true "Register payload"
 // @Success 201 {object} dto.AuthResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 409 {object} dto.ErrorResponse
 // @Router /auth/register [post]
 func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
@@ -66,7 +102,7 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Login
-// @Description Authenticate user and return JWT tokens.
+// @Description Authenticate user and return JWT tokens. Failed attempts delay the account's next attempt progressively and eventually lock it; an address failing across accounts is blocked. Throttled attempts answer 429 with Retry-After.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -74,6 +110,7 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} dto.AuthResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
 // @Router /auth/login [post]
 func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 	var req dto.LoginRequest
@@ -81,22 +118,309 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
 		return
 	}
-	resp, err := h.service.Login(r.Context(), req)
+	resp, err := h.service.Login(r.Context(), req, h.clientIP(r))
 	if err != nil {
-		writeError(w, pkgErrors.FromError(err))
+		writeThrottledError(w, pkgErrors.FromError(err))
+		return
+	}
+	resource := utils.NewResource(resp.ID, "user", "/auth/me/"+resp.ID, resp)
+	if resp.MFARequired {
+		utils.Respond(w, http.StatusOK, "mfa required", resource)
+		return
+	}
+	utils.Respond(w, http.StatusOK, "login succeeded", resource)
+}
+
+// @Summary Complete MFA login
+// @Description Exchange the mfa_token returned by login, valid for five minutes, and a TOTP or recovery code for tokens. Wrong codes count as failed logins.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAChallengeRequest true "MFA challenge payload"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Router /auth/mfa/challenge [post]
+func (h *Handler) mfaChallenge(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	resp, err := h.service.CompleteMFALogin(r.Context(), req, h.clientIP(r))
+	if err != nil {
+		writeThrottledError(w, pkgErrors.FromError(err))
 		return
 	}
 	resource := utils.NewResource(resp.ID, "user", "/auth/me/"+resp.ID, resp)
 	utils.Respond(w, http.StatusOK, "login succeeded", resource)
 }
 
+// @Summary Start MFA setup
+// @Description Generate a TOTP secret for the signed-in user. MFA is enabled once /auth/mfa/verify accepts a code; admin routes require tokens issued after a second factor.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.MFASetupResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/mfa/setup [post]
+func (h *Handler) mfaSetup(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp, err := h.service.SetupMFA(r.Context(), userID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resource := utils.NewResource(userID.String(), "mfa_setup", "/auth/mfa/setup", resp)
+	utils.Respond(w, http.StatusOK, "mfa setup started", resource)
+}
+
+// @Summary Verify MFA setup
+// @Description Enable MFA with a code from the authenticator and return ten single-use recovery codes, shown only once. Sign in again to get tokens carrying the second factor.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAVerifyRequest true "MFA verify payload"
+// @Success 200 {object} dto.MFAVerifyResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/mfa/verify [post]
+func (h *Handler) mfaVerify(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	resp, err := h.service.VerifyMFA(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resource := utils.NewResource(userID.String(), "mfa_recovery_codes", "/auth/mfa/verify", resp)
+	utils.Respond(w, http.StatusOK, "mfa enabled", resource)
+}
+
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access/refresh pair. Refresh tokens are single use; replaying one revokes every token issued from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshRequest true "Refresh payload"
+// @Success 200 {object} dto.AuthResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	resp, err := h.service.Refresh(r.Context(), req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resource := utils.NewResource(resp.ID, "user", "/auth/me/"+resp.ID, resp)
+	utils.Respond(w, http.StatusOK, "tokens refreshed", resource)
+}
+
+// @Summary Logout
+// @Description Revoke the access token used for this request until it expires. When a refresh token is supplied, every refresh token issued from the same login is revoked as well.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.LogoutRequest false "Logout payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeError(w, pkgErrors.New("unauthorized", "missing claims"))
+		return
+	}
+	var req dto.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	tokenID, _ := uuid.Parse(claims.ID)
+	userID, _ := uuid.Parse(claims.UserID)
+	if claims.ExpiresAt == nil {
+		writeError(w, pkgErrors.New("bad_request", "token cannot be revoked"))
+		return
+	}
+	cmd := assembler.LogoutCommand{
+		UserID:       userID,
+		TokenID:      tokenID,
+		ExpiresAt:    claims.ExpiresAt.Time,
+		RefreshToken: req.RefreshToken,
+	}
+	if err := h.service.Logout(r.Context(), cmd); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	h.revoked.Add(claims.ID, claims.ExpiresAt.Time)
+	utils.Respond(w, http.StatusOK, "logged out", dto.SuccessResponse{
+		ID:      claims.ID,
+		Message: "token revoked",
+	})
+}
+
+// @Summary Forgot password
+// @Description Mail a single-use password reset link valid for one hour. The response is the same whether or not an account exists for the email.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Forgot password payload"
+// @Success 202 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/password/forgot [post]
+func (h *Handler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	if err := h.service.ForgotPassword(r.Context(), req); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusAccepted, "password reset requested", dto.SuccessResponse{
+		Message: "if an account exists for this email, a reset link has been sent",
+	})
+}
+
+// @Summary Reset password
+// @Description Set a new password with a reset token. The token works once, and every refresh token of the user is revoked.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "Reset password payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/password/reset [post]
+func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	if err := h.service.ResetPassword(r.Context(), req); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusOK, "password reset", dto.SuccessResponse{
+		Message: "password updated; sign in again",
+	})
+}
+
+// @Summary Verify email
+// @Description Confirm the email address with a verification token. Tokens issued afterwards, including on refresh, allow booking.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Verify email payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *Handler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	if err := h.service.VerifyEmail(r.Context(), req); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusOK, "email verified", dto.SuccessResponse{
+		Message: "email address verified",
+	})
+}
+
+// @Summary Resend verification email
+// @Description Mail a new email verification link to the signed-in user.
+// @Tags Auth
+// @Produce json
+// @Success 202 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/verify-email/resend [post]
+func (h *Handler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	if err := h.service.ResendVerification(r.Context(), userID); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusAccepted, "verification email sent", dto.SuccessResponse{
+		ID:      userID.String(),
+		Message: "verification link sent",
+	})
+}
+
+// @Summary List revoked tokens (service)
+// @Description Access token IDs revoked before their expiry, pulled periodically by services and the gateway to reject revoked tokens.
+// @Tags Internal
+// @Produce json
+// @Success 200 {object} dto.RevocationListResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/revocations [get]
+func (h *Handler) revocations(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.service.Revocations(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToRevocationList(tokens)
+	resource := utils.NewResource("revocations", "revocation_list", "/auth/revocations", resp)
+	utils.Respond(w, http.StatusOK, "revoked tokens listed", resource)
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys access and refresh tokens are signed with, identified by the kid token header. Services cache this set to verify tokens.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} middleware.JWKSet
+// @Router /auth/.well-known/jwks.json [get]
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = json.NewEncoder(w).Encode(h.keys.JWKS())
+}
+
 // @Summary Get profile
-// @Description Retrieve profile information for a user ID.
+// @Description Retrieve profile information for a user ID. Users see their own profile; admins see any.
 // @Tags Auth
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} dto.ProfileResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /auth/me/{id} [get]
@@ -107,6 +431,11 @@ func (h *Handler) me(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid id"))
 		return
 	}
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || (!claims.Admin() && claims.UserID != userID.String()) {
+		writeError(w, pkgErrors.New("forbidden", "insufficient role"))
+		return
+	}
 	user, err := h.service.Me(r.Context(), userID)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
@@ -117,6 +446,146 @@ func (h *Handler) me(w http.ResponseWriter, r *http.Request) {
 	utils.Respond(w, http.StatusOK, "profile retrieved", resource)
 }
 
+// @Summary Update profile
+// @Description Change the signed-in user's name, phone (E.164), locale (e.g. en-US) or preferences. Omitted fields keep their value; an empty string clears one.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.UpdateProfileRequest true "Profile payload"
+// @Success 200 {object} dto.ProfileResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/me [patch]
+func (h *Handler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	user, err := h.service.UpdateProfile(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToProfile(user)
+	resource := utils.NewResource(resp.ID, "user", "/auth/me/"+resp.ID, resp)
+	utils.Respond(w, http.StatusOK, "profile updated", resource)
+}
+
+// @Summary Change password
+// @Description Set a new password after confirming the current one. Every refresh token of the user is revoked; wrong passwords count as failed logins.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangePasswordRequest true "Change password payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/me/password [post]
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	if err := h.service.ChangePassword(r.Context(), userID, req); err != nil {
+		writeThrottledError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusOK, "password changed", dto.SuccessResponse{
+		ID:      userID.String(),
+		Message: "password updated; sign in again",
+	})
+}
+
+// @Summary Export personal data
+// @Description Download everything the platform holds about the signed-in user: profile, staff roles, bookings, payments and notifications.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.DataExportResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/me/export [get]
+func (h *Handler) exportData(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp, err := h.service.Export(r.Context(), userID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="personal-data.json"`)
+	resource := utils.NewResource(userID.String(), "personal_data_export", "/auth/me/export", resp)
+	utils.Respond(w, http.StatusOK, "personal data exported", resource)
+}
+
+// @Summary Erase account
+// @Description Anonymize the signed-in user after confirming the password: email, password, profile, second factor and staff roles are removed, notifications sent to the user are redacted and every token is revoked. Bookings and payments are kept as financial records under the anonymized user ID.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EraseAccountRequest true "Erase payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/me [delete]
+func (h *Handler) eraseAccount(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeError(w, pkgErrors.New("unauthorized", "missing claims"))
+		return
+	}
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.EraseAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	if err := h.service.Erase(r.Context(), userID, req); err != nil {
+		writeThrottledError(w, pkgErrors.FromError(err))
+		return
+	}
+	// The account is gone either way; a token that cannot be revoked
+	// simply expires.
+	if tokenID, err := uuid.Parse(claims.ID); err == nil && claims.ExpiresAt != nil {
+		cmd := assembler.LogoutCommand{UserID: userID, TokenID: tokenID, ExpiresAt: claims.ExpiresAt.Time}
+		if h.service.Logout(r.Context(), cmd) == nil {
+			h.revoked.Add(claims.ID, claims.ExpiresAt.Time)
+		}
+	}
+	utils.Respond(w, http.StatusOK, "account erased", dto.SuccessResponse{
+		ID:      userID.String(),
+		Message: "personal data erased",
+	})
+}
+
 // @Summary List users (admin)
 // @Tags Auth
 // @Produce json
@@ -127,7 +596,7 @@ func (h *Handler) me(w http.ResponseWriter, r *http.Request) {
 // @Router /auth/users [get]
 func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
 	if !isAdmin(r) {
-		writeError(w, pkgErrors.New("forbidden", "admin only"))
+		writeError(w, pkgErrors.New("forbidden", "admin only; sign in with multi-factor authentication"))
 		return
 	}
 	opts := parseQueryOptions(r)
@@ -160,8 +629,8 @@ func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid id"))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.AuthContextKey).(*middleware.Claims)
-	if !ok || (claims.Role != "admin" && claims.UserID != userID.String()) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || (!claims.Admin() && claims.UserID != userID.String()) {
 		writeError(w, pkgErrors.New("forbidden", "insufficient role"))
 		return
 	}
@@ -175,13 +644,242 @@ func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 	utils.Respond(w, http.StatusOK, "user retrieved", resource)
 }
 
+// @Summary Unlock user (admin)
+// @Description Lift a lockout caused by failed logins and reset the user's failed-attempt count.
+// @Tags Auth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.ProfileResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/users/{id}/unlock [post]
+func (h *Handler) unlockUser(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		writeError(w, pkgErrors.New("forbidden", "admin only; sign in with multi-factor authentication"))
+		return
+	}
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	adminID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	user, err := h.service.Unlock(r.Context(), userID, adminID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	dto := assembler.ToProfile(user)
+	resource := utils.NewResource(dto.ID, "user", "/auth/users/"+dto.ID, dto)
+	utils.Respond(w, http.StatusOK, "user unlocked", resource)
+}
+
+// @Summary List hotel staff roles (admin)
+// @Tags Auth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} dto.HotelRoleResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/users/{id}/hotel-roles [get]
+func (h *Handler) listHotelRoles(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		writeError(w, pkgErrors.New("forbidden", "admin only; sign in with multi-factor authentication"))
+		return
+	}
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	grants, err := h.service.HotelRoles(r.Context(), userID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var resources []utils.Resource
+	for _, g := range assembler.ToHotelRoles(grants) {
+		resources = append(resources, utils.NewResource(g.HotelID+":"+g.Role, "hotel_role", "/auth/users/"+g.UserID+"/hotel-roles", g))
+	}
+	utils.RespondWithCount(w, http.StatusOK, "hotel roles listed", resources, len(resources))
+}
+
+// @Summary Assign hotel staff role (admin)
+// @Description Grant hotel_manager or front_desk at one hotel. The user's tokens carry the grant from their next login or refresh.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body dto.HotelRoleRequest true "Hotel role payload"
+// @Success 201 {object} dto.HotelRoleResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/users/{id}/hotel-roles [post]
+func (h *Handler) assignHotelRole(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		writeError(w, pkgErrors.New("forbidden", "admin only; sign in with multi-factor authentication"))
+		return
+	}
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	adminID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.HotelRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	grant, err := h.service.AssignHotelRole(r.Context(), userID, adminID, req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToHotelRole(grant)
+	resource := utils.NewResource(resp.HotelID+":"+resp.Role, "hotel_role", "/auth/users/"+resp.UserID+"/hotel-roles", resp)
+	utils.Respond(w, http.StatusCreated, "hotel role assigned", resource)
+}
+
+// @Summary Remove hotel staff role (admin)
+// @Description Revoke a grant. Access tokens already issued keep it until they expire.
+// @Tags Auth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param hotel_id path string true "Hotel ID"
+// @Param role path string true "hotel_manager or front_desk"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/users/{id}/hotel-roles/{hotel_id}/{role} [delete]
+func (h *Handler) removeHotelRole(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		writeError(w, pkgErrors.New("forbidden", "admin only; sign in with multi-factor authentication"))
+		return
+	}
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	hotelID, err := uuid.Parse(chi.URLParam(r, "hotel_id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid hotel id"))
+		return
+	}
+	adminID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	if err := h.service.RemoveHotelRole(r.Context(), userID, hotelID, chi.URLParam(r, "role"), adminID); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusOK, "hotel role removed", dto.SuccessResponse{
+		ID:      userID.String(),
+		Message: "hotel role removed",
+	})
+}
+
 func writeError(w http.ResponseWriter, err pkgErrors.APIError) {
 	utils.Respond(w, pkgErrors.StatusCode(err), err.Message, err)
 }
 
+// writeThrottledError is writeError plus Retry-After on throttled logins.
+func writeThrottledError(w http.ResponseWriter, err pkgErrors.APIError) {
+	if details, ok := err.Details.(map[string]int); ok && err.Code == "too_many_requests" {
+		w.Header().Set("Retry-After", strconv.Itoa(details["retry_after_seconds"]))
+	}
+	writeError(w, err)
+}
+
 func isAdmin(r *http.Request) bool {
-	if claims, ok := r.Context().Value(middleware.AuthContextKey).(*middleware.Claims); ok {
-		return claims.Role == "admin"
+	if claims, ok := middleware.ClaimsFromContext(r.Context()); ok {
+		return claims.Admin()
+	}
+	return false
+}
+
+// clientIP returns the caller's address. A trusted proxy (the gateway)
+// appends the address it saw to X-Forwarded-For, so the last entry is used
+// for requests it relays; earlier entries are client-supplied. Anyone else
+// reaching the service directly could forge the header, so their own
+// address is used instead.
+func (h *Handler) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && h.proxies.trusts(host) {
+		parts := strings.Split(forwarded, ",")
+		if last := strings.TrimSpace(parts[len(parts)-1]); last != "" {
+			return last
+		}
+	}
+	return host
+}
+
+// trustedProxies matches peer addresses against configured proxies. Host
+// names are resolved on every check so a proxy that restarts on a new
+// address stays trusted.
+type trustedProxies struct {
+	nets  []*net.IPNet
+	hosts []string
+}
+
+func parseTrustedProxies(entries []string) trustedProxies {
+	var t trustedProxies
+	for _, entry := range entries {
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			t.nets = append(t.nets, cidr)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			t.nets = append(t.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		t.hosts = append(t.hosts, entry)
+	}
+	return t
+}
+
+func (t trustedProxies) trusts(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range t.nets {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	for _, host := range t.hosts {
+		resolved, err := net.LookupIP(host)
+		if err != nil {
+			continue
+		}
+		for _, candidate := range resolved {
+			if candidate.Equal(ip) {
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -2,21 +2,27 @@ package authhttp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/token"
 	auth "github.com/ftryyln/hotel-booking-microservices/internal/usecase/auth"
+	pkgDomain "github.com/ftryyln/hotel-booking-microservices/pkg/domain"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 type authUsecaseStub struct {
@@ -57,16 +63,144 @@ func (a *authRepoStub) List(ctx context.Context, opts query.Options) ([]domain.U
 	return out, nil
 }
 
+func (a *authRepoStub) CreateRefreshToken(ctx context.Context, t domain.RefreshToken) error {
+	return nil
+}
+
+func (a *authRepoStub) FindRefreshToken(ctx context.Context, id uuid.UUID) (domain.RefreshToken, error) {
+	return domain.RefreshToken{}, pkgErrors.New("not_found", "refresh token not found")
+}
+
+func (a *authRepoStub) UseRefreshToken(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	return false, nil
+}
+
+func (a *authRepoStub) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	return nil
+}
+
+func (a *authRepoStub) RevokeToken(ctx context.Context, t domain.RevokedToken) error {
+	return nil
+}
+
+func (a *authRepoStub) ListRevoked(ctx context.Context, now time.Time) ([]domain.RevokedToken, error) {
+	return nil, nil
+}
+
+func (a *authRepoStub) UpdatePassword(ctx context.Context, id uuid.UUID, hash string) error {
+	return nil
+}
+
+func (a *authRepoStub) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return nil
+}
+
+func (a *authRepoStub) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return nil
+}
+
+func (a *authRepoStub) CreateOneTimeToken(ctx context.Context, t domain.OneTimeToken) error {
+	return nil
+}
+
+func (a *authRepoStub) ConsumeOneTimeToken(ctx context.Context, purpose domain.TokenPurpose, hash string, at time.Time) (domain.OneTimeToken, error) {
+	return domain.OneTimeToken{}, pkgErrors.New("not_found", "token not found")
+}
+
+func (a *authRepoStub) RecordLoginFailure(ctx context.Context, id uuid.UUID, at time.Time) (int, error) {
+	return 1, nil
+}
+
+func (a *authRepoStub) LockUser(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return nil
+}
+
+func (a *authRepoStub) ClearLoginFailures(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (a *authRepoStub) RecordEvent(ctx context.Context, event pkgDomain.DomainEvent) error {
+	return nil
+}
+
+func (a *authRepoStub) SetMFASecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	return nil
+}
+
+func (a *authRepoStub) EnableMFA(ctx context.Context, userID uuid.UUID, at time.Time, step int64, codes []domain.RecoveryCode) error {
+	return nil
+}
+
+func (a *authRepoStub) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	return false, nil
+}
+
+func (a *authRepoStub) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	return false, nil
+}
+
+func (a *authRepoStub) AssignHotelRole(ctx context.Context, grant domain.HotelRole) error {
+	return nil
+}
+
+func (a *authRepoStub) RemoveHotelRole(ctx context.Context, userID, hotelID uuid.UUID, role valueobject.Role) (bool, error) {
+	return false, nil
+}
+
+func (a *authRepoStub) ListHotelRoles(ctx context.Context, userID uuid.UUID) ([]domain.HotelRole, error) {
+	return nil, nil
+}
+
+func (a *authRepoStub) UpdateProfile(ctx context.Context, id uuid.UUID, profile domain.Profile) error {
+	return nil
+}
+
+func (a *authRepoStub) EraseUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return nil
+}
+
+type notifierStub struct{}
+
+func (n *notifierStub) SendPasswordReset(ctx context.Context, email, secret string) error {
+	return nil
+}
+
+func (n *notifierStub) SendEmailVerification(ctx context.Context, email, secret string) error {
+	return nil
+}
+
+type personalDataStub struct{}
+
+func (p *personalDataStub) Collect(ctx context.Context, userID uuid.UUID, email string) (domain.PersonalData, error) {
+	return domain.PersonalData{}, nil
+}
+
+func (p *personalDataStub) Erase(ctx context.Context, userID uuid.UUID, email string) error {
+	return nil
+}
+
 type issuerStub struct{}
 
-func (i *issuerStub) Generate(ctx context.Context, user domain.User) (string, string, error) {
+func (i *issuerStub) Generate(ctx context.Context, user domain.User, session domain.RefreshToken) (string, string, error) {
 	return "access", "refresh", nil
 }
 
+func (i *issuerStub) ParseRefresh(ctx context.Context, token string) (uuid.UUID, error) {
+	return uuid.Nil, pkgErrors.New("unauthorized", "invalid refresh token")
+}
+
+func (i *issuerStub) IssueMFAChallenge(ctx context.Context, user domain.User) (string, error) {
+	return "mfa", nil
+}
+
+func (i *issuerStub) ParseMFAChallenge(ctx context.Context, token string) (uuid.UUID, error) {
+	return uuid.Nil, pkgErrors.New("unauthorized", "invalid mfa token")
+}
+
 func TestAuthHandlerRegister(t *testing.T) {
-	svc := auth.NewService(&authRepoStub{}, &issuerStub{})
+	svc := auth.NewService(&authRepoStub{}, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
 	r := chi.NewRouter()
-	h := NewHandler(svc, "secret")
+	h := NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil)
 	r.Mount("/auth", h.Routes())
 
 	req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"email":"a@b.com","password":"x","role":"customer"}`))
@@ -75,6 +209,74 @@ func TestAuthHandlerRegister(t *testing.T) {
 	require.Equal(t, http.StatusCreated, rec.Code)
 }
 
+func TestAuthHandlerPasswordAndVerificationFlows(t *testing.T) {
+	svc := auth.NewService(&authRepoStub{}, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	r := chi.NewRouter()
+	r.Mount("/auth", NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil).Routes())
+
+	for _, tc := range []struct {
+		path, body string
+		status     int
+	}{
+		// unknown accounts get the same answer as known ones
+		{"/auth/password/forgot", `{"email":"nobody@example.com"}`, http.StatusAccepted},
+		{"/auth/password/forgot", `{"email":"not-an-email"}`, http.StatusBadRequest},
+		{"/auth/password/reset", `{"token":"unknown","password":"x"}`, http.StatusBadRequest},
+		{"/auth/verify-email", `{"token":"unknown"}`, http.StatusBadRequest},
+		{"/auth/verify-email/resend", ``, http.StatusUnauthorized},
+		{"/auth/mfa/challenge", `{"mfa_token":"forged","code":"123456"}`, http.StatusUnauthorized},
+		{"/auth/mfa/challenge", `{"code":"123456"}`, http.StatusBadRequest},
+		{"/auth/mfa/setup", ``, http.StatusUnauthorized},
+		{"/auth/mfa/verify", `{"code":"123456"}`, http.StatusUnauthorized},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+		require.Equal(t, tc.status, rec.Code, tc.path)
+	}
+}
+
+func TestAuthHandlerLogoutRevokesToken(t *testing.T) {
+	keys, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	svc := auth.NewService(&authRepoStub{}, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	revoked := middleware.NewRevocationList(func(context.Context) (map[string]time.Time, error) {
+		return map[string]time.Time{}, nil
+	}, time.Minute)
+	r := chi.NewRouter()
+	r.Mount("/auth", NewHandler(svc, middleware.NewVerifier("secret", keys), revoked, keys, nil).Routes())
+
+	user := domain.User{ID: uuid.New(), Role: "customer"}
+	access, _, err := token.NewJWTIssuer(keys).Generate(context.Background(), user, domain.NewRefreshToken(user.ID, uuid.Nil, false, time.Now()))
+	require.NoError(t, err)
+
+	logout := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, logout())
+	require.Equal(t, http.StatusUnauthorized, logout())
+}
+
+func TestAuthHandlerPublishesJWKS(t *testing.T) {
+	keys, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	svc := auth.NewService(&authRepoStub{}, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	r := chi.NewRouter()
+	r.Mount("/auth", NewHandler(svc, middleware.NewVerifier("secret", keys), nil, keys, nil).Routes())
+
+	req := httptest.NewRequest(http.MethodGet, middleware.JWKSPath, nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var set middleware.JWKSet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+	require.Equal(t, keys.JWKS(), set)
+}
+
 func TestAuthHandlerListUsersParsesPagination(t *testing.T) {
 	// list /users is protected by JWT; handler pagination is covered indirectly via service tests.
 }
@@ -82,11 +284,11 @@ func TestAuthHandlerListUsersParsesPagination(t *testing.T) {
 func TestAuthHandler_ListUsers_AdminRole(t *testing.T) {
 	admin := domain.User{ID: uuid.New(), Email: "admin@example.com", Role: "admin"}
 	repo := &authRepoStub{users: map[string]domain.User{"admin@example.com": admin}}
-	svc := auth.NewService(repo, &issuerStub{})
-	h := NewHandler(svc, "secret")
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	h := NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/auth/users", nil)
-	req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: admin.ID.String(), Role: "admin"}))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: admin.ID.String(), Role: "admin", AMR: []string{middleware.AMRPassword, middleware.AMROTP}}))
 	rec := httptest.NewRecorder()
 
 	r := chi.NewRouter()
@@ -98,8 +300,8 @@ func TestAuthHandler_ListUsers_AdminRole(t *testing.T) {
 
 func TestAuthHandler_ListUsers_ForbiddenForNonAdmin(t *testing.T) {
 	repo := &authRepoStub{users: map[string]domain.User{}}
-	svc := auth.NewService(repo, &issuerStub{})
-	h := NewHandler(svc, "secret")
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	h := NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/auth/users", nil)
 	req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: uuid.NewString(), Role: "customer"}))
@@ -114,12 +316,12 @@ func TestAuthHandler_ListUsers_ForbiddenForNonAdmin(t *testing.T) {
 
 func TestAuthHandler_GetUser_NotFound(t *testing.T) {
 	repo := &authRepoStub{users: map[string]domain.User{}}
-	svc := auth.NewService(repo, &issuerStub{})
-	h := NewHandler(svc, "secret")
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	h := NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil)
 
 	id := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/auth/users/"+id.String(), nil)
-	req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: uuid.NewString(), Role: "admin"}))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: uuid.NewString(), Role: "admin", AMR: []string{middleware.AMRPassword, middleware.AMROTP}}))
 	rec := httptest.NewRecorder()
 
 	r := chi.NewRouter()
@@ -128,3 +330,47 @@ func TestAuthHandler_GetUser_NotFound(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestAuthHandlerLoginThrottlesByGatewayAddress(t *testing.T) {
+	svc := auth.NewService(&authRepoStub{}, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{IPMaxAttempts: 1, LockoutDuration: time.Minute})
+	r := chi.NewRouter()
+	r.Mount("/auth", NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, []string{"192.0.2.0/30"}).Routes())
+
+	login := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"nobody@example.com","password":"x"}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	// through the gateway the client controls every entry but the last one, appended by the gateway
+	require.Equal(t, http.StatusUnauthorized, login("192.0.2.1:4000", "9.9.9.9, 10.0.0.1").Code)
+	rec := login("192.0.2.1:4000", "8.8.8.8, 10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusUnauthorized, login("192.0.2.1:4000", "10.0.0.2").Code)
+
+	// a direct caller cannot rotate the header to escape its own block
+	require.Equal(t, http.StatusUnauthorized, login("203.0.113.7:4000", "10.0.0.3").Code)
+	require.Equal(t, http.StatusTooManyRequests, login("203.0.113.7:4000", "10.0.0.4").Code)
+}
+
+func TestAuthHandlerUnlockRequiresAdmin(t *testing.T) {
+	repo := &authRepoStub{users: map[string]domain.User{}}
+	user := domain.User{ID: uuid.New(), Email: "user@example.com", Role: "customer"}
+	repo.users[user.Email] = user
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	h := NewHandler(svc, middleware.NewVerifier("secret", nil), nil, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/auth/users/{id}/unlock", h.unlockUser)
+
+	for role, status := range map[string]int{"customer": http.StatusForbidden, "admin": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/users/"+user.ID.String()+"/unlock", nil)
+		claims := &middleware.Claims{UserID: uuid.NewString(), Role: role, AMR: []string{middleware.AMRPassword, middleware.AMROTP}}
+		req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, claims))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, status, rec.Code, role)
+	}
+}
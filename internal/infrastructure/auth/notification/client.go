@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+// HTTPNotifier mails account links through the notification service, whose
+// dispatcher delivers messages to the target email address.
+type HTTPNotifier struct {
+	baseURL string
+	appURL  string
+	tokens  *middleware.ServiceSigner
+	client  *http.Client
+}
+
+// NewHTTPNotifier builds links under appURL, the address of the page that
+// submits the token back to auth-service.
+func NewHTTPNotifier(baseURL, appURL string, tokens *middleware.ServiceSigner) domain.AccountNotifier {
+	return &HTTPNotifier{baseURL: baseURL, appURL: appURL, tokens: tokens, client: &http.Client{Timeout: 3 * time.Second}}
+}
+
+func (n *HTTPNotifier) SendPasswordReset(ctx context.Context, email, secret string) error {
+	link := n.link("/reset-password", secret)
+	message := fmt.Sprintf("We received a request to reset your password. Use this link within %s to choose a new one:\n\n%s\n\nIf you did not ask for a reset, you can ignore this email.",
+		domain.PasswordResetTTL, link)
+	return n.send(ctx, string(domain.PurposePasswordReset), email, message)
+}
+
+func (n *HTTPNotifier) SendEmailVerification(ctx context.Context, email, secret string) error {
+	link := n.link("/verify-email", secret)
+	message := fmt.Sprintf("Confirm your email address to start booking. Use this link within %s:\n\n%s",
+		domain.EmailVerificationTTL, link)
+	return n.send(ctx, string(domain.PurposeEmailVerification), email, message)
+}
+
+func (n *HTTPNotifier) link(path, secret string) string {
+	return n.appURL + path + "?token=" + url.QueryEscape(secret)
+}
+
+func (n *HTTPNotifier) send(ctx context.Context, kind, target, message string) error {
+	token, err := n.tokens.Token(time.Minute)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(dto.NotificationRequest{Type: kind, Target: target, Message: message})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL+"/notifications", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notify failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
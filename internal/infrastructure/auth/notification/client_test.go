@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+func TestHTTPNotifierSendsLinkAsService(t *testing.T) {
+	var got dto.NotificationRequest
+	srv := httptest.NewServer(middleware.JWT("secret", middleware.RoleService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	})))
+	defer srv.Close()
+
+	notifier := NewHTTPNotifier(srv.URL, "https://app.example.com", middleware.NewSharedSecretSigner("auth-service", "secret"))
+	require.NoError(t, notifier.SendPasswordReset(context.Background(), "user@example.com", "a+b/c"))
+	require.Equal(t, "password_reset", got.Type)
+	require.Equal(t, "user@example.com", got.Target)
+	require.True(t, strings.Contains(got.Message, "https://app.example.com/reset-password?token=a%2Bb%2Fc"))
+}
+
+func TestHTTPNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fail", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	notifier := NewHTTPNotifier(srv.URL, "https://app.example.com", middleware.NewSharedSecretSigner("auth-service", "secret"))
+	require.Error(t, notifier.SendEmailVerification(context.Background(), "user@example.com", "token"))
+}
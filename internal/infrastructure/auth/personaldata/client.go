@@ -0,0 +1,158 @@
+package personaldata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+// notificationPage is how many notifications are fetched per request.
+const notificationPage = 100
+
+// HTTPSource collects and erases a user's data in the booking, payment and
+// notification services, authenticating with a service token.
+type HTTPSource struct {
+	bookingURL      string
+	paymentURL      string
+	notificationURL string
+	tokens          *middleware.ServiceSigner
+	client          *http.Client
+}
+
+func NewHTTPSource(bookingURL, paymentURL, notificationURL string, tokens *middleware.ServiceSigner) domain.PersonalDataSource {
+	return &HTTPSource{
+		bookingURL:      bookingURL,
+		paymentURL:      paymentURL,
+		notificationURL: notificationURL,
+		tokens:          tokens,
+		client:          &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Collect fetches the user's bookings, the payment of each booking and the
+// notifications sent to email.
+func (s *HTTPSource) Collect(ctx context.Context, userID uuid.UUID, email string) (domain.PersonalData, error) {
+	token, err := s.tokens.Token(time.Minute)
+	if err != nil {
+		return domain.PersonalData{}, err
+	}
+	var data domain.PersonalData
+	if data.Bookings, err = s.list(ctx, token, s.bookingURL+"/internal/users/"+userID.String()+"/bookings"); err != nil {
+		return domain.PersonalData{}, err
+	}
+	for _, raw := range data.Bookings {
+		var booking struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &booking); err != nil {
+			return domain.PersonalData{}, err
+		}
+		payment, err := s.get(ctx, token, s.paymentURL+"/payments/by-booking/"+url.PathEscape(booking.ID))
+		if err != nil {
+			return domain.PersonalData{}, err
+		}
+		if payment != nil {
+			data.Payments = append(data.Payments, payment)
+		}
+	}
+	for offset := 0; ; offset += notificationPage {
+		page, err := s.list(ctx, token, fmt.Sprintf("%s/notifications?target=%s&limit=%d&offset=%d",
+			s.notificationURL, url.QueryEscape(email), notificationPage, offset))
+		if err != nil {
+			return domain.PersonalData{}, err
+		}
+		data.Notifications = append(data.Notifications, page...)
+		if len(page) < notificationPage {
+			break
+		}
+	}
+	return data, nil
+}
+
+// Erase redacts the notifications sent to email. Bookings and payments stay
+// as financial records keyed by user ID only.
+func (s *HTTPSource) Erase(ctx context.Context, _ uuid.UUID, email string) error {
+	token, err := s.tokens.Token(time.Minute)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(dto.EraseNotificationsRequest{Target: email})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, s.notificationURL+"/notifications/erase", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.do(req, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("erase notifications failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// list returns the attributes of each resource in a list response.
+func (s *HTTPSource) list(ctx context.Context, token, target string) ([]json.RawMessage, error) {
+	var envelope struct {
+		Data []struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if _, err := s.fetch(ctx, token, target, false, &envelope); err != nil {
+		return nil, err
+	}
+	records := make([]json.RawMessage, 0, len(envelope.Data))
+	for _, r := range envelope.Data {
+		records = append(records, r.Attributes)
+	}
+	return records, nil
+}
+
+// get returns the attributes of a single resource, or nil when not found.
+func (s *HTTPSource) get(ctx context.Context, token, target string) (json.RawMessage, error) {
+	var envelope struct {
+		Data struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	found, err := s.fetch(ctx, token, target, true, &envelope)
+	if err != nil || !found {
+		return nil, err
+	}
+	return envelope.Data.Attributes, nil
+}
+
+// fetch decodes the response envelope. A 404 reports not found when the
+// resource is optional and fails otherwise.
+func (s *HTTPSource) fetch(ctx context.Context, token, target string, optional bool, envelope any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req, token)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if optional && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("fetch %s failed with status %d", req.URL.Path, resp.StatusCode)
+	}
+	return true, json.NewDecoder(resp.Body).Decode(envelope)
+}
+
+func (s *HTTPSource) do(req *http.Request, token string) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.client.Do(req)
+}
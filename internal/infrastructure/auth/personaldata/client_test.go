@@ -0,0 +1,77 @@
+package personaldata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/utils"
+)
+
+func TestHTTPSourceCollectsAcrossServices(t *testing.T) {
+	userID := uuid.New()
+	paid, unpaid := uuid.NewString(), uuid.NewString()
+	var erased dto.EraseNotificationsRequest
+
+	r := chi.NewRouter()
+	r.Use(middleware.JWT("secret", middleware.RoleService))
+	r.Get("/internal/users/{id}/bookings", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, userID.String(), chi.URLParam(r, "id"))
+		utils.RespondWithCount(w, http.StatusOK, "bookings listed", []utils.Resource{
+			utils.NewResource(paid, "booking", "", dto.BookingResponse{ID: paid}),
+			utils.NewResource(unpaid, "booking", "", dto.BookingResponse{ID: unpaid}),
+		}, 2)
+	})
+	r.Get("/payments/by-booking/{booking_id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "booking_id") != paid {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		utils.Respond(w, http.StatusOK, "payment retrieved", utils.NewResource("p1", "payment", "", dto.PaymentResponse{ID: "p1", Status: "paid"}))
+	})
+	r.Get("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "user+tag@example.com", r.URL.Query().Get("target"))
+		utils.RespondWithCount(w, http.StatusOK, "notifications listed", []utils.Resource{
+			utils.NewResource("n1", "notification", "", dto.NotificationResponse{ID: "n1"}),
+		}, 1)
+	})
+	r.Post("/notifications/erase", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&erased))
+		utils.Respond(w, http.StatusOK, "notifications erased", dto.SuccessResponse{})
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	source := NewHTTPSource(srv.URL, srv.URL, srv.URL, middleware.NewSharedSecretSigner("auth-service", "secret"))
+	data, err := source.Collect(context.Background(), userID, "user+tag@example.com")
+	require.NoError(t, err)
+	require.Len(t, data.Bookings, 2)
+	require.Len(t, data.Payments, 1)
+	require.Len(t, data.Notifications, 1)
+	var payment dto.PaymentResponse
+	require.NoError(t, json.Unmarshal(data.Payments[0], &payment))
+	require.Equal(t, "p1", payment.ID)
+
+	require.NoError(t, source.Erase(context.Background(), userID, "user+tag@example.com"))
+	require.Equal(t, "user+tag@example.com", erased.Target)
+}
+
+func TestHTTPSourceFailsWhenAServiceFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fail", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	source := NewHTTPSource(srv.URL, srv.URL, srv.URL, middleware.NewSharedSecretSigner("auth-service", "secret"))
+	_, err := source.Collect(context.Background(), uuid.New(), "user@example.com")
+	require.Error(t, err)
+	require.Error(t, source.Erase(context.Background(), uuid.New(), "user@example.com"))
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// auditPersonalFields are the audit payload fields holding personal data.
+var auditPersonalFields = []string{"email", "ip"}
+
+// EraseUser anonymizes the user in one transaction. The row stays so that
+// bookings and payments keep a valid user ID.
+func (r *GormRepository) EraseUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&userModel{}).Where("id = ?", id).Updates(map[string]any{
+			"email":                 domain.ErasedEmail(id),
+			"password":              "",
+			"name":                  "",
+			"phone":                 "",
+			"locale":                "",
+			"preferences":           "",
+			"mfa_secret":            "",
+			"mfa_enabled_at":        nil,
+			"failed_login_attempts": 0,
+			"last_failed_login_at":  nil,
+			"locked_until":          nil,
+			"erased_at":             at,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return pkgErrors.New("not_found", "user not found")
+		}
+		for _, model := range []any{&oneTimeTokenModel{}, &recoveryCodeModel{}, &hotelRoleModel{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&refreshTokenModel{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return redactAuditEvents(tx, id)
+	})
+}
+
+// redactAuditEvents drops personal fields from the user's audit payloads and
+// keeps the events themselves.
+func redactAuditEvents(tx *gorm.DB, id uuid.UUID) error {
+	var events []auditEventModel
+	if err := tx.Where("aggregate_id = ?", id).Find(&events).Error; err != nil {
+		return err
+	}
+	for _, e := range events {
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(e.Payload), &payload); err != nil {
+			return err
+		}
+		changed := false
+		for _, field := range auditPersonalFields {
+			if _, ok := payload[field]; ok {
+				delete(payload, field)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		redacted, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&auditEventModel{}).Where("id = ?", e.ID).Update("payload", string(redacted)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
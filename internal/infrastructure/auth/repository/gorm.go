@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
-// GormRepository implements UserRepository via GORM.
+// GormRepository implements Repository via GORM.
 type GormRepository struct {
 	db *gorm.DB
 }
@@ -22,12 +23,27 @@ func NewGormRepository(db *gorm.DB) *GormRepository {
 	return &GormRepository{db: db}
 }
 
-// AutoMigrate ensures users table exists.
+// userColumns were added to users after the initial schema.
+var userColumns = []string{"EmailVerifiedAt", "FailedLoginAttempts", "LastFailedLoginAt", "LockedUntil", "MFASecret", "MFAEnabledAt", "MFALastStep",
+	"Name", "Phone", "Locale", "Preferences", "ErasedAt"}
+
+// AutoMigrate ensures users, refresh_tokens, revoked_tokens, one_time_tokens,
+// mfa_recovery_codes and auth_audit_events tables exist.
 func AutoMigrate(db *gorm.DB) error {
-	if db.Migrator().HasTable(&userModel{}) {
-		return nil
+	if !db.Migrator().HasTable(&userModel{}) {
+		if err := db.AutoMigrate(&userModel{}); err != nil {
+			return err
+		}
+	}
+	for _, column := range userColumns {
+		if db.Migrator().HasColumn(&userModel{}, column) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&userModel{}, column); err != nil {
+			return err
+		}
 	}
-	return db.AutoMigrate(&userModel{})
+	return db.AutoMigrate(&refreshTokenModel{}, &revokedTokenModel{}, &oneTimeTokenModel{}, &recoveryCodeModel{}, &hotelRoleModel{}, &auditEventModel{})
 }
 
 func (r *GormRepository) Create(ctx context.Context, user domain.User) error {
@@ -68,34 +84,129 @@ func (r *GormRepository) List(ctx context.Context, opts query.Options) ([]domain
 	return users, nil
 }
 
+func (r *GormRepository) UpdatePassword(ctx context.Context, id uuid.UUID, hash string) error {
+	res := r.db.WithContext(ctx).Model(&userModel{}).Where("id = ?", id).Update("password", hash)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("not_found", "user not found")
+	}
+	return nil
+}
+
+// MarkEmailVerified keeps the first verification time when called again.
+func (r *GormRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&userModel{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", at).Error
+}
+
+// UpdateProfile replaces the profile fields as a whole.
+func (r *GormRepository) UpdateProfile(ctx context.Context, id uuid.UUID, profile domain.Profile) error {
+	res := r.db.WithContext(ctx).Model(&userModel{}).Where("id = ?", id).Updates(map[string]any{
+		"name":        profile.Name,
+		"phone":       profile.Phone,
+		"locale":      profile.Locale,
+		"preferences": encodePreferences(profile.Preferences),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("not_found", "user not found")
+	}
+	return nil
+}
+
 type userModel struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Email     string    `gorm:"uniqueIndex;not null"`
-	Password  string
-	Role      string
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	ID                  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Email               string    `gorm:"uniqueIndex;not null"`
+	Password            string
+	Role                string
+	CreatedAt           time.Time `gorm:"column:created_at;autoCreateTime"`
+	EmailVerifiedAt     *time.Time
+	FailedLoginAttempts int `gorm:"not null;default:0"`
+	LastFailedLoginAt   *time.Time
+	LockedUntil         *time.Time
+	MFASecret           string     `gorm:"column:mfa_secret"`
+	MFAEnabledAt        *time.Time `gorm:"column:mfa_enabled_at"`
+	MFALastStep         int64      `gorm:"column:mfa_last_step;not null;default:0"`
+	Name                string
+	Phone               string
+	Locale              string
+	// Preferences is a JSON object of string values.
+	Preferences string `gorm:"type:text"`
+	ErasedAt    *time.Time
 }
 
 func (userModel) TableName() string { return "users" }
 
 func toModel(u domain.User) userModel {
 	return userModel{
-		ID:        u.ID,
-		Email:     u.Email,
-		Password:  u.Password,
-		Role:      u.Role,
-		CreatedAt: u.CreatedAt,
+		ID:                  u.ID,
+		Email:               u.Email,
+		Password:            u.Password,
+		Role:                u.Role,
+		CreatedAt:           u.CreatedAt,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
+		FailedLoginAttempts: u.Attempts.Failures,
+		LastFailedLoginAt:   u.Attempts.LastFailureAt,
+		LockedUntil:         u.Attempts.LockedUntil,
+		MFASecret:           u.MFA.Secret,
+		MFAEnabledAt:        u.MFA.EnabledAt,
+		MFALastStep:         u.MFA.LastStep,
+		Name:                u.Profile.Name,
+		Phone:               u.Profile.Phone,
+		Locale:              u.Profile.Locale,
+		Preferences:         encodePreferences(u.Profile.Preferences),
+		ErasedAt:            u.ErasedAt,
 	}
 }
 
 func toDomain(m userModel) domain.User {
 	return domain.User{
-		ID:        m.ID,
-		Email:     m.Email,
-		Password:  m.Password,
-		Role:      m.Role,
-		CreatedAt: m.CreatedAt,
+		ID:              m.ID,
+		Email:           m.Email,
+		Password:        m.Password,
+		Role:            m.Role,
+		CreatedAt:       m.CreatedAt,
+		EmailVerifiedAt: m.EmailVerifiedAt,
+		Attempts: domain.LoginAttempts{
+			Failures:      m.FailedLoginAttempts,
+			LastFailureAt: m.LastFailedLoginAt,
+			LockedUntil:   m.LockedUntil,
+		},
+		MFA: domain.MFA{
+			Secret:    m.MFASecret,
+			EnabledAt: m.MFAEnabledAt,
+			LastStep:  m.MFALastStep,
+		},
+		Profile: domain.Profile{
+			Name:        m.Name,
+			Phone:       m.Phone,
+			Locale:      m.Locale,
+			Preferences: decodePreferences(m.Preferences),
+		},
+		ErasedAt: m.ErasedAt,
+	}
+}
+
+func encodePreferences(prefs map[string]string) string {
+	if len(prefs) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(prefs)
+	return string(data)
+}
+
+func decodePreferences(raw string) map[string]string {
+	if raw == "" {
+		return nil
 	}
+	var prefs map[string]string
+	_ = json.Unmarshal([]byte(raw), &prefs)
+	return prefs
 }
 
 func translateErr(err error) error {
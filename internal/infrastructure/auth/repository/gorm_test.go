@@ -3,6 +3,7 @@ package repository_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
 	repo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/repository"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestGormRepositoryCreateUser(t *testing.T) {
@@ -40,3 +42,237 @@ func newTestDB(t *testing.T) *gorm.DB {
 }
 
 func ctxBackground() context.Context { return context.Background() }
+
+func TestGormRepositoryRefreshTokens(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	first := auth.NewRefreshToken(uuid.New(), uuid.Nil, false, now)
+	second := auth.NewRefreshToken(first.UserID, first.FamilyID, true, now)
+	require.NoError(t, r.CreateRefreshToken(ctxBackground(), first))
+	require.NoError(t, r.CreateRefreshToken(ctxBackground(), second))
+
+	used, err := r.UseRefreshToken(ctxBackground(), first.ID, now)
+	require.NoError(t, err)
+	require.True(t, used)
+	used, err = r.UseRefreshToken(ctxBackground(), first.ID, now)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	require.NoError(t, r.RevokeFamily(ctxBackground(), first.FamilyID, now))
+	got, err := r.FindRefreshToken(ctxBackground(), second.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+	require.True(t, got.MFA)
+	used, err = r.UseRefreshToken(ctxBackground(), second.ID, now)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	_, err = r.FindRefreshToken(ctxBackground(), uuid.New())
+	require.Error(t, err)
+}
+
+func TestGormRepositoryRevokedTokens(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	active := auth.RevokedToken{ID: uuid.New(), ExpiresAt: now.Add(time.Minute), RevokedAt: now}
+	expired := auth.RevokedToken{ID: uuid.New(), ExpiresAt: now.Add(-time.Minute), RevokedAt: now}
+	require.NoError(t, r.RevokeToken(ctxBackground(), active))
+	require.NoError(t, r.RevokeToken(ctxBackground(), active))
+	require.NoError(t, r.RevokeToken(ctxBackground(), expired))
+
+	tokens, err := r.ListRevoked(ctxBackground(), now)
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, 0, len(tokens))
+	for _, tok := range tokens {
+		ids = append(ids, tok.ID)
+	}
+	require.Contains(t, ids, active.ID)
+	require.NotContains(t, ids, expired.ID)
+}
+
+func TestGormRepositoryOneTimeTokens(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	user := auth.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Password: "hash", Role: "customer", CreatedAt: now}
+	require.NoError(t, r.Create(ctxBackground(), user))
+
+	token, secret, err := auth.NewOneTimeToken(user.ID, auth.PurposePasswordReset, now)
+	require.NoError(t, err)
+	require.NoError(t, r.CreateOneTimeToken(ctxBackground(), token))
+	hash := auth.HashOneTimeSecret(secret)
+
+	_, err = r.ConsumeOneTimeToken(ctxBackground(), auth.PurposeEmailVerification, hash, now)
+	require.Error(t, err)
+	_, err = r.ConsumeOneTimeToken(ctxBackground(), auth.PurposePasswordReset, hash, token.ExpiresAt)
+	require.Error(t, err)
+	used, err := r.ConsumeOneTimeToken(ctxBackground(), auth.PurposePasswordReset, hash, now)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, used.UserID)
+	_, err = r.ConsumeOneTimeToken(ctxBackground(), auth.PurposePasswordReset, hash, now)
+	require.Error(t, err)
+
+	require.NoError(t, r.UpdatePassword(ctxBackground(), user.ID, "new-hash"))
+	require.NoError(t, r.MarkEmailVerified(ctxBackground(), user.ID, now))
+	require.NoError(t, r.MarkEmailVerified(ctxBackground(), user.ID, now.Add(time.Hour)))
+	found, err := r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", found.Password)
+	require.True(t, found.EmailVerified())
+	require.WithinDuration(t, now, *found.EmailVerifiedAt, time.Second)
+}
+
+func TestGormRepositoryLoginAttempts(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	user := auth.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Password: "hash", Role: "customer", CreatedAt: now}
+	require.NoError(t, r.Create(ctxBackground(), user))
+
+	for want := 1; want <= 2; want++ {
+		failures, err := r.RecordLoginFailure(ctxBackground(), user.ID, now)
+		require.NoError(t, err)
+		require.Equal(t, want, failures)
+	}
+	require.NoError(t, r.LockUser(ctxBackground(), user.ID, now.Add(time.Hour)))
+	found, err := r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Zero(t, found.Attempts.Failures)
+	require.True(t, found.Attempts.Locked(now))
+
+	require.NoError(t, r.ClearLoginFailures(ctxBackground(), user.ID))
+	found, err = r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, auth.LoginAttempts{}, found.Attempts)
+
+	require.NoError(t, r.RecordEvent(ctxBackground(), auth.NewUserLocked(user, 5, now.Add(time.Hour), "10.0.0.1")))
+	var count int64
+	require.NoError(t, db.Table("auth_audit_events").Where("event_type = ? AND aggregate_id = ?", auth.EventTypeUserLocked, user.ID).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+}
+
+func TestGormRepositoryMFA(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	user := auth.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Password: "hash", Role: "admin", CreatedAt: now}
+	require.NoError(t, r.Create(ctxBackground(), user))
+
+	require.NoError(t, r.SetMFASecret(ctxBackground(), user.ID, "SECRET"))
+	codes, plain, err := auth.NewRecoveryCodes(user.ID, now)
+	require.NoError(t, err)
+	require.NoError(t, r.EnableMFA(ctxBackground(), user.ID, now, 100, codes))
+	found, err := r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.True(t, found.MFA.Enabled())
+	require.Equal(t, "SECRET", found.MFA.Secret)
+	require.EqualValues(t, 100, found.MFA.LastStep)
+
+	// enrollment cannot be restarted or repeated once enabled
+	require.Error(t, r.SetMFASecret(ctxBackground(), user.ID, "OTHER"))
+	require.Error(t, r.EnableMFA(ctxBackground(), user.ID, now, 101, nil))
+
+	// steps are accepted once and only moving forward
+	for _, tc := range []struct {
+		step int64
+		want bool
+	}{{100, false}, {101, true}, {101, false}, {99, false}} {
+		ok, err := r.UseTOTPStep(ctxBackground(), user.ID, tc.step)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, ok, tc.step)
+	}
+
+	ok, err := r.UseRecoveryCode(ctxBackground(), user.ID, auth.HashRecoveryCode(plain[0]), now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = r.UseRecoveryCode(ctxBackground(), user.ID, auth.HashRecoveryCode(plain[0]), now)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = r.UseRecoveryCode(ctxBackground(), uuid.New(), auth.HashRecoveryCode(plain[1]), now)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestGormRepositoryHotelRoles(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	userID, hotelID := uuid.New(), uuid.New()
+	grant := auth.HotelRole{UserID: userID, HotelID: hotelID, Role: valueobject.RoleFrontDesk, GrantedBy: uuid.New(), CreatedAt: time.Now().UTC()}
+	require.NoError(t, r.AssignHotelRole(ctxBackground(), grant))
+	require.Error(t, r.AssignHotelRole(ctxBackground(), grant))
+	manager := grant
+	manager.Role = valueobject.RoleHotelManager
+	require.NoError(t, r.AssignHotelRole(ctxBackground(), manager))
+
+	grants, err := r.ListHotelRoles(ctxBackground(), userID)
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+	require.Equal(t, hotelID, grants[0].HotelID)
+
+	removed, err := r.RemoveHotelRole(ctxBackground(), userID, hotelID, valueobject.RoleFrontDesk)
+	require.NoError(t, err)
+	require.True(t, removed)
+	removed, err = r.RemoveHotelRole(ctxBackground(), userID, hotelID, valueobject.RoleFrontDesk)
+	require.NoError(t, err)
+	require.False(t, removed)
+	grants, err = r.ListHotelRoles(ctxBackground(), userID)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, valueobject.RoleHotelManager, grants[0].Role)
+}
+
+func TestGormRepositoryProfileAndErasure(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+
+	now := time.Now().UTC()
+	user := auth.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Password: "hash", Role: "customer"}
+	require.NoError(t, r.Create(ctxBackground(), user))
+	profile := auth.Profile{Name: "Ada", Phone: "+6281234567890", Locale: "id-ID", Preferences: map[string]string{"currency": "IDR"}}
+	require.NoError(t, r.UpdateProfile(ctxBackground(), user.ID, profile))
+	require.Error(t, r.UpdateProfile(ctxBackground(), uuid.New(), profile))
+	stored, err := r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, profile, stored.Profile)
+
+	require.NoError(t, r.AssignHotelRole(ctxBackground(), auth.HotelRole{UserID: user.ID, HotelID: uuid.New(), Role: valueobject.RoleFrontDesk, GrantedBy: uuid.New(), CreatedAt: now}))
+	require.NoError(t, r.RecordEvent(ctxBackground(), auth.NewUserLocked(user, 5, now.Add(time.Hour), "10.0.0.1")))
+	session := auth.NewRefreshToken(user.ID, uuid.Nil, false, now)
+	require.NoError(t, r.CreateRefreshToken(ctxBackground(), session))
+
+	require.NoError(t, r.EraseUser(ctxBackground(), user.ID, now))
+	require.Error(t, r.EraseUser(ctxBackground(), uuid.New(), now))
+	erased, err := r.FindByID(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, auth.ErasedEmail(user.ID), erased.Email)
+	require.Empty(t, erased.Password)
+	require.Empty(t, erased.Profile.Name)
+	require.NotNil(t, erased.ErasedAt)
+	grants, err := r.ListHotelRoles(ctxBackground(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, grants)
+	token, err := r.FindRefreshToken(ctxBackground(), session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, token.RevokedAt)
+
+	var payload string
+	require.NoError(t, db.Raw("SELECT payload FROM auth_audit_events WHERE aggregate_id = ?", user.ID).Scan(&payload).Error)
+	require.NotEmpty(t, payload)
+	require.NotContains(t, payload, user.Email)
+	require.NotContains(t, payload, "10.0.0.1")
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	pkgDomain "github.com/ftryyln/hotel-booking-microservices/pkg/domain"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// RecordLoginFailure increments the count in the database so that
+// concurrent failures against one account are all counted.
+func (r *GormRepository) RecordLoginFailure(ctx context.Context, id uuid.UUID, at time.Time) (int, error) {
+	var model userModel
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&userModel{}).Where("id = ?", id).Updates(map[string]any{
+			"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+			"last_failed_login_at":  at,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return pkgErrors.New("not_found", "user not found")
+		}
+		return tx.Select("failed_login_attempts").Take(&model, "id = ?", id).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return model.FailedLoginAttempts, nil
+}
+
+func (r *GormRepository) LockUser(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&userModel{}).Where("id = ?", id).Updates(map[string]any{
+		"failed_login_attempts": 0,
+		"locked_until":          until,
+	}).Error
+}
+
+func (r *GormRepository) ClearLoginFailures(ctx context.Context, id uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&userModel{}).Where("id = ?", id).Updates(map[string]any{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("not_found", "user not found")
+	}
+	return nil
+}
+
+type auditEventModel struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AggregateID uuid.UUID `gorm:"type:uuid;index"`
+	EventType   string    `gorm:"index"`
+	Payload     string    `gorm:"type:text"`
+	OccurredAt  time.Time
+}
+
+func (auditEventModel) TableName() string { return "auth_audit_events" }
+
+func (r *GormRepository) RecordEvent(ctx context.Context, event pkgDomain.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	model := auditEventModel{
+		ID:          uuid.New(),
+		AggregateID: event.AggregateID(),
+		EventType:   event.EventType(),
+		Payload:     string(payload),
+		OccurredAt:  event.OccurredAt(),
+	}
+	return r.db.WithContext(ctx).Create(&model).Error
+}
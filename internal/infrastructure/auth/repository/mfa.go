@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+type recoveryCodeModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	Hash      string    `gorm:"uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UsedAt    *time.Time
+}
+
+func (recoveryCodeModel) TableName() string { return "mfa_recovery_codes" }
+
+func (r *GormRepository) SetMFASecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	res := r.db.WithContext(ctx).Model(&userModel{}).
+		Where("id = ? AND mfa_enabled_at IS NULL", userID).
+		Update("mfa_secret", secret)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("conflict", "mfa already enabled")
+	}
+	return nil
+}
+
+func (r *GormRepository) EnableMFA(ctx context.Context, userID uuid.UUID, at time.Time, step int64, codes []domain.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&userModel{}).
+			Where("id = ? AND mfa_enabled_at IS NULL", userID).
+			Updates(map[string]any{"mfa_enabled_at": at, "mfa_last_step": step})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return pkgErrors.New("conflict", "mfa already enabled")
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&recoveryCodeModel{}).Error; err != nil {
+			return err
+		}
+		models := make([]recoveryCodeModel, 0, len(codes))
+		for _, c := range codes {
+			models = append(models, recoveryCodeModel{ID: c.ID, UserID: c.UserID, Hash: c.Hash, CreatedAt: c.CreatedAt, UsedAt: c.UsedAt})
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		return tx.Create(&models).Error
+	})
+}
+
+// UseTOTPStep relies on a conditional update so that one code presented
+// twice, even concurrently, is accepted once.
+func (r *GormRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&userModel{}).
+		Where("id = ? AND mfa_last_step < ?", userID, step).
+		Update("mfa_last_step", step)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *GormRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&recoveryCodeModel{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
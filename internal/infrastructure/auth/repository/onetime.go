@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+type oneTimeTokenModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	Purpose   string
+	Hash      string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UsedAt    *time.Time
+}
+
+func (oneTimeTokenModel) TableName() string { return "one_time_tokens" }
+
+func (r *GormRepository) CreateOneTimeToken(ctx context.Context, t domain.OneTimeToken) error {
+	model := oneTimeTokenModel{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Purpose:   string(t.Purpose),
+		Hash:      t.Hash,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt,
+		UsedAt:    t.UsedAt,
+	}
+	return r.db.WithContext(ctx).Create(&model).Error
+}
+
+// ConsumeOneTimeToken relies on a conditional update so that a token
+// redeemed concurrently is only accepted once.
+func (r *GormRepository) ConsumeOneTimeToken(ctx context.Context, purpose domain.TokenPurpose, hash string, at time.Time) (domain.OneTimeToken, error) {
+	notFound := pkgErrors.New("not_found", "token not found")
+	var model oneTimeTokenModel
+	if err := r.db.WithContext(ctx).Take(&model, "hash = ? AND purpose = ?", hash, string(purpose)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.OneTimeToken{}, notFound
+		}
+		return domain.OneTimeToken{}, err
+	}
+	res := r.db.WithContext(ctx).Model(&oneTimeTokenModel{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", model.ID, at).
+		Update("used_at", at)
+	if res.Error != nil {
+		return domain.OneTimeToken{}, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return domain.OneTimeToken{}, notFound
+	}
+	return domain.OneTimeToken{
+		ID:        model.ID,
+		UserID:    model.UserID,
+		Purpose:   domain.TokenPurpose(model.Purpose),
+		Hash:      model.Hash,
+		ExpiresAt: model.ExpiresAt,
+		CreatedAt: model.CreatedAt,
+		UsedAt:    &at,
+	}, nil
+}
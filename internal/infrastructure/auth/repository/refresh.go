@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+type refreshTokenModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	FamilyID  uuid.UUID `gorm:"type:uuid;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	ExpiresAt time.Time
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	MFA       bool `gorm:"column:mfa;not null;default:false"`
+}
+
+func (refreshTokenModel) TableName() string { return "refresh_tokens" }
+
+func (r *GormRepository) CreateRefreshToken(ctx context.Context, t domain.RefreshToken) error {
+	model := refreshTokenModel{
+		ID:        t.ID,
+		FamilyID:  t.FamilyID,
+		UserID:    t.UserID,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt,
+		UsedAt:    t.UsedAt,
+		RevokedAt: t.RevokedAt,
+		MFA:       t.MFA,
+	}
+	return r.db.WithContext(ctx).Create(&model).Error
+}
+
+func (r *GormRepository) FindRefreshToken(ctx context.Context, id uuid.UUID) (domain.RefreshToken, error) {
+	var model refreshTokenModel
+	if err := r.db.WithContext(ctx).Take(&model, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.RefreshToken{}, pkgErrors.New("not_found", "refresh token not found")
+		}
+		return domain.RefreshToken{}, err
+	}
+	return domain.RefreshToken{
+		ID:        model.ID,
+		FamilyID:  model.FamilyID,
+		UserID:    model.UserID,
+		ExpiresAt: model.ExpiresAt,
+		CreatedAt: model.CreatedAt,
+		UsedAt:    model.UsedAt,
+		RevokedAt: model.RevokedAt,
+		MFA:       model.MFA,
+	}, nil
+}
+
+// UseRefreshToken relies on a conditional update so that of two concurrent
+// refreshes with the same token only one succeeds.
+func (r *GormRepository) UseRefreshToken(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&refreshTokenModel{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *GormRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&refreshTokenModel{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *GormRepository) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&refreshTokenModel{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+)
+
+type revokedTokenModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+	RevokedAt time.Time
+}
+
+func (revokedTokenModel) TableName() string { return "revoked_tokens" }
+
+// RevokeToken ignores tokens that are already revoked so logging out twice succeeds.
+func (r *GormRepository) RevokeToken(ctx context.Context, t domain.RevokedToken) error {
+	model := revokedTokenModel{ID: t.ID, ExpiresAt: t.ExpiresAt, RevokedAt: t.RevokedAt}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model).Error
+}
+
+func (r *GormRepository) ListRevoked(ctx context.Context, now time.Time) ([]domain.RevokedToken, error) {
+	var models []revokedTokenModel
+	if err := r.db.WithContext(ctx).Where("expires_at > ?", now).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	tokens := make([]domain.RevokedToken, 0, len(models))
+	for _, m := range models {
+		tokens = append(tokens, domain.RevokedToken{ID: m.ID, ExpiresAt: m.ExpiresAt, RevokedAt: m.RevokedAt})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+type hotelRoleModel struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	HotelID   uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	Role      string    `gorm:"primaryKey"`
+	GrantedBy uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (hotelRoleModel) TableName() string { return "user_hotel_roles" }
+
+// AssignHotelRole relies on the primary key so that concurrent grants of
+// the same role store one row.
+func (r *GormRepository) AssignHotelRole(ctx context.Context, grant domain.HotelRole) error {
+	model := hotelRoleModel{
+		UserID:    grant.UserID,
+		HotelID:   grant.HotelID,
+		Role:      string(grant.Role),
+		GrantedBy: grant.GrantedBy,
+		CreatedAt: grant.CreatedAt,
+	}
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("conflict", "hotel role already assigned")
+	}
+	return nil
+}
+
+func (r *GormRepository) RemoveHotelRole(ctx context.Context, userID, hotelID uuid.UUID, role valueobject.Role) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND hotel_id = ? AND role = ?", userID, hotelID, string(role)).
+		Delete(&hotelRoleModel{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *GormRepository) ListHotelRoles(ctx context.Context, userID uuid.UUID) ([]domain.HotelRole, error) {
+	var models []hotelRoleModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	grants := make([]domain.HotelRole, 0, len(models))
+	for _, m := range models {
+		grants = append(grants, domain.HotelRole{
+			UserID:    m.UserID,
+			HotelID:   m.HotelID,
+			Role:      valueobject.Role(m.Role),
+			GrantedBy: m.GrantedBy,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return grants, nil
+}
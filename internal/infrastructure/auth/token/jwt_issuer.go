@@ -4,36 +4,80 @@ import (
 	"context"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
-// JWTIssuer issues HS256 tokens.
+// accessTokenTTL bounds how long an access token authorizes API calls.
+const accessTokenTTL = 30 * time.Minute
+
+// mfaChallengeTTL bounds how long a passed password step waits for the
+// second factor.
+const mfaChallengeTTL = 5 * time.Minute
+
+// JWTIssuer signs tokens carrying the shared middleware.Claims with the
+// key ring's current key.
 type JWTIssuer struct {
-	secret []byte
+	keys     *KeyRing
+	verifier *middleware.Verifier
 }
 
-func NewJWTIssuer(secret string) *JWTIssuer {
-	return &JWTIssuer{secret: []byte(secret)}
+func NewJWTIssuer(keys *KeyRing) *JWTIssuer {
+	return &JWTIssuer{keys: keys, verifier: middleware.NewVerifier("", keys)}
 }
 
-func (i *JWTIssuer) Generate(ctx context.Context, user domain.User) (string, string, error) {
-	accessClaims := jwt.MapClaims{
-		"sub":   user.ID.String(),
-		"role":  user.Role,
-		"email": user.Email,
-		"exp":   time.Now().Add(30 * time.Minute).Unix(),
+func (i *JWTIssuer) Generate(ctx context.Context, user domain.User, session domain.RefreshToken) (string, string, error) {
+	now := time.Now()
+	accessClaims := middleware.NewClaims(middleware.TokenTypeAccess, user.ID.String(), user.Role, user.Email, now, accessTokenTTL)
+	accessClaims.EmailVerified = user.EmailVerified()
+	accessClaims.AMR = []string{middleware.AMRPassword}
+	if session.MFA {
+		accessClaims.AMR = append(accessClaims.AMR, middleware.AMROTP)
 	}
-	refreshClaims := jwt.MapClaims{
-		"sub": user.ID.String(),
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+	for _, grant := range user.HotelRoles {
+		accessClaims.HotelRoles = append(accessClaims.HotelRoles, middleware.HotelRole{HotelID: grant.HotelID.String(), Role: string(grant.Role)})
 	}
+	refreshClaims := middleware.NewClaims(middleware.TokenTypeRefresh, user.ID.String(), "", "", now, session.ExpiresAt.Sub(now))
+	refreshClaims.ID = session.ID.String()
 
-	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString(i.secret)
+	access, err := i.keys.sign(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
-	refresh, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(i.secret)
+	refresh, err := i.keys.sign(refreshClaims)
 	return access, refresh, err
 }
+
+func (i *JWTIssuer) ParseRefresh(ctx context.Context, token string) (uuid.UUID, error) {
+	invalid := pkgErrors.New("unauthorized", "invalid refresh token")
+	claims, err := i.verifier.Parse(ctx, token)
+	if err != nil || claims.Type != middleware.TokenTypeRefresh {
+		return uuid.Nil, invalid
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+	return id, nil
+}
+
+func (i *JWTIssuer) IssueMFAChallenge(ctx context.Context, user domain.User) (string, error) {
+	claims := middleware.NewClaims(middleware.TokenTypeMFA, user.ID.String(), "", "", time.Now(), mfaChallengeTTL)
+	return i.keys.sign(claims)
+}
+
+func (i *JWTIssuer) ParseMFAChallenge(ctx context.Context, token string) (uuid.UUID, error) {
+	invalid := pkgErrors.New("unauthorized", "invalid mfa token")
+	claims, err := i.verifier.Parse(ctx, token)
+	if err != nil || claims.Type != middleware.TokenTypeMFA {
+		return uuid.Nil, invalid
+	}
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+	return id, nil
+}
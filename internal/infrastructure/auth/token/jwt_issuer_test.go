@@ -0,0 +1,151 @@
+package token_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/token"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+func TestJWTIssuerClaimsContract(t *testing.T) {
+	keys, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	issuer := token.NewJWTIssuer(keys)
+	verifier := middleware.NewVerifier("", keys)
+	user := domain.User{ID: uuid.New(), Email: "user@example.com", Role: "customer"}
+	session := domain.NewRefreshToken(user.ID, uuid.Nil, false, time.Now())
+
+	access, refresh, err := issuer.Generate(context.Background(), user, session)
+	require.NoError(t, err)
+
+	claims, err := verifier.ParseAccess(context.Background(), access)
+	require.NoError(t, err)
+	require.Equal(t, user.ID.String(), claims.UserID)
+	require.Equal(t, user.ID.String(), claims.Subject)
+	require.Equal(t, "customer", claims.Role)
+	require.Equal(t, user.Email, claims.Email)
+	require.False(t, claims.EmailVerified)
+	require.NotEmpty(t, claims.ID)
+	require.Empty(t, claims.HotelRoles)
+
+	hotelID := uuid.New()
+	user.HotelRoles = []domain.HotelRole{{UserID: user.ID, HotelID: hotelID, Role: valueobject.RoleFrontDesk}}
+	staffAccess, _, err := issuer.Generate(context.Background(), user, session)
+	require.NoError(t, err)
+	staff, err := verifier.ParseAccess(context.Background(), staffAccess)
+	require.NoError(t, err)
+	require.Equal(t, []middleware.HotelRole{{HotelID: hotelID.String(), Role: "front_desk"}}, staff.HotelRoles)
+	user.HotelRoles = nil
+
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+	verifiedAccess, _, err := issuer.Generate(context.Background(), user, session)
+	require.NoError(t, err)
+	verified, err := verifier.ParseAccess(context.Background(), verifiedAccess)
+	require.NoError(t, err)
+	require.True(t, verified.EmailVerified)
+
+	// refresh tokens never authorize API calls, and access tokens cannot refresh
+	_, err = verifier.ParseAccess(context.Background(), refresh)
+	require.Error(t, err)
+	_, err = issuer.ParseRefresh(context.Background(), access)
+	require.Error(t, err)
+
+	id, err := issuer.ParseRefresh(context.Background(), refresh)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, id)
+
+	other, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	_, err = middleware.NewVerifier("", other).ParseAccess(context.Background(), access)
+	require.Error(t, err)
+}
+
+func TestJWTIssuerMFA(t *testing.T) {
+	keys, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	issuer := token.NewJWTIssuer(keys)
+	verifier := middleware.NewVerifier("", keys)
+	user := domain.User{ID: uuid.New(), Email: "admin@example.com", Role: "admin"}
+
+	for _, mfa := range []bool{false, true} {
+		access, _, err := issuer.Generate(context.Background(), user, domain.NewRefreshToken(user.ID, uuid.Nil, mfa, time.Now()))
+		require.NoError(t, err)
+		claims, err := verifier.ParseAccess(context.Background(), access)
+		require.NoError(t, err)
+		require.Contains(t, claims.AMR, middleware.AMRPassword)
+		require.Equal(t, mfa, claims.MFA())
+		require.Equal(t, mfa, claims.Admin())
+	}
+
+	// the challenge only identifies the user for the second step
+	challenge, err := issuer.IssueMFAChallenge(context.Background(), user)
+	require.NoError(t, err)
+	id, err := issuer.ParseMFAChallenge(context.Background(), challenge)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, id)
+	_, err = verifier.ParseAccess(context.Background(), challenge)
+	require.Error(t, err)
+	_, err = issuer.ParseRefresh(context.Background(), challenge)
+	require.Error(t, err)
+
+	access, refresh, err := issuer.Generate(context.Background(), user, domain.NewRefreshToken(user.ID, uuid.Nil, true, time.Now()))
+	require.NoError(t, err)
+	for _, other := range []string{access, refresh} {
+		_, err = issuer.ParseMFAChallenge(context.Background(), other)
+		require.Error(t, err)
+	}
+}
+
+func TestLoadKeyRingRotation(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldFile := writePEM(t, dir, "old.pem", oldKey)
+	newFile := writePEM(t, dir, "new.pem", newKey)
+
+	before, err := token.LoadKeyRing(oldFile, nil)
+	require.NoError(t, err)
+	user := domain.User{ID: uuid.New(), Role: "customer"}
+	oldAccess, _, err := token.NewJWTIssuer(before).Generate(context.Background(), user, domain.NewRefreshToken(user.ID, uuid.Nil, false, time.Now()))
+	require.NoError(t, err)
+
+	// after rotating, the old key stays published so its tokens keep verifying
+	after, err := token.LoadKeyRing(newFile, []string{oldFile})
+	require.NoError(t, err)
+	require.Len(t, after.JWKS().Keys, 2)
+	require.Equal(t, "RSA", after.JWKS().Keys[0].Kty)
+
+	newAccess, _, err := token.NewJWTIssuer(after).Generate(context.Background(), user, domain.NewRefreshToken(user.ID, uuid.Nil, false, time.Now()))
+	require.NoError(t, err)
+	verifier := middleware.NewVerifier("", after)
+	_, err = verifier.ParseAccess(context.Background(), oldAccess)
+	require.NoError(t, err)
+	_, err = verifier.ParseAccess(context.Background(), newAccess)
+	require.NoError(t, err)
+}
+
+func writePEM(t *testing.T, dir, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return file
+}
@@ -0,0 +1,157 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+// KeyRing holds the key new tokens are signed with plus additional public
+// keys that stay published, so tokens signed with a retired key keep
+// verifying until they expire and a new key can be published before use.
+// It also publishes the keys other services sign their service tokens with.
+type KeyRing struct {
+	signer crypto.Signer
+	method jwt.SigningMethod
+	kid    string
+	keys   map[string]middleware.VerificationKey
+	jwks   middleware.JWKSet
+}
+
+// NewKeyRing signs with signer, an Ed25519 or RSA private key, and also
+// publishes the additional public keys.
+func NewKeyRing(signer crypto.Signer, additional ...crypto.PublicKey) (*KeyRing, error) {
+	method, err := middleware.SigningMethodFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	ring := &KeyRing{signer: signer, method: method, keys: map[string]middleware.VerificationKey{}}
+	for i, pub := range append([]crypto.PublicKey{signer.Public()}, additional...) {
+		jwk, err := ring.publish(pub, "")
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			ring.kid = jwk.Kid
+		}
+	}
+	return ring, nil
+}
+
+// TrustService publishes pub as the key service signs its service tokens
+// with. Tokens it verifies must carry that service as their subject.
+func (k *KeyRing) TrustService(service string, pub crypto.PublicKey) error {
+	_, err := k.publish(pub, service)
+	return err
+}
+
+// LoadServiceKeys publishes the service keys listed as name=file entries,
+// e.g. booking-service=/keys/booking.pub. A file may hold the public or
+// the private key.
+func (k *KeyRing) LoadServiceKeys(entries []string) error {
+	for _, entry := range entries {
+		service, file, ok := strings.Cut(entry, "=")
+		if !ok || service == "" || file == "" {
+			return fmt.Errorf("service key %q: want name=file", entry)
+		}
+		key, err := readPublicKey(file)
+		if err != nil {
+			return err
+		}
+		if err := k.TrustService(service, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServiceSigner mints service tokens for service with the ring's signing key.
+func (k *KeyRing) ServiceSigner(service string) (*middleware.ServiceSigner, error) {
+	return middleware.NewServiceSigner(service, k.signer)
+}
+
+func (k *KeyRing) publish(pub crypto.PublicKey, service string) (middleware.JWK, error) {
+	jwk, err := middleware.NewJWK(pub)
+	if err != nil {
+		return middleware.JWK{}, err
+	}
+	if existing, dup := k.keys[jwk.Kid]; dup {
+		if existing.Service != service {
+			return jwk, fmt.Errorf("key %s is already published for %q", jwk.Kid, existing.Service)
+		}
+		return jwk, nil
+	}
+	jwk.Service = service
+	k.keys[jwk.Kid] = middleware.VerificationKey{Public: pub, Service: service}
+	k.jwks.Keys = append(k.jwks.Keys, jwk)
+	return jwk, nil
+}
+
+// LoadKeyRing reads the signing key and the additional keys from PEM files.
+// Additional keys may be public or private keys.
+func LoadKeyRing(signingFile string, additionalFiles []string) (*KeyRing, error) {
+	signing, err := middleware.ReadPEMKey(signingFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := signing.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: signing key must be a private key", signingFile)
+	}
+	additional := make([]crypto.PublicKey, 0, len(additionalFiles))
+	for _, file := range additionalFiles {
+		key, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		additional = append(additional, key)
+	}
+	return NewKeyRing(signer, additional...)
+}
+
+// readPublicKey reads a PEM public key, or the public half of a private key.
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	key, err := middleware.ReadPEMKey(file)
+	if err != nil {
+		return nil, err
+	}
+	if private, ok := key.(crypto.Signer); ok {
+		return private.Public(), nil
+	}
+	return key, nil
+}
+
+// GenerateKeyRing signs with a fresh Ed25519 key. Tokens do not survive a
+// restart, so it only suits local development.
+func GenerateKeyRing() (*KeyRing, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyRing(private)
+}
+
+// Key implements middleware.KeySource for tokens verified inside auth-service.
+func (k *KeyRing) Key(_ context.Context, kid string) (middleware.VerificationKey, error) {
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	return middleware.VerificationKey{}, pkgErrors.New("unauthorized", "unknown signing key")
+}
+
+// JWKS returns the published public keys.
+func (k *KeyRing) JWKS() middleware.JWKSet { return k.jwks }
+
+func (k *KeyRing) sign(claims middleware.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	return token.SignedString(k.signer)
+}
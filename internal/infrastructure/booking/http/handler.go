@@ -13,8 +13,10 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking/assembler"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/utils"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // Handler exposes booking endpoints.
@@ -29,12 +31,32 @@ func NewHandler(service *booking.Service) *Handler {
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Get("/bookings", h.listBookings)
-	r.Post("/bookings", h.createBooking)
+	r.With(middleware.RequireVerifiedEmail).Post("/bookings", h.createBooking)
 	r.Get("/bookings/{id}", h.getBooking)
 	r.Get("/bookings/{id}/status", h.getStatus)
 	r.Post("/bookings/{id}/cancel", h.cancelBooking)
-	r.Post("/bookings/{id}/status", h.updateStatus)
-	r.Post("/bookings/{id}/checkpoint", h.checkpoint)
+	r.Post("/bookings/{id}/modify", h.modifyBooking)
+	r.With(middleware.RequirePermission(valueobject.PermBookingCheckIn, h.bookingHotel)).Post("/bookings/{id}/checkpoint", h.checkpoint)
+	r.With(middleware.RequireVerifiedEmail).Post("/reservations", h.createReservation)
+	r.Get("/reservations/{id}", h.getReservation)
+	r.Post("/reservations/{id}/cancel", h.cancelReservation)
+	r.Group(func(r chi.Router) {
+		r.Use(adminOnly)
+		r.Post("/bookings/{id}/status", h.updateStatus)
+		r.Get("/outbox", h.listOutbox)
+		r.Get("/outbox/{id}", h.getOutboxMessage)
+		r.Post("/outbox/{id}/replay", h.replayOutbox)
+	})
+	return r
+}
+
+// InternalRoutes exposes endpoints for other services only; mount them
+// behind middleware.JWT restricted to middleware.RoleService. Each route
+// accepts only the service that calls it.
+func (h *Handler) InternalRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.With(middleware.AllowServices("payment-service")).Post("/bookings/{id}/status", h.syncStatus)
+	r.With(middleware.AllowServices("auth-service")).Get("/users/{id}/bookings", h.userBookings)
 	return r
 }
 
@@ -43,17 +65,24 @@ func (h *Handler) Routes() http.Handler {
 // @Accept json
 // @Produce json
 // @Param request body dto.BookingRequest true "Booking payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
 // @Success 201 {object} dto.BookingResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /bookings [post]
 func (h *Handler) createBooking(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
 	var input dto.BookingRequest
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
 		return
 	}
-	cmd, err := assembler.FromRequest(input)
+	cmd, err := assembler.FromRequest(userID, input)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
@@ -70,12 +99,15 @@ func (h *Handler) createBooking(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Cancel booking
+// @Description Confirmed bookings are charged the cancellation policy penalty; the rest is refunded asynchronously. Only the booking's owner, staff holding booking:write at its hotel (hotel_manager) or an admin may cancel it.
 // @Tags Bookings
 // @Produce json
 // @Param id path string true "Booking ID"
-// @Success 200 {object} dto.BookingResponse
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
+// @Success 200 {object} dto.BookingCancellationResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /bookings/{id}/cancel [post]
 func (h *Handler) cancelBooking(w http.ResponseWriter, r *http.Request) {
@@ -84,21 +116,168 @@ func (h *Handler) cancelBooking(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid id"))
 		return
 	}
-	if err := h.service.CancelBooking(r.Context(), bookingID); err != nil {
+	if err := h.authorizeBooking(r, bookingID, valueobject.PermBookingWrite); err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
 	}
-	bk, err := h.service.GetBooking(r.Context(), bookingID)
+	res, err := h.service.CancelBooking(r.Context(), bookingID)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
 	}
-	resp := assembler.ToResponse(bk, domain.PaymentResult{})
-	resource := utils.NewResource(resp.ID, "booking", "/api/v1/bookings/"+resp.ID, resp)
+	resp := assembler.ToCancellationResponse(res)
+	resource := utils.NewResource(resp.Booking.ID, "booking", "/api/v1/bookings/"+resp.Booking.ID, resp)
 	utils.Respond(w, http.StatusOK, "booking cancelled", resource)
 }
 
+// @Summary Modify booking
+// @Description Change dates, guests or room type of a confirmed booking; the price difference is charged or refunded. Only the booking's owner, staff holding booking:write at its hotel (hotel_manager) or an admin may modify it.
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body dto.ModifyBookingRequest true "Modification payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
+// @Success 200 {object} dto.BookingModificationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /bookings/{id}/modify [post]
+func (h *Handler) modifyBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	if err := h.authorizeBooking(r, bookingID, valueobject.PermBookingWrite); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var req dto.ModifyBookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	cmd, err := assembler.FromModifyRequest(req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	res, err := h.service.ModifyBooking(r.Context(), bookingID, cmd)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToModificationResponse(res)
+	resource := utils.NewResource(resp.Booking.ID, "booking", "/api/v1/bookings/"+resp.Booking.ID, resp)
+	utils.Respond(w, http.StatusOK, "booking modified", resource)
+}
+
+// @Summary Create reservation
+// @Description Book several rooms at once; availability is checked for every line and one combined payment is opened.
+// @Tags Reservations
+// @Accept json
+// @Produce json
+// @Param request body dto.ReservationRequest true "Reservation payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
+// @Success 201 {object} dto.ReservationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /reservations [post]
+func (h *Handler) createReservation(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.UserIDFromContext(r.Context())
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var input dto.ReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	cmd, err := assembler.FromReservationRequest(userID, input)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+
+	res, pay, err := h.service.CreateReservation(r.Context(), cmd)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToReservationResponse(res, pay)
+	resource := utils.NewResource(resp.ID, "reservation", "/api/v1/reservations/"+resp.ID, resp)
+	utils.Respond(w, http.StatusCreated, "reservation created", resource)
+}
+
+// @Summary Get reservation
+// @Tags Reservations
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} dto.ReservationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /reservations/{id} [get]
+func (h *Handler) getReservation(w http.ResponseWriter, r *http.Request) {
+	reservationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	res, err := h.service.GetReservation(r.Context(), reservationID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	if err := h.authorizeBooking(r, res.LeadBookingID, valueobject.PermBookingRead); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToReservationResponse(res, domain.PaymentResult{})
+	resource := utils.NewResource(resp.ID, "reservation", "/api/v1/reservations/"+resp.ID, resp)
+	utils.Respond(w, http.StatusOK, "reservation retrieved", resource)
+}
+
+// @Summary Cancel reservation
+// @Description Cancels every active line; single rooms are cancelled through the booking cancel endpoint. The same access rules as for the lead booking apply.
+// @Tags Reservations
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
+// @Success 200 {object} dto.ReservationCancellationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /reservations/{id}/cancel [post]
+func (h *Handler) cancelReservation(w http.ResponseWriter, r *http.Request) {
+	reservationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	if err := h.authorizeReservation(r, reservationID, valueobject.PermBookingWrite); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	res, err := h.service.CancelReservation(r.Context(), reservationID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToReservationCancellationResponse(reservationID, res)
+	resource := utils.NewResource(resp.ID, "reservation", "/api/v1/reservations/"+resp.ID, resp)
+	utils.Respond(w, http.StatusOK, "reservation cancelled", resource)
+}
+
 // @Summary Get booking
+// @Description Only the booking's owner, staff holding booking:read at its hotel or an admin may read it; anyone else gets 404.
 // @Tags Bookings
 // @Produce json
 // @Param id path string true "Booking ID"
@@ -113,17 +292,21 @@ func (h *Handler) getBooking(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid id"))
 		return
 	}
-	bk, err := h.service.GetBooking(r.Context(), bookingID)
+	bk, err := h.authorizedBooking(r, bookingID, valueobject.PermBookingRead)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
 	}
 	resp := assembler.ToResponse(bk, domain.PaymentResult{})
+	if room, err := h.service.GetRoomAssignment(r.Context(), bookingID); err == nil {
+		resp.Room = assembler.ToRoomAssignment(room)
+	}
 	resource := utils.NewResource(resp.ID, "booking", "/api/v1/bookings/"+resp.ID, resp)
 	utils.Respond(w, http.StatusOK, "booking retrieved", resource)
 }
 
 // @Summary Get booking status
+// @Description The same access rules as for reading the booking apply.
 // @Tags Bookings
 // @Produce json
 // @Param id path string true "Booking ID"
@@ -138,7 +321,7 @@ func (h *Handler) getStatus(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid id"))
 		return
 	}
-	resp, err := h.service.GetBooking(r.Context(), bookingID)
+	resp, err := h.authorizedBooking(r, bookingID, valueobject.PermBookingRead)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
@@ -147,32 +330,67 @@ func (h *Handler) getStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary List bookings
+// @Description Customers only see their own bookings; admins may filter by any user, and hotel staff by a hotel_id they hold booking:read at.
 // @Tags Bookings
 // @Produce json
 // @Param limit query int false "pagination limit (default 50)"
 // @Param offset query int false "pagination offset"
+// @Param user_id query string false "user id (admin only)"
+// @Param status query string false "comma separated statuses"
+// @Param hotel_id query string false "hotel id"
+// @Param room_type_id query string false "room type id"
+// @Param check_in_from query string false "check-in on or after (YYYY-MM-DD)"
+// @Param check_in_to query string false "check-in before (YYYY-MM-DD)"
+// @Param check_out_from query string false "check-out on or after (YYYY-MM-DD)"
+// @Param check_out_to query string false "check-out before (YYYY-MM-DD)"
+// @Param created_from query string false "created on or after (YYYY-MM-DD or RFC3339)"
+// @Param created_to query string false "created before (YYYY-MM-DD or RFC3339)"
+// @Param sort query string false "e.g. -check_in,created_at (check_in, check_out, created_at, total_price, status)"
 // @Success 200 {array} dto.BookingResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /bookings [get]
 func (h *Handler) listBookings(w http.ResponseWriter, r *http.Request) {
-	opts := parseQueryOptions(r)
-	list, err := h.service.ListBookings(r.Context(), opts)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		writeError(w, pkgErrors.New("unauthorized", "missing claims"))
+		return
+	}
+	filter, err := assembler.FromSearchQuery(parseSearchQuery(r))
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
 	}
-	resp := make([]dto.BookingResponse, 0, len(list))
-	for _, b := range list {
+	// Staff may list the bookings of a hotel they hold booking:read at; everyone
+	// else, including admins without a second factor, sees their own bookings.
+	if !claims.Can(valueobject.PermBookingRead, filter.HotelID) {
+		userID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			writeError(w, pkgErrors.New("forbidden", "insufficient role"))
+			return
+		}
+		filter.UserID = userID
+	}
+
+	result, err := h.service.SearchBookings(r.Context(), filter)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := make([]dto.BookingResponse, 0, len(result.Bookings))
+	for _, b := range result.Bookings {
 		resp = append(resp, assembler.ToResponse(b, domain.PaymentResult{}))
 	}
 	var resources []utils.Resource
 	for _, b := range resp {
 		resources = append(resources, utils.NewResource(b.ID, "booking", "/api/v1/bookings/"+b.ID, b))
 	}
-	utils.RespondWithCount(w, http.StatusOK, "bookings listed", resources, len(resources))
+	utils.RespondWithTotal(w, http.StatusOK, "bookings listed", resources, len(resources), result.Total)
 }
 
 // @Summary Booking checkpoint
+// @Description Check a guest in or out. Requires booking:checkin at the booking's hotel (hotel_manager, front_desk) or admin.
 // @Tags Bookings
 // @Accept json
 // @Produce json
@@ -180,6 +398,8 @@ func (h *Handler) listBookings(w http.ResponseWriter, r *http.Request) {
 // @Param request body dto.CheckpointRequest true "Checkpoint payload"
 // @Success 200 {object} dto.BookingResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /bookings/{id}/checkpoint [post]
 func (h *Handler) checkpoint(w http.ResponseWriter, r *http.Request) {
@@ -193,7 +413,7 @@ func (h *Handler) checkpoint(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
 		return
 	}
-	if err := h.service.Checkpoint(r.Context(), bookingID, req.Action); err != nil {
+	if err := h.service.Checkpoint(r.Context(), bookingID, req.Action, req.RoomNumber); err != nil {
 		writeError(w, pkgErrors.FromError(err))
 		return
 	}
@@ -203,6 +423,9 @@ func (h *Handler) checkpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	resp := assembler.ToResponse(bk, domain.PaymentResult{})
+	if room, err := h.service.GetRoomAssignment(r.Context(), bookingID); err == nil {
+		resp.Room = assembler.ToRoomAssignment(room)
+	}
 	resource := utils.NewResource(resp.ID, "booking", "/api/v1/bookings/"+resp.ID, resp)
 	utils.Respond(w, http.StatusOK, "status updated", resource)
 }
@@ -245,12 +468,208 @@ func (h *Handler) updateStatus(w http.ResponseWriter, r *http.Request) {
 	utils.Respond(w, http.StatusOK, "booking status updated", resource)
 }
 
+// @Summary Apply payment outcome to booking (service token only)
+// @Tags Internal
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body map[string]string true "Status payload"
+// @Success 200 {object} dto.BookingResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /internal/bookings/{id}/status [post]
+// @Security BearerAuth
+func (h *Handler) syncStatus(w http.ResponseWriter, r *http.Request) {
+	h.updateStatus(w, r)
+}
+
+// @Summary List a user's bookings (service token only)
+// @Description Every booking of the user, collected by auth-service for personal data exports.
+// @Tags Internal
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} dto.BookingResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /internal/users/{id}/bookings [get]
+// @Security BearerAuth
+func (h *Handler) userBookings(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	bookings, err := h.service.UserBookings(r.Context(), userID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var resources []utils.Resource
+	for _, b := range bookings {
+		resp := assembler.ToResponse(b, domain.PaymentResult{})
+		resources = append(resources, utils.NewResource(resp.ID, "booking", "/api/v1/bookings/"+resp.ID, resp))
+	}
+	utils.RespondWithCount(w, http.StatusOK, "bookings listed", resources, len(resources))
+}
+
+// @Summary List outbox messages (admin)
+// @Tags Outbox
+// @Produce json
+// @Param status query string false "pending, delivered or failed"
+// @Param limit query int false "pagination limit (default 50)"
+// @Param offset query int false "pagination offset"
+// @Success 200 {array} dto.OutboxMessageResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /outbox [get]
+func (h *Handler) listOutbox(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	list, err := h.service.ListOutbox(r.Context(), q.Get("status"), query.Options{Limit: limit, Offset: offset})
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var resources []utils.Resource
+	for _, m := range list {
+		resp := assembler.ToOutboxResponse(m)
+		resources = append(resources, utils.NewResource(resp.ID, "outbox_message", "/api/v1/outbox/"+resp.ID, resp))
+	}
+	utils.RespondWithCount(w, http.StatusOK, "outbox messages listed", resources, len(resources))
+}
+
+// @Summary Get outbox message (admin)
+// @Tags Outbox
+// @Produce json
+// @Param id path string true "Outbox message ID"
+// @Success 200 {object} dto.OutboxMessageResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /outbox/{id} [get]
+func (h *Handler) getOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	m, err := h.service.GetOutboxMessage(r.Context(), id)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToOutboxResponse(m)
+	utils.Respond(w, http.StatusOK, "outbox message retrieved", utils.NewResource(resp.ID, "outbox_message", "/api/v1/outbox/"+resp.ID, resp))
+}
+
+// @Summary Replay outbox message (admin)
+// @Description Puts a stuck or failed event back in the queue for immediate delivery.
+// @Tags Outbox
+// @Produce json
+// @Param id path string true "Outbox message ID"
+// @Success 200 {object} dto.OutboxMessageResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /outbox/{id}/replay [post]
+func (h *Handler) replayOutbox(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	m, err := h.service.ReplayOutbox(r.Context(), id)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToOutboxResponse(m)
+	utils.Respond(w, http.StatusOK, "outbox message queued for replay", utils.NewResource(resp.ID, "outbox_message", "/api/v1/outbox/"+resp.ID, resp))
+}
+
+// bookingHotel resolves the hotel of /bookings/{id} routes.
+func (h *Handler) bookingHotel(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, pkgErrors.New("bad_request", "invalid id")
+	}
+	return h.service.BookingHotel(r.Context(), id)
+}
+
+// authorizeBooking lets the booking's owner, staff holding perm at its hotel
+// and admins act on it. Anyone else is told the booking does not exist, so
+// other customers' booking IDs cannot be probed.
+func (h *Handler) authorizeBooking(r *http.Request, bookingID uuid.UUID, perm valueobject.Permission) error {
+	_, err := h.authorizedBooking(r, bookingID, perm)
+	return err
+}
+
+// authorizedBooking is authorizeBooking returning the booking it checked.
+func (h *Handler) authorizedBooking(r *http.Request, bookingID uuid.UUID, perm valueobject.Permission) (domain.Booking, error) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		return domain.Booking{}, pkgErrors.New("unauthorized", "missing claims")
+	}
+	bk, err := h.service.GetBooking(r.Context(), bookingID)
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	if userID, err := middleware.UserIDFromContext(r.Context()); err == nil && userID == bk.UserID {
+		return bk, nil
+	}
+	if claims.Admin() {
+		return bk, nil
+	}
+	if hotelID, err := h.service.BookingHotel(r.Context(), bookingID); err == nil && claims.Can(perm, hotelID) {
+		return bk, nil
+	}
+	return domain.Booking{}, pkgErrors.New("not_found", "booking not found")
+}
+
+// authorizeReservation applies the lead booking's access rules to its reservation.
+func (h *Handler) authorizeReservation(r *http.Request, reservationID uuid.UUID, perm valueobject.Permission) error {
+	res, err := h.service.GetReservation(r.Context(), reservationID)
+	if err != nil {
+		return err
+	}
+	return h.authorizeBooking(r, res.LeadBookingID, perm)
+}
+
+// adminOnly rejects callers whose JWT claims are not the admin role
+// confirmed with a second factor.
+func adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.ClaimsFromContext(r.Context())
+		if !ok || !claims.Admin() {
+			writeError(w, pkgErrors.New("forbidden", "insufficient role"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func writeError(w http.ResponseWriter, err pkgErrors.APIError) {
 	utils.Respond(w, pkgErrors.StatusCode(err), err.Message, err)
 }
 
-func parseQueryOptions(r *http.Request) query.Options {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	return query.Options{Limit: limit, Offset: offset}
+func parseSearchQuery(r *http.Request) dto.BookingSearchQuery {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	return dto.BookingSearchQuery{
+		UserID:       q.Get("user_id"),
+		Status:       q.Get("status"),
+		HotelID:      q.Get("hotel_id"),
+		RoomTypeID:   q.Get("room_type_id"),
+		CheckInFrom:  q.Get("check_in_from"),
+		CheckInTo:    q.Get("check_in_to"),
+		CheckOutFrom: q.Get("check_out_from"),
+		CheckOutTo:   q.Get("check_out_to"),
+		CreatedFrom:  q.Get("created_from"),
+		CreatedTo:    q.Get("created_to"),
+		Sort:         q.Get("sort"),
+		Limit:        limit,
+		Offset:       offset,
+	}
 }
@@ -2,8 +2,10 @@ package bookinghttp_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,7 +17,9 @@ import (
 	hdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	bookinghttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/http"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestBookingHandlerListWithPagination(t *testing.T) {
@@ -32,14 +36,262 @@ func TestBookingHandlerListWithPagination(t *testing.T) {
 	r.Mount("/", h.Routes())
 
 	req := httptest.NewRequest(http.MethodGet, "/bookings?limit=1&offset=0", nil)
+	req = withClaims(req, uuid.New(), "admin")
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
 	require.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestBookingHandlerListScopesCustomers(t *testing.T) {
+	mine, theirs := uuid.New(), uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	for _, userID := range []uuid.UUID{mine, theirs, theirs} {
+		id := uuid.New()
+		repo.store[id] = domain.Booking{ID: id, UserID: userID, Status: "confirmed", CheckIn: time.Now(), CheckOut: time.Now().Add(24 * time.Hour)}
+	}
+	svc := booking.NewService(repo, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+
+	list := func(target string, userID uuid.UUID, role string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if userID != uuid.Nil {
+			req = withClaims(req, userID, role)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// a customer asking for someone else's bookings still only sees their own
+	code, body := list("/bookings?user_id="+theirs.String(), mine, "customer")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `"total":1`)
+
+	code, body = list("/bookings?user_id="+theirs.String(), uuid.New(), "admin")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `"total":2`)
+
+	code, _ = list("/bookings?status=unknown", mine, "customer")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("/bookings?sort=password", mine, "customer")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("/bookings", uuid.Nil, "")
+	require.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestBookingHandlerStatusRoutes(t *testing.T) {
+	id := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{
+		id: {ID: id, Status: "pending_payment", CheckIn: time.Now(), CheckOut: time.Now().Add(24 * time.Hour)},
+	}}
+	svc := booking.NewService(repo, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	h := bookinghttp.NewHandler(svc)
+	r := chi.NewRouter()
+	r.Mount("/", h.Routes())
+	r.Route("/internal", func(r chi.Router) {
+		r.Use(middleware.JWT("secret", middleware.RoleService))
+		r.Mount("/", h.InternalRoutes())
+	})
+
+	send := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	body := func() *strings.Reader { return strings.NewReader(`{"status":"confirmed"}`) }
+
+	// customers cannot change a booking status themselves
+	req := withClaims(httptest.NewRequest(http.MethodPost, "/bookings/"+id.String()+"/status", body()), uuid.New(), "customer")
+	require.Equal(t, http.StatusForbidden, send(req))
+
+	// the internal route only accepts service tokens
+	req = httptest.NewRequest(http.MethodPost, "/internal/bookings/"+id.String()+"/status", body())
+	require.Equal(t, http.StatusUnauthorized, send(req))
+
+	// and of those only payment-service's
+	token, err := middleware.IssueServiceToken("secret", "hotel-service", time.Minute)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/internal/bookings/"+id.String()+"/status", body())
+	req.Header.Set("Authorization", "Bearer "+token)
+	require.Equal(t, http.StatusForbidden, send(req))
+
+	token, err = middleware.IssueServiceToken("secret", "payment-service", time.Minute)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/internal/bookings/"+id.String()+"/status", body())
+	req.Header.Set("Authorization", "Bearer "+token)
+	require.Equal(t, http.StatusOK, send(req))
+}
+
+func TestBookingHandlerCreateRequiresUserToken(t *testing.T) {
+	svc := booking.NewService(&bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+
+	body := `{"room_type_id":"` + uuid.NewString() + `","check_in":"2030-01-01","check_out":"2030-01-02"}`
+	service := &middleware.Claims{UserID: "payment-service", Role: middleware.RoleService, EmailVerified: true}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(body)),
+		// service tokens carry a service name rather than a user ID
+		httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(body)).
+			WithContext(context.WithValue(context.Background(), middleware.AuthContextKey, service)),
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestBookingHandlerCreateRequiresVerifiedEmail(t *testing.T) {
+	svc := booking.NewService(&bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+
+	unverified := &middleware.Claims{UserID: uuid.NewString(), Role: "customer"}
+	for _, path := range []string{"/bookings", "/reservations"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)).
+			WithContext(context.WithValue(context.Background(), middleware.AuthContextKey, unverified))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestBookingHandlerCheckpointRequiresHotelStaff(t *testing.T) {
+	id, hotelID := uuid.New(), uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{
+		id: {ID: id, RoomTypeID: uuid.New(), Status: "confirmed", CheckIn: time.Now(), CheckOut: time.Now().Add(24 * time.Hour)},
+	}}
+	svc := booking.NewService(repo, &hotelRepoStub{hotelID: hotelID}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+
+	checkIn := func(hotelRoles ...middleware.HotelRole) int {
+		claims := &middleware.Claims{UserID: uuid.NewString(), Role: "customer", EmailVerified: true, HotelRoles: hotelRoles}
+		req := httptest.NewRequest(http.MethodPost, "/bookings/"+id.String()+"/checkpoint", strings.NewReader(`{"action":"check_in"}`)).
+			WithContext(context.WithValue(context.Background(), middleware.AuthContextKey, claims))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, checkIn())
+	require.Equal(t, http.StatusForbidden, checkIn(middleware.HotelRole{HotelID: uuid.NewString(), Role: string(valueobject.RoleFrontDesk)}))
+	require.Equal(t, http.StatusOK, checkIn(middleware.HotelRole{HotelID: hotelID.String(), Role: string(valueobject.RoleFrontDesk)}))
+}
+
+func TestReservationHandlerRequiresOwnership(t *testing.T) {
+	hotelID, ownerID := uuid.New(), uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, reservations: map[uuid.UUID]domain.Reservation{}}
+	lines := []domain.Booking{
+		{ID: uuid.New(), RoomTypeID: uuid.New(), Status: "pending_payment", CheckIn: time.Now().Add(48 * time.Hour), CheckOut: time.Now().Add(72 * time.Hour), TotalPrice: 100},
+		{ID: uuid.New(), RoomTypeID: uuid.New(), Status: "pending_payment", CheckIn: time.Now().Add(48 * time.Hour), CheckOut: time.Now().Add(72 * time.Hour), TotalPrice: 100},
+	}
+	res, err := domain.NewReservation(ownerID, lines)
+	require.NoError(t, err)
+	repo.reservations[res.ID] = res
+	for _, line := range res.Lines {
+		repo.store[line.ID] = line
+	}
+	svc := booking.NewService(repo, &hotelRepoStub{hotelID: hotelID}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+	send := func(method, path string, userID uuid.UUID) int {
+		req := withClaims(httptest.NewRequest(method, path, nil), userID, "customer")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	stranger := uuid.New()
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/reservations/"+res.ID.String(), stranger))
+	require.Equal(t, http.StatusNotFound, send(http.MethodPost, "/reservations/"+res.ID.String()+"/cancel", stranger))
+	for _, line := range res.Lines {
+		require.Equal(t, domain.StatusPendingPayment, repo.store[line.ID].Status)
+	}
+
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/reservations/"+res.ID.String(), ownerID))
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/reservations/"+res.ID.String()+"/cancel", ownerID))
+	for _, line := range res.Lines {
+		require.Equal(t, domain.StatusCancelled, repo.store[line.ID].Status)
+	}
+}
+
+func TestBookingHandlerCancelAndModifyRequireOwnership(t *testing.T) {
+	hotelID, ownerID := uuid.New(), uuid.New()
+	newBooking := func(repo *bookingRepoStub) uuid.UUID {
+		id := uuid.New()
+		repo.store[id] = domain.Booking{ID: id, UserID: ownerID, RoomTypeID: uuid.New(), Status: "pending_payment", CheckIn: time.Now().Add(48 * time.Hour), CheckOut: time.Now().Add(72 * time.Hour), TotalPrice: 100}
+		return id
+	}
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	svc := booking.NewService(repo, &hotelRepoStub{hotelID: hotelID}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+	send := func(claims *middleware.Claims, path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).
+			WithContext(context.WithValue(context.Background(), middleware.AuthContextKey, claims))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	customer := func(id uuid.UUID, roles ...middleware.HotelRole) *middleware.Claims {
+		return &middleware.Claims{UserID: id.String(), Role: "customer", EmailVerified: true, HotelRoles: roles}
+	}
+
+	// another customer cannot touch the booking, nor learn that it exists
+	id := newBooking(repo)
+	require.Equal(t, http.StatusNotFound, send(customer(uuid.New()), "/bookings/"+id.String()+"/cancel", ""))
+	require.Equal(t, http.StatusNotFound, send(customer(uuid.New()), "/bookings/"+id.String()+"/modify", `{"guests":2}`))
+	// front desk staff may check guests in but not cancel their bookings
+	frontDesk := middleware.HotelRole{HotelID: hotelID.String(), Role: string(valueobject.RoleFrontDesk)}
+	require.Equal(t, http.StatusNotFound, send(customer(uuid.New(), frontDesk), "/bookings/"+id.String()+"/cancel", ""))
+	require.Equal(t, domain.StatusPendingPayment, repo.store[id].Status)
+
+	require.Equal(t, http.StatusOK, send(customer(ownerID), "/bookings/"+id.String()+"/cancel", ""))
+	manager := middleware.HotelRole{HotelID: hotelID.String(), Role: string(valueobject.RoleHotelManager)}
+	require.Equal(t, http.StatusOK, send(customer(uuid.New(), manager), "/bookings/"+newBooking(repo).String()+"/cancel", ""))
+	admin := &middleware.Claims{UserID: uuid.NewString(), Role: "admin", AMR: []string{middleware.AMRPassword, middleware.AMROTP}}
+	require.Equal(t, http.StatusOK, send(admin, "/bookings/"+newBooking(repo).String()+"/cancel", ""))
+}
+
+func TestBookingHandlerGetRequiresOwnership(t *testing.T) {
+	hotelID, ownerID, id := uuid.New(), uuid.New(), uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{
+		id: {ID: id, UserID: ownerID, RoomTypeID: uuid.New(), Status: "confirmed", CheckIn: time.Now().Add(48 * time.Hour), CheckOut: time.Now().Add(72 * time.Hour), TotalPrice: 100},
+	}}
+	svc := booking.NewService(repo, &hotelRepoStub{hotelID: hotelID}, &paymentGatewayStub{}, &notificationGatewayStub{})
+	r := chi.NewRouter()
+	r.Mount("/", bookinghttp.NewHandler(svc).Routes())
+	get := func(claims *middleware.Claims, path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil).
+			WithContext(context.WithValue(context.Background(), middleware.AuthContextKey, claims))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	customer := func(id uuid.UUID, roles ...middleware.HotelRole) *middleware.Claims {
+		return &middleware.Claims{UserID: id.String(), Role: "customer", EmailVerified: true, HotelRoles: roles}
+	}
+	frontDesk := middleware.HotelRole{HotelID: hotelID.String(), Role: string(valueobject.RoleFrontDesk)}
+
+	for _, path := range []string{"/bookings/" + id.String(), "/bookings/" + id.String() + "/status"} {
+		// another customer is told the booking does not exist
+		require.Equal(t, http.StatusNotFound, get(customer(uuid.New()), path), path)
+		require.Equal(t, http.StatusOK, get(customer(ownerID), path), path)
+		require.Equal(t, http.StatusOK, get(customer(uuid.New(), frontDesk), path), path)
+	}
+}
+
+func withClaims(req *http.Request, userID uuid.UUID, role string) *http.Request {
+	claims := &middleware.Claims{UserID: userID.String(), Role: role, EmailVerified: true, AMR: []string{middleware.AMRPassword, middleware.AMROTP}}
+	return req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, claims))
+}
+
 // stubs for booking handler test
 type bookingRepoStub struct {
-	store map[uuid.UUID]domain.Booking
+	store        map[uuid.UUID]domain.Booking
+	reservations map[uuid.UUID]domain.Reservation
 }
 
 func (b *bookingRepoStub) Create(ctx context.Context, bk domain.Booking) error { return nil }
@@ -65,12 +317,113 @@ func (b *bookingRepoStub) List(ctx context.Context, opts query.Options) ([]domai
 func (b *bookingRepoStub) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	return nil
 }
+func (b *bookingRepoStub) FindPendingBefore(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusPendingPayment && v.CreatedAt.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) FindNoShows(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusConfirmed && v.CheckIn.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if f.UserID == uuid.Nil || v.UserID == f.UserID {
+			out = append(out, v)
+		}
+	}
+	return domain.SearchResult{Bookings: out, Total: int64(len(out))}, nil
+}
+func (b *bookingRepoStub) FindDueForCheckout(context.Context, time.Time, uuid.UUID, int) ([]domain.Booking, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) ClaimOutbox(context.Context, time.Time, time.Duration, int) ([]domain.OutboxMessage, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) MarkDelivered(context.Context, uuid.UUID, time.Time) error { return nil }
+func (b *bookingRepoStub) MarkAttemptFailed(context.Context, uuid.UUID, string, time.Time, bool) error {
+	return nil
+}
+func (b *bookingRepoStub) ListOutbox(context.Context, string, query.Options) ([]domain.OutboxMessage, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) FindOutbox(context.Context, uuid.UUID) (domain.OutboxMessage, error) {
+	return domain.OutboxMessage{}, errors.New("not found")
+}
+func (b *bookingRepoStub) ReplayOutbox(context.Context, uuid.UUID) error { return nil }
+func (b *bookingRepoStub) SaveSaga(context.Context, domain.Saga, ...domain.Booking) error {
+	return nil
+}
+func (b *bookingRepoStub) FindSaga(context.Context, uuid.UUID) (domain.Saga, error) {
+	return domain.Saga{}, errors.New("not found")
+}
+func (b *bookingRepoStub) ClaimStaleSagas(context.Context, time.Time, time.Time, int) ([]domain.Saga, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) FindReservation(_ context.Context, id uuid.UUID) (domain.Reservation, error) {
+	res, ok := b.reservations[id]
+	if !ok {
+		return domain.Reservation{}, errors.New("not found")
+	}
+	for i, line := range res.Lines {
+		res.Lines[i] = b.store[line.ID]
+	}
+	return res, nil
+}
+func (b *bookingRepoStub) FindAssignment(context.Context, uuid.UUID) (domain.RoomAssignment, error) {
+	return domain.RoomAssignment{}, errors.New("not found")
+}
+func (b *bookingRepoStub) AssignRoom(ctx context.Context, bk domain.Booking, _ domain.RoomPicker) (domain.RoomAssignment, error) {
+	b.store[bk.ID] = bk
+	return domain.RoomAssignment{BookingID: bk.ID}, nil
+}
+func (b *bookingRepoStub) ReleaseRoom(ctx context.Context, bk domain.Booking) error {
+	b.store[bk.ID] = bk
+	return nil
+}
 func (b *bookingRepoStub) Save(ctx context.Context, bk domain.Booking) error {
 	b.store[bk.ID] = bk
 	return nil
 }
 
-type hotelRepoStub struct{}
+func (b *bookingRepoStub) Transition(ctx context.Context, bk domain.Booking, from string) error {
+	if b.store[bk.ID].Status != from {
+		return errors.New("booking is no longer " + from)
+	}
+	b.store[bk.ID] = bk
+	return nil
+}
+
+func (b *bookingRepoStub) Reserve(ctx context.Context, bk domain.Booking, _ domain.ReservationCheck) error {
+	b.store[bk.ID] = bk
+	return nil
+}
+
+func (b *bookingRepoStub) Amend(ctx context.Context, bk domain.Booking, _ domain.ReservationCheck) error {
+	b.store[bk.ID] = bk
+	return nil
+}
+func (b *bookingRepoStub) ReserveGroup(ctx context.Context, r domain.Reservation, _ domain.ReservationCheck) error {
+	for _, line := range r.Lines {
+		b.store[line.ID] = line
+	}
+	return nil
+}
+
+// hotelRepoStub places every room type in hotelID.
+type hotelRepoStub struct {
+	hotelID uuid.UUID
+}
 
 func (h *hotelRepoStub) CreateHotel(context.Context, hdomain.Hotel) error { return nil }
 func (h *hotelRepoStub) ListHotels(ctx context.Context, opts query.Options) ([]hdomain.Hotel, error) {
@@ -84,8 +437,8 @@ func (h *hotelRepoStub) ListAllRoomTypes(context.Context, query.Options) ([]hdom
 	return nil, nil
 }
 func (h *hotelRepoStub) CreateRoom(context.Context, hdomain.Room) error { return nil }
-func (h *hotelRepoStub) GetRoomType(context.Context, uuid.UUID) (hdomain.RoomType, error) {
-	return hdomain.RoomType{}, nil
+func (h *hotelRepoStub) GetRoomType(_ context.Context, id uuid.UUID) (hdomain.RoomType, error) {
+	return hdomain.RoomType{ID: id, HotelID: h.hotelID}, nil
 }
 func (h *hotelRepoStub) ListRooms(context.Context, query.Options) ([]hdomain.Room, error) {
 	return nil, nil
@@ -100,6 +453,9 @@ func (h *hotelRepoStub) GetRoom(context.Context, uuid.UUID) (hdomain.Room, error
 }
 func (h *hotelRepoStub) UpdateRoom(context.Context, uuid.UUID, hdomain.Room) error { return nil }
 func (h *hotelRepoStub) DeleteRoom(context.Context, uuid.UUID) error               { return nil }
+func (h *hotelRepoStub) NightlyInventory(context.Context, []uuid.UUID, valueobject.DateRange) ([]hdomain.NightlyInventory, error) {
+	return nil, nil
+}
 
 type paymentGatewayStub struct{}
 
@@ -112,6 +468,17 @@ func (p *paymentGatewayStub) Initiate(context.Context, uuid.UUID, float64) (doma
 	}, nil
 }
 
+func (p *paymentGatewayStub) Expire(context.Context, uuid.UUID) error { return nil }
+func (p *paymentGatewayStub) RequestAdditional(context.Context, uuid.UUID, float64) (domain.PaymentResult, error) {
+	return domain.PaymentResult{}, nil
+}
+func (p *paymentGatewayStub) Refund(context.Context, uuid.UUID, float64, string) (domain.RefundResult, error) {
+	return domain.RefundResult{}, nil
+}
+func (p *paymentGatewayStub) VoidAdjustments(context.Context, uuid.UUID) (float64, error) {
+	return 0, nil
+}
+
 type notificationGatewayStub struct{}
 
 func (n *notificationGatewayStub) Notify(context.Context, string, any) error { return nil }
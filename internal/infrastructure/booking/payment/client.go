@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -12,17 +13,19 @@ import (
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 // HTTPGateway calls payment service over HTTP.
 type HTTPGateway struct {
 	baseURL string
+	tokens  *middleware.ServiceSigner
 	client  *http.Client
 }
 
-func NewHTTPGateway(baseURL string) domain.PaymentGateway {
-	return &HTTPGateway{baseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+func NewHTTPGateway(baseURL string, tokens *middleware.ServiceSigner) domain.PaymentGateway {
+	return &HTTPGateway{baseURL: baseURL, tokens: tokens, client: &http.Client{Timeout: 5 * time.Second}}
 }
 
 func (g *HTTPGateway) Initiate(ctx context.Context, bookingID uuid.UUID, amount float64) (domain.PaymentResult, error) {
@@ -59,3 +62,151 @@ func (g *HTTPGateway) Initiate(ctx context.Context, bookingID uuid.UUID, amount
 		PaymentURL: result.PaymentURL,
 	}, nil
 }
+
+// Expire fails the pending payment of a booking.
+func (g *HTTPGateway) Expire(ctx context.Context, bookingID uuid.UUID) error {
+	url := fmt.Sprintf("%s/payments/by-booking/%s/expire", g.baseURL, bookingID.String())
+	req, _ := http.NewRequestWithContext(asService(ctx), http.MethodPost, url, nil)
+	if err := g.authorize(req); err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return pkgErrors.New("not_found", "payment not found")
+	case resp.StatusCode == http.StatusConflict:
+		return pkgErrors.New("conflict", "payment already settled")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("payment expiry failed: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RequestAdditional opens an adjustment payment for a price increase.
+func (g *HTTPGateway) RequestAdditional(ctx context.Context, bookingID uuid.UUID, amount float64) (domain.PaymentResult, error) {
+	payload := map[string]any{"booking_id": bookingID.String(), "amount": amount, "currency": "IDR", "kind": "adjustment"}
+	var result dto.PaymentResponse
+	if err := g.send(ctx, http.MethodPost, "/payments", payload, &result); err != nil {
+		return domain.PaymentResult{}, err
+	}
+	paymentID, _ := uuid.Parse(result.ID)
+	return domain.PaymentResult{
+		ID:         paymentID,
+		Status:     result.Status,
+		Provider:   result.Provider,
+		PaymentURL: result.PaymentURL,
+	}, nil
+}
+
+// Refund returns up to amount of what was paid for bookingID. The payment
+// service only refunds collected money, so the result may carry less.
+func (g *HTTPGateway) Refund(ctx context.Context, bookingID uuid.UUID, amount float64, reason string) (domain.RefundResult, error) {
+	payload := map[string]any{"amount": amount, "reason": reason}
+	var result dto.RefundResponse
+	if err := g.send(asService(ctx), http.MethodPost, "/payments/by-booking/"+bookingID.String()+"/refund", payload, &result); err != nil {
+		return domain.RefundResult{}, err
+	}
+	paymentID, _ := uuid.Parse(result.ID)
+	return domain.RefundResult{
+		PaymentID: paymentID,
+		Amount:    result.Amount,
+		Status:    result.Status,
+		Reference: result.Reference,
+	}, nil
+}
+
+// VoidAdjustments fails the unpaid adjustment payments of bookingID and
+// returns their total.
+func (g *HTTPGateway) VoidAdjustments(ctx context.Context, bookingID uuid.UUID) (float64, error) {
+	var voided []dto.PaymentResponse
+	if err := g.sendList(asService(ctx), http.MethodPost, "/payments/by-booking/"+bookingID.String()+"/adjustments/void", &voided); err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, p := range voided {
+		total += p.Amount
+	}
+	return total, nil
+}
+
+// send performs an authorized JSON call and unwraps the response attributes into out.
+func (g *HTTPGateway) send(ctx context.Context, method, path string, payload any, out any) error {
+	envelope := struct {
+		Data struct {
+			Attributes any `json:"attributes"`
+		} `json:"data"`
+	}{}
+	envelope.Data.Attributes = out
+	return g.do(ctx, method, path, payload, &envelope)
+}
+
+// sendList performs an authorized call answered with a list of resources and
+// unwraps their attributes into out.
+func (g *HTTPGateway) sendList(ctx context.Context, method, path string, out any) error {
+	var envelope struct {
+		Data []struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := g.do(ctx, method, path, nil, &envelope); err != nil {
+		return err
+	}
+	items := make([]json.RawMessage, 0, len(envelope.Data))
+	for _, item := range envelope.Data {
+		items = append(items, item.Attributes)
+	}
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (g *HTTPGateway) do(ctx context.Context, method, path string, payload any, envelope any) error {
+	var body io.Reader
+	if payload != nil {
+		raw, _ := json.Marshal(payload)
+		body = bytes.NewReader(raw)
+	}
+	req, _ := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if err := g.authorize(req); err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return pkgErrors.New("not_found", "payment not found")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("payment request %s %s failed: %d", method, path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(envelope)
+}
+
+// asService drops the caller's token so the request carries a service token,
+// as required by the payment routes reserved to services and admins.
+func asService(ctx context.Context) context.Context {
+	return context.WithValue(ctx, middleware.AuthTokenKey, "")
+}
+
+// authorize forwards the caller's token, minting a service token when there
+// is none (e.g. scheduled jobs).
+func (g *HTTPGateway) authorize(req *http.Request) error {
+	token, ok := req.Context().Value(middleware.AuthTokenKey).(string)
+	if !ok || token == "" {
+		var err error
+		if token, err = g.tokens.Token(time.Minute); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 func TestHTTPGatewayInitiateSuccess(t *testing.T) {
@@ -17,7 +19,7 @@ func TestHTTPGatewayInitiateSuccess(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	gw := NewHTTPGateway(srv.URL)
+	gw := NewHTTPGateway(srv.URL, middleware.NewSharedSecretSigner("booking-service", "secret"))
 	res, err := gw.Initiate(context.Background(), uuid.New(), 1000)
 	require.NoError(t, err)
 	require.Equal(t, "pending", res.Status)
@@ -29,7 +31,7 @@ func TestHTTPGatewayInitiateError(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	gw := NewHTTPGateway(srv.URL)
+	gw := NewHTTPGateway(srv.URL, middleware.NewSharedSecretSigner("booking-service", "secret"))
 	_, err := gw.Initiate(context.Background(), uuid.New(), 1000)
 	require.Error(t, err)
 }
@@ -40,7 +42,23 @@ func TestHTTPGatewayInitiateBadJSON(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	gw := NewHTTPGateway(srv.URL)
+	gw := NewHTTPGateway(srv.URL, middleware.NewSharedSecretSigner("booking-service", "secret"))
 	_, err := gw.Initiate(context.Background(), uuid.New(), 1000)
 	require.Error(t, err)
 }
+
+func TestHTTPGatewayExpireUsesServiceToken(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// compensation runs in the customer's request, whose token the expire route rejects
+	ctx := context.WithValue(context.Background(), middleware.AuthTokenKey, "customer-token")
+	gw := NewHTTPGateway(srv.URL, middleware.NewSharedSecretSigner("booking-service", "secret"))
+	require.NoError(t, gw.Expire(ctx, uuid.New()))
+	require.NotEqual(t, "Bearer customer-token", auth)
+	require.NotEmpty(t, auth)
+}
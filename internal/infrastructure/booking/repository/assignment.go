@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// unassignableStatuses lists room states that cannot receive a guest.
+var unassignableStatuses = append(append([]string{}, valueobject.OutOfOrderRoomStatuses...), string(valueobject.RoomOccupied))
+
+// AssignRoom saves the checked-in booking and assigns it a room inside one
+// transaction. A room stays taken until its active assignment is closed,
+// which covers every stay overlapping the current guest's.
+func (r *GormRepository) AssignRoom(ctx context.Context, b domain.Booking, pick domain.RoomPicker) (domain.RoomAssignment, error) {
+	var assignment domain.RoomAssignment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockRoomType(tx, b.RoomTypeID); err != nil {
+			return err
+		}
+
+		busy := tx.Model(&checkinModel{}).Select("room_id").Where("check_out_at IS NULL AND room_id IS NOT NULL")
+		var free []domain.RoomCandidate
+		err := tx.Table("rooms").
+			Select("id, number").
+			Where("room_type_id = ? AND deleted_at IS NULL", b.RoomTypeID).
+			Where("status NOT IN ?", unassignableStatuses).
+			Where("id NOT IN (?)", busy).
+			Order("number ASC").
+			Scan(&free).Error
+		if err != nil {
+			return err
+		}
+
+		room, err := pick(free)
+		if err != nil {
+			return err
+		}
+
+		model := toModel(b)
+		if err := tx.Save(&model).Error; err != nil {
+			return err
+		}
+
+		roomID := room.ID
+		checkin := checkinModel{ID: uuid.New(), BookingID: b.ID, RoomID: &roomID, CheckInAt: time.Now()}
+		if err := tx.Create(&checkin).Error; err != nil {
+			return err
+		}
+		if err := writeOutbox(tx, b); err != nil {
+			return err
+		}
+
+		if err := tx.Table("rooms").Where("id = ?", room.ID).Update("status", string(valueobject.RoomOccupied)).Error; err != nil {
+			return err
+		}
+
+		assignment = checkin.toDomain(room.Number)
+		return nil
+	})
+	return assignment, err
+}
+
+// ReleaseRoom moves the booking out of checked_in and closes its active
+// assignment. The status change is conditional, so when two checkout runs
+// race only the first one completes the booking. Bookings checked in before
+// room assignment existed have no assignment and only change status.
+func (r *GormRepository) ReleaseRoom(ctx context.Context, b domain.Booking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&bookingModel{}).
+			Where("id = ? AND status = ?", b.ID, domain.StatusCheckedIn).
+			Update("status", b.Status)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return pkgErrors.New("conflict", "booking already checked out")
+		}
+		if err := writeOutbox(tx, b); err != nil {
+			return err
+		}
+
+		var checkin checkinModel
+		err := tx.Where("booking_id = ? AND check_out_at IS NULL", b.ID).Take(&checkin).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&checkin).Update("check_out_at", now).Error; err != nil {
+			return err
+		}
+		if checkin.RoomID == nil {
+			return nil
+		}
+		return tx.Table("rooms").
+			Where("id = ? AND status = ?", *checkin.RoomID, string(valueobject.RoomOccupied)).
+			Update("status", string(valueobject.RoomAvailable)).Error
+	})
+}
+
+func (r *GormRepository) FindAssignment(ctx context.Context, bookingID uuid.UUID) (domain.RoomAssignment, error) {
+	var checkin checkinModel
+	if err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).Take(&checkin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.RoomAssignment{}, pkgErrors.New("not_found", "room assignment not found")
+		}
+		return domain.RoomAssignment{}, err
+	}
+	var number string
+	if checkin.RoomID != nil {
+		if err := r.db.WithContext(ctx).Table("rooms").Select("number").Where("id = ?", *checkin.RoomID).Scan(&number).Error; err != nil {
+			return domain.RoomAssignment{}, err
+		}
+	}
+	return checkin.toDomain(number), nil
+}
+
+type checkinModel struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	BookingID  uuid.UUID  `gorm:"type:uuid;uniqueIndex"`
+	RoomID     *uuid.UUID `gorm:"type:uuid;index"`
+	CheckInAt  time.Time
+	CheckOutAt *time.Time
+}
+
+func (checkinModel) TableName() string { return "checkins" }
+
+func (m checkinModel) toDomain(roomNumber string) domain.RoomAssignment {
+	a := domain.RoomAssignment{
+		ID:           m.ID,
+		BookingID:    m.BookingID,
+		RoomNumber:   roomNumber,
+		CheckedInAt:  m.CheckInAt,
+		CheckedOutAt: m.CheckOutAt,
+	}
+	if m.RoomID != nil {
+		a.RoomID = *m.RoomID
+	}
+	return a
+}
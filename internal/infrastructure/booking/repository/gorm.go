@@ -20,27 +20,24 @@ type GormRepository struct {
 
 func NewGormRepository(db *gorm.DB) *GormRepository { return &GormRepository{db: db} }
 
-// AutoMigrate ensures bookings table exists.
+// AutoMigrate ensures bookings, checkins, reservations, outbox and saga tables exist.
 func AutoMigrate(db *gorm.DB) error {
-	if db.Migrator().HasTable(&bookingModel{}) {
-		return nil
+	if !db.Migrator().HasTable(&bookingModel{}) {
+		if err := db.AutoMigrate(&bookingModel{}); err != nil {
+			return err
+		}
 	}
-	return db.AutoMigrate(&bookingModel{})
+	return db.AutoMigrate(&checkinModel{}, &reservationModel{}, &outboxModel{}, &sagaModel{})
 }
 
 func (r *GormRepository) Create(ctx context.Context, b domain.Booking) error {
-	return r.db.WithContext(ctx).Create(&bookingModel{
-		ID:          b.ID,
-		UserID:      b.UserID,
-		RoomTypeID:  b.RoomTypeID,
-		CheckIn:     b.CheckIn,
-		CheckOut:    b.CheckOut,
-		Status:      b.Status,
-		Guests:      b.Guests,
-		TotalPrice:  b.TotalPrice,
-		TotalNights: b.TotalNights,
-		CreatedAt:   b.CreatedAt,
-	}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		model := toModel(b)
+		if err := tx.Create(&model).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, b)
+	})
 }
 
 func (r *GormRepository) FindByID(ctx context.Context, id uuid.UUID) (domain.Booking, error) {
@@ -80,18 +77,28 @@ func (r *GormRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status
 }
 
 func (r *GormRepository) Save(ctx context.Context, b domain.Booking) error {
-	return r.db.WithContext(ctx).Save(&bookingModel{
-		ID:          b.ID,
-		UserID:      b.UserID,
-		RoomTypeID:  b.RoomTypeID,
-		CheckIn:     b.CheckIn,
-		CheckOut:    b.CheckOut,
-		Status:      b.Status,
-		Guests:      b.Guests,
-		TotalPrice:  b.TotalPrice,
-		TotalNights: b.TotalNights,
-		CreatedAt:   b.CreatedAt,
-	}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		model := toModel(b)
+		if err := tx.Save(&model).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, b)
+	})
+}
+
+func (r *GormRepository) Transition(ctx context.Context, b domain.Booking, from string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&bookingModel{}).
+			Where("id = ? AND status = ?", b.ID, from).
+			Update("status", b.Status)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return pkgErrors.New("conflict", "booking is no longer "+from)
+		}
+		return writeOutbox(tx, b)
+	})
 }
 
 func (r *GormRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Booking, error) {
@@ -106,22 +113,94 @@ func (r *GormRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]
 	return bookings, nil
 }
 
+// FindNoShows returns confirmed bookings whose check-in date is before cutoff.
+func (r *GormRepository) FindNoShows(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var models []bookingModel
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND check_in < ?", domain.StatusConfirmed, cutoff).
+		Order("check_in ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	bookings := make([]domain.Booking, 0, len(models))
+	for _, m := range models {
+		bookings = append(bookings, m.toDomain())
+	}
+	return bookings, nil
+}
+
+// FindDueForCheckout pages through checked_in bookings whose check-out is
+// before the given time using the (status, check_out) index, keyed by ID so
+// bookings that fail to complete do not block later pages.
+func (r *GormRepository) FindDueForCheckout(ctx context.Context, before time.Time, after uuid.UUID, limit int) ([]domain.Booking, error) {
+	var models []bookingModel
+	tx := r.db.WithContext(ctx).
+		Where("status = ? AND check_out < ?", domain.StatusCheckedIn, before)
+	if after != uuid.Nil {
+		tx = tx.Where("id > ?", after)
+	}
+	if err := tx.Order("id ASC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	bookings := make([]domain.Booking, 0, len(models))
+	for _, m := range models {
+		bookings = append(bookings, m.toDomain())
+	}
+	return bookings, nil
+}
+
+func (r *GormRepository) FindPendingBefore(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var models []bookingModel
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", domain.StatusPendingPayment, cutoff).
+		Order("created_at ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	bookings := make([]domain.Booking, 0, len(models))
+	for _, m := range models {
+		bookings = append(bookings, m.toDomain())
+	}
+	return bookings, nil
+}
+
 
 type bookingModel struct {
 	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
 	UserID      uuid.UUID `gorm:"type:uuid;index"`
 	RoomTypeID  uuid.UUID `gorm:"type:uuid;index"`
 	CheckIn     time.Time
-	CheckOut    time.Time
-	Status      string `gorm:"index"`
+	CheckOut    time.Time `gorm:"index:idx_bookings_status_check_out,priority:2"`
+	Status      string    `gorm:"index;index:idx_bookings_status_check_out,priority:1"`
 	Guests      int
 	TotalPrice  float64 `gorm:"type:numeric"`
 	TotalNights int
 	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+
+	ReservationID *uuid.UUID `gorm:"type:uuid;index"`
 }
 
 func (bookingModel) TableName() string { return "bookings" }
 
+func toModel(b domain.Booking) bookingModel {
+	return bookingModel{
+		ID:          b.ID,
+		UserID:      b.UserID,
+		RoomTypeID:  b.RoomTypeID,
+		CheckIn:     b.CheckIn,
+		CheckOut:    b.CheckOut,
+		Status:      b.Status,
+		Guests:      b.Guests,
+		TotalPrice:  b.TotalPrice,
+		TotalNights: b.TotalNights,
+		CreatedAt:   b.CreatedAt,
+
+		ReservationID: nullableUUID(b.ReservationID),
+	}
+}
+
 func (m bookingModel) toDomain() domain.Booking {
 	return domain.Booking{
 		ID:          m.ID,
@@ -134,7 +213,23 @@ func (m bookingModel) toDomain() domain.Booking {
 		TotalPrice:  m.TotalPrice,
 		TotalNights: m.TotalNights,
 		CreatedAt:   m.CreatedAt,
+
+		ReservationID: derefUUID(m.ReservationID),
+	}
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func derefUUID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
 	}
+	return *id
 }
 
 func translateErr(err error) error {
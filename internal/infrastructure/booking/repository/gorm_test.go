@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -11,7 +12,11 @@ import (
 	"gorm.io/gorm"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	hdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	repo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/repository"
+	hotelrepo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/hotel/repository"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
 func TestGormRepositoryCreate(t *testing.T) {
@@ -38,6 +43,321 @@ func TestGormRepositoryCreate(t *testing.T) {
 	require.Equal(t, int64(1), count)
 }
 
+func TestGormRepositoryTransitionIsConditional(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	booking := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: time.Now(), CheckOut: time.Now().Add(24 * time.Hour), Status: domain.StatusConfirmed}
+	require.NoError(t, r.Create(ctx, booking))
+
+	require.NoError(t, booking.MarkNoShow(domain.CancellationQuote{}))
+	require.NoError(t, r.Transition(ctx, booking, domain.StatusConfirmed))
+	// a second run holding the same stale booking loses the race
+	err := r.Transition(ctx, booking, domain.StatusConfirmed)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+
+	stored, err := r.FindByID(ctx, booking.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusNoShow, stored.Status)
+	messages, err := r.ListOutbox(ctx, domain.OutboxPending, query.Options{Limit: 1000})
+	require.NoError(t, err)
+	published := 0
+	for _, m := range messages {
+		if m.AggregateID == booking.ID {
+			published++
+		}
+	}
+	require.Equal(t, 1, published)
+}
+
+func TestGormRepositoryReserveCountsInventory(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	rt := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Deluxe", Capacity: 2, BasePrice: 100}
+	require.NoError(t, hotels.CreateRoomType(ctx, rt))
+	require.NoError(t, hotels.CreateRoom(ctx, hdomain.Room{ID: uuid.New(), RoomTypeID: rt.ID, Number: "101", Status: "available"}))
+	require.NoError(t, hotels.CreateRoom(ctx, hdomain.Room{ID: uuid.New(), RoomTypeID: rt.ID, Number: "102", Status: "maintenance"}))
+	deleted := hdomain.Room{ID: uuid.New(), RoomTypeID: rt.ID, Number: "103", Status: "available"}
+	require.NoError(t, hotels.CreateRoom(ctx, deleted))
+	require.NoError(t, hotels.DeleteRoom(ctx, deleted.ID))
+
+	checkIn := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+	existing := domain.Booking{
+		ID:          uuid.New(),
+		UserID:      uuid.New(),
+		RoomTypeID:  rt.ID,
+		CheckIn:     checkIn,
+		CheckOut:    checkIn.AddDate(0, 0, 2),
+		Status:      domain.StatusConfirmed,
+		TotalNights: 2,
+	}
+	require.NoError(t, r.Create(ctx, existing))
+
+	var rooms int
+	var overlapping []domain.Booking
+	candidate := existing
+	candidate.ID = uuid.New()
+	candidate.CheckIn = checkIn.AddDate(0, 0, 1)
+	candidate.CheckOut = checkIn.AddDate(0, 0, 3)
+	err := r.Reserve(ctx, candidate, func(ctx context.Context, inv domain.Inventory) error {
+		var err error
+		if rooms, err = inv.CountBookableRooms(ctx, rt.ID); err != nil {
+			return err
+		}
+		overlapping, err = inv.FindOverlapping(ctx, rt.ID, candidate.Stay())
+		if err != nil {
+			return err
+		}
+		return domain.NewAvailabilityService().EnsureAvailable(rooms, candidate.Stay(), overlapping)
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, rooms)
+	require.Len(t, overlapping, 1)
+
+	_, err = r.FindByID(ctx, candidate.ID)
+	require.Error(t, err)
+
+	err = r.Reserve(ctx, domain.Booking{ID: uuid.New(), RoomTypeID: uuid.New()}, func(context.Context, domain.Inventory) error { return nil })
+	require.Error(t, err)
+}
+
+func TestGormRepositoryAmend(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	rt := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Suite", Capacity: 2, BasePrice: 100}
+	require.NoError(t, hotels.CreateRoomType(ctx, rt))
+
+	checkIn := time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC)
+	bk := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Status: domain.StatusConfirmed, TotalNights: 1, TotalPrice: 100}
+	require.NoError(t, r.Create(ctx, bk))
+
+	amended := bk
+	amended.CheckOut = checkIn.AddDate(0, 0, 3)
+	amended.TotalNights = 3
+	amended.TotalPrice = 300
+
+	rejected := func(context.Context, domain.Inventory) error { return errors.New("full") }
+	require.Error(t, r.Amend(ctx, amended, rejected))
+	stored, err := r.FindByID(ctx, bk.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, stored.TotalNights)
+
+	require.NoError(t, r.Amend(ctx, amended, func(context.Context, domain.Inventory) error { return nil }))
+	stored, err = r.FindByID(ctx, bk.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3, stored.TotalNights)
+	require.Equal(t, 300.0, stored.TotalPrice)
+}
+
+func TestGormRepositoryReserveGroup(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	twin := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Twin", Capacity: 2, BasePrice: 100}
+	family := hdomain.RoomType{ID: uuid.New(), HotelID: twin.HotelID, Name: "Family", Capacity: 4, BasePrice: 200}
+	require.NoError(t, hotels.CreateRoomType(ctx, twin))
+	require.NoError(t, hotels.CreateRoomType(ctx, family))
+
+	checkIn := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	res, err := domain.NewReservation(userID, []domain.Booking{
+		{ID: uuid.New(), RoomTypeID: family.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Status: domain.StatusPendingPayment, Guests: 4, TotalNights: 2, TotalPrice: 400, CreatedAt: time.Now()},
+		{ID: uuid.New(), RoomTypeID: twin.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Status: domain.StatusPendingPayment, Guests: 2, TotalNights: 2, TotalPrice: 200, CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+
+	require.Error(t, r.ReserveGroup(ctx, res, func(context.Context, domain.Inventory) error { return errors.New("full") }))
+	_, err = r.FindReservation(ctx, res.ID)
+	require.Error(t, err)
+	_, err = r.FindByID(ctx, res.LeadBookingID)
+	require.Error(t, err)
+
+	require.NoError(t, r.ReserveGroup(ctx, res, func(context.Context, domain.Inventory) error { return nil }))
+	stored, err := r.FindReservation(ctx, res.ID)
+	require.NoError(t, err)
+	require.Equal(t, userID, stored.UserID)
+	require.Len(t, stored.Lines, 2)
+	require.Equal(t, res.LeadBookingID, stored.Lines[0].ID)
+	require.Equal(t, 600.0, stored.TotalPrice())
+
+	line, err := r.FindByID(ctx, res.Lines[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, res.ID, line.ReservationID)
+}
+
+func TestGormRepositorySearch(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	hotelID := uuid.New()
+	inHotel := hdomain.RoomType{ID: uuid.New(), HotelID: hotelID, Name: "Deluxe", Capacity: 2, BasePrice: 100}
+	elsewhere := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Deluxe", Capacity: 2, BasePrice: 100}
+	require.NoError(t, hotels.CreateRoomType(ctx, inHotel))
+	require.NoError(t, hotels.CreateRoomType(ctx, elsewhere))
+
+	userID := uuid.New()
+	day := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+	seed := []domain.Booking{
+		{ID: uuid.New(), UserID: userID, RoomTypeID: inHotel.ID, CheckIn: day, CheckOut: day.AddDate(0, 0, 2), Status: domain.StatusConfirmed, TotalPrice: 200},
+		{ID: uuid.New(), UserID: userID, RoomTypeID: inHotel.ID, CheckIn: day.AddDate(0, 0, 10), CheckOut: day.AddDate(0, 0, 11), Status: domain.StatusCancelled, TotalPrice: 100},
+		{ID: uuid.New(), UserID: userID, RoomTypeID: elsewhere.ID, CheckIn: day.AddDate(0, 0, 5), CheckOut: day.AddDate(0, 0, 8), Status: domain.StatusConfirmed, TotalPrice: 300},
+		{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: inHotel.ID, CheckIn: day, CheckOut: day.AddDate(0, 0, 1), Status: domain.StatusConfirmed, TotalPrice: 100},
+	}
+	for _, b := range seed {
+		require.NoError(t, r.Create(ctx, b))
+	}
+
+	res, err := r.Search(ctx, domain.Filter{UserID: userID, Sort: []query.Sort{{Field: "total_price", Desc: true}}, Page: query.Options{Limit: 2}})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), res.Total)
+	require.Len(t, res.Bookings, 2)
+	require.Equal(t, seed[2].ID, res.Bookings[0].ID)
+
+	res, err = r.Search(ctx, domain.Filter{UserID: userID, HotelID: hotelID, Statuses: []string{domain.StatusConfirmed}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.Total)
+	require.Equal(t, seed[0].ID, res.Bookings[0].ID)
+
+	res, err = r.Search(ctx, domain.Filter{UserID: userID, CheckInFrom: day.AddDate(0, 0, 1), CheckOutTo: day.AddDate(0, 0, 9)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.Total)
+	require.Equal(t, seed[2].ID, res.Bookings[0].ID)
+}
+
+func TestGormRepositoryOutbox(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	rt := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Loft", Capacity: 2, BasePrice: 100}
+	require.NoError(t, hotels.CreateRoomType(ctx, rt))
+
+	checkIn := time.Date(2032, 1, 1, 0, 0, 0, 0, time.UTC)
+	bk := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Status: domain.StatusPendingPayment}
+	bk.RecordEvent(domain.NewBookingCreated(bk.ID, bk.UserID, rt.ID, 100, 1))
+
+	// a rejected reservation stores neither the booking nor its events
+	require.Error(t, r.Reserve(ctx, bk, func(context.Context, domain.Inventory) error { return errors.New("full") }))
+	forBooking := func() []domain.OutboxMessage {
+		all, err := r.ListOutbox(ctx, "", query.Options{Limit: 1000})
+		require.NoError(t, err)
+		var out []domain.OutboxMessage
+		for _, m := range all {
+			if m.AggregateID == bk.ID {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	require.Empty(t, forBooking())
+
+	require.NoError(t, r.Reserve(ctx, bk, func(context.Context, domain.Inventory) error { return nil }))
+	stored := forBooking()
+	require.Len(t, stored, 1)
+	require.Equal(t, domain.EventTypeBookingCreated, stored[0].EventType)
+	require.Equal(t, domain.OutboxPending, stored[0].Status)
+	require.Contains(t, string(stored[0].Payload), bk.ID.String())
+
+	// a claimed message is leased away from other relays
+	now := time.Now().Add(time.Second)
+	claimed, err := r.ClaimOutbox(ctx, now, time.Minute, 1000)
+	require.NoError(t, err)
+	require.NotEmpty(t, claimed)
+	again, err := r.ClaimOutbox(ctx, now, time.Minute, 1000)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	id := stored[0].ID
+	require.NoError(t, r.MarkAttemptFailed(ctx, id, "down", now, true))
+	failed, err := r.FindOutbox(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, domain.OutboxFailed, failed.Status)
+	require.Equal(t, 1, failed.Attempts)
+	require.Equal(t, "down", failed.LastError)
+
+	require.NoError(t, r.ReplayOutbox(ctx, id))
+	require.NoError(t, r.MarkDelivered(ctx, id, time.Now()))
+	delivered, err := r.FindOutbox(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, domain.OutboxDelivered, delivered.Status)
+	require.NotNil(t, delivered.DeliveredAt)
+
+	_, err = r.FindOutbox(ctx, uuid.New())
+	require.Error(t, err)
+}
+
+func TestGormRepositorySagas(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	bk := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New()}
+	bk.RecordEvent(domain.NewBookingCreated(bk.ID, bk.UserID, bk.RoomTypeID, 100, 1))
+	saga := domain.NewCreationSaga(bk.ID, uuid.Nil, 100)
+	require.NoError(t, r.SaveSaga(ctx, saga))
+
+	saga.Advance(domain.SagaStepNotified)
+	require.NoError(t, r.SaveSaga(ctx, saga, bk))
+	stored, err := r.FindSaga(ctx, saga.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.SagaCompleted, stored.Status)
+	require.Equal(t, domain.SagaStepNotified, stored.Step)
+	require.Equal(t, uuid.Nil, stored.ReservationID)
+
+	events, err := r.ListOutbox(ctx, "", query.Options{Limit: 1000})
+	require.NoError(t, err)
+	published := 0
+	for _, m := range events {
+		if m.AggregateID == bk.ID {
+			published++
+		}
+	}
+	require.Equal(t, 1, published)
+
+	// only unfinished sagas without recent progress are claimed, once
+	stale := domain.NewCreationSaga(uuid.New(), uuid.New(), 200)
+	require.NoError(t, r.SaveSaga(ctx, stale))
+	later := time.Now().Add(time.Hour)
+	claimed, err := r.ClaimStaleSagas(ctx, later, later, 100)
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, c := range claimed {
+		ids = append(ids, c.ID)
+	}
+	require.Contains(t, ids, stale.ID)
+	require.NotContains(t, ids, saga.ID)
+	again, err := r.ClaimStaleSagas(ctx, later, later, 100)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	_, err = r.FindSaga(ctx, uuid.New())
+	require.Error(t, err)
+}
+
 // repoTestBookingModel mirrors bookingModel table name for counting.
 type repoTestBookingModel struct {
 	ID uuid.UUID `gorm:"type:uuid;primaryKey"`
@@ -51,3 +371,136 @@ func newTestDB(t *testing.T) *gorm.DB {
 	require.NoError(t, err)
 	return db
 }
+
+func TestGormRepositoryFindPendingBefore(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	cutoff := time.Now().Add(-15 * time.Minute)
+	stale := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), Status: domain.StatusPendingPayment, CreatedAt: cutoff.Add(-time.Hour)}
+	fresh := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), Status: domain.StatusPendingPayment, CreatedAt: time.Now()}
+	paid := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), Status: domain.StatusConfirmed, CreatedAt: cutoff.Add(-time.Hour)}
+	for _, b := range []domain.Booking{stale, fresh, paid} {
+		require.NoError(t, r.Create(ctx, b))
+	}
+
+	pending, err := r.FindPendingBefore(ctx, cutoff)
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, b := range pending {
+		ids = append(ids, b.ID)
+	}
+	require.Contains(t, ids, stale.ID)
+	require.NotContains(t, ids, fresh.ID)
+	require.NotContains(t, ids, paid.ID)
+}
+
+func TestGormRepositoryFindNoShows(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	cutoff := time.Date(2031, 5, 10, 0, 0, 0, 0, time.UTC)
+	missed := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: cutoff.AddDate(0, 0, -1), CheckOut: cutoff.AddDate(0, 0, 1), Status: domain.StatusConfirmed}
+	arriving := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: cutoff, CheckOut: cutoff.AddDate(0, 0, 1), Status: domain.StatusConfirmed}
+	staying := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: cutoff.AddDate(0, 0, -1), CheckOut: cutoff.AddDate(0, 0, 1), Status: domain.StatusCheckedIn}
+	for _, b := range []domain.Booking{missed, arriving, staying} {
+		require.NoError(t, r.Create(ctx, b))
+	}
+
+	found, err := r.FindNoShows(ctx, cutoff)
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, 0, len(found))
+	for _, b := range found {
+		ids = append(ids, b.ID)
+	}
+	require.Contains(t, ids, missed.ID)
+	require.NotContains(t, ids, arriving.ID)
+	require.NotContains(t, ids, staying.ID)
+}
+
+func TestGormRepositoryFindDueForCheckout(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	day := time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)
+	var due []uuid.UUID
+	for i := 0; i < 5; i++ {
+		b := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: day.AddDate(0, 0, -2), CheckOut: day.AddDate(0, 0, -i), Status: domain.StatusCheckedIn}
+		require.NoError(t, r.Create(ctx, b))
+		due = append(due, b.ID)
+	}
+	later := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: uuid.New(), CheckIn: day, CheckOut: day.AddDate(0, 0, 1), Status: domain.StatusCheckedIn}
+	require.NoError(t, r.Create(ctx, later))
+
+	before := day.AddDate(0, 0, 1)
+	first, err := r.FindDueForCheckout(ctx, before, uuid.Nil, 3)
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+	rest, err := r.FindDueForCheckout(ctx, before, first[2].ID, 3)
+	require.NoError(t, err)
+	require.Len(t, rest, 2)
+
+	var seen []uuid.UUID
+	for _, b := range append(first, rest...) {
+		seen = append(seen, b.ID)
+	}
+	require.ElementsMatch(t, due, seen)
+
+	// a second checkout of the same booking is rejected
+	done := first[0]
+	done.Status = domain.StatusCompleted
+	require.NoError(t, r.ReleaseRoom(ctx, done))
+	err = r.ReleaseRoom(ctx, done)
+	require.Error(t, err)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+}
+
+func TestGormRepositoryAssignAndReleaseRoom(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, hotelrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	hotels := hotelrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	rt := hdomain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Single", Capacity: 1, BasePrice: 100}
+	require.NoError(t, hotels.CreateRoomType(ctx, rt))
+	room := hdomain.Room{ID: uuid.New(), RoomTypeID: rt.ID, Number: "301", Status: "available"}
+	require.NoError(t, hotels.CreateRoom(ctx, room))
+
+	checkIn := time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)
+	first := domain.Booking{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Status: domain.StatusCheckedIn}
+	second := first
+	second.ID = uuid.New()
+	require.NoError(t, r.Create(ctx, first))
+	require.NoError(t, r.Create(ctx, second))
+
+	assignment, err := r.AssignRoom(ctx, first, domain.PickRoom(""))
+	require.NoError(t, err)
+	require.Equal(t, room.ID, assignment.RoomID)
+	occupied, err := hotels.GetRoom(ctx, room.ID)
+	require.NoError(t, err)
+	require.Equal(t, "occupied", occupied.Status)
+
+	_, err = r.AssignRoom(ctx, second, domain.PickRoom("301"))
+	require.Error(t, err)
+
+	first.Status = domain.StatusCompleted
+	require.NoError(t, r.ReleaseRoom(ctx, first))
+	released, err := r.FindAssignment(ctx, first.ID)
+	require.NoError(t, err)
+	require.False(t, released.Active())
+	require.Equal(t, "301", released.RoomNumber)
+	freed, err := hotels.GetRoom(ctx, room.ID)
+	require.NoError(t, err)
+	require.Equal(t, "available", freed.Status)
+
+	_, err = r.AssignRoom(ctx, second, domain.PickRoom("301"))
+	require.NoError(t, err)
+}
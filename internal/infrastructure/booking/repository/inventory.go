@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// Reserve locks the room type row so concurrent reservations for the same
+// inventory are serialized, then runs check and inserts the booking.
+func (r *GormRepository) Reserve(ctx context.Context, b domain.Booking, check domain.ReservationCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockRoomType(tx, b.RoomTypeID); err != nil {
+			return err
+		}
+
+		if err := check(ctx, gormInventory{db: tx}); err != nil {
+			return err
+		}
+
+		model := toModel(b)
+		if err := tx.Create(&model).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, b)
+	})
+}
+
+// Amend locks the room type the booking moves into, runs check and saves the
+// amended booking within the same transaction.
+func (r *GormRepository) Amend(ctx context.Context, b domain.Booking, check domain.ReservationCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockRoomType(tx, b.RoomTypeID); err != nil {
+			return err
+		}
+
+		if err := check(ctx, gormInventory{db: tx}); err != nil {
+			return err
+		}
+
+		model := toModel(b)
+		if err := tx.Save(&model).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, b)
+	})
+}
+
+// lockRoomType takes a row lock on the room type, serializing inventory changes for it.
+func lockRoomType(tx *gorm.DB, roomTypeID uuid.UUID) error {
+	var locked struct{ ID uuid.UUID }
+	err := tx.Table("room_types").
+		Select("id").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", roomTypeID).
+		Take(&locked).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return pkgErrors.New("not_found", "room type not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// gormInventory reads inventory inside the reservation transaction.
+type gormInventory struct {
+	db *gorm.DB
+}
+
+func (i gormInventory) CountBookableRooms(ctx context.Context, roomTypeID uuid.UUID) (int, error) {
+	var count int64
+	err := i.db.WithContext(ctx).Table("rooms").
+		Where("room_type_id = ? AND deleted_at IS NULL", roomTypeID).
+		Where("status NOT IN ?", valueobject.OutOfOrderRoomStatuses).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (i gormInventory) FindOverlapping(ctx context.Context, roomTypeID uuid.UUID, stay valueobject.DateRange) ([]domain.Booking, error) {
+	var models []bookingModel
+	err := i.db.WithContext(ctx).
+		Where("room_type_id = ? AND status NOT IN ?", roomTypeID, valueobject.ReleasedBookingStatuses).
+		Where("check_in < ? AND check_out > ?", stay.End, stay.Start).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	bookings := make([]domain.Booking, 0, len(models))
+	for _, m := range models {
+		bookings = append(bookings, m.toDomain())
+	}
+	return bookings, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+)
+
+type outboxModel struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AggregateID   uuid.UUID `gorm:"type:uuid;index"`
+	EventType     string
+	Payload       string `gorm:"type:text"`
+	Status        string `gorm:"index:idx_booking_outbox_due,priority:1"`
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time `gorm:"index:idx_booking_outbox_due,priority:2"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+	DeliveredAt   *time.Time
+}
+
+func (outboxModel) TableName() string { return "booking_outbox" }
+
+// writeOutbox stores the pending events of bookings inside tx, so they are
+// only published when the booking change they describe commits.
+func writeOutbox(tx *gorm.DB, bookings ...domain.Booking) error {
+	var models []outboxModel
+	now := time.Now()
+	for _, b := range bookings {
+		for _, event := range b.Events() {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			models = append(models, outboxModel{
+				ID:            uuid.New(),
+				AggregateID:   event.AggregateID(),
+				EventType:     event.EventType(),
+				Payload:       string(payload),
+				Status:        domain.OutboxPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	return tx.Create(&models).Error
+}
+
+// ClaimOutbox pushes the next attempt of each due message past the lease with
+// a conditional update; a message another relay claimed first is no longer
+// due and is skipped.
+func (r *GormRepository) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxMessage, error) {
+	var due []outboxModel
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", domain.OutboxPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]domain.OutboxMessage, 0, len(due))
+	for _, m := range due {
+		res := r.db.WithContext(ctx).Model(&outboxModel{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", m.ID, domain.OutboxPending, now).
+			Update("next_attempt_at", now.Add(lease))
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			claimed = append(claimed, m.toDomain())
+		}
+	}
+	return claimed, nil
+}
+
+func (r *GormRepository) MarkDelivered(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&outboxModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       domain.OutboxDelivered,
+		"delivered_at": at,
+		"last_error":   "",
+	}).Error
+}
+
+func (r *GormRepository) MarkAttemptFailed(ctx context.Context, id uuid.UUID, reason string, next time.Time, giveUp bool) error {
+	status := domain.OutboxPending
+	if giveUp {
+		status = domain.OutboxFailed
+	}
+	return r.db.WithContext(ctx).Model(&outboxModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          status,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      reason,
+		"next_attempt_at": next,
+	}).Error
+}
+
+func (r *GormRepository) ListOutbox(ctx context.Context, status string, opts query.Options) ([]domain.OutboxMessage, error) {
+	qo := opts.Normalize(50)
+	tx := r.db.WithContext(ctx).Order("created_at DESC").Limit(qo.Limit).Offset(qo.Offset)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	var models []outboxModel
+	if err := tx.Find(&models).Error; err != nil {
+		return nil, err
+	}
+	messages := make([]domain.OutboxMessage, 0, len(models))
+	for _, m := range models {
+		messages = append(messages, m.toDomain())
+	}
+	return messages, nil
+}
+
+func (r *GormRepository) FindOutbox(ctx context.Context, id uuid.UUID) (domain.OutboxMessage, error) {
+	var model outboxModel
+	if err := r.db.WithContext(ctx).Take(&model, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.OutboxMessage{}, pkgErrors.New("not_found", "outbox message not found")
+		}
+		return domain.OutboxMessage{}, err
+	}
+	return model.toDomain(), nil
+}
+
+func (r *GormRepository) ReplayOutbox(ctx context.Context, id uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&outboxModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          domain.OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("not_found", "outbox message not found")
+	}
+	return nil
+}
+
+func (m outboxModel) toDomain() domain.OutboxMessage {
+	return domain.OutboxMessage{
+		ID:            m.ID,
+		AggregateID:   m.AggregateID,
+		EventType:     m.EventType,
+		Payload:       []byte(m.Payload),
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+		DeliveredAt:   m.DeliveredAt,
+	}
+}
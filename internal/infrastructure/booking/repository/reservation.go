@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+type reservationModel struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID `gorm:"type:uuid;index"`
+	LeadBookingID uuid.UUID `gorm:"type:uuid"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (reservationModel) TableName() string { return "reservations" }
+
+// ReserveGroup locks every room type of the reservation in a stable order so
+// concurrent group and single reservations cannot deadlock, runs check and
+// inserts the reservation with its lines.
+func (r *GormRepository) ReserveGroup(ctx context.Context, res domain.Reservation, check domain.ReservationCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range roomTypesOf(res.Lines) {
+			if err := lockRoomType(tx, id); err != nil {
+				return err
+			}
+		}
+
+		if err := check(ctx, gormInventory{db: tx}); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&reservationModel{
+			ID:            res.ID,
+			UserID:        res.UserID,
+			LeadBookingID: res.LeadBookingID,
+			CreatedAt:     res.CreatedAt,
+		}).Error; err != nil {
+			return err
+		}
+		models := make([]bookingModel, 0, len(res.Lines))
+		for _, line := range res.Lines {
+			models = append(models, toModel(line))
+		}
+		if err := tx.Create(&models).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, res.Lines...)
+	})
+}
+
+// FindReservation loads a reservation with all of its lines, lead first.
+func (r *GormRepository) FindReservation(ctx context.Context, id uuid.UUID) (domain.Reservation, error) {
+	var model reservationModel
+	if err := r.db.WithContext(ctx).Take(&model, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Reservation{}, pkgErrors.New("not_found", "reservation not found")
+		}
+		return domain.Reservation{}, err
+	}
+
+	var lines []bookingModel
+	if err := r.db.WithContext(ctx).Where("reservation_id = ?", id).Order("created_at ASC").Find(&lines).Error; err != nil {
+		return domain.Reservation{}, err
+	}
+	res := domain.Reservation{
+		ID:            model.ID,
+		UserID:        model.UserID,
+		LeadBookingID: model.LeadBookingID,
+		CreatedAt:     model.CreatedAt,
+		Lines:         make([]domain.Booking, 0, len(lines)),
+	}
+	for _, m := range lines {
+		if m.ID == model.LeadBookingID {
+			res.Lines = append([]domain.Booking{m.toDomain()}, res.Lines...)
+			continue
+		}
+		res.Lines = append(res.Lines, m.toDomain())
+	}
+	return res, nil
+}
+
+// roomTypesOf returns the distinct room types of lines in a stable lock order.
+func roomTypesOf(lines []domain.Booking) []uuid.UUID {
+	seen := map[uuid.UUID]struct{}{}
+	ids := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		if _, ok := seen[line.RoomTypeID]; ok {
+			continue
+		}
+		seen[line.RoomTypeID] = struct{}{}
+		ids = append(ids, line.RoomTypeID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	return ids
+}
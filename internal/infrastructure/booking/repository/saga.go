@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+type sagaModel struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	BookingID     uuid.UUID  `gorm:"type:uuid;index"`
+	ReservationID *uuid.UUID `gorm:"type:uuid"`
+	Amount        float64    `gorm:"type:numeric"`
+	Step          string
+	Status        string `gorm:"index:idx_booking_sagas_stale,priority:1"`
+	LastError     string
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;index:idx_booking_sagas_stale,priority:2"`
+}
+
+func (sagaModel) TableName() string { return "booking_sagas" }
+
+func (r *GormRepository) SaveSaga(ctx context.Context, s domain.Saga, publish ...domain.Booking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		model := sagaModel{
+			ID:            s.ID,
+			BookingID:     s.BookingID,
+			ReservationID: nullableUUID(s.ReservationID),
+			Amount:        s.Amount,
+			Step:          s.Step,
+			Status:        s.Status,
+			LastError:     s.LastError,
+			CreatedAt:     s.CreatedAt,
+			UpdatedAt:     time.Now(),
+		}
+		if err := tx.Save(&model).Error; err != nil {
+			return err
+		}
+		return writeOutbox(tx, publish...)
+	})
+}
+
+func (r *GormRepository) FindSaga(ctx context.Context, id uuid.UUID) (domain.Saga, error) {
+	var model sagaModel
+	if err := r.db.WithContext(ctx).Take(&model, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Saga{}, pkgErrors.New("not_found", "saga not found")
+		}
+		return domain.Saga{}, err
+	}
+	return model.toDomain(), nil
+}
+
+// ClaimStaleSagas touches each stale saga with a conditional update; a saga
+// another recovery claimed first is no longer stale and is skipped.
+func (r *GormRepository) ClaimStaleSagas(ctx context.Context, before, now time.Time, limit int) ([]domain.Saga, error) {
+	unfinished := []string{domain.SagaRunning, domain.SagaCompensating}
+	var stale []sagaModel
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", unfinished, before).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&stale).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]domain.Saga, 0, len(stale))
+	for _, m := range stale {
+		res := r.db.WithContext(ctx).Model(&sagaModel{}).
+			Where("id = ? AND status IN ? AND updated_at < ?", m.ID, unfinished, before).
+			Update("updated_at", now)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			claimed = append(claimed, m.toDomain())
+		}
+	}
+	return claimed, nil
+}
+
+func (m sagaModel) toDomain() domain.Saga {
+	return domain.Saga{
+		ID:            m.ID,
+		BookingID:     m.BookingID,
+		ReservationID: derefUUID(m.ReservationID),
+		Amount:        m.Amount,
+		Step:          m.Step,
+		Status:        m.Status,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+)
+
+// Search filters, counts and pages bookings. The hotel criterion resolves
+// through room_types, which live in the same database as bookings.
+func (r *GormRepository) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	tx := applyFilter(r.db.WithContext(ctx), f).Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return domain.SearchResult{}, err
+	}
+
+	for _, s := range f.Sort {
+		tx = tx.Order(s.Clause())
+	}
+	if len(f.Sort) == 0 {
+		tx = tx.Order("created_at DESC")
+	}
+	page := f.Page.Normalize(50)
+
+	var models []bookingModel
+	if err := tx.Limit(page.Limit).Offset(page.Offset).Find(&models).Error; err != nil {
+		return domain.SearchResult{}, err
+	}
+	bookings := make([]domain.Booking, 0, len(models))
+	for _, m := range models {
+		bookings = append(bookings, m.toDomain())
+	}
+	return domain.SearchResult{Bookings: bookings, Total: total}, nil
+}
+
+func applyFilter(db *gorm.DB, f domain.Filter) *gorm.DB {
+	tx := db.Model(&bookingModel{})
+	if f.UserID != uuid.Nil {
+		tx = tx.Where("user_id = ?", f.UserID)
+	}
+	if len(f.Statuses) > 0 {
+		tx = tx.Where("status IN ?", f.Statuses)
+	}
+	if f.RoomTypeID != uuid.Nil {
+		tx = tx.Where("room_type_id = ?", f.RoomTypeID)
+	}
+	if f.HotelID != uuid.Nil {
+		tx = tx.Where("room_type_id IN (?)", db.Table("room_types").Select("id").Where("hotel_id = ?", f.HotelID))
+	}
+	if !f.CheckInFrom.IsZero() {
+		tx = tx.Where("check_in >= ?", f.CheckInFrom)
+	}
+	if !f.CheckInTo.IsZero() {
+		tx = tx.Where("check_in < ?", f.CheckInTo)
+	}
+	if !f.CheckOutFrom.IsZero() {
+		tx = tx.Where("check_out >= ?", f.CheckOutFrom)
+	}
+	if !f.CheckOutTo.IsZero() {
+		tx = tx.Where("check_out < ?", f.CheckOutTo)
+	}
+	if !f.CreatedFrom.IsZero() {
+		tx = tx.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		tx = tx.Where("created_at < ?", f.CreatedTo)
+	}
+	return tx
+}
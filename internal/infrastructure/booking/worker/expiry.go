@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	bookinguc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
+)
+
+// PaymentExpiryScheduler expires bookings whose payment hold has lapsed.
+type PaymentExpiryScheduler struct {
+	cron       *cron.Cron
+	service    *bookinguc.Service
+	holdWindow time.Duration
+	logger     *zap.Logger
+}
+
+// NewPaymentExpiryScheduler creates a new scheduler instance.
+// holdWindow should match the payment invoice lifetime.
+func NewPaymentExpiryScheduler(service *bookinguc.Service, holdWindow time.Duration, logger *zap.Logger) *PaymentExpiryScheduler {
+	return &PaymentExpiryScheduler{
+		cron:       cron.New(),
+		service:    service,
+		holdWindow: holdWindow,
+		logger:     logger,
+	}
+}
+
+// Start initializes and starts the cron scheduler.
+// Schedule: every minute, so holds are released shortly after they lapse.
+func (s *PaymentExpiryScheduler) Start() error {
+	_, err := s.cron.AddFunc("@every 1m", func() {
+		if err := s.runExpiry(); err != nil {
+			s.logger.Error("❌ Payment expiry failed", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("✅ Payment expiry scheduler started", zap.Duration("hold_window", s.holdWindow))
+	return nil
+}
+
+// Stop gracefully stops the cron scheduler.
+func (s *PaymentExpiryScheduler) Stop() {
+	if s.cron != nil {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+		s.logger.Info("🛑 Payment expiry scheduler stopped")
+	}
+}
+
+// runExpiry expires pending bookings created before the hold window.
+func (s *PaymentExpiryScheduler) runExpiry() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	report, err := s.service.ExpireUnpaid(ctx, time.Now().Add(-s.holdWindow))
+	for bookingID, failure := range report.Failed {
+		s.logger.Error("❌ Payment expiry failed for booking", zap.String("booking_id", bookingID.String()), zap.Error(failure))
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Processed > 0 {
+		s.logger.Info("✅ Expired unpaid bookings",
+			zap.Int("expired_bookings", report.Processed),
+			zap.Int("skipped_bookings", report.Skipped),
+			zap.Int("failed_bookings", len(report.Failed)))
+	}
+	return nil
+}
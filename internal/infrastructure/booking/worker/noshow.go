@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	bookinguc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
+)
+
+// NoShowScheduler marks confirmed bookings whose guest never checked in.
+type NoShowScheduler struct {
+	cron    *cron.Cron
+	service *bookinguc.Service
+	policy  domain.NoShowPolicy
+	logger  *zap.Logger
+}
+
+// NewNoShowScheduler creates a new scheduler instance.
+func NewNoShowScheduler(service *bookinguc.Service, policy domain.NoShowPolicy, logger *zap.Logger) *NoShowScheduler {
+	return &NoShowScheduler{
+		cron:    cron.New(),
+		service: service,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+// Start initializes and starts the cron scheduler.
+// Schedule: nightly at 02:00, after the previous check-in day has ended.
+func (s *NoShowScheduler) Start() error {
+	_, err := s.cron.AddFunc("0 2 * * *", func() {
+		if err := s.runNoShows(); err != nil {
+			s.logger.Error("❌ No-show marking failed", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("✅ No-show scheduler started (runs daily at 02:00)", zap.Int("charge_nights", s.policy.ChargeNights))
+	return nil
+}
+
+// Stop gracefully stops the cron scheduler.
+func (s *NoShowScheduler) Stop() {
+	if s.cron != nil {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+		s.logger.Info("🛑 No-show scheduler stopped")
+	}
+}
+
+// runNoShows marks bookings whose check-in day is over.
+func (s *NoShowScheduler) runNoShows() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	today := time.Now().Truncate(24 * time.Hour)
+	report, err := s.service.MarkNoShows(ctx, today, s.policy)
+	for bookingID, failure := range report.Failed {
+		s.logger.Error("❌ No-show marking failed for booking", zap.String("booking_id", bookingID.String()), zap.Error(failure))
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Processed > 0 {
+		s.logger.Info("✅ Marked no-show bookings",
+			zap.Int("no_show_bookings", report.Processed),
+			zap.Int("already_marked", report.Skipped),
+			zap.Int("failed_bookings", len(report.Failed)))
+	}
+	return nil
+}
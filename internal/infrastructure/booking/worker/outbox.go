@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	bookinguc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
+)
+
+// outboxBatchSize bounds how many events one relay run delivers.
+const outboxBatchSize = 100
+
+// OutboxRelayScheduler delivers booking events stored in the outbox.
+type OutboxRelayScheduler struct {
+	cron    *cron.Cron
+	service *bookinguc.Service
+	logger  *zap.Logger
+}
+
+// NewOutboxRelayScheduler creates a new scheduler instance.
+func NewOutboxRelayScheduler(service *bookinguc.Service, logger *zap.Logger) *OutboxRelayScheduler {
+	return &OutboxRelayScheduler{
+		cron:    cron.New(),
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Start initializes and starts the cron scheduler.
+// Schedule: every 5 seconds, so notifications follow booking changes closely.
+func (s *OutboxRelayScheduler) Start() error {
+	_, err := s.cron.AddFunc("@every 5s", func() {
+		if err := s.runRelay(); err != nil {
+			s.logger.Error("❌ Outbox relay failed", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("✅ Outbox relay scheduler started")
+	return nil
+}
+
+// Stop gracefully stops the cron scheduler.
+func (s *OutboxRelayScheduler) Stop() {
+	if s.cron != nil {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+		s.logger.Info("🛑 Outbox relay scheduler stopped")
+	}
+}
+
+// runRelay delivers the next batch of due outbox events.
+func (s *OutboxRelayScheduler) runRelay() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := s.service.RelayOutbox(ctx, outboxBatchSize)
+	for messageID, failure := range report.Errors {
+		s.logger.Error("❌ Outbox delivery failed", zap.String("message_id", messageID.String()), zap.Error(failure))
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Retrying > 0 || report.Failed > 0 {
+		s.logger.Warn("⚠️ Outbox delivery failures",
+			zap.Int("delivered_events", report.Delivered),
+			zap.Int("retrying_events", report.Retrying),
+			zap.Int("failed_events", report.Failed))
+	} else if report.Delivered > 0 {
+		s.logger.Info("✅ Relayed outbox events", zap.Int("delivered_events", report.Delivered))
+	}
+	return nil
+}
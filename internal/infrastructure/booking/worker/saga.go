@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	bookinguc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
+)
+
+// SagaRecoveryScheduler resumes booking creation sagas interrupted by a crash.
+type SagaRecoveryScheduler struct {
+	cron    *cron.Cron
+	service *bookinguc.Service
+	logger  *zap.Logger
+}
+
+// NewSagaRecoveryScheduler creates a new scheduler instance.
+func NewSagaRecoveryScheduler(service *bookinguc.Service, logger *zap.Logger) *SagaRecoveryScheduler {
+	return &SagaRecoveryScheduler{
+		cron:    cron.New(),
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Start initializes and starts the cron scheduler.
+// Schedule: every minute.
+func (s *SagaRecoveryScheduler) Start() error {
+	_, err := s.cron.AddFunc("@every 1m", func() {
+		if err := s.runRecovery(); err != nil {
+			s.logger.Error("❌ Saga recovery failed", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cron.Start()
+	s.logger.Info("✅ Saga recovery scheduler started")
+	return nil
+}
+
+// Stop gracefully stops the cron scheduler.
+func (s *SagaRecoveryScheduler) Stop() {
+	if s.cron != nil {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+		s.logger.Info("🛑 Saga recovery scheduler stopped")
+	}
+}
+
+// runRecovery completes or compensates stale creation sagas.
+func (s *SagaRecoveryScheduler) runRecovery() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	count, err := s.service.RecoverSagas(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		s.logger.Info("✅ Recovered booking sagas", zap.Int("recovered_sagas", count))
+	}
+	return nil
+}
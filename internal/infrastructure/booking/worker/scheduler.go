@@ -59,15 +59,21 @@ func (s *AutoCheckoutScheduler) runAutoCheckout() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	count, err := s.service.AutoCheckout(ctx)
+	report, err := s.service.CheckoutDue(ctx, time.Now())
+	for bookingID, failure := range report.Failed {
+		s.logger.Error("❌ Auto-checkout failed for booking", zap.String("booking_id", bookingID.String()), zap.Error(failure))
+	}
 	if err != nil {
 		return err
 	}
 
-	if count == 0 {
-		s.logger.Info("✅ No bookings to auto-checkout today")
+	if report.Completed == 0 {
+		s.logger.Info("✅ No bookings to auto-checkout today", zap.Int("already_completed", report.Skipped))
 	} else {
-		s.logger.Info("✅ Auto-checkout completed", zap.Int("processed_bookings", count))
+		s.logger.Info("✅ Auto-checkout completed",
+			zap.Int("processed_bookings", report.Completed),
+			zap.Int("already_completed", report.Skipped),
+			zap.Int("failed_bookings", len(report.Failed)))
 	}
 
 	return nil
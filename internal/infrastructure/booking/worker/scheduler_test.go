@@ -2,6 +2,7 @@ package worker_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
 	hdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestAutoCheckoutSchedulerCreation(t *testing.T) {
@@ -52,6 +54,50 @@ func TestAutoCheckoutSchedulerStartStop(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 }
 
+func TestPaymentExpirySchedulerStartStop(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := bookinguc.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+	scheduler := bookingworker.NewPaymentExpiryScheduler(service, 15*time.Minute, zap.NewNop())
+	require.NotNil(t, scheduler)
+
+	require.NoError(t, scheduler.Start())
+	scheduler.Stop()
+}
+
+func TestNoShowSchedulerStartStop(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := bookinguc.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+	scheduler := bookingworker.NewNoShowScheduler(service, domain.NoShowPolicy{ChargeNights: 1}, zap.NewNop())
+	require.NotNil(t, scheduler)
+
+	require.NoError(t, scheduler.Start())
+	scheduler.Stop()
+}
+
+func TestOutboxRelaySchedulerStartStop(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := bookinguc.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+	scheduler := bookingworker.NewOutboxRelayScheduler(service, zap.NewNop())
+	require.NotNil(t, scheduler)
+
+	require.NoError(t, scheduler.Start())
+	scheduler.Stop()
+}
+
+func TestSagaRecoverySchedulerStartStop(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := bookinguc.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+	scheduler := bookingworker.NewSagaRecoveryScheduler(service, zap.NewNop())
+	require.NotNil(t, scheduler)
+
+	require.NoError(t, scheduler.Start())
+	scheduler.Stop()
+}
+
 // Test stubs
 type bookingRepoStub struct {
 	store map[uuid.UUID]domain.Booking
@@ -97,11 +143,102 @@ func (b *bookingRepoStub) List(ctx context.Context, _ query.Options) ([]domain.B
 	return out, nil
 }
 
+func (b *bookingRepoStub) FindPendingBefore(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusPendingPayment && v.CreatedAt.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) FindNoShows(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusConfirmed && v.CheckIn.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if f.UserID == uuid.Nil || v.UserID == f.UserID {
+			out = append(out, v)
+		}
+	}
+	return domain.SearchResult{Bookings: out, Total: int64(len(out))}, nil
+}
+func (b *bookingRepoStub) FindDueForCheckout(context.Context, time.Time, uuid.UUID, int) ([]domain.Booking, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) ClaimOutbox(context.Context, time.Time, time.Duration, int) ([]domain.OutboxMessage, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) MarkDelivered(context.Context, uuid.UUID, time.Time) error { return nil }
+func (b *bookingRepoStub) MarkAttemptFailed(context.Context, uuid.UUID, string, time.Time, bool) error {
+	return nil
+}
+func (b *bookingRepoStub) ListOutbox(context.Context, string, query.Options) ([]domain.OutboxMessage, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) FindOutbox(context.Context, uuid.UUID) (domain.OutboxMessage, error) {
+	return domain.OutboxMessage{}, errors.New("not found")
+}
+func (b *bookingRepoStub) ReplayOutbox(context.Context, uuid.UUID) error { return nil }
+func (b *bookingRepoStub) SaveSaga(context.Context, domain.Saga, ...domain.Booking) error {
+	return nil
+}
+func (b *bookingRepoStub) FindSaga(context.Context, uuid.UUID) (domain.Saga, error) {
+	return domain.Saga{}, errors.New("not found")
+}
+func (b *bookingRepoStub) ClaimStaleSagas(context.Context, time.Time, time.Time, int) ([]domain.Saga, error) {
+	return nil, nil
+}
+func (b *bookingRepoStub) FindReservation(context.Context, uuid.UUID) (domain.Reservation, error) {
+	return domain.Reservation{}, errors.New("not found")
+}
+func (b *bookingRepoStub) FindAssignment(context.Context, uuid.UUID) (domain.RoomAssignment, error) {
+	return domain.RoomAssignment{}, errors.New("not found")
+}
+func (b *bookingRepoStub) AssignRoom(ctx context.Context, bk domain.Booking, _ domain.RoomPicker) (domain.RoomAssignment, error) {
+	b.store[bk.ID] = bk
+	return domain.RoomAssignment{BookingID: bk.ID}, nil
+}
+func (b *bookingRepoStub) ReleaseRoom(ctx context.Context, bk domain.Booking) error {
+	b.store[bk.ID] = bk
+	return nil
+}
 func (b *bookingRepoStub) Save(ctx context.Context, bk domain.Booking) error {
 	b.store[bk.ID] = bk
 	return nil
 }
 
+func (b *bookingRepoStub) Transition(ctx context.Context, bk domain.Booking, from string) error {
+	if b.store[bk.ID].Status != from {
+		return errors.New("booking is no longer " + from)
+	}
+	b.store[bk.ID] = bk
+	return nil
+}
+
+func (b *bookingRepoStub) Reserve(ctx context.Context, bk domain.Booking, _ domain.ReservationCheck) error {
+	b.store[bk.ID] = bk
+	return nil
+}
+
+func (b *bookingRepoStub) Amend(ctx context.Context, bk domain.Booking, _ domain.ReservationCheck) error {
+	b.store[bk.ID] = bk
+	return nil
+}
+func (b *bookingRepoStub) ReserveGroup(ctx context.Context, r domain.Reservation, _ domain.ReservationCheck) error {
+	for _, line := range r.Lines {
+		b.store[line.ID] = line
+	}
+	return nil
+}
+
 type hotelRepoStub struct {
 	roomType hdomain.RoomType
 	err      error
@@ -138,6 +275,9 @@ func (h *hotelRepoStub) GetRoom(context.Context, uuid.UUID) (hdomain.Room, error
 }
 func (h *hotelRepoStub) UpdateRoom(context.Context, uuid.UUID, hdomain.Room) error { return nil }
 func (h *hotelRepoStub) DeleteRoom(context.Context, uuid.UUID) error               { return nil }
+func (h *hotelRepoStub) NightlyInventory(context.Context, []uuid.UUID, valueobject.DateRange) ([]hdomain.NightlyInventory, error) {
+	return nil, nil
+}
 
 type paymentGatewayStub struct{}
 
@@ -150,6 +290,17 @@ func (p *paymentGatewayStub) Initiate(context.Context, uuid.UUID, float64) (doma
 	}, nil
 }
 
+func (p *paymentGatewayStub) Expire(context.Context, uuid.UUID) error { return nil }
+func (p *paymentGatewayStub) RequestAdditional(context.Context, uuid.UUID, float64) (domain.PaymentResult, error) {
+	return domain.PaymentResult{}, nil
+}
+func (p *paymentGatewayStub) Refund(context.Context, uuid.UUID, float64, string) (domain.RefundResult, error) {
+	return domain.RefundResult{}, nil
+}
+func (p *paymentGatewayStub) VoidAdjustments(context.Context, uuid.UUID) (float64, error) {
+	return 0, nil
+}
+
 type notificationGatewayStub struct{}
 
 func (n *notificationGatewayStub) Notify(context.Context, string, any) error {
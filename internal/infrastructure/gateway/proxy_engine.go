@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/ftryyln/hotel-booking-microservices/pkg/config"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 const (
@@ -33,13 +34,14 @@ type proxyEngine struct {
 	readyCh          chan struct{}
 	readyOnce        sync.Once
 	upstreams        map[string]*upstreamTarget
-	jwtSecret        string
+	verifier         *middleware.Verifier
+	revoked          middleware.RevocationChecker
 	circuitWindow    time.Duration
 	circuitThreshold float64
 	circuitCooldown  time.Duration
 }
 
-func NewProxyEngine(cfg config.Config, log *zap.Logger) (*proxyEngine, error) {
+func NewProxyEngine(cfg config.Config, log *zap.Logger, verifier *middleware.Verifier, revoked middleware.RevocationChecker) (*proxyEngine, error) {
 	engine := &proxyEngine{
 		mode:           cfg.GatewayMode,
 		log:            log,
@@ -52,7 +54,8 @@ func NewProxyEngine(cfg config.Config, log *zap.Logger) (*proxyEngine, error) {
 		},
 		readyCh:          make(chan struct{}),
 		upstreams:        map[string]*upstreamTarget{},
-		jwtSecret:        cfg.JWTSecret,
+		verifier:         verifier,
+		revoked:          revoked,
 		circuitWindow:    cfg.CircuitWindow,
 		circuitThreshold: cfg.CircuitThreshold,
 		circuitCooldown:  cfg.CircuitCooldown,
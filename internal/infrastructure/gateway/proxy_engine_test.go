@@ -13,6 +13,7 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"github.com/ftryyln/hotel-booking-microservices/pkg/config"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 func TestServeHTTP_RouteMatchAndAuthForward(t *testing.T) {
@@ -72,7 +73,7 @@ func TestServeHTTP_AuthValidateMissingToken(t *testing.T) {
 	defer upstream.Close()
 
 	engine := newTestEngine()
-	engine.jwtSecret = "secret"
+	engine.verifier = middleware.NewVerifier("secret", nil)
 	upURL := upstream.URL
 	target := &upstreamTarget{url: mustParseURL(upURL), health: "/healthz", status: upstreamStatus{Healthy: true, WindowStartedAt: time.Now()}}
 	engine.upstreams[upURL] = target
@@ -8,11 +8,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 
 	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
-	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 // proxy serve
@@ -69,17 +67,17 @@ func (p *proxyEngine) ensureAuth(r *http.Request, route *route) pkgErrors.APIErr
 		return pkgErrors.New("unauthorized", "missing bearer token")
 	}
 
-	if p.jwtSecret == "" {
-		return pkgErrors.New("unauthorized", "jwt secret not configured")
+	if p.verifier == nil {
+		return pkgErrors.New("unauthorized", "jwt verifier not configured")
 	}
 
-	claims := &middleware.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(p.jwtSecret), nil
-	})
-	if err != nil || !token.Valid {
+	claims, err := p.verifier.ParseAccess(r.Context(), tokenString)
+	if err != nil {
 		return pkgErrors.New("unauthorized", "invalid token")
 	}
+	if p.revoked != nil && p.revoked.Revoked(claims.ID) {
+		return pkgErrors.New("unauthorized", "token revoked")
+	}
 	return pkgErrors.APIError{}
 }
 
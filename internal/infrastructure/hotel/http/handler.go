@@ -1,9 +1,11 @@
 package hotelhttp
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -15,38 +17,64 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/utils"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // Handler exposes hotel endpoints.
 type Handler struct {
-	service   *hoteluc.Service
-	jwtSecret string
+	service  *hoteluc.Service
+	verifier *middleware.Verifier
+	revoked  middleware.RevocationChecker
 }
 
-func NewHandler(service *hoteluc.Service, jwtSecret string) *Handler {
-	return &Handler{service: service, jwtSecret: jwtSecret}
+func NewHandler(service *hoteluc.Service, verifier *middleware.Verifier, revoked middleware.RevocationChecker) *Handler {
+	return &Handler{service: service, verifier: verifier, revoked: revoked}
 }
 
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Get("/hotels", h.listHotels)
 	r.Get("/hotels/{id}", h.getHotel)
+	r.Get("/hotels/{id}/availability", h.hotelAvailability)
 	r.Get("/room-types", h.listRoomTypes)
+	r.Get("/room-types/{id}/availability", h.roomTypeAvailability)
 	r.Get("/rooms", h.listRooms)
 	r.Get("/rooms/{id}", h.getRoom)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.JWT(h.jwtSecret, "admin"))
+		r.Use(middleware.Authenticate(h.verifier, h.revoked, "admin"))
 		r.Post("/hotels", h.createHotel)
-		r.Put("/hotels/{id}", h.updateHotel)
 		r.Delete("/hotels/{id}", h.deleteHotel)
+	})
+	// Hotel staff manage the hotels they hold a role at; admins manage all.
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(h.verifier, h.revoked))
+		r.With(middleware.RequirePermission(valueobject.PermHotelUpdate, hotelParam)).Put("/hotels/{id}", h.updateHotel)
 		r.Post("/room-types", h.createRoomType)
 		r.Post("/rooms", h.createRoom)
-		r.Put("/rooms/{id}", h.updateRoom)
-		r.Delete("/rooms/{id}", h.deleteRoom)
+		r.With(middleware.RequirePermission(valueobject.PermRoomUpdate, h.roomHotel)).Put("/rooms/{id}", h.updateRoom)
+		r.With(middleware.RequirePermission(valueobject.PermRoomDelete, h.roomHotel)).Delete("/rooms/{id}", h.deleteRoom)
 	})
 	return r
 }
 
+// hotelParam resolves the hotel of /hotels/{id} routes.
+func hotelParam(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, pkgErrors.New("bad_request", "invalid id")
+	}
+	return id, nil
+}
+
+// roomHotel resolves the hotel of /rooms/{id} routes.
+func (h *Handler) roomHotel(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, pkgErrors.New("bad_request", "invalid id")
+	}
+	return h.service.RoomHotel(r.Context(), id)
+}
+
 // @Summary Create hotel
 // @Tags Hotels
 // @Accept json
@@ -125,12 +153,14 @@ func (h *Handler) getHotel(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Create room type
+// @Description Requires room_type:create at the hotel (hotel_manager) or admin.
 // @Tags Hotels
 // @Accept json
 // @Produce json
 // @Param request body dto.RoomTypeRequest true "Room type payload"
 // @Success 201 {object} dto.CreatedRoomTypeResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /room-types [post]
 func (h *Handler) createRoomType(w http.ResponseWriter, r *http.Request) {
@@ -139,6 +169,15 @@ func (h *Handler) createRoomType(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
 		return
 	}
+	hotelID, err := uuid.Parse(req.HotelID)
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid hotel id"))
+		return
+	}
+	if err := middleware.Authorize(r.Context(), valueobject.PermRoomTypeCreate, middleware.ForHotel(hotelID)); err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
 	id, err := h.service.CreateRoomType(r.Context(), req)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
@@ -179,12 +218,14 @@ func (h *Handler) listRoomTypes(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Create room
+// @Description Requires room:create at the room type's hotel (hotel_manager) or admin.
 // @Tags Hotels
 // @Accept json
 // @Produce json
 // @Param request body dto.RoomRequest true "Room payload"
 // @Success 201 {object} dto.CreatedRoomResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /rooms [post]
 func (h *Handler) createRoom(w http.ResponseWriter, r *http.Request) {
@@ -193,6 +234,18 @@ func (h *Handler) createRoom(w http.ResponseWriter, r *http.Request) {
 		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
 		return
 	}
+	roomTypeID, err := uuid.Parse(req.RoomTypeID)
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid room type id"))
+		return
+	}
+	err = middleware.Authorize(r.Context(), valueobject.PermRoomCreate, func(ctx context.Context) (uuid.UUID, error) {
+		return h.service.RoomTypeHotel(ctx, roomTypeID)
+	})
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
 	id, err := h.service.CreateRoom(r.Context(), req)
 	if err != nil {
 		writeError(w, pkgErrors.FromError(err))
@@ -231,6 +284,7 @@ func (h *Handler) listRooms(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Update hotel
+// @Description Requires hotel:update at the hotel (hotel_manager) or admin.
 // @Tags Hotels
 // @Accept json
 // @Produce json
@@ -238,6 +292,7 @@ func (h *Handler) listRooms(w http.ResponseWriter, r *http.Request) {
 // @Param request body dto.HotelUpdateRequest true "Hotel update payload"
 // @Success 200 {object} dto.HotelResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /hotels/{id} [put]
@@ -314,6 +369,7 @@ func (h *Handler) getRoom(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Update room
+// @Description Requires room:update at the room's hotel (hotel_manager, front_desk) or admin.
 // @Tags Hotels
 // @Accept json
 // @Produce json
@@ -321,6 +377,7 @@ func (h *Handler) getRoom(w http.ResponseWriter, r *http.Request) {
 // @Param request body dto.RoomUpdateRequest true "Room update payload"
 // @Success 200 {object} dto.RoomResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /rooms/{id} [put]
@@ -348,10 +405,12 @@ func (h *Handler) updateRoom(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Delete room
+// @Description Requires room:delete at the room's hotel (hotel_manager) or admin.
 // @Tags Hotels
 // @Produce json
 // @Param id path string true "Room ID"
 // @Success 200 {object} dto.SuccessResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /rooms/{id} [delete]
@@ -372,6 +431,73 @@ func (h *Handler) deleteRoom(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Hotel availability calendar
+// @Tags Hotels
+// @Produce json
+// @Param id path string true "Hotel ID"
+// @Param from query string false "first night (YYYY-MM-DD, default today)"
+// @Param to query string false "departure date (YYYY-MM-DD, default from + 7 days)"
+// @Success 200 {array} dto.RoomTypeAvailabilityResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /hotels/{id}/availability [get]
+func (h *Handler) hotelAvailability(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	stay, err := parseAvailabilityWindow(r)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp, err := h.service.HotelAvailability(r.Context(), id, stay)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var resources []utils.Resource
+	for _, a := range resp {
+		dtoResp := assembler.ToAvailabilityResponse(a)
+		resources = append(resources, utils.NewResource(dtoResp.RoomTypeID, "room_type_availability", "/api/v1/room-types/"+dtoResp.RoomTypeID+"/availability", dtoResp))
+	}
+	utils.RespondWithCount(w, http.StatusOK, "hotel availability retrieved", resources, len(resources))
+}
+
+// @Summary Room type availability calendar
+// @Tags Hotels
+// @Produce json
+// @Param id path string true "Room type ID"
+// @Param from query string false "first night (YYYY-MM-DD, default today)"
+// @Param to query string false "departure date (YYYY-MM-DD, default from + 7 days)"
+// @Success 200 {object} dto.RoomTypeAvailabilityResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /room-types/{id}/availability [get]
+func (h *Handler) roomTypeAvailability(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid id"))
+		return
+	}
+	stay, err := parseAvailabilityWindow(r)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp, err := h.service.RoomTypeAvailability(r.Context(), id, stay)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	dtoResp := assembler.ToAvailabilityResponse(resp)
+	resource := utils.NewResource(dtoResp.RoomTypeID, "room_type_availability", "/api/v1/room-types/"+dtoResp.RoomTypeID+"/availability", dtoResp)
+	utils.Respond(w, http.StatusOK, "room type availability retrieved", resource)
+}
+
 func writeJSON(w http.ResponseWriter, status int, body any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -387,3 +513,25 @@ func parseQueryOptions(r *http.Request) query.Options {
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 	return query.Options{Limit: limit, Offset: offset}
 }
+
+// parseAvailabilityWindow reads from/to dates, defaulting to the next 7 nights.
+func parseAvailabilityWindow(r *http.Request) (valueobject.DateRange, error) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return valueobject.DateRange{}, pkgErrors.New("bad_request", "invalid from date")
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 7)
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return valueobject.DateRange{}, pkgErrors.New("bad_request", "invalid to date")
+		}
+		to = parsed
+	}
+	return valueobject.NewDateRange(from, to)
+}
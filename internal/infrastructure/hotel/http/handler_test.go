@@ -6,21 +6,26 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	authdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
+	"github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/auth/token"
 	hotelhttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/hotel/http"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/hotel"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestHotelHandlerListHotelsPagination(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -30,8 +35,10 @@ func TestHotelHandlerListHotelsPagination(t *testing.T) {
 	require.Equal(t, http.StatusOK, rec.Code)
 }
 
-// minimal repo stub for handler test
-type hotelRepoStub struct{}
+// minimal repo stub for handler test; every room type belongs to hotelID
+type hotelRepoStub struct {
+	hotelID uuid.UUID
+}
 
 func (h *hotelRepoStub) CreateHotel(ctx context.Context, hotel domain.Hotel) error { return nil }
 func (h *hotelRepoStub) ListHotels(ctx context.Context, opts query.Options) ([]domain.Hotel, error) {
@@ -45,8 +52,8 @@ func (h *hotelRepoStub) ListAllRoomTypes(context.Context, query.Options) ([]doma
 	return []domain.RoomType{}, nil
 }
 func (h *hotelRepoStub) CreateRoom(context.Context, domain.Room) error { return nil }
-func (h *hotelRepoStub) GetRoomType(context.Context, uuid.UUID) (domain.RoomType, error) {
-	return domain.RoomType{}, nil
+func (h *hotelRepoStub) GetRoomType(ctx context.Context, id uuid.UUID) (domain.RoomType, error) {
+	return domain.RoomType{ID: id, HotelID: h.hotelID}, nil
 }
 func (h *hotelRepoStub) ListRooms(context.Context, query.Options) ([]domain.Room, error) {
 	return []domain.Room{}, nil
@@ -59,15 +66,18 @@ func (h *hotelRepoStub) UpdateHotel(ctx context.Context, id uuid.UUID, hotel dom
 }
 func (h *hotelRepoStub) DeleteHotel(context.Context, uuid.UUID) error { return nil }
 func (h *hotelRepoStub) GetRoom(ctx context.Context, id uuid.UUID) (domain.Room, error) {
-	return domain.Room{ID: id, Number: "101", Status: "available"}, nil
+	return domain.Room{ID: id, RoomTypeID: uuid.New(), Number: "101", Status: "available"}, nil
 }
 func (h *hotelRepoStub) UpdateRoom(context.Context, uuid.UUID, domain.Room) error { return nil }
 func (h *hotelRepoStub) DeleteRoom(context.Context, uuid.UUID) error              { return nil }
+func (h *hotelRepoStub) NightlyInventory(context.Context, []uuid.UUID, valueobject.DateRange) ([]domain.NightlyInventory, error) {
+	return nil, nil
+}
 
 func TestHotelHandlerUpdateHotel(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -85,7 +95,7 @@ func TestHotelHandlerUpdateHotel(t *testing.T) {
 func TestHotelHandlerDeleteHotel(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -100,7 +110,7 @@ func TestHotelHandlerDeleteHotel(t *testing.T) {
 func TestHotelHandlerGetRoom(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -115,7 +125,7 @@ func TestHotelHandlerGetRoom(t *testing.T) {
 func TestHotelHandlerUpdateRoom(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -133,7 +143,7 @@ func TestHotelHandlerUpdateRoom(t *testing.T) {
 func TestHotelHandlerDeleteRoom(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -148,7 +158,7 @@ func TestHotelHandlerDeleteRoom(t *testing.T) {
 func TestHotelHandlerUpdateHotelInvalidID(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
-	h := hotelhttp.NewHandler(svc, "secret")
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
 
@@ -161,3 +171,55 @@ func TestHotelHandlerUpdateHotelInvalidID(t *testing.T) {
 	// Update requires admin auth, so without JWT we expect 401 (not 400 for invalid ID)
 	require.Equal(t, http.StatusUnauthorized, rec.Code)
 }
+
+func TestHotelHandlerRoomTypeAvailability(t *testing.T) {
+	repo := &hotelRepoStub{}
+	svc := hotel.NewService(repo)
+	h := hotelhttp.NewHandler(svc, middleware.NewVerifier("secret", nil), nil)
+	r := chi.NewRouter()
+	r.Mount("/", h.Routes())
+
+	rtID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/room-types/"+rtID.String()+"/availability?from=2025-01-01&to=2025-01-04", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/room-types/"+rtID.String()+"/availability?from=2025-01-04&to=2025-01-01", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHotelHandlerStaffScopedToTheirHotel(t *testing.T) {
+	keys, err := token.GenerateKeyRing()
+	require.NoError(t, err)
+	mine, other := uuid.New(), uuid.New()
+	repo := &hotelRepoStub{hotelID: mine}
+	h := hotelhttp.NewHandler(hotel.NewService(repo), middleware.NewVerifier("secret", keys), nil)
+	r := chi.NewRouter()
+	r.Mount("/", h.Routes())
+
+	clerk := authdomain.User{ID: uuid.New(), Role: "customer", HotelRoles: []authdomain.HotelRole{{HotelID: mine, Role: valueobject.RoleFrontDesk}}}
+	access, _, err := token.NewJWTIssuer(keys).Generate(context.Background(), clerk, authdomain.NewRefreshToken(clerk.ID, uuid.Nil, false, time.Now()))
+	require.NoError(t, err)
+
+	call := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+access)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	room := "/rooms/" + uuid.NewString()
+	require.Equal(t, http.StatusOK, call(http.MethodPut, room, `{"status":"maintenance"}`))
+	// front desk cannot delete rooms or manage the hotel itself
+	require.Equal(t, http.StatusForbidden, call(http.MethodDelete, room, ""))
+	require.Equal(t, http.StatusForbidden, call(http.MethodPut, "/hotels/"+mine.String(), `{"name":"H","address":"A"}`))
+	require.Equal(t, http.StatusForbidden, call(http.MethodPost, "/hotels", `{"name":"H","address":"A"}`))
+
+	// rooms of another hotel are out of scope
+	repo.hotelID = other
+	require.Equal(t, http.StatusForbidden, call(http.MethodPut, room, `{"status":"maintenance"}`))
+	require.Equal(t, http.StatusForbidden, call(http.MethodPost, "/room-types", `{"hotel_id":"`+mine.String()+`","name":"Suite","capacity":2,"base_price":100}`))
+}
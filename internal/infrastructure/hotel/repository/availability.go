@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+type roomCountRow struct {
+	RoomTypeID uuid.UUID
+	Total      int
+	OutOfOrder int
+}
+
+type stayCountRow struct {
+	RoomTypeID uuid.UUID
+	CheckIn    time.Time
+	CheckOut   time.Time
+	Booked     int
+}
+
+// NightlyInventory aggregates rooms and inventory-holding bookings into one
+// row per room type and night of the stay.
+func (r *GormRepository) NightlyInventory(ctx context.Context, roomTypeIDs []uuid.UUID, stay valueobject.DateRange) ([]domain.NightlyInventory, error) {
+	if len(roomTypeIDs) == 0 {
+		return []domain.NightlyInventory{}, nil
+	}
+
+	var rooms []roomCountRow
+	err := r.db.WithContext(ctx).Table("rooms").
+		Select("room_type_id, COUNT(*) AS total, SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS out_of_order", valueobject.OutOfOrderRoomStatuses).
+		Where("room_type_id IN ? AND deleted_at IS NULL", roomTypeIDs).
+		Group("room_type_id").
+		Scan(&rooms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var stays []stayCountRow
+	err = r.db.WithContext(ctx).Table("bookings").
+		Select("room_type_id, check_in, check_out, COUNT(*) AS booked").
+		Where("room_type_id IN ? AND status NOT IN ?", roomTypeIDs, valueobject.ReleasedBookingStatuses).
+		Where("check_in < ? AND check_out > ?", stay.End, stay.Start).
+		Group("room_type_id, check_in, check_out").
+		Scan(&stays).Error
+	if err != nil {
+		return nil, err
+	}
+
+	roomsByType := make(map[uuid.UUID]roomCountRow, len(rooms))
+	for _, row := range rooms {
+		roomsByType[row.RoomTypeID] = row
+	}
+	staysByType := make(map[uuid.UUID][]stayCountRow, len(stays))
+	for _, row := range stays {
+		staysByType[row.RoomTypeID] = append(staysByType[row.RoomTypeID], row)
+	}
+
+	out := make([]domain.NightlyInventory, 0, len(roomTypeIDs)*stay.Nights())
+	for _, id := range roomTypeIDs {
+		counts := roomsByType[id]
+		for night := stay.Start; night.Before(stay.End); night = night.AddDate(0, 0, 1) {
+			booked := 0
+			for _, s := range staysByType[id] {
+				if (valueobject.DateRange{Start: s.CheckIn, End: s.CheckOut}).Contains(night) {
+					booked += s.Booked
+				}
+			}
+			out = append(out, domain.NightlyInventory{
+				RoomTypeID:  id,
+				Night:       night,
+				TotalRooms:  counts.Total,
+				OutOfOrder:  counts.OutOfOrder,
+				BookedRooms: booked,
+			})
+		}
+	}
+	return out, nil
+}
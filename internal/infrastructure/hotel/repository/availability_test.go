@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	bookingdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
+	bookingrepo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/booking/repository"
+	repo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/hotel/repository"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+func TestNightlyInventory(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	require.NoError(t, bookingrepo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	bookings := bookingrepo.NewGormRepository(db)
+	ctx := context.Background()
+
+	rt := domain.RoomType{ID: uuid.New(), HotelID: uuid.New(), Name: "Suite", Capacity: 2, BasePrice: 200}
+	require.NoError(t, r.CreateRoomType(ctx, rt))
+	for i, status := range []string{"available", "available", "maintenance"} {
+		require.NoError(t, r.CreateRoom(ctx, domain.Room{ID: uuid.New(), RoomTypeID: rt.ID, Number: string(rune('A' + i)), Status: status}))
+	}
+
+	start := time.Date(2031, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, b := range []bookingdomain.Booking{
+		{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: start, CheckOut: start.AddDate(0, 0, 2), Status: bookingdomain.StatusConfirmed},
+		{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: start.AddDate(0, 0, 1), CheckOut: start.AddDate(0, 0, 3), Status: bookingdomain.StatusPendingPayment},
+		{ID: uuid.New(), UserID: uuid.New(), RoomTypeID: rt.ID, CheckIn: start, CheckOut: start.AddDate(0, 0, 3), Status: bookingdomain.StatusCancelled},
+	} {
+		require.NoError(t, bookings.Create(ctx, b))
+	}
+
+	stay, err := valueobject.NewDateRange(start, start.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	nights, err := r.NightlyInventory(ctx, []uuid.UUID{rt.ID}, stay)
+	require.NoError(t, err)
+	require.Len(t, nights, 3)
+
+	booked := []int{1, 2, 1}
+	for i, n := range nights {
+		require.Equal(t, 3, n.TotalRooms)
+		require.Equal(t, 1, n.OutOfOrder)
+		require.Equal(t, booked[i], n.BookedRooms)
+	}
+	require.Equal(t, 0, nights[1].Remaining())
+}
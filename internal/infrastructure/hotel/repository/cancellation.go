@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// cancellationColumns stores an optional cancellation policy on hotels and room types.
+// A NULL cancellation_free_days means no policy is configured at that level.
+type cancellationColumns struct {
+	CancellationFreeDays      *int
+	CancellationPenaltyPct    *float64 `gorm:"column:cancellation_penalty_percent;type:numeric"`
+	CancellationNonRefundable bool     `gorm:"default:false"`
+}
+
+func fromPolicy(p *valueobject.CancellationPolicy) cancellationColumns {
+	if p == nil {
+		return cancellationColumns{}
+	}
+	days, pct := p.FreeCancellationDays, p.PenaltyPercent
+	return cancellationColumns{
+		CancellationFreeDays:      &days,
+		CancellationPenaltyPct:    &pct,
+		CancellationNonRefundable: p.NonRefundable,
+	}
+}
+
+func (c cancellationColumns) toPolicy() *valueobject.CancellationPolicy {
+	if c.CancellationFreeDays == nil {
+		return nil
+	}
+	p := valueobject.CancellationPolicy{
+		FreeCancellationDays: *c.CancellationFreeDays,
+		NonRefundable:        c.CancellationNonRefundable,
+	}
+	if c.CancellationPenaltyPct != nil {
+		p.PenaltyPercent = *c.CancellationPenaltyPct
+	}
+	return &p
+}
+
+// updates returns the column updates for an explicitly provided policy.
+func (c cancellationColumns) updates() map[string]interface{} {
+	return map[string]interface{}{
+		"cancellation_free_days":       c.CancellationFreeDays,
+		"cancellation_penalty_percent": c.CancellationPenaltyPct,
+		"cancellation_non_refundable":  c.CancellationNonRefundable,
+	}
+}
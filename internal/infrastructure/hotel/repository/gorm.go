@@ -32,6 +32,8 @@ func (r *GormRepository) CreateHotel(ctx context.Context, h domain.Hotel) error
 		Description: h.Description,
 		Address:     h.Address,
 		CreatedAt:   h.CreatedAt,
+
+		Cancellation: fromPolicy(h.CancellationPolicy),
 	}).Error
 }
 
@@ -60,6 +62,8 @@ func (r *GormRepository) CreateRoomType(ctx context.Context, rt domain.RoomType)
 		Capacity:  rt.Capacity,
 		BasePrice: rt.BasePrice,
 		Amenities: rt.Amenities,
+
+		Cancellation: fromPolicy(rt.CancellationPolicy),
 	}).Error
 }
 
@@ -161,14 +165,21 @@ func (r *GormRepository) GetHotel(ctx context.Context, id uuid.UUID) (domain.Hot
 	return model.toDomain(), nil
 }
 
+// UpdateHotel replaces hotel details; the cancellation policy is only changed when set.
 func (r *GormRepository) UpdateHotel(ctx context.Context, id uuid.UUID, h domain.Hotel) error {
+	updates := map[string]interface{}{
+		"name":        h.Name,
+		"description": h.Description,
+		"address":     h.Address,
+	}
+	if h.CancellationPolicy != nil {
+		for column, value := range fromPolicy(h.CancellationPolicy).updates() {
+			updates[column] = value
+		}
+	}
 	result := r.db.WithContext(ctx).Model(&hotelModel{}).
 		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"name":        h.Name,
-			"description": h.Description,
-			"address":     h.Address,
-		})
+		Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -196,6 +207,8 @@ type hotelModel struct {
 	Address     string
 	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime"`
 	DeletedAt   gorm.DeletedAt `gorm:"index"` // Soft delete support
+
+	Cancellation cancellationColumns `gorm:"embedded"`
 }
 
 func (hotelModel) TableName() string { return "hotels" }
@@ -207,6 +220,8 @@ func (m hotelModel) toDomain() domain.Hotel {
 		Description: m.Description,
 		Address:     m.Address,
 		CreatedAt:   m.CreatedAt,
+
+		CancellationPolicy: m.Cancellation.toPolicy(),
 	}
 }
 
@@ -217,6 +232,8 @@ type roomTypeModel struct {
 	Capacity  int
 	BasePrice float64 `gorm:"type:numeric"`
 	Amenities string
+
+	Cancellation cancellationColumns `gorm:"embedded"`
 }
 
 func (roomTypeModel) TableName() string { return "room_types" }
@@ -229,6 +246,8 @@ func (m roomTypeModel) toDomain() domain.RoomType {
 		Capacity:  m.Capacity,
 		BasePrice: m.BasePrice,
 		Amenities: m.Amenities,
+
+		CancellationPolicy: m.Cancellation.toPolicy(),
 	}
 }
 
@@ -12,6 +12,7 @@ import (
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	repo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/hotel/repository"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestHotelGormRepository(t *testing.T) {
@@ -27,6 +28,33 @@ func TestHotelGormRepository(t *testing.T) {
 	require.Len(t, hotels, 1)
 }
 
+func TestHotelGormRepositoryCancellationPolicy(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	h := domain.Hotel{ID: uuid.New(), Name: "Policy", Address: "Addr"}
+	require.NoError(t, r.CreateHotel(ctx, h))
+	stored, err := r.GetHotel(ctx, h.ID)
+	require.NoError(t, err)
+	require.Nil(t, stored.CancellationPolicy)
+
+	policy := valueobject.CancellationPolicy{FreeCancellationDays: 3, PenaltyPercent: 50}
+	h.CancellationPolicy = &policy
+	require.NoError(t, r.UpdateHotel(ctx, h.ID, h))
+	stored, err = r.GetHotel(ctx, h.ID)
+	require.NoError(t, err)
+	require.Equal(t, &policy, stored.CancellationPolicy)
+
+	strict := valueobject.CancellationPolicy{PenaltyPercent: 100, NonRefundable: true}
+	rt := domain.RoomType{ID: uuid.New(), HotelID: h.ID, Name: "Saver", Capacity: 2, BasePrice: 100, CancellationPolicy: &strict}
+	require.NoError(t, r.CreateRoomType(ctx, rt))
+	storedRT, err := r.GetRoomType(ctx, rt.ID)
+	require.NoError(t, err)
+	require.Equal(t, strict, storedRT.EffectiveCancellationPolicy(stored))
+}
+
 func newTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
@@ -28,11 +28,15 @@ func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 	r.Post("/notifications", h.send)
 	r.Get("/notifications", h.list)
+	r.Post("/notifications/erase", h.erase)
+	r.Get("/notifications/dead-letter", h.deadLetters)
 	r.Get("/notifications/{id}", h.get)
+	r.Post("/notifications/{id}/retry", h.retry)
 	return r
 }
 
 // @Summary Send notification
+// @Description Queues the notification and returns at once; delivery workers dispatch it in the background.
 // @Tags Notifications
 // @Accept json
 // @Produce json
@@ -61,22 +65,103 @@ func (h *Handler) send(w http.ResponseWriter, r *http.Request) {
 	utils.Respond(w, http.StatusAccepted, "notification accepted", resource)
 }
 
-// @Summary List notifications (in-memory)
+// @Summary List notifications
 // @Tags Notifications
 // @Produce json
 // @Param limit query int false "pagination limit (default 50)"
 // @Param offset query int false "pagination offset"
+// @Param type query string false "notification type"
+// @Param target query string false "only notifications sent to this target"
+// @Param status query string false "delivery status (queued, sent, failed, dead_letter)"
+// @Param created_from query string false "created on or after (YYYY-MM-DD or RFC3339)"
+// @Param created_to query string false "created before (YYYY-MM-DD or RFC3339)"
 // @Success 200 {array} dto.NotificationResponse
+// @Failure 400 {object} dto.ErrorResponse
 // @Router /notifications [get]
 func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
-	opts := parseQueryOptions(r)
-	items := h.service.List(r.Context(), opts)
-	dtoItems := assembler.ToResponses(items)
+	filter, err := assembler.FromSearchQuery(parseSearchQuery(r))
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	result, err := h.service.Search(r.Context(), filter)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	dtoItems := assembler.ToResponses(result.Notifications)
 	var resources []utils.Resource
 	for _, n := range dtoItems {
 		resources = append(resources, utils.NewResource(n.ID, "notification", "/api/v1/notifications/"+n.ID, n))
 	}
-	utils.RespondWithCount(w, http.StatusOK, "notifications listed", resources, len(resources))
+	utils.RespondWithTotal(w, http.StatusOK, "notifications listed", resources, len(resources), result.Total)
+}
+
+// @Summary Erase notifications of a recipient
+// @Description Redact the target and message of every notification sent to target. auth-service calls it when a user erases their account.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param request body dto.EraseNotificationsRequest true "Erase payload"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /notifications/erase [post]
+func (h *Handler) erase(w http.ResponseWriter, r *http.Request) {
+	var req dto.EraseNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	erased, err := h.service.EraseTarget(r.Context(), req.Target)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	utils.Respond(w, http.StatusOK, "notifications erased", dto.SuccessResponse{
+		Message: strconv.FormatInt(erased, 10) + " notifications erased",
+	})
+}
+
+// @Summary List dead-lettered notifications
+// @Description Notifications that failed every delivery attempt, newest first.
+// @Tags Notifications
+// @Produce json
+// @Param limit query int false "pagination limit (default 50)"
+// @Param offset query int false "pagination offset"
+// @Success 200 {array} dto.NotificationResponse
+// @Router /notifications/dead-letter [get]
+func (h *Handler) deadLetters(w http.ResponseWriter, r *http.Request) {
+	q := parseSearchQuery(r)
+	result, err := h.service.DeadLetters(r.Context(), query.Options{Limit: q.Limit, Offset: q.Offset})
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	var resources []utils.Resource
+	for _, n := range assembler.ToResponses(result.Notifications) {
+		resources = append(resources, utils.NewResource(n.ID, "notification", "/api/v1/notifications/"+n.ID, n))
+	}
+	utils.RespondWithTotal(w, http.StatusOK, "dead-lettered notifications listed", resources, len(resources), result.Total)
+}
+
+// @Summary Retry dead-lettered notification
+// @Description Puts a dead-lettered notification back in the queue with a fresh attempt budget.
+// @Tags Notifications
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 202 {object} dto.NotificationResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /notifications/{id}/retry [post]
+func (h *Handler) retry(w http.ResponseWriter, r *http.Request) {
+	notification, err := h.service.Retry(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resp := assembler.ToResponse(notification)
+	resource := utils.NewResource(resp.ID, "notification", "/api/v1/notifications/"+resp.ID, resp)
+	utils.Respond(w, http.StatusAccepted, "notification queued for retry", resource)
 }
 
 // @Summary Get notification by ID
@@ -88,9 +173,9 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
 // @Router /notifications/{id} [get]
 func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	notification, ok := h.service.Get(r.Context(), id)
-	if !ok {
-		writeError(w, pkgErrors.New("not_found", "notification not found"))
+	notification, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
 		return
 	}
 	resp := assembler.ToResponse(notification)
@@ -102,8 +187,17 @@ func writeError(w http.ResponseWriter, err pkgErrors.APIError) {
 	utils.Respond(w, pkgErrors.StatusCode(err), err.Message, err)
 }
 
-func parseQueryOptions(r *http.Request) query.Options {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	return query.Options{Limit: limit, Offset: offset}
+func parseSearchQuery(r *http.Request) dto.NotificationSearchQuery {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	return dto.NotificationSearchQuery{
+		Type:        q.Get("type"),
+		Target:      q.Get("target"),
+		Status:      q.Get("status"),
+		CreatedFrom: q.Get("created_from"),
+		CreatedTo:   q.Get("created_to"),
+		Limit:       limit,
+		Offset:      offset,
+	}
 }
@@ -6,20 +6,46 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
 
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
 	notificationhttp "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/http"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 )
 
 type dispatcherStub struct{ err error }
 
 func (d *dispatcherStub) Dispatch(ctx context.Context, target, message string) error { return d.err }
 
+type repoStub struct{ items []domain.Notification }
+
+func (r *repoStub) Create(ctx context.Context, n domain.Notification) error {
+	r.items = append(r.items, n)
+	return nil
+}
+
+func (r *repoStub) UpdateDelivery(ctx context.Context, n domain.Notification) error { return nil }
+
+func (r *repoStub) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.Notification, error) {
+	return nil, nil
+}
+
+func (r *repoStub) FindByID(ctx context.Context, id string) (domain.Notification, error) {
+	return domain.Notification{}, pkgErrors.New("not_found", "notification not found")
+}
+
+func (r *repoStub) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	return domain.SearchResult{Notifications: r.items, Total: int64(len(r.items))}, nil
+}
+
+func (r *repoStub) EraseTarget(ctx context.Context, target string) (int64, error) { return 0, nil }
+
 func TestNotificationHandlerSendAndList(t *testing.T) {
-	svc := notification.NewService(&dispatcherStub{})
+	svc := notification.NewService(&repoStub{}, &dispatcherStub{})
 	h := notificationhttp.NewHandler(svc)
 	r := chi.NewRouter()
 	r.Mount("/", h.Routes())
@@ -33,4 +59,20 @@ func TestNotificationHandlerSendAndList(t *testing.T) {
 	recList := httptest.NewRecorder()
 	r.ServeHTTP(recList, reqList)
 	require.Equal(t, http.StatusOK, recList.Code)
+	require.Contains(t, recList.Body.String(), `"status":"queued"`)
+
+	reqBad := httptest.NewRequest(http.MethodGet, "/notifications?status=delivered", nil)
+	recBad := httptest.NewRecorder()
+	r.ServeHTTP(recBad, reqBad)
+	require.Equal(t, http.StatusBadRequest, recBad.Code)
+
+	reqDead := httptest.NewRequest(http.MethodGet, "/notifications/dead-letter", nil)
+	recDead := httptest.NewRecorder()
+	r.ServeHTTP(recDead, reqDead)
+	require.Equal(t, http.StatusOK, recDead.Code)
+
+	reqRetry := httptest.NewRequest(http.MethodPost, "/notifications/00000000-0000-0000-0000-000000000000/retry", nil)
+	recRetry := httptest.NewRecorder()
+	r.ServeHTTP(recRetry, reqRetry)
+	require.Equal(t, http.StatusNotFound, recRetry.Code)
 }
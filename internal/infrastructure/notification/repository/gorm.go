@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// GormRepository persists notifications using GORM.
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository { return &GormRepository{db: db} }
+
+// AutoMigrate ensures schema is present.
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&notificationModel{})
+}
+
+func (r *GormRepository) Create(ctx context.Context, n domain.Notification) error {
+	model := toModel(n)
+	return r.db.WithContext(ctx).Create(&model).Error
+}
+
+func (r *GormRepository) UpdateDelivery(ctx context.Context, n domain.Notification) error {
+	res := r.db.WithContext(ctx).Model(&notificationModel{}).Where("id = ?", n.ID).Updates(map[string]any{
+		"status":          n.Status,
+		"attempts":        n.Attempts,
+		"last_error":      n.LastError,
+		"next_attempt_at": n.NextAttemptAt,
+		"updated_at":      n.UpdatedAt,
+		"sent_at":         n.SentAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return pkgErrors.New("not_found", "notification not found")
+	}
+	return nil
+}
+
+// ClaimDue pushes the next attempt of each due notification past the lease
+// with a conditional update; one another worker claimed first is no longer
+// due and is skipped.
+func (r *GormRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.Notification, error) {
+	pending := []string{domain.StatusQueued, domain.StatusFailed}
+	var due []notificationModel
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_attempt_at <= ?", pending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]domain.Notification, 0, len(due))
+	for _, m := range due {
+		res := r.db.WithContext(ctx).Model(&notificationModel{}).
+			Where("id = ? AND status IN ? AND next_attempt_at <= ?", m.ID, pending, now).
+			Update("next_attempt_at", now.Add(lease))
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			m.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, toDomain(m))
+		}
+	}
+	return claimed, nil
+}
+
+func (r *GormRepository) FindByID(ctx context.Context, id string) (domain.Notification, error) {
+	var model notificationModel
+	if err := r.db.WithContext(ctx).First(&model, "id = ?", id).Error; err != nil {
+		return domain.Notification{}, translateErr(err)
+	}
+	return toDomain(model), nil
+}
+
+// Search filters, counts and pages notifications, newest first.
+func (r *GormRepository) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	tx := applyFilter(r.db.WithContext(ctx), f).Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return domain.SearchResult{}, err
+	}
+	page := f.Page.Normalize(50)
+
+	var models []notificationModel
+	if err := tx.Order("created_at DESC").Limit(page.Limit).Offset(page.Offset).Find(&models).Error; err != nil {
+		return domain.SearchResult{}, err
+	}
+	list := make([]domain.Notification, 0, len(models))
+	for _, m := range models {
+		list = append(list, toDomain(m))
+	}
+	return domain.SearchResult{Notifications: list, Total: total}, nil
+}
+
+// EraseTarget redacts the target, message and last error, which may quote the
+// address, of every notification sent to target. Undelivered ones are
+// dead-lettered so that workers stop trying to reach the erased address.
+func (r *GormRepository) EraseTarget(ctx context.Context, target string) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&notificationModel{}).Where("target = ?", target).Updates(map[string]any{
+		"target":     domain.Erased,
+		"message":    domain.Erased,
+		"last_error": "",
+		"status":     gorm.Expr("CASE WHEN status = ? THEN status ELSE ? END", domain.StatusSent, domain.StatusDeadLetter),
+		"updated_at": time.Now().UTC(),
+	})
+	return res.RowsAffected, res.Error
+}
+
+func applyFilter(db *gorm.DB, f domain.Filter) *gorm.DB {
+	tx := db.Model(&notificationModel{})
+	if f.Type != "" {
+		tx = tx.Where("type = ?", f.Type)
+	}
+	if f.Target != "" {
+		tx = tx.Where("target = ?", f.Target)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if !f.CreatedFrom.IsZero() {
+		tx = tx.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		tx = tx.Where("created_at < ?", f.CreatedTo)
+	}
+	return tx
+}
+
+type notificationModel struct {
+	ID            string `gorm:"type:uuid;primaryKey"`
+	Type          string `gorm:"index"`
+	Target        string `gorm:"index"`
+	Message       string `gorm:"type:text"`
+	Status        string `gorm:"index;index:idx_notifications_due,priority:1"`
+	Attempts      int
+	LastError     string    `gorm:"type:text"`
+	NextAttemptAt time.Time `gorm:"index:idx_notifications_due,priority:2"`
+	CreatedAt     time.Time `gorm:"index"`
+	UpdatedAt     time.Time
+	SentAt        *time.Time
+}
+
+func (notificationModel) TableName() string { return "notifications" }
+
+func toModel(n domain.Notification) notificationModel {
+	return notificationModel{
+		ID:            n.ID,
+		Type:          n.Type,
+		Target:        n.Target,
+		Message:       n.Message,
+		Status:        n.Status,
+		Attempts:      n.Attempts,
+		LastError:     n.LastError,
+		NextAttemptAt: n.NextAttemptAt,
+		CreatedAt:     n.CreatedAt,
+		UpdatedAt:     n.UpdatedAt,
+		SentAt:        n.SentAt,
+	}
+}
+
+func toDomain(m notificationModel) domain.Notification {
+	return domain.Notification{
+		ID:            m.ID,
+		Type:          m.Type,
+		Target:        m.Target,
+		Message:       m.Message,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+		SentAt:        m.SentAt,
+	}
+}
+
+func translateErr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkgErrors.New("not_found", "notification not found")
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlite "github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
+	repo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/repository"
+)
+
+func TestNotificationGormRepository(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	target := uuid.NewString() + "@example.com"
+	now := time.Now().UTC()
+	sent := notification.Notification{ID: uuid.NewString(), Type: "email", Target: target, Message: "hello", Status: notification.StatusQueued, CreatedAt: now.Add(-48 * time.Hour)}
+	failed := notification.Notification{ID: uuid.NewString(), Type: "sms", Target: target, Message: "hi", Status: notification.StatusQueued, CreatedAt: now}
+	require.NoError(t, r.Create(ctx, sent))
+	require.NoError(t, r.Create(ctx, failed))
+
+	sent.MarkSent(now)
+	require.NoError(t, r.UpdateDelivery(ctx, sent))
+	failed.MarkFailed(errors.New("550 mailbox "+target+" unavailable"), now)
+	require.NoError(t, r.UpdateDelivery(ctx, failed))
+	require.Error(t, r.UpdateDelivery(ctx, notification.Notification{ID: uuid.NewString()}))
+
+	found, err := r.FindByID(ctx, failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, notification.StatusFailed, found.Status)
+	require.Equal(t, 1, found.Attempts)
+	require.Contains(t, found.LastError, "mailbox")
+	_, err = r.FindByID(ctx, uuid.NewString())
+	require.Error(t, err)
+
+	result, err := r.Search(ctx, notification.Filter{Target: target})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Total)
+	require.Equal(t, failed.ID, result.Notifications[0].ID, "newest first")
+	result, err = r.Search(ctx, notification.Filter{Target: target, Status: notification.StatusSent})
+	require.NoError(t, err)
+	require.Len(t, result.Notifications, 1)
+	require.NotNil(t, result.Notifications[0].SentAt)
+	result, err = r.Search(ctx, notification.Filter{Target: target, Type: "sms", CreatedFrom: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, result.Notifications, 1)
+	result, err = r.Search(ctx, notification.Filter{Target: target, CreatedTo: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, result.Notifications, 1)
+	require.Equal(t, sent.ID, result.Notifications[0].ID)
+
+	erased, err := r.EraseTarget(ctx, target)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), erased)
+	found, err = r.FindByID(ctx, failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, notification.Erased, found.Target)
+	require.Empty(t, found.LastError)
+	require.Equal(t, notification.StatusDeadLetter, found.Status, "undelivered notifications to an erased target are abandoned")
+	found, err = r.FindByID(ctx, sent.ID)
+	require.NoError(t, err)
+	require.Equal(t, notification.StatusSent, found.Status)
+}
+
+func TestNotificationGormRepositoryClaimDue(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	// The shared in-memory database may hold rows from other tests; count only ours.
+	now := time.Now().UTC().Add(time.Hour)
+	due := notification.Notification{ID: uuid.NewString(), Target: "a", Message: "m", Status: notification.StatusQueued, NextAttemptAt: now.Add(-time.Minute), CreatedAt: now}
+	later := notification.Notification{ID: uuid.NewString(), Target: "b", Message: "m", Status: notification.StatusFailed, NextAttemptAt: now.Add(time.Hour), CreatedAt: now}
+	dead := notification.Notification{ID: uuid.NewString(), Target: "c", Message: "m", Status: notification.StatusDeadLetter, NextAttemptAt: now.Add(-time.Minute), CreatedAt: now}
+	for _, n := range []notification.Notification{due, later, dead} {
+		require.NoError(t, r.Create(ctx, n))
+	}
+
+	claimed, err := r.ClaimDue(ctx, now, time.Minute, 100)
+	require.NoError(t, err)
+	require.Contains(t, ids(claimed), due.ID)
+	require.NotContains(t, ids(claimed), later.ID)
+	require.NotContains(t, ids(claimed), dead.ID)
+
+	// the lease hides the claimed notification from other workers
+	claimed, err = r.ClaimDue(ctx, now, time.Minute, 100)
+	require.NoError(t, err)
+	require.NotContains(t, ids(claimed), due.ID)
+	claimed, err = r.ClaimDue(ctx, now.Add(2*time.Minute), time.Minute, 100)
+	require.NoError(t, err)
+	require.Contains(t, ids(claimed), due.ID)
+}
+
+func ids(list []notification.Notification) []string {
+	out := make([]string, 0, len(list))
+	for _, n := range list {
+		out = append(out, n.ID)
+	}
+	return out
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
+	notificationuc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification"
+)
+
+const (
+	// claimTimeout bounds one query for due notifications.
+	claimTimeout = 10 * time.Second
+	// dispatchTimeout bounds one delivery attempt; it stays well under the
+	// claim lease so a slow attempt is not picked up by another worker.
+	dispatchTimeout = 30 * time.Second
+)
+
+// Pool delivers queued notifications with a fixed number of workers. A poller
+// claims due notifications and hands them to the workers one at a time, so a
+// claim never waits long for a free worker.
+type Pool struct {
+	service  *notificationuc.Service
+	workers  int
+	interval time.Duration
+	logger   *zap.Logger
+
+	jobs chan domain.Notification
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPool creates a pool of workers that polls for due notifications every interval.
+func NewPool(service *notificationuc.Service, workers int, interval time.Duration, logger *zap.Logger) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Pool{
+		service:  service,
+		workers:  workers,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start launches the poller and the workers.
+func (p *Pool) Start() {
+	p.jobs = make(chan domain.Notification)
+	p.stop = make(chan struct{})
+	p.wg.Add(p.workers + 1)
+	go p.poll()
+	for i := 0; i < p.workers; i++ {
+		go p.work()
+	}
+	p.logger.Info("✅ Notification workers started", zap.Int("workers", p.workers))
+}
+
+// Stop stops claiming new notifications and waits for the workers to finish
+// the ones already handed out, or for ctx to expire. Claimed notifications
+// that were not handed out are picked up again once their lease expires.
+func (p *Pool) Stop(ctx context.Context) error {
+	close(p.stop)
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		p.logger.Info("🛑 Notification workers drained")
+		return nil
+	case <-ctx.Done():
+		p.logger.Warn("⚠️ Notification workers did not drain in time", zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) poll() {
+	defer p.wg.Done()
+	defer close(p.jobs)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		// A full batch means more may be due, so claim again right away.
+		if full := p.handOut(); full {
+			select {
+			case <-p.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handOut claims one batch of due notifications and passes them to the
+// workers. It reports whether the batch was full.
+func (p *Pool) handOut() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), claimTimeout)
+	claimed, err := p.service.ClaimDue(ctx, p.workers)
+	cancel()
+	if err != nil {
+		p.logger.Error("❌ Claiming notifications failed", zap.Error(err))
+		return false
+	}
+	for _, n := range claimed {
+		select {
+		case p.jobs <- n:
+		case <-p.stop:
+			return false
+		}
+	}
+	return len(claimed) == p.workers
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for n := range p.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+		delivered, err := p.service.Deliver(ctx, n)
+		cancel()
+		switch {
+		case err != nil:
+			p.logger.Error("❌ Recording notification delivery failed", zap.String("notification_id", n.ID), zap.Error(err))
+		case delivered.Status == domain.StatusDeadLetter:
+			p.logger.Warn("⚠️ Notification dead-lettered",
+				zap.String("notification_id", n.ID),
+				zap.Int("attempts", delivered.Attempts),
+				zap.String("last_error", delivered.LastError))
+		case delivered.Status == domain.StatusFailed:
+			p.logger.Warn("⚠️ Notification delivery failed; retrying",
+				zap.String("notification_id", n.ID),
+				zap.Int("attempts", delivered.Attempts),
+				zap.Time("next_attempt_at", delivered.NextAttemptAt))
+		}
+	}
+}
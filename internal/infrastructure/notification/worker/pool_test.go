@@ -0,0 +1,75 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlite "github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
+	notificationrepo "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/repository"
+	notificationworker "github.com/ftryyln/hotel-booking-microservices/internal/infrastructure/notification/worker"
+	notificationuc "github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification"
+	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification/assembler"
+)
+
+func TestPoolDeliversAndDrainsOnStop(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, notificationrepo.AutoMigrate(db))
+	dispatcher := &blockingDispatcher{release: make(chan struct{}), started: make(chan string, 10)}
+	service := notificationuc.NewService(notificationrepo.NewGormRepository(db), dispatcher)
+	ctx := context.Background()
+
+	failing, err := service.Send(ctx, assembler.Command{Type: "email", Target: "bounce@example.com", Message: "hi"})
+	require.NoError(t, err)
+	slow, err := service.Send(ctx, assembler.Command{Type: "email", Target: "slow@example.com", Message: "hi"})
+	require.NoError(t, err)
+
+	pool := notificationworker.NewPool(service, 2, 10*time.Millisecond, zap.NewNop())
+	pool.Start()
+	require.Eventually(t, func() bool {
+		n, err := service.Get(ctx, failing.ID)
+		return err == nil && n.Status == domain.StatusFailed
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "slow@example.com", <-dispatcher.started)
+
+	// Stop waits for the in-flight delivery instead of abandoning it.
+	stopped := make(chan error, 1)
+	go func() { stopped <- pool.Stop(ctx) }()
+	select {
+	case <-stopped:
+		t.Fatal("stop returned before the in-flight delivery finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(dispatcher.release)
+	require.NoError(t, <-stopped)
+
+	n, err := service.Get(ctx, slow.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusSent, n.Status)
+	n, err = service.Get(ctx, failing.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, n.Attempts, "the retry waits for its backoff")
+}
+
+// blockingDispatcher fails for bounce@example.com and holds every other
+// delivery until release is closed.
+type blockingDispatcher struct {
+	release chan struct{}
+	started chan string
+}
+
+func (d *blockingDispatcher) Dispatch(ctx context.Context, target, message string) error {
+	if target == "bounce@example.com" {
+		return errors.New("mailbox unavailable")
+	}
+	d.started <- target
+	<-d.release
+	return nil
+}
@@ -11,31 +11,78 @@ import (
 	"github.com/google/uuid"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/payment"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
-// HTTPStatusClient notifies booking service of payments.
+// statusUpdateAttempts bounds how often a status update is sent before the
+// failure is reported to the caller.
+const statusUpdateAttempts = 3
+
+// HTTPStatusClient notifies booking service of payments through its
+// internal, service-only routes.
 type HTTPStatusClient struct {
 	baseURL string
+	tokens  *middleware.ServiceSigner
 	client  *http.Client
+	backoff time.Duration
 }
 
-func NewHTTPStatusClient(baseURL string) domain.BookingStatusUpdater {
-	return &HTTPStatusClient{baseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+func NewHTTPStatusClient(baseURL string, tokens *middleware.ServiceSigner) domain.BookingStatusUpdater {
+	return &HTTPStatusClient{
+		baseURL: baseURL,
+		tokens:  tokens,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		backoff: 200 * time.Millisecond,
+	}
 }
 
+// Update sends the booking status with a service token, retrying transport
+// errors and 5xx responses with a doubling backoff. Rejections by the
+// booking service are returned without retrying.
 func (c *HTTPStatusClient) Update(ctx context.Context, bookingID uuid.UUID, status string) error {
-	payload := map[string]string{"status": status}
-	body, _ := json.Marshal(payload)
-	url := fmt.Sprintf("%s/bookings/%s/status", c.baseURL, bookingID.String())
+	body, _ := json.Marshal(map[string]string{"status": status})
+	url := fmt.Sprintf("%s/internal/bookings/%s/status", c.baseURL, bookingID.String())
+
+	var err error
+	delay := c.backoff
+	for attempt := 1; attempt <= statusUpdateAttempts; attempt++ {
+		var retry bool
+		if retry, err = c.send(ctx, url, body); err == nil || !retry {
+			return err
+		}
+		if attempt == statusUpdateAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+// send performs one update and reports whether a failure is worth retrying.
+func (c *HTTPStatusClient) send(ctx context.Context, url string, body []byte) (bool, error) {
+	token, err := c.tokens.Token(time.Minute)
+	if err != nil {
+		return false, err
+	}
 	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return true, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("failed to update booking status: %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("failed to update booking status: %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return false, pkgErrors.New("bad_request", fmt.Sprintf("booking service rejected status update: %d", resp.StatusCode))
 	}
-	return nil
+	return false, nil
 }
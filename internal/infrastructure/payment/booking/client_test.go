@@ -4,30 +4,68 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
 )
 
 func TestHTTPGatewayUpdateSuccess(t *testing.T) {
+	bookingID := uuid.New()
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/internal/bookings/"+bookingID.String()+"/status", r.URL.Path)
+		claims := &middleware.Claims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims, func(*jwt.Token) (interface{}, error) {
+			return []byte("secret"), nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, middleware.RoleService, claims.Role)
+		require.Equal(t, "payment-service", claims.UserID)
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer srv.Close()
 
-	gw := NewHTTPStatusClient(srv.URL)
-	err := gw.Update(context.Background(), uuid.New(), "confirmed")
+	gw := NewHTTPStatusClient(srv.URL, middleware.NewSharedSecretSigner("payment-service", "secret"))
+	err := gw.Update(context.Background(), bookingID, "confirmed")
 	require.NoError(t, err)
 }
 
 func TestHTTPGatewayUpdateError(t *testing.T) {
+	calls := 0
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
 		http.Error(w, "fail", http.StatusBadRequest)
 	}))
 	defer srv.Close()
 
-	gw := NewHTTPStatusClient(srv.URL)
+	gw := NewHTTPStatusClient(srv.URL, middleware.NewSharedSecretSigner("payment-service", "secret"))
 	err := gw.Update(context.Background(), uuid.New(), "confirmed")
 	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestHTTPGatewayUpdateRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < statusUpdateAttempts {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	gw := &HTTPStatusClient{baseURL: srv.URL, tokens: middleware.NewSharedSecretSigner("payment-service", "secret"), client: srv.Client(), backoff: time.Millisecond}
+	require.NoError(t, gw.Update(context.Background(), uuid.New(), "confirmed"))
+	require.Equal(t, statusUpdateAttempts, calls)
+
+	calls = -10
+	require.Error(t, gw.Update(context.Background(), uuid.New(), "confirmed"))
+	require.Equal(t, -10+statusUpdateAttempts, calls)
 }
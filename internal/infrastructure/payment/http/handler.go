@@ -26,6 +26,15 @@ func (h *Handler) GetPayment(w http.ResponseWriter, r *http.Request)    { h.getP
 func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) { h.handleWebhook(w, r) }
 func (h *Handler) Refund(w http.ResponseWriter, r *http.Request)        { h.refund(w, r) }
 func (h *Handler) GetByBooking(w http.ResponseWriter, r *http.Request)  { h.getByBooking(w, r) }
+func (h *Handler) ExpireByBooking(w http.ResponseWriter, r *http.Request) {
+	h.expireByBooking(w, r)
+}
+func (h *Handler) RefundByBooking(w http.ResponseWriter, r *http.Request) {
+	h.refundByBooking(w, r)
+}
+func (h *Handler) VoidAdjustments(w http.ResponseWriter, r *http.Request) {
+	h.voidAdjustments(w, r)
+}
 
 type webhookResponse struct {
 	PaymentID string `json:"payment_id"`
@@ -42,6 +51,9 @@ func (h *Handler) Routes() http.Handler {
 	r.Post("/payments", h.createPayment)
 	r.Get("/payments/{id}", h.getPayment)
 	r.Get("/payments/by-booking/{booking_id}", h.getByBooking)
+	r.Post("/payments/by-booking/{booking_id}/expire", h.expireByBooking)
+	r.Post("/payments/by-booking/{booking_id}/refund", h.refundByBooking)
+	r.Post("/payments/by-booking/{booking_id}/adjustments/void", h.voidAdjustments)
 	r.Post("/payments/webhook", h.handleWebhook)
 	r.Post("/payments/refund", h.refund)
 	return r
@@ -52,6 +64,7 @@ func (h *Handler) Routes() http.Handler {
 // @Accept json
 // @Produce json
 // @Param request body dto.PaymentRequest true "Payment payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
 // @Success 201 {object} dto.PaymentResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Security BearerAuth
@@ -119,8 +132,10 @@ func (h *Handler) handleWebhook(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param request body dto.RefundRequest true "Refund payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
 // @Success 200 {object} dto.RefundResponse
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /payments/refund [post]
 func (h *Handler) refund(w http.ResponseWriter, r *http.Request) {
@@ -194,6 +209,100 @@ func (h *Handler) getByBooking(w http.ResponseWriter, r *http.Request) {
 	utils.Respond(w, http.StatusOK, "payment retrieved", resource)
 }
 
+// @Summary Expire pending payment for booking
+// @Tags Payments
+// @Produce json
+// @Param booking_id path string true "Booking ID"
+// @Success 200 {object} dto.PaymentResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /payments/by-booking/{booking_id}/expire [post]
+func (h *Handler) expireByBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "booking_id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid booking id"))
+		return
+	}
+	resp, err := h.service.ExpireByBooking(r.Context(), bookingID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	dtoResp := assembler.ToResponse(resp)
+	resource := utils.NewResource(dtoResp.ID, "payment", "/api/v1/payments/"+dtoResp.ID, dtoResp)
+	utils.Respond(w, http.StatusOK, "payment expired", resource)
+}
+
+// @Summary Refund booking payments
+// @Description Returns up to the requested amount of what the booking has paid, from the booking payment first and then from paid adjustments. The response carries the amount actually refunded.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param booking_id path string true "Booking ID"
+// @Param request body dto.BookingRefundRequest true "Refund payload"
+// @Param Idempotency-Key header string false "Key making retries of this request safe"
+// @Success 200 {object} dto.RefundResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /payments/by-booking/{booking_id}/refund [post]
+func (h *Handler) refundByBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "booking_id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid booking id"))
+		return
+	}
+	var req dto.BookingRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid payload"))
+		return
+	}
+	cmd, err := assembler.FromBookingRefundRequest(bookingID, req)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	res, err := h.service.RefundByBooking(r.Context(), cmd)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	dtoResp := assembler.ToRefundResponse(res)
+	resource := utils.NewResource(dtoResp.ID, "refund", "/api/v1/payments/refund/"+dtoResp.ID, dtoResp)
+	utils.Respond(w, http.StatusOK, "refund created", resource)
+}
+
+// @Summary Void pending adjustments for booking
+// @Description Fails the adjustment payments of a booking that are still unpaid, e.g. when it is cancelled. Returns the voided payments.
+// @Tags Payments
+// @Produce json
+// @Param booking_id path string true "Booking ID"
+// @Success 200 {array} dto.PaymentResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /payments/by-booking/{booking_id}/adjustments/void [post]
+func (h *Handler) voidAdjustments(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := uuid.Parse(chi.URLParam(r, "booking_id"))
+	if err != nil {
+		writeError(w, pkgErrors.New("bad_request", "invalid booking id"))
+		return
+	}
+	voided, err := h.service.VoidAdjustments(r.Context(), bookingID)
+	if err != nil {
+		writeError(w, pkgErrors.FromError(err))
+		return
+	}
+	resources := make([]utils.Resource, 0, len(voided))
+	for _, p := range voided {
+		dtoResp := assembler.ToResponse(p)
+		resources = append(resources, utils.NewResource(dtoResp.ID, "payment", "/api/v1/payments/"+dtoResp.ID, dtoResp))
+	}
+	utils.RespondWithCount(w, http.StatusOK, "adjustments voided", resources, len(resources))
+}
+
 func writeJSON(w http.ResponseWriter, status int, body any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
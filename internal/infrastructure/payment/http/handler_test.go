@@ -82,6 +82,21 @@ func (p *paymentRepoStub) UpdateStatus(ctx context.Context, id uuid.UUID, status
 	p.store[id] = pay
 	return nil
 }
+func (p *paymentRepoStub) ListByBooking(ctx context.Context, bookingID uuid.UUID) ([]domain.Payment, error) {
+	var out []domain.Payment
+	for _, v := range p.store {
+		if v.BookingID == bookingID {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (p *paymentRepoStub) TransitionStatus(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	return false, nil
+}
+func (p *paymentRepoStub) AddRefunded(ctx context.Context, id uuid.UUID, amount float64) (bool, error) {
+	return false, nil
+}
 
 type providerStub struct{}
 
@@ -73,6 +73,15 @@ func (p *paymentRepoStub2) UpdateStatus(_ context.Context, id uuid.UUID, status,
 	p.store[id] = pay
 	return nil
 }
+func (p *paymentRepoStub2) ListByBooking(context.Context, uuid.UUID) ([]domain.Payment, error) {
+	return nil, nil
+}
+func (p *paymentRepoStub2) TransitionStatus(context.Context, uuid.UUID, string, string) (bool, error) {
+	return false, nil
+}
+func (p *paymentRepoStub2) AddRefunded(context.Context, uuid.UUID, float64) (bool, error) {
+	return false, nil
+}
 
 type providerStub2 struct{}
 
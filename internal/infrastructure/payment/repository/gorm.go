@@ -42,12 +42,46 @@ func (r *GormRepository) FindByID(ctx context.Context, id uuid.UUID) (domain.Pay
 
 func (r *GormRepository) FindByBookingID(ctx context.Context, bookingID uuid.UUID) (domain.Payment, error) {
 	var model paymentModel
-	if err := r.db.WithContext(ctx).First(&model, "booking_id = ?", bookingID).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&model, "booking_id = ? AND kind = ?", bookingID, domain.KindBooking).Error; err != nil {
 		return domain.Payment{}, translateErr(err)
 	}
 	return toDomain(model), nil
 }
 
+func (r *GormRepository) ListByBooking(ctx context.Context, bookingID uuid.UUID) ([]domain.Payment, error) {
+	var models []paymentModel
+	if err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	payments := make([]domain.Payment, 0, len(models))
+	for _, m := range models {
+		payments = append(payments, toDomain(m))
+	}
+	return payments, nil
+}
+
+func (r *GormRepository) TransitionStatus(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&paymentModel{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// AddRefunded applies the bounds check in the UPDATE itself so concurrent
+// refunds of the same payment cannot together exceed its amount.
+func (r *GormRepository) AddRefunded(ctx context.Context, id uuid.UUID, amount float64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&paymentModel{}).
+		Where("id = ? AND status = ? AND refunded + ? <= amount AND refunded + ? >= 0", id, domain.StatusPaid, amount, amount).
+		Update("refunded", gorm.Expr("refunded + ?", amount))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
 func (r *GormRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentURL, rawPayload, signature string) error {
 	updates := map[string]any{"status": status}
 	if paymentURL != "" {
@@ -71,10 +105,12 @@ func (r *GormRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status,
 
 type paymentModel struct {
 	ID               uuid.UUID `gorm:"type:uuid;primaryKey"`
-	BookingID        uuid.UUID `gorm:"type:uuid;uniqueIndex"`
+	BookingID        uuid.UUID `gorm:"type:uuid;index"`
 	Amount           float64   `gorm:"type:numeric"`
 	Currency         string
-	Status           string `gorm:"index"`
+	Status           string  `gorm:"index"`
+	Kind             string  `gorm:"default:booking"`
+	Refunded         float64 `gorm:"type:numeric;not null;default:0"`
 	Provider         string
 	PaymentURL       string
 	WebhookPayload   string `gorm:"type:text"`
@@ -85,12 +121,18 @@ type paymentModel struct {
 func (paymentModel) TableName() string { return "payments" }
 
 func toModel(p domain.Payment) paymentModel {
+	kind := p.Kind
+	if kind == "" {
+		kind = domain.KindBooking
+	}
 	return paymentModel{
 		ID:               p.ID,
 		BookingID:        p.BookingID,
 		Amount:           p.Amount,
 		Currency:         p.Currency,
 		Status:           p.Status,
+		Kind:             kind,
+		Refunded:         p.Refunded,
 		Provider:         p.Provider,
 		PaymentURL:       p.PaymentURL,
 		WebhookPayload:   p.WebhookPayload,
@@ -106,6 +148,8 @@ func toDomain(m paymentModel) domain.Payment {
 		Amount:           m.Amount,
 		Currency:         m.Currency,
 		Status:           m.Status,
+		Kind:             m.Kind,
+		Refunded:         m.Refunded,
 		Provider:         m.Provider,
 		PaymentURL:       m.PaymentURL,
 		WebhookPayload:   m.WebhookPayload,
@@ -38,6 +38,45 @@ func TestPaymentGormRepository(t *testing.T) {
 	require.Equal(t, "paid", updated.Status)
 	require.Equal(t, "payload", updated.WebhookPayload)
 	require.Equal(t, "sig", updated.WebhookSignature)
+
+	ok, err := r.AddRefunded(context.Background(), p.ID, 60)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = r.AddRefunded(context.Background(), p.ID, 50)
+	require.NoError(t, err)
+	require.False(t, ok)
+	refunded, err := r.FindByID(context.Background(), p.ID)
+	require.NoError(t, err)
+	require.Equal(t, 60.0, refunded.Refunded)
+}
+
+func TestPaymentGormRepositoryAdjustments(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, repo.AutoMigrate(db))
+	r := repo.NewGormRepository(db)
+	ctx := context.Background()
+
+	bookingID := uuid.New()
+	lead := payment.Payment{ID: uuid.New(), BookingID: bookingID, Amount: 100, Status: "paid", Kind: payment.KindBooking}
+	adjustment := payment.Payment{ID: uuid.New(), BookingID: bookingID, Amount: 40, Status: "pending", Kind: payment.KindAdjustment}
+	require.NoError(t, r.Create(ctx, lead))
+	require.NoError(t, r.Create(ctx, adjustment))
+
+	payments, err := r.ListByBooking(ctx, bookingID)
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+
+	// unpaid payments cannot be refunded
+	ok, err := r.AddRefunded(ctx, adjustment.ID, 10)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = r.TransitionStatus(ctx, adjustment.ID, "pending", "failed")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = r.TransitionStatus(ctx, adjustment.ID, "pending", "failed")
+	require.NoError(t, err)
+	require.False(t, ok)
 }
 
 func newTestDB(t *testing.T) *gorm.DB {
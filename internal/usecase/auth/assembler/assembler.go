@@ -1,15 +1,86 @@
 package assembler
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 )
 
+// LogoutCommand identifies the access token being revoked and the user it
+// was issued to, plus an optional refresh token to revoke with it.
+type LogoutCommand struct {
+	UserID       uuid.UUID
+	TokenID      uuid.UUID
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
 // ToProfile maps domain user to profile DTO.
 func ToProfile(u domain.User) dto.ProfileResponse {
 	return dto.ProfileResponse{
-		ID:    u.ID.String(),
-		Email: u.Email,
-		Role:  u.Role,
+		ID:            u.ID.String(),
+		Email:         u.Email,
+		Role:          u.Role,
+		Name:          u.Profile.Name,
+		Phone:         u.Profile.Phone,
+		Locale:        u.Profile.Locale,
+		Preferences:   u.Profile.Preferences,
+		EmailVerified: u.EmailVerified(),
+		LockedUntil:   u.Attempts.LockedUntil,
+		MFAEnabled:    u.MFA.Enabled(),
+		ErasedAt:      u.ErasedAt,
+	}
+}
+
+// ToDataExport combines the user's own data with what other services hold.
+func ToDataExport(u domain.User, grants []domain.HotelRole, data domain.PersonalData, at time.Time) dto.DataExportResponse {
+	return dto.DataExportResponse{
+		Profile:       ToProfile(u),
+		HotelRoles:    ToHotelRoles(grants),
+		Bookings:      nonNil(data.Bookings),
+		Payments:      nonNil(data.Payments),
+		Notifications: nonNil(data.Notifications),
+		ExportedAt:    at,
+	}
+}
+
+// nonNil makes empty record lists encode as [] rather than null.
+func nonNil(records []json.RawMessage) []json.RawMessage {
+	if records == nil {
+		return []json.RawMessage{}
+	}
+	return records
+}
+
+// ToRevocationList maps revoked tokens to DTO.
+func ToRevocationList(tokens []domain.RevokedToken) dto.RevocationListResponse {
+	resp := dto.RevocationListResponse{Tokens: make([]dto.RevokedTokenResponse, 0, len(tokens))}
+	for _, t := range tokens {
+		resp.Tokens = append(resp.Tokens, dto.RevokedTokenResponse{ID: t.ID.String(), ExpiresAt: t.ExpiresAt})
+	}
+	return resp
+}
+
+// ToHotelRole maps a staff role grant to DTO.
+func ToHotelRole(g domain.HotelRole) dto.HotelRoleResponse {
+	return dto.HotelRoleResponse{
+		UserID:    g.UserID.String(),
+		HotelID:   g.HotelID.String(),
+		Role:      string(g.Role),
+		GrantedBy: g.GrantedBy.String(),
+		CreatedAt: g.CreatedAt,
+	}
+}
+
+// ToHotelRoles maps staff role grants to DTOs.
+func ToHotelRoles(grants []domain.HotelRole) []dto.HotelRoleResponse {
+	resp := make([]dto.HotelRoleResponse, 0, len(grants))
+	for _, g := range grants {
+		resp = append(resp, ToHotelRole(g))
 	}
+	return resp
 }
@@ -2,34 +2,39 @@ package auth
 
 import (
 	"context"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/auth/assembler"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/logger"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // Service coordinates registration/login use cases.
 type Service struct {
-	repo   domain.UserRepository
-	issuer domain.TokenIssuer
+	repo     domain.Repository
+	issuer   domain.TokenIssuer
+	notifier domain.AccountNotifier
+	personal domain.PersonalDataSource
+	lockout  domain.LockoutPolicy
+	ips      *ipThrottle
 }
 
-func NewService(repo domain.UserRepository, issuer domain.TokenIssuer) *Service {
-	return &Service{repo: repo, issuer: issuer}
+func NewService(repo domain.Repository, issuer domain.TokenIssuer, notifier domain.AccountNotifier, personal domain.PersonalDataSource, lockout domain.LockoutPolicy) *Service {
+	return &Service{repo: repo, issuer: issuer, notifier: notifier, personal: personal, lockout: lockout, ips: newIPThrottle(lockout)}
 }
 
-var allowedRoles = map[string]struct{}{
-	"customer": {},
-	"admin":    {},
-}
-
-// Register creates new user and issues tokens.
+// Register creates new user and issues tokens. Self-registration only yields
+// customers; staff and admin rights are granted by an admin.
 func (s *Service) Register(ctx context.Context, req dto.RegisterRequest) (dto.AuthResponse, error) {
 	email, err := valueobject.NormalizeEmail(req.Email)
 	if err != nil {
@@ -42,6 +47,9 @@ func (s *Service) Register(ctx context.Context, req dto.RegisterRequest) (dto.Au
 	if err != nil {
 		return dto.AuthResponse{}, err
 	}
+	if role != valueobject.RoleCustomer {
+		return dto.AuthResponse{}, errors.New("forbidden", "only customers can self-register")
+	}
 
 	if _, err := s.repo.FindByEmail(ctx, email); err == nil {
 		return dto.AuthResponse{}, errors.New("conflict", "email already used")
@@ -63,28 +71,373 @@ func (s *Service) Register(ctx context.Context, req dto.RegisterRequest) (dto.Au
 	if err := s.repo.Create(ctx, user); err != nil {
 		return dto.AuthResponse{}, err
 	}
+	// A failed delivery does not undo the registration; the user can ask
+	// for another link once logged in.
+	_ = s.sendVerification(ctx, user)
 
 	return s.issueTokens(ctx, user)
 }
 
-// Login verifies credentials.
-func (s *Service) Login(ctx context.Context, req dto.LoginRequest) (dto.AuthResponse, error) {
+// Login verifies credentials. Failures from the client address and against
+// the account are counted: each failure delays the account's next attempt
+// further, enough of them lock the account, and an address failing across
+// accounts is blocked. Throttled attempts are refused before the password is
+// checked.
+func (s *Service) Login(ctx context.Context, req dto.LoginRequest, clientIP string) (dto.AuthResponse, error) {
+	invalid := errors.New("unauthorized", "invalid credentials")
+	now := time.Now().UTC()
+	if wait := s.ips.retryAfter(clientIP, now); wait > 0 {
+		return dto.AuthResponse{}, tooManyAttempts("too many failed logins from this address", wait)
+	}
 	email, err := valueobject.NormalizeEmail(req.Email)
 	if err != nil {
-		return dto.AuthResponse{}, errors.New("unauthorized", "invalid credentials")
+		s.ips.fail(clientIP, now)
+		return dto.AuthResponse{}, invalid
 	}
 	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return dto.AuthResponse{}, errors.New("unauthorized", "invalid credentials")
+		s.ips.fail(clientIP, now)
+		return dto.AuthResponse{}, invalid
+	}
+	if user.Attempts.Locked(now) {
+		return dto.AuthResponse{}, tooManyAttempts("account temporarily locked", user.Attempts.RetryAfter(s.lockout, now))
+	}
+	if wait := user.Attempts.RetryAfter(s.lockout, now); wait > 0 {
+		return dto.AuthResponse{}, tooManyAttempts("too many failed logins; retry later", wait)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return dto.AuthResponse{}, errors.New("unauthorized", "invalid credentials")
+		s.ips.fail(clientIP, now)
+		if err := s.recordLoginFailure(ctx, user, clientIP, now); err != nil {
+			return dto.AuthResponse{}, err
+		}
+		return dto.AuthResponse{}, invalid
 	}
 
+	if user.MFA.Enabled() {
+		// Failures are only cleared once the second factor passes too, so
+		// knowing the password does not reset the count of code guesses.
+		challenge, err := s.issuer.IssueMFAChallenge(ctx, user)
+		if err != nil {
+			return dto.AuthResponse{}, err
+		}
+		return dto.AuthResponse{
+			ID:          user.ID.String(),
+			Email:       user.Email,
+			Role:        user.Role,
+			MFARequired: true,
+			MFAToken:    challenge,
+		}, nil
+	}
+	if err := s.clearLoginFailures(ctx, user); err != nil {
+		return dto.AuthResponse{}, err
+	}
 	return s.issueTokens(ctx, user)
 }
 
+// CompleteMFALogin exchanges the challenge Login returned and a TOTP or
+// recovery code for tokens. Wrong codes count as failed logins.
+func (s *Service) CompleteMFALogin(ctx context.Context, req dto.MFAChallengeRequest, clientIP string) (dto.AuthResponse, error) {
+	now := time.Now().UTC()
+	if wait := s.ips.retryAfter(clientIP, now); wait > 0 {
+		return dto.AuthResponse{}, tooManyAttempts("too many failed logins from this address", wait)
+	}
+	id, err := s.issuer.ParseMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		return dto.AuthResponse{}, errors.New("unauthorized", "invalid mfa token")
+	}
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil || !user.MFA.Enabled() {
+		return dto.AuthResponse{}, errors.New("unauthorized", "invalid mfa token")
+	}
+	if user.Attempts.Locked(now) {
+		return dto.AuthResponse{}, tooManyAttempts("account temporarily locked", user.Attempts.RetryAfter(s.lockout, now))
+	}
+	if wait := user.Attempts.RetryAfter(s.lockout, now); wait > 0 {
+		return dto.AuthResponse{}, tooManyAttempts("too many failed logins; retry later", wait)
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user, req.Code, now)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+	if !ok {
+		s.ips.fail(clientIP, now)
+		if err := s.recordLoginFailure(ctx, user, clientIP, now); err != nil {
+			return dto.AuthResponse{}, err
+		}
+		return dto.AuthResponse{}, errors.New("unauthorized", "invalid verification code")
+	}
+	if err := s.clearLoginFailures(ctx, user); err != nil {
+		return dto.AuthResponse{}, err
+	}
+	return s.issueSession(ctx, user, uuid.Nil, true)
+}
+
+// SetupMFA starts TOTP enrollment with a new secret. It takes effect once
+// VerifyMFA confirms the authenticator produces matching codes.
+func (s *Service) SetupMFA(ctx context.Context, id uuid.UUID) (dto.MFASetupResponse, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return dto.MFASetupResponse{}, errors.New("not_found", "user not found")
+	}
+	if user.MFA.Enabled() {
+		return dto.MFASetupResponse{}, errors.New("conflict", "mfa already enabled")
+	}
+	secret, err := domain.NewTOTPSecret()
+	if err != nil {
+		return dto.MFASetupResponse{}, err
+	}
+	if err := s.repo.SetMFASecret(ctx, id, secret); err != nil {
+		return dto.MFASetupResponse{}, err
+	}
+	return dto.MFASetupResponse{Secret: secret, OTPAuthURI: domain.TOTPURI(user.Email, secret)}, nil
+}
+
+// VerifyMFA enables the pending enrollment when code matches its secret and
+// returns recovery codes, which are not retrievable afterwards. Tokens
+// issued before enrollment do not carry the second factor; the user signs
+// in again to get ones that do.
+func (s *Service) VerifyMFA(ctx context.Context, id uuid.UUID, req dto.MFAVerifyRequest) (dto.MFAVerifyResponse, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return dto.MFAVerifyResponse{}, errors.New("not_found", "user not found")
+	}
+	if user.MFA.Enabled() {
+		return dto.MFAVerifyResponse{}, errors.New("conflict", "mfa already enabled")
+	}
+	if user.MFA.Secret == "" {
+		return dto.MFAVerifyResponse{}, errors.New("bad_request", "mfa setup not started")
+	}
+	now := time.Now().UTC()
+	step, ok := domain.MatchTOTP(user.MFA.Secret, req.Code, now)
+	if !ok {
+		return dto.MFAVerifyResponse{}, errors.New("bad_request", "invalid verification code")
+	}
+	codes, plain, err := domain.NewRecoveryCodes(id, now)
+	if err != nil {
+		return dto.MFAVerifyResponse{}, err
+	}
+	if err := s.repo.EnableMFA(ctx, id, now, step, codes); err != nil {
+		return dto.MFAVerifyResponse{}, err
+	}
+	return dto.MFAVerifyResponse{RecoveryCodes: plain}, nil
+}
+
+// Unlock lifts a lockout and clears the failed-login count of a user.
+func (s *Service) Unlock(ctx context.Context, id, adminID uuid.UUID) (domain.User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return domain.User{}, errors.New("not_found", "user not found")
+	}
+	if err := s.repo.ClearLoginFailures(ctx, id); err != nil {
+		return domain.User{}, err
+	}
+	if err := s.repo.RecordEvent(ctx, domain.NewUserUnlocked(id, adminID)); err != nil {
+		return domain.User{}, err
+	}
+	user.Attempts = domain.LoginAttempts{}
+	return user, nil
+}
+
+// HotelRoles lists the staff roles granted to a user.
+func (s *Service) HotelRoles(ctx context.Context, id uuid.UUID) ([]domain.HotelRole, error) {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, errors.New("not_found", "user not found")
+	}
+	return s.repo.ListHotelRoles(ctx, id)
+}
+
+// AssignHotelRole grants a user a staff role at a hotel. The grant reaches
+// the user's access tokens when they next log in or refresh.
+func (s *Service) AssignHotelRole(ctx context.Context, id, adminID uuid.UUID, req dto.HotelRoleRequest) (domain.HotelRole, error) {
+	hotelID, err := uuid.Parse(req.HotelID)
+	if err != nil || hotelID == uuid.Nil {
+		return domain.HotelRole{}, errors.New("bad_request", "invalid hotel id")
+	}
+	role, err := valueobject.ParseHotelRole(req.Role)
+	if err != nil {
+		return domain.HotelRole{}, err
+	}
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return domain.HotelRole{}, errors.New("not_found", "user not found")
+	}
+	grant := domain.HotelRole{UserID: id, HotelID: hotelID, Role: role, GrantedBy: adminID, CreatedAt: time.Now().UTC()}
+	if err := s.repo.AssignHotelRole(ctx, grant); err != nil {
+		return domain.HotelRole{}, err
+	}
+	if err := s.repo.RecordEvent(ctx, domain.NewHotelRoleAssigned(grant)); err != nil {
+		return domain.HotelRole{}, err
+	}
+	return grant, nil
+}
+
+// RemoveHotelRole revokes a staff role grant. Access tokens already issued
+// keep it until they expire.
+func (s *Service) RemoveHotelRole(ctx context.Context, id, hotelID uuid.UUID, rawRole string, adminID uuid.UUID) error {
+	role, err := valueobject.ParseHotelRole(rawRole)
+	if err != nil {
+		return err
+	}
+	removed, err := s.repo.RemoveHotelRole(ctx, id, hotelID, role)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("not_found", "hotel role not found")
+	}
+	return s.repo.RecordEvent(ctx, domain.NewHotelRoleRemoved(id, hotelID, role, adminID))
+}
+
+// Refresh exchanges a refresh token for a new access/refresh pair. Each
+// refresh token works once: it is rotated for a new token of the same
+// family, and presenting a rotated token again revokes the whole family
+// since either the client or an attacker holds a stolen copy.
+func (s *Service) Refresh(ctx context.Context, req dto.RefreshRequest) (dto.AuthResponse, error) {
+	invalid := errors.New("unauthorized", "invalid refresh token")
+	id, err := s.issuer.ParseRefresh(ctx, req.RefreshToken)
+	if err != nil {
+		return dto.AuthResponse{}, invalid
+	}
+	session, err := s.repo.FindRefreshToken(ctx, id)
+	if err != nil {
+		return dto.AuthResponse{}, invalid
+	}
+
+	now := time.Now().UTC()
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return dto.AuthResponse{}, invalid
+	}
+	rotated, err := s.repo.UseRefreshToken(ctx, session.ID, now)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+	if !rotated {
+		if err := s.repo.RevokeFamily(ctx, session.FamilyID, now); err != nil {
+			return dto.AuthResponse{}, err
+		}
+		return dto.AuthResponse{}, errors.New("unauthorized", "refresh token reuse detected")
+	}
+
+	user, err := s.repo.FindByID(ctx, session.UserID)
+	if err != nil {
+		return dto.AuthResponse{}, invalid
+	}
+	return s.issueSession(ctx, user, session.FamilyID, session.MFA)
+}
+
+// Logout revokes the caller's access token until it expires and, when given,
+// every refresh token of the same login.
+func (s *Service) Logout(ctx context.Context, cmd assembler.LogoutCommand) error {
+	if cmd.TokenID == uuid.Nil {
+		return errors.New("bad_request", "token cannot be revoked")
+	}
+	now := time.Now().UTC()
+	if cmd.RefreshToken != "" {
+		invalid := errors.New("unauthorized", "invalid refresh token")
+		id, err := s.issuer.ParseRefresh(ctx, cmd.RefreshToken)
+		if err != nil {
+			return invalid
+		}
+		session, err := s.repo.FindRefreshToken(ctx, id)
+		if err != nil || session.UserID != cmd.UserID {
+			return invalid
+		}
+		if err := s.repo.RevokeFamily(ctx, session.FamilyID, now); err != nil {
+			return err
+		}
+	}
+	return s.repo.RevokeToken(ctx, domain.RevokedToken{ID: cmd.TokenID, ExpiresAt: cmd.ExpiresAt, RevokedAt: now})
+}
+
+// Revocations lists access tokens that are revoked and not yet expired.
+func (s *Service) Revocations(ctx context.Context) ([]domain.RevokedToken, error) {
+	return s.repo.ListRevoked(ctx, time.Now().UTC())
+}
+
+// ForgotPassword mails a password reset link when an account exists for
+// the email. Every well-formed address gets the same answer, so the endpoint
+// cannot be used to discover accounts; failures to issue or deliver the link
+// are only logged.
+func (s *Service) ForgotPassword(ctx context.Context, req dto.ForgotPasswordRequest) error {
+	email, err := valueobject.NormalizeEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	if err := s.sendPasswordReset(ctx, user); err != nil {
+		logger.WithContext(ctx).Error("failed to send password reset email",
+			zap.String("user_id", user.ID.String()), zap.Error(err))
+	}
+	return nil
+}
+
+// sendPasswordReset records a password reset token for user and mails it.
+func (s *Service) sendPasswordReset(ctx context.Context, user domain.User) error {
+	token, secret, err := domain.NewOneTimeToken(user.ID, domain.PurposePasswordReset, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateOneTimeToken(ctx, token); err != nil {
+		return err
+	}
+	return s.notifier.SendPasswordReset(ctx, user.Email, secret)
+}
+
+// ResetPassword sets a new password with a reset token and signs the user
+// out of every session. Receiving the link proves the user owns the email
+// address, so the address counts as verified too.
+func (s *Service) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	if req.Password == "" {
+		return errors.New("bad_request", "password required")
+	}
+	now := time.Now().UTC()
+	token, err := s.consume(ctx, domain.PurposePasswordReset, req.Token, now)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, token.UserID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUserRefreshTokens(ctx, token.UserID, now); err != nil {
+		return err
+	}
+	return s.repo.MarkEmailVerified(ctx, token.UserID, now)
+}
+
+// VerifyEmail marks the email address of the token's user verified. Access
+// tokens issued afterwards, including on refresh, carry the verified claim.
+func (s *Service) VerifyEmail(ctx context.Context, req dto.VerifyEmailRequest) error {
+	now := time.Now().UTC()
+	token, err := s.consume(ctx, domain.PurposeEmailVerification, req.Token, now)
+	if err != nil {
+		return err
+	}
+	return s.repo.MarkEmailVerified(ctx, token.UserID, now)
+}
+
+// ResendVerification mails a new verification link to an unverified user.
+func (s *Service) ResendVerification(ctx context.Context, id uuid.UUID) error {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return errors.New("not_found", "user not found")
+	}
+	if user.EmailVerified() {
+		return errors.New("conflict", "email already verified")
+	}
+	if err := s.sendVerification(ctx, user); err != nil {
+		return errors.New("bad_gateway", "failed to send verification email")
+	}
+	return nil
+}
+
 // Me returns profile info.
 func (s *Service) Me(ctx context.Context, id uuid.UUID) (domain.User, error) {
 	user, err := s.repo.FindByID(ctx, id)
@@ -94,6 +447,110 @@ func (s *Service) Me(ctx context.Context, id uuid.UUID) (domain.User, error) {
 	return user, nil
 }
 
+// UpdateProfile changes the profile fields present in req.
+func (s *Service) UpdateProfile(ctx context.Context, id uuid.UUID, req dto.UpdateProfileRequest) (domain.User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return domain.User{}, errors.New("not_found", "user not found")
+	}
+	profile := user.Profile
+	if req.Name != nil {
+		profile.Name = strings.TrimSpace(*req.Name)
+		if utf8.RuneCountInString(profile.Name) > domain.MaxNameLength {
+			return domain.User{}, errors.New("bad_request", "name too long")
+		}
+	}
+	if req.Phone != nil {
+		if profile.Phone, err = valueobject.NormalizePhone(*req.Phone); err != nil {
+			return domain.User{}, err
+		}
+	}
+	if req.Locale != nil {
+		if profile.Locale, err = valueobject.ParseLocale(*req.Locale); err != nil {
+			return domain.User{}, err
+		}
+	}
+	if req.Preferences != nil {
+		if err := checkPreferences(req.Preferences); err != nil {
+			return domain.User{}, err
+		}
+		profile.Preferences = req.Preferences
+	}
+	if err := s.repo.UpdateProfile(ctx, id, profile); err != nil {
+		return domain.User{}, err
+	}
+	user.Profile = profile
+	return user, nil
+}
+
+// ChangePassword replaces the password after confirming the current one and
+// signs the user out of every session.
+func (s *Service) ChangePassword(ctx context.Context, id uuid.UUID, req dto.ChangePasswordRequest) error {
+	if req.NewPassword == "" {
+		return errors.New("bad_request", "password required")
+	}
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return errors.New("not_found", "user not found")
+	}
+	now := time.Now().UTC()
+	if err := s.confirmPassword(ctx, user, req.CurrentPassword, now); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, id, string(hash)); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUserRefreshTokens(ctx, id, now); err != nil {
+		return err
+	}
+	return s.repo.RecordEvent(ctx, domain.NewPasswordChanged(id))
+}
+
+// Export collects everything the platform holds about a user: the account
+// and staff roles here, and bookings, payments and notifications from the
+// services that own them.
+func (s *Service) Export(ctx context.Context, id uuid.UUID) (dto.DataExportResponse, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return dto.DataExportResponse{}, errors.New("not_found", "user not found")
+	}
+	grants, err := s.repo.ListHotelRoles(ctx, id)
+	if err != nil {
+		return dto.DataExportResponse{}, err
+	}
+	data, err := s.personal.Collect(ctx, id, user.Email)
+	if err != nil {
+		return dto.DataExportResponse{}, errors.New("bad_gateway", "failed to collect data from other services")
+	}
+	return assembler.ToDataExport(user, grants, data, time.Now().UTC()), nil
+}
+
+// Erase anonymizes the account after confirming the password. Personal data
+// held by other services is erased first, so a failure there leaves the
+// account intact and the user can retry. Bookings and payments are kept as
+// financial records.
+func (s *Service) Erase(ctx context.Context, id uuid.UUID, req dto.EraseAccountRequest) error {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil || user.ErasedAt != nil {
+		return errors.New("not_found", "user not found")
+	}
+	now := time.Now().UTC()
+	if err := s.confirmPassword(ctx, user, req.Password, now); err != nil {
+		return err
+	}
+	if err := s.personal.Erase(ctx, id, user.Email); err != nil {
+		return errors.New("bad_gateway", "failed to erase data held by other services; try again")
+	}
+	if err := s.repo.EraseUser(ctx, id, now); err != nil {
+		return err
+	}
+	return s.repo.RecordEvent(ctx, domain.NewUserErased(id))
+}
+
 // List returns all users (admin use).
 func (s *Service) List(ctx context.Context, opts query.Options) ([]domain.User, error) {
 	users, err := s.repo.List(ctx, opts.Normalize(50))
@@ -112,8 +569,24 @@ func (s *Service) Get(ctx context.Context, id uuid.UUID) (domain.User, error) {
 	return user, nil
 }
 
+// issueTokens starts a new refresh token family for a password-only login.
 func (s *Service) issueTokens(ctx context.Context, user domain.User) (dto.AuthResponse, error) {
-	access, refresh, err := s.issuer.Generate(ctx, user)
+	return s.issueSession(ctx, user, uuid.Nil, false)
+}
+
+// issueSession records a refresh token of family and issues it with an
+// access token carrying the user's current staff roles.
+func (s *Service) issueSession(ctx context.Context, user domain.User, family uuid.UUID, mfa bool) (dto.AuthResponse, error) {
+	grants, err := s.repo.ListHotelRoles(ctx, user.ID)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+	user.HotelRoles = grants
+	session := domain.NewRefreshToken(user.ID, family, mfa, time.Now().UTC())
+	if err := s.repo.CreateRefreshToken(ctx, session); err != nil {
+		return dto.AuthResponse{}, err
+	}
+	access, refresh, err := s.issuer.Generate(ctx, user, session)
 	if err != nil {
 		return dto.AuthResponse{}, err
 	}
@@ -125,3 +598,94 @@ func (s *Service) issueTokens(ctx context.Context, user domain.User) (dto.AuthRe
 		RefreshToken: refresh,
 	}, nil
 }
+
+// sendVerification records a verification token for user and mails it.
+func (s *Service) sendVerification(ctx context.Context, user domain.User) error {
+	token, secret, err := domain.NewOneTimeToken(user.ID, domain.PurposeEmailVerification, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateOneTimeToken(ctx, token); err != nil {
+		return err
+	}
+	return s.notifier.SendEmailVerification(ctx, user.Email, secret)
+}
+
+// consume redeems the one-time token secret for purpose.
+func (s *Service) consume(ctx context.Context, purpose domain.TokenPurpose, secret string, now time.Time) (domain.OneTimeToken, error) {
+	if secret == "" {
+		return domain.OneTimeToken{}, errors.New("bad_request", "token required")
+	}
+	token, err := s.repo.ConsumeOneTimeToken(ctx, purpose, domain.HashOneTimeSecret(secret), now)
+	if err != nil {
+		return domain.OneTimeToken{}, errors.New("bad_request", "invalid or expired token")
+	}
+	return token, nil
+}
+
+// recordLoginFailure counts a failed password for user and locks the account
+// once the policy's limit is reached.
+func (s *Service) recordLoginFailure(ctx context.Context, user domain.User, clientIP string, now time.Time) error {
+	failures, err := s.repo.RecordLoginFailure(ctx, user.ID, now)
+	if err != nil {
+		return err
+	}
+	if s.lockout.MaxAttempts <= 0 || failures < s.lockout.MaxAttempts {
+		return nil
+	}
+	until := now.Add(s.lockout.LockoutDuration)
+	if err := s.repo.LockUser(ctx, user.ID, until); err != nil {
+		return err
+	}
+	return s.repo.RecordEvent(ctx, domain.NewUserLocked(user, failures, until, clientIP))
+}
+
+// confirmPassword re-checks the password of a signed-in user before a
+// sensitive change. Wrong guesses count as failed logins, so a stolen access
+// token cannot be used to brute-force the password.
+func (s *Service) confirmPassword(ctx context.Context, user domain.User, password string, now time.Time) error {
+	if user.Attempts.Locked(now) {
+		return tooManyAttempts("account temporarily locked", user.Attempts.RetryAfter(s.lockout, now))
+	}
+	if wait := user.Attempts.RetryAfter(s.lockout, now); wait > 0 {
+		return tooManyAttempts("too many failed attempts; retry later", wait)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err := s.recordLoginFailure(ctx, user, "", now); err != nil {
+			return err
+		}
+		return errors.New("forbidden", "incorrect password")
+	}
+	return s.clearLoginFailures(ctx, user)
+}
+
+func checkPreferences(prefs map[string]string) error {
+	if len(prefs) > domain.MaxPreferences {
+		return errors.New("bad_request", "too many preferences")
+	}
+	for key, value := range prefs {
+		if key == "" || len(key) > domain.MaxPreferenceKey || len(value) > domain.MaxPreferenceValue {
+			return errors.New("bad_request", "invalid preference "+key)
+		}
+	}
+	return nil
+}
+
+func (s *Service) clearLoginFailures(ctx context.Context, user domain.User) error {
+	if user.Attempts.Failures == 0 && user.Attempts.LockedUntil == nil {
+		return nil
+	}
+	return s.repo.ClearLoginFailures(ctx, user.ID)
+}
+
+// checkSecondFactor accepts a TOTP code of a step not used before, or an
+// unused recovery code.
+func (s *Service) checkSecondFactor(ctx context.Context, user domain.User, code string, now time.Time) (bool, error) {
+	if step, ok := domain.MatchTOTP(user.MFA.Secret, code, now); ok {
+		return s.repo.UseTOTPStep(ctx, user.ID, step)
+	}
+	if code == "" {
+		return false, nil
+	}
+	return s.repo.UseRecoveryCode(ctx, user.ID, domain.HashRecoveryCode(code), now)
+}
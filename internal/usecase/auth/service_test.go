@@ -2,7 +2,9 @@ package auth_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,24 +13,35 @@ import (
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/auth"
+	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/auth/assembler"
+	pkgDomain "github.com/ftryyln/hotel-booking-microservices/pkg/domain"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestRegisterAndLogin(t *testing.T) {
 	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
 	issuer := &issuerStub{}
-	svc := auth.NewService(repo, issuer)
+	svc := auth.NewService(repo, issuer, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
 
 	// register
-	resp, err := svc.Register(context.Background(), dto.RegisterRequest{
+	_, err := svc.Register(context.Background(), dto.RegisterRequest{
 		Email:    "User@Example.com",
 		Password: "secret",
 		Role:     "admin",
 	})
+	require.Equal(t, "forbidden", pkgErrors.FromError(err).Code)
+	require.Empty(t, repo.users)
+
+	resp, err := svc.Register(context.Background(), dto.RegisterRequest{
+		Email:    "User@Example.com",
+		Password: "secret",
+	})
 	require.NoError(t, err)
 	require.Equal(t, "user@example.com", repo.lastCreated.Email)
-	require.Equal(t, "admin", repo.lastCreated.Role)
+	require.Equal(t, "customer", repo.lastCreated.Role)
 	require.NotEmpty(t, resp.AccessToken)
 
 	// duplicate email
@@ -42,7 +55,7 @@ func TestRegisterAndLogin(t *testing.T) {
 	login, err := svc.Login(context.Background(), dto.LoginRequest{
 		Email:    "user@example.com",
 		Password: "secret",
-	})
+	}, "")
 	require.NoError(t, err)
 	require.Equal(t, resp.ID, login.ID)
 
@@ -50,14 +63,14 @@ func TestRegisterAndLogin(t *testing.T) {
 	_, err = svc.Login(context.Background(), dto.LoginRequest{
 		Email:    "user@example.com",
 		Password: "wrong",
-	})
+	}, "")
 	require.Error(t, err)
 }
 
 func TestMeListGet(t *testing.T) {
 	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
 	issuer := &issuerStub{}
-	svc := auth.NewService(repo, issuer)
+	svc := auth.NewService(repo, issuer, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
 	user := domain.User{
 		ID:        uuid.New(),
 		Email:     "user@example.com",
@@ -83,10 +96,399 @@ func TestMeListGet(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	login, err := svc.Register(context.Background(), dto.RegisterRequest{
+		Email:    "user@example.com",
+		Password: "secret",
+	})
+	require.NoError(t, err)
+
+	rotated, err := svc.Refresh(context.Background(), dto.RefreshRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+	require.Equal(t, login.ID, rotated.ID)
+	require.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+
+	first := repo.sessions[uuid.MustParse(login.RefreshToken)]
+	second := repo.sessions[uuid.MustParse(rotated.RefreshToken)]
+	require.Equal(t, first.FamilyID, second.FamilyID)
+
+	// replaying the rotated token revokes the family, including the newest token
+	_, err = svc.Refresh(context.Background(), dto.RefreshRequest{RefreshToken: login.RefreshToken})
+	require.EqualError(t, err, "refresh token reuse detected")
+	require.NotNil(t, repo.sessions[second.ID].RevokedAt)
+
+	_, err = svc.Refresh(context.Background(), dto.RefreshRequest{RefreshToken: rotated.RefreshToken})
+	require.Error(t, err)
+
+	_, err = svc.Refresh(context.Background(), dto.RefreshRequest{RefreshToken: "garbage"})
+	require.Error(t, err)
+}
+
+func TestLogoutRevokesAccessTokenAndRefreshFamily(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	login, err := svc.Register(context.Background(), dto.RegisterRequest{
+		Email:    "user@example.com",
+		Password: "secret",
+	})
+	require.NoError(t, err)
+
+	tokenID := uuid.New()
+	err = svc.Logout(context.Background(), assembler.LogoutCommand{
+		UserID:       uuid.MustParse(login.ID),
+		TokenID:      tokenID,
+		ExpiresAt:    time.Now().Add(time.Minute),
+		RefreshToken: login.RefreshToken,
+	})
+	require.NoError(t, err)
+
+	revoked, err := svc.Revocations(context.Background())
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	require.Equal(t, tokenID, revoked[0].ID)
+
+	_, err = svc.Refresh(context.Background(), dto.RefreshRequest{RefreshToken: login.RefreshToken})
+	require.Error(t, err)
+
+	// tokens without a jti cannot be revoked
+	err = svc.Logout(context.Background(), assembler.LogoutCommand{UserID: uuid.MustParse(login.ID)})
+	require.Error(t, err)
+}
+
+func TestPasswordResetAndEmailVerification(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	notifier := &notifierStub{}
+	svc := auth.NewService(repo, &issuerStub{}, notifier, &personalDataStub{}, domain.LockoutPolicy{})
+	ctx := context.Background()
+	login, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID := uuid.MustParse(login.ID)
+	require.False(t, repo.users[userID].EmailVerified())
+
+	// registration mails a verification link; its token only verifies once
+	verification := notifier.sent["email_verification:user@example.com"]
+	require.NotEmpty(t, verification)
+	require.Error(t, svc.ResetPassword(ctx, dto.ResetPasswordRequest{Token: verification, Password: "other"}))
+	require.NoError(t, svc.VerifyEmail(ctx, dto.VerifyEmailRequest{Token: verification}))
+	require.True(t, repo.users[userID].EmailVerified())
+	require.Error(t, svc.VerifyEmail(ctx, dto.VerifyEmailRequest{Token: verification}))
+	require.Error(t, svc.ResendVerification(ctx, userID))
+
+	// unknown addresses succeed without sending anything
+	require.NoError(t, svc.ForgotPassword(ctx, dto.ForgotPasswordRequest{Email: "nobody@example.com"}))
+	require.Empty(t, notifier.sent["password_reset:nobody@example.com"])
+
+	require.NoError(t, svc.ForgotPassword(ctx, dto.ForgotPasswordRequest{Email: "user@example.com"}))
+	reset := notifier.sent["password_reset:user@example.com"]
+	require.NotEmpty(t, reset)
+	for _, stored := range repo.tokens {
+		require.NotEqual(t, reset, stored.Hash)
+	}
+
+	require.NoError(t, svc.ResetPassword(ctx, dto.ResetPasswordRequest{Token: reset, Password: "new-secret"}))
+	require.Error(t, svc.ResetPassword(ctx, dto.ResetPasswordRequest{Token: reset, Password: "again"}))
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "")
+	require.Error(t, err)
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "new-secret"}, "")
+	require.NoError(t, err)
+
+	// sessions from before the reset are signed out
+	_, err = svc.Refresh(ctx, dto.RefreshRequest{RefreshToken: login.RefreshToken})
+	require.Error(t, err)
+}
+
+func TestForgotPasswordHidesDeliveryFailures(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	notifier := &notifierStub{}
+	svc := auth.NewService(repo, &issuerStub{}, notifier, &personalDataStub{}, domain.LockoutPolicy{})
+	ctx := context.Background()
+	_, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+
+	// a failed delivery answers like an unknown address
+	notifier.err = errors.New("notification service down")
+	require.NoError(t, svc.ForgotPassword(ctx, dto.ForgotPasswordRequest{Email: "user@example.com"}))
+	require.NoError(t, svc.ForgotPassword(ctx, dto.ForgotPasswordRequest{Email: "nobody@example.com"}))
+	require.Empty(t, notifier.sent["password_reset:user@example.com"])
+}
+
+func TestOneTimeTokenExpires(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	notifier := &notifierStub{}
+	svc := auth.NewService(repo, &issuerStub{}, notifier, &personalDataStub{}, domain.LockoutPolicy{})
+	ctx := context.Background()
+	_, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, svc.ForgotPassword(ctx, dto.ForgotPasswordRequest{Email: "user@example.com"}))
+
+	for id, tok := range repo.tokens {
+		tok.ExpiresAt = time.Now().Add(-time.Minute)
+		repo.tokens[id] = tok
+	}
+	err = svc.ResetPassword(ctx, dto.ResetPasswordRequest{Token: notifier.sent["password_reset:user@example.com"], Password: "new-secret"})
+	require.EqualError(t, err, "invalid or expired token")
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{MaxAttempts: 3, LockoutDuration: time.Hour})
+	ctx := context.Background()
+	registered, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID := uuid.MustParse(registered.ID)
+
+	// a success in between restarts the count
+	for _, password := range []string{"wrong", "wrong", "secret", "wrong", "wrong"} {
+		_, _ = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: password}, "10.0.0.1")
+	}
+	require.Empty(t, repo.events)
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "wrong"}, "10.0.0.1")
+	require.EqualError(t, err, "invalid credentials")
+	require.Len(t, repo.events, 1)
+	require.Equal(t, domain.EventTypeUserLocked, repo.events[0].EventType())
+
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "10.0.0.1")
+	require.EqualError(t, err, "account temporarily locked")
+	require.Equal(t, 3600, pkgErrors.FromError(err).Details.(map[string]int)["retry_after_seconds"])
+
+	unlocked, err := svc.Unlock(ctx, userID, uuid.New())
+	require.NoError(t, err)
+	require.False(t, unlocked.Attempts.Locked(time.Now()))
+	require.Equal(t, domain.EventTypeUserUnlocked, repo.events[1].EventType())
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "10.0.0.1")
+	require.NoError(t, err)
+}
+
+func TestLoginDelaysAttemptsAfterFailure(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{BaseDelay: time.Minute, MaxDelay: time.Hour})
+	ctx := context.Background()
+	_, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "wrong"}, "")
+	require.EqualError(t, err, "invalid credentials")
+	// even the right password waits out the delay
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "")
+	require.Equal(t, "too_many_requests", pkgErrors.FromError(err).Code)
+
+	policy := domain.LockoutPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	require.Equal(t, time.Duration(0), policy.Delay(0))
+	require.Equal(t, time.Second, policy.Delay(1))
+	require.Equal(t, 4*time.Second, policy.Delay(3))
+	require.Equal(t, 5*time.Second, policy.Delay(10))
+}
+
+func TestLoginBlocksAddressFailingAcrossAccounts(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{IPMaxAttempts: 3, LockoutDuration: time.Hour})
+	ctx := context.Background()
+	_, err := svc.Register(ctx, dto.RegisterRequest{Email: "user@example.com", Password: "secret"})
+	require.NoError(t, err)
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		_, err = svc.Login(ctx, dto.LoginRequest{Email: email, Password: "guess"}, "10.0.0.1")
+		require.EqualError(t, err, "invalid credentials")
+	}
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "10.0.0.1")
+	require.Equal(t, "too_many_requests", pkgErrors.FromError(err).Code)
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "user@example.com", Password: "secret"}, "10.0.0.2")
+	require.NoError(t, err)
+}
+
+func TestMFAEnrollmentAndTwoStepLogin(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	ctx := context.Background()
+	registered, err := svc.Register(ctx, dto.RegisterRequest{Email: "admin@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID := uuid.MustParse(registered.ID)
+
+	_, err = svc.VerifyMFA(ctx, userID, dto.MFAVerifyRequest{Code: "123456"})
+	require.EqualError(t, err, "mfa setup not started")
+	setup, err := svc.SetupMFA(ctx, userID)
+	require.NoError(t, err)
+	require.Contains(t, setup.OTPAuthURI, "secret="+setup.Secret)
+
+	_, err = svc.VerifyMFA(ctx, userID, dto.MFAVerifyRequest{Code: "000000x"})
+	require.EqualError(t, err, "invalid verification code")
+	code, err := domain.TOTPCode(setup.Secret, domain.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	enrolled, err := svc.VerifyMFA(ctx, userID, dto.MFAVerifyRequest{Code: code})
+	require.NoError(t, err)
+	require.Len(t, enrolled.RecoveryCodes, domain.RecoveryCodeCount)
+	_, err = svc.SetupMFA(ctx, userID)
+	require.EqualError(t, err, "mfa already enabled")
+
+	// the password alone yields a challenge, not tokens
+	login, err := svc.Login(ctx, dto.LoginRequest{Email: "admin@example.com", Password: "secret"}, "")
+	require.NoError(t, err)
+	require.True(t, login.MFARequired)
+	require.Empty(t, login.AccessToken)
+	require.Empty(t, login.RefreshToken)
+
+	// the code that enabled MFA cannot be replayed
+	_, err = svc.CompleteMFALogin(ctx, dto.MFAChallengeRequest{MFAToken: login.MFAToken, Code: code}, "")
+	require.EqualError(t, err, "invalid verification code")
+
+	// recovery codes work once, in any case and with or without the dash
+	recovery := strings.ToUpper(strings.ReplaceAll(enrolled.RecoveryCodes[0], "-", ""))
+	session, err := svc.CompleteMFALogin(ctx, dto.MFAChallengeRequest{MFAToken: login.MFAToken, Code: recovery}, "")
+	require.NoError(t, err)
+	require.True(t, repo.sessions[uuid.MustParse(session.RefreshToken)].MFA)
+	_, err = svc.CompleteMFALogin(ctx, dto.MFAChallengeRequest{MFAToken: login.MFAToken, Code: recovery}, "")
+	require.EqualError(t, err, "invalid verification code")
+
+	// refreshing keeps the second factor
+	rotated, err := svc.Refresh(ctx, dto.RefreshRequest{RefreshToken: session.RefreshToken})
+	require.NoError(t, err)
+	require.True(t, repo.sessions[uuid.MustParse(rotated.RefreshToken)].MFA)
+
+	_, err = svc.CompleteMFALogin(ctx, dto.MFAChallengeRequest{MFAToken: "forged", Code: code}, "")
+	require.EqualError(t, err, "invalid mfa token")
+}
+
+func TestMFACodeGuessesCountTowardsLockout(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{MaxAttempts: 2, LockoutDuration: time.Hour})
+	ctx := context.Background()
+	registered, err := svc.Register(ctx, dto.RegisterRequest{Email: "admin@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID := uuid.MustParse(registered.ID)
+	setup, err := svc.SetupMFA(ctx, userID)
+	require.NoError(t, err)
+	code, err := domain.TOTPCode(setup.Secret, domain.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	_, err = svc.VerifyMFA(ctx, userID, dto.MFAVerifyRequest{Code: code})
+	require.NoError(t, err)
+
+	// a fresh challenge per guess does not reset the count
+	for i := 0; i < 2; i++ {
+		login, err := svc.Login(ctx, dto.LoginRequest{Email: "admin@example.com", Password: "secret"}, "")
+		require.NoError(t, err)
+		_, err = svc.CompleteMFALogin(ctx, dto.MFAChallengeRequest{MFAToken: login.MFAToken, Code: "not-a-code"}, "")
+		require.EqualError(t, err, "invalid verification code")
+	}
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "admin@example.com", Password: "secret"}, "")
+	require.EqualError(t, err, "account temporarily locked")
+}
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 seed "12345678901234567890", truncated to six digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for at, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		code, err := domain.TOTPCode(secret, domain.TOTPStep(time.Unix(at, 0)))
+		require.NoError(t, err)
+		require.Equal(t, want, code)
+	}
+
+	step, ok := domain.MatchTOTP(secret, "287082", time.Unix(89, 0))
+	require.True(t, ok)
+	require.Equal(t, int64(1), step)
+	_, ok = domain.MatchTOTP(secret, "287082", time.Unix(120, 0))
+	require.False(t, ok)
+}
+
+func TestHotelRoleAssignment(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	issuer := &issuerStub{}
+	svc := auth.NewService(repo, issuer, &notifierStub{}, &personalDataStub{}, domain.LockoutPolicy{})
+	ctx := context.Background()
+	registered, err := svc.Register(ctx, dto.RegisterRequest{Email: "clerk@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID, adminID, hotelID := uuid.MustParse(registered.ID), uuid.New(), uuid.New()
+
+	_, err = svc.AssignHotelRole(ctx, userID, adminID, dto.HotelRoleRequest{HotelID: hotelID.String(), Role: "admin"})
+	require.EqualError(t, err, "invalid hotel role")
+	_, err = svc.AssignHotelRole(ctx, userID, adminID, dto.HotelRoleRequest{HotelID: "nope", Role: "front_desk"})
+	require.EqualError(t, err, "invalid hotel id")
+	_, err = svc.AssignHotelRole(ctx, uuid.New(), adminID, dto.HotelRoleRequest{HotelID: hotelID.String(), Role: "front_desk"})
+	require.EqualError(t, err, "user not found")
+
+	grant, err := svc.AssignHotelRole(ctx, userID, adminID, dto.HotelRoleRequest{HotelID: hotelID.String(), Role: "front_desk"})
+	require.NoError(t, err)
+	require.Equal(t, adminID, grant.GrantedBy)
+	_, err = svc.AssignHotelRole(ctx, userID, adminID, dto.HotelRoleRequest{HotelID: hotelID.String(), Role: "front_desk"})
+	require.EqualError(t, err, "hotel role already assigned")
+	require.Equal(t, domain.EventTypeHotelRoleAssigned, repo.events[0].EventType())
+
+	// new tokens carry the grant
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "clerk@example.com", Password: "secret"}, "")
+	require.NoError(t, err)
+	require.Equal(t, []domain.HotelRole{grant}, issuer.issued.HotelRoles)
+
+	require.NoError(t, svc.RemoveHotelRole(ctx, userID, hotelID, "front_desk", adminID))
+	require.EqualError(t, svc.RemoveHotelRole(ctx, userID, hotelID, "front_desk", adminID), "hotel role not found")
+	require.Equal(t, domain.EventTypeHotelRoleRemoved, repo.events[1].EventType())
+	grants, err := svc.HotelRoles(ctx, userID)
+	require.NoError(t, err)
+	require.Empty(t, grants)
+}
+
+func TestProfileExportAndErasure(t *testing.T) {
+	repo := &userRepoStub{users: map[uuid.UUID]domain.User{}}
+	personal := &personalDataStub{}
+	svc := auth.NewService(repo, &issuerStub{}, &notifierStub{}, personal, domain.LockoutPolicy{})
+	ctx := context.Background()
+	registered, err := svc.Register(ctx, dto.RegisterRequest{Email: "guest@example.com", Password: "secret"})
+	require.NoError(t, err)
+	userID := uuid.MustParse(registered.ID)
+
+	bad := "12345"
+	_, err = svc.UpdateProfile(ctx, userID, dto.UpdateProfileRequest{Phone: &bad})
+	require.Equal(t, "bad_request", pkgErrors.FromError(err).Code)
+	_, err = svc.UpdateProfile(ctx, userID, dto.UpdateProfileRequest{Locale: &bad})
+	require.EqualError(t, err, "invalid locale")
+	name, phone, locale := "  Ada Guest ", "+62 812-3456-7890", "id_id"
+	updated, err := svc.UpdateProfile(ctx, userID, dto.UpdateProfileRequest{Name: &name, Phone: &phone, Locale: &locale, Preferences: map[string]string{"currency": "IDR"}})
+	require.NoError(t, err)
+	require.Equal(t, domain.Profile{Name: "Ada Guest", Phone: "+6281234567890", Locale: "id-ID", Preferences: map[string]string{"currency": "IDR"}}, updated.Profile)
+
+	// changing the password needs the current one and signs out other sessions
+	session, err := svc.Login(ctx, dto.LoginRequest{Email: "guest@example.com", Password: "secret"}, "")
+	require.NoError(t, err)
+	err = svc.ChangePassword(ctx, userID, dto.ChangePasswordRequest{CurrentPassword: "guess", NewPassword: "better"})
+	require.EqualError(t, err, "incorrect password")
+	require.NoError(t, svc.ChangePassword(ctx, userID, dto.ChangePasswordRequest{CurrentPassword: "secret", NewPassword: "better"}))
+	require.NotNil(t, repo.sessions[uuid.MustParse(session.RefreshToken)].RevokedAt)
+
+	export, err := svc.Export(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "Ada Guest", export.Profile.Name)
+	require.Len(t, export.Bookings, 1)
+	require.NotNil(t, export.Payments)
+
+	// a failure elsewhere leaves the account untouched so the user can retry
+	personal.err = errors.New("booking-service down")
+	err = svc.Erase(ctx, userID, dto.EraseAccountRequest{Password: "better"})
+	require.Equal(t, "bad_gateway", pkgErrors.FromError(err).Code)
+	require.Equal(t, "guest@example.com", repo.users[userID].Email)
+
+	personal.err = nil
+	err = svc.Erase(ctx, userID, dto.EraseAccountRequest{Password: "secret"})
+	require.EqualError(t, err, "incorrect password")
+	require.NoError(t, svc.Erase(ctx, userID, dto.EraseAccountRequest{Password: "better"}))
+	require.Equal(t, []string{"guest@example.com"}, personal.erased)
+	require.Equal(t, domain.ErasedEmail(userID), repo.users[userID].Email)
+	require.Equal(t, domain.EventTypeUserErased, repo.events[len(repo.events)-1].EventType())
+
+	_, err = svc.Login(ctx, dto.LoginRequest{Email: "guest@example.com", Password: "better"}, "")
+	require.EqualError(t, err, "invalid credentials")
+	require.EqualError(t, svc.Erase(ctx, userID, dto.EraseAccountRequest{Password: "better"}), "user not found")
+}
+
 // stubs
 
 type userRepoStub struct {
 	users       map[uuid.UUID]domain.User
+	sessions    map[uuid.UUID]domain.RefreshToken
+	revoked     map[uuid.UUID]domain.RevokedToken
+	tokens      map[uuid.UUID]domain.OneTimeToken
+	recovery    map[uuid.UUID]domain.RecoveryCode
+	grants      []domain.HotelRole
+	events      []pkgDomain.DomainEvent
 	lastCreated domain.User
 }
 
@@ -130,8 +532,293 @@ func (u *userRepoStub) List(ctx context.Context, _ query.Options) ([]domain.User
 	return out, nil
 }
 
-type issuerStub struct{}
+func (u *userRepoStub) CreateRefreshToken(ctx context.Context, t domain.RefreshToken) error {
+	if u.sessions == nil {
+		u.sessions = map[uuid.UUID]domain.RefreshToken{}
+	}
+	u.sessions[t.ID] = t
+	return nil
+}
+
+func (u *userRepoStub) FindRefreshToken(ctx context.Context, id uuid.UUID) (domain.RefreshToken, error) {
+	if t, ok := u.sessions[id]; ok {
+		return t, nil
+	}
+	return domain.RefreshToken{}, errors.New("not found")
+}
+
+func (u *userRepoStub) UseRefreshToken(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	t, ok := u.sessions[id]
+	if !ok || t.UsedAt != nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = &at
+	u.sessions[id] = t
+	return true, nil
+}
+
+func (u *userRepoStub) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	for id, t := range u.sessions {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			u.sessions[id] = t
+		}
+	}
+	return nil
+}
+
+func (u *userRepoStub) RevokeToken(ctx context.Context, t domain.RevokedToken) error {
+	if u.revoked == nil {
+		u.revoked = map[uuid.UUID]domain.RevokedToken{}
+	}
+	u.revoked[t.ID] = t
+	return nil
+}
+
+func (u *userRepoStub) ListRevoked(ctx context.Context, now time.Time) ([]domain.RevokedToken, error) {
+	var out []domain.RevokedToken
+	for _, t := range u.revoked {
+		if now.Before(t.ExpiresAt) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (u *userRepoStub) UpdatePassword(ctx context.Context, id uuid.UUID, hash string) error {
+	usr, ok := u.users[id]
+	if !ok {
+		return errors.New("not found")
+	}
+	usr.Password = hash
+	u.users[id] = usr
+	return nil
+}
+
+func (u *userRepoStub) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	usr, ok := u.users[id]
+	if ok && usr.EmailVerifiedAt == nil {
+		usr.EmailVerifiedAt = &at
+		u.users[id] = usr
+	}
+	return nil
+}
+
+func (u *userRepoStub) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	for id, t := range u.sessions {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			u.sessions[id] = t
+		}
+	}
+	return nil
+}
+
+func (u *userRepoStub) CreateOneTimeToken(ctx context.Context, t domain.OneTimeToken) error {
+	if u.tokens == nil {
+		u.tokens = map[uuid.UUID]domain.OneTimeToken{}
+	}
+	u.tokens[t.ID] = t
+	return nil
+}
+
+func (u *userRepoStub) ConsumeOneTimeToken(ctx context.Context, purpose domain.TokenPurpose, hash string, at time.Time) (domain.OneTimeToken, error) {
+	for id, t := range u.tokens {
+		if t.Purpose == purpose && t.Hash == hash && t.UsedAt == nil && at.Before(t.ExpiresAt) {
+			t.UsedAt = &at
+			u.tokens[id] = t
+			return t, nil
+		}
+	}
+	return domain.OneTimeToken{}, errors.New("not found")
+}
+
+func (u *userRepoStub) RecordLoginFailure(ctx context.Context, id uuid.UUID, at time.Time) (int, error) {
+	usr := u.users[id]
+	usr.Attempts.Failures++
+	usr.Attempts.LastFailureAt = &at
+	u.users[id] = usr
+	return usr.Attempts.Failures, nil
+}
+
+func (u *userRepoStub) LockUser(ctx context.Context, id uuid.UUID, until time.Time) error {
+	usr := u.users[id]
+	usr.Attempts.Failures = 0
+	usr.Attempts.LockedUntil = &until
+	u.users[id] = usr
+	return nil
+}
+
+func (u *userRepoStub) ClearLoginFailures(ctx context.Context, id uuid.UUID) error {
+	usr := u.users[id]
+	usr.Attempts = domain.LoginAttempts{}
+	u.users[id] = usr
+	return nil
+}
+
+func (u *userRepoStub) RecordEvent(ctx context.Context, event pkgDomain.DomainEvent) error {
+	u.events = append(u.events, event)
+	return nil
+}
+
+func (u *userRepoStub) SetMFASecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	usr := u.users[userID]
+	usr.MFA = domain.MFA{Secret: secret}
+	u.users[userID] = usr
+	return nil
+}
+
+func (u *userRepoStub) EnableMFA(ctx context.Context, userID uuid.UUID, at time.Time, step int64, codes []domain.RecoveryCode) error {
+	usr := u.users[userID]
+	usr.MFA.EnabledAt = &at
+	usr.MFA.LastStep = step
+	u.users[userID] = usr
+	u.recovery = map[uuid.UUID]domain.RecoveryCode{}
+	for _, c := range codes {
+		u.recovery[c.ID] = c
+	}
+	return nil
+}
+
+func (u *userRepoStub) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	usr := u.users[userID]
+	if usr.MFA.LastStep >= step {
+		return false, nil
+	}
+	usr.MFA.LastStep = step
+	u.users[userID] = usr
+	return true, nil
+}
+
+func (u *userRepoStub) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	for id, c := range u.recovery {
+		if c.UserID == userID && c.Hash == hash && c.UsedAt == nil {
+			c.UsedAt = &at
+			u.recovery[id] = c
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (u *userRepoStub) AssignHotelRole(ctx context.Context, grant domain.HotelRole) error {
+	for _, g := range u.grants {
+		if g.UserID == grant.UserID && g.HotelID == grant.HotelID && g.Role == grant.Role {
+			return pkgErrors.New("conflict", "hotel role already assigned")
+		}
+	}
+	u.grants = append(u.grants, grant)
+	return nil
+}
+
+func (u *userRepoStub) RemoveHotelRole(ctx context.Context, userID, hotelID uuid.UUID, role valueobject.Role) (bool, error) {
+	for i, g := range u.grants {
+		if g.UserID == userID && g.HotelID == hotelID && g.Role == role {
+			u.grants = append(u.grants[:i], u.grants[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (u *userRepoStub) ListHotelRoles(ctx context.Context, userID uuid.UUID) ([]domain.HotelRole, error) {
+	var out []domain.HotelRole
+	for _, g := range u.grants {
+		if g.UserID == userID {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+func (u *userRepoStub) UpdateProfile(ctx context.Context, id uuid.UUID, profile domain.Profile) error {
+	usr, ok := u.users[id]
+	if !ok {
+		return errors.New("not found")
+	}
+	usr.Profile = profile
+	u.users[id] = usr
+	return nil
+}
 
-func (i *issuerStub) Generate(ctx context.Context, user domain.User) (access, refresh string, err error) {
-	return "access", "refresh", nil
+func (u *userRepoStub) EraseUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	usr, ok := u.users[id]
+	if !ok {
+		return errors.New("not found")
+	}
+	u.users[id] = domain.User{ID: id, Email: domain.ErasedEmail(id), Role: usr.Role, CreatedAt: usr.CreatedAt, ErasedAt: &at}
+	return u.RevokeUserRefreshTokens(ctx, id, at)
+}
+
+// notifierStub records the last secret sent per purpose and address.
+type notifierStub struct {
+	sent map[string]string
+	err  error
+}
+
+func (n *notifierStub) SendPasswordReset(ctx context.Context, email, secret string) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.record("password_reset", email, secret)
+	return nil
+}
+
+func (n *notifierStub) SendEmailVerification(ctx context.Context, email, secret string) error {
+	n.record("email_verification", email, secret)
+	return nil
+}
+
+func (n *notifierStub) record(purpose, email, secret string) {
+	if n.sent == nil {
+		n.sent = map[string]string{}
+	}
+	n.sent[purpose+":"+email] = secret
+}
+
+// personalDataStub holds one booking per user and records erased addresses,
+// failing every call when err is set.
+type personalDataStub struct {
+	erased []string
+	err    error
+}
+
+func (p *personalDataStub) Collect(ctx context.Context, userID uuid.UUID, email string) (domain.PersonalData, error) {
+	booking, _ := json.Marshal(map[string]string{"id": uuid.NewString(), "user_id": userID.String()})
+	return domain.PersonalData{Bookings: []json.RawMessage{booking}}, p.err
+}
+
+func (p *personalDataStub) Erase(ctx context.Context, userID uuid.UUID, email string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.erased = append(p.erased, email)
+	return nil
+}
+
+// issuerStub uses the session ID as the refresh token, and the user ID
+// behind a prefix as the MFA challenge. It keeps the last user it issued for.
+type issuerStub struct {
+	issued domain.User
+}
+
+func (i *issuerStub) Generate(ctx context.Context, user domain.User, session domain.RefreshToken) (access, refresh string, err error) {
+	i.issued = user
+	return "access", session.ID.String(), nil
+}
+
+func (i *issuerStub) ParseRefresh(ctx context.Context, token string) (uuid.UUID, error) {
+	return uuid.Parse(token)
+}
+
+func (i *issuerStub) IssueMFAChallenge(ctx context.Context, user domain.User) (string, error) {
+	return "mfa:" + user.ID.String(), nil
+}
+
+func (i *issuerStub) ParseMFAChallenge(ctx context.Context, token string) (uuid.UUID, error) {
+	id, ok := strings.CutPrefix(token, "mfa:")
+	if !ok {
+		return uuid.Nil, errors.New("invalid mfa token")
+	}
+	return uuid.Parse(id)
 }
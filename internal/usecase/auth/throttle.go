@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/auth"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// maxTrackedIPs bounds the throttle's memory; beyond it, addresses whose
+// failures have gone quiet are forgotten.
+const maxTrackedIPs = 10000
+
+// ipThrottle counts failed logins per client address in memory, so one
+// address guessing passwords across many accounts is blocked without
+// slowing anyone else. Counts are per replica; the per-account state kept
+// with the user is what holds across replicas.
+type ipThrottle struct {
+	policy domain.LockoutPolicy
+
+	mu      sync.Mutex
+	entries map[string]ipAttempts
+}
+
+type ipAttempts struct {
+	failures     int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newIPThrottle(policy domain.LockoutPolicy) *ipThrottle {
+	return &ipThrottle{policy: policy, entries: map[string]ipAttempts{}}
+}
+
+func (t *ipThrottle) enabled(ip string) bool {
+	return t.policy.IPMaxAttempts > 0 && ip != ""
+}
+
+// retryAfter returns how long ip stays blocked at now.
+func (t *ipThrottle) retryAfter(ip string, now time.Time) time.Duration {
+	if !t.enabled(ip) {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if blocked := t.entries[ip].blockedUntil; now.Before(blocked) {
+		return blocked.Sub(now)
+	}
+	return 0
+}
+
+// fail counts a failed login from ip. Failures older than the lockout
+// duration are forgotten.
+func (t *ipThrottle) fail(ip string, now time.Time) {
+	if !t.enabled(ip) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entries[ip]
+	if now.Sub(e.last) > t.policy.LockoutDuration {
+		e.failures = 0
+	}
+	e.failures++
+	e.last = now
+	if e.failures >= t.policy.IPMaxAttempts {
+		e.failures = 0
+		e.blockedUntil = now.Add(t.policy.LockoutDuration)
+	}
+	t.entries[ip] = e
+	if len(t.entries) > maxTrackedIPs {
+		t.prune(now)
+	}
+}
+
+func (t *ipThrottle) prune(now time.Time) {
+	for ip, e := range t.entries {
+		if now.Sub(e.last) > t.policy.LockoutDuration && !now.Before(e.blockedUntil) {
+			delete(t.entries, ip)
+		}
+	}
+}
+
+// tooManyAttempts reports a throttled login with the seconds to wait.
+func tooManyAttempts(message string, wait time.Duration) error {
+	err := errors.New("too_many_requests", message)
+	err.Details = map[string]int{"retry_after_seconds": int(math.Ceil(wait.Seconds()))}
+	return err
+}
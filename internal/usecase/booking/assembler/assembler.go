@@ -1,6 +1,7 @@
 package assembler
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,6 +9,8 @@ import (
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // CreateCommand represents inbound booking creation intent.
@@ -19,6 +22,70 @@ type CreateCommand struct {
 	Guests     int
 }
 
+// ModifyCommand represents a booking amendment; zero fields keep the current value.
+type ModifyCommand struct {
+	RoomTypeID uuid.UUID
+	CheckIn    time.Time
+	CheckOut   time.Time
+	Guests     int
+}
+
+// ReservationCommand represents inbound multi-room reservation intent.
+type ReservationCommand struct {
+	UserID uuid.UUID
+	Lines  []ReservationLine
+}
+
+// ReservationLine is one room requested within a reservation.
+type ReservationLine struct {
+	RoomTypeID uuid.UUID
+	CheckIn    time.Time
+	CheckOut   time.Time
+	Guests     int
+}
+
+// Modification is the outcome of amending a booking: the saved booking and
+// how the price difference was settled.
+type Modification struct {
+	Booking    domain.Booking
+	PriceDelta float64
+	Payment    domain.PaymentResult
+	Refund     domain.RefundResult
+}
+
+// Cancellation is the outcome of cancelling a booking: the penalty kept
+// under the cancellation policy and the refund queued for the rest.
+type Cancellation struct {
+	Booking domain.Booking
+	Quote   domain.CancellationQuote
+}
+
+// CheckoutReport summarizes an automatic checkout run: bookings completed,
+// bookings another run completed first, and the bookings that failed.
+type CheckoutReport struct {
+	Completed int
+	Skipped   int
+	Failed    map[uuid.UUID]error
+}
+
+// BatchReport summarizes a scheduled run over bookings: bookings processed,
+// bookings a concurrent run or a settled payment took out of scope, and the
+// bookings that failed.
+type BatchReport struct {
+	Processed int
+	Skipped   int
+	Failed    map[uuid.UUID]error
+}
+
+// RelayReport summarizes one outbox relay run. Errors holds the delivery
+// failure of each message that failed.
+type RelayReport struct {
+	Delivered int
+	Retrying  int
+	Failed    int
+	Errors    map[uuid.UUID]error
+}
+
 // ToResponse maps domain booking plus optional payment info to response DTO.
 func ToResponse(b domain.Booking, payment domain.PaymentResult) dto.BookingResponse {
 	resp := dto.BookingResponse{
@@ -30,6 +97,9 @@ func ToResponse(b domain.Booking, payment domain.PaymentResult) dto.BookingRespo
 		CheckIn:     b.CheckIn,
 		CheckOut:    b.CheckOut,
 	}
+	if b.ReservationID != uuid.Nil {
+		resp.ReservationID = b.ReservationID.String()
+	}
 	if payment.ID != uuid.Nil {
 		resp.Payment = &dto.PaymentResponse{
 			ID:         payment.ID.String(),
@@ -41,12 +111,85 @@ func ToResponse(b domain.Booking, payment domain.PaymentResult) dto.BookingRespo
 	return resp
 }
 
-// FromRequest validates incoming DTO to command.
-func FromRequest(req dto.BookingRequest) (CreateCommand, error) {
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		return CreateCommand{}, pkgErrors.New("bad_request", "invalid user id")
+// ToRoomAssignment maps a room assignment to DTO.
+func ToRoomAssignment(a domain.RoomAssignment) *dto.RoomAssignment {
+	return &dto.RoomAssignment{
+		RoomID:       a.RoomID.String(),
+		RoomNumber:   a.RoomNumber,
+		CheckedInAt:  a.CheckedInAt,
+		CheckedOutAt: a.CheckedOutAt,
+	}
+}
+
+// ToModificationResponse maps a modification outcome to DTO.
+func ToModificationResponse(m Modification) dto.BookingModificationResponse {
+	resp := dto.BookingModificationResponse{
+		Booking:    ToResponse(m.Booking, domain.PaymentResult{}),
+		PriceDelta: m.PriceDelta,
+	}
+	if m.Payment.ID != uuid.Nil {
+		resp.Payment = &dto.PaymentResponse{
+			ID:         m.Payment.ID.String(),
+			Status:     m.Payment.Status,
+			Provider:   m.Payment.Provider,
+			PaymentURL: m.Payment.PaymentURL,
+		}
+	}
+	if m.Refund.PaymentID != uuid.Nil {
+		resp.Refund = &dto.RefundResponse{
+			ID:        m.Refund.PaymentID.String(),
+			Amount:    m.Refund.Amount,
+			Status:    m.Refund.Status,
+			Reference: m.Refund.Reference,
+		}
+	}
+	return resp
+}
+
+// ToCancellationResponse maps a cancellation outcome to DTO.
+func ToCancellationResponse(c Cancellation) dto.BookingCancellationResponse {
+	return dto.BookingCancellationResponse{
+		Booking:      ToResponse(c.Booking, domain.PaymentResult{}),
+		Penalty:      c.Quote.Penalty,
+		RefundAmount: c.Quote.Refund,
+	}
+}
+
+// ToReservationResponse maps a reservation plus its combined payment to DTO.
+func ToReservationResponse(r domain.Reservation, payment domain.PaymentResult) dto.ReservationResponse {
+	resp := dto.ReservationResponse{
+		ID:         r.ID.String(),
+		TotalPrice: r.TotalPrice(),
+		Lines:      make([]dto.BookingResponse, 0, len(r.Lines)),
+	}
+	for _, line := range r.Lines {
+		resp.Lines = append(resp.Lines, ToResponse(line, domain.PaymentResult{}))
+	}
+	if payment.ID != uuid.Nil {
+		resp.Payment = &dto.PaymentResponse{
+			ID:         payment.ID.String(),
+			Status:     payment.Status,
+			Provider:   payment.Provider,
+			PaymentURL: payment.PaymentURL,
+		}
+	}
+	return resp
+}
+
+// ToReservationCancellationResponse maps the cancelled lines of a reservation to DTO.
+func ToReservationCancellationResponse(id uuid.UUID, cs []Cancellation) dto.ReservationCancellationResponse {
+	resp := dto.ReservationCancellationResponse{
+		ID:    id.String(),
+		Lines: make([]dto.BookingCancellationResponse, 0, len(cs)),
+	}
+	for _, c := range cs {
+		resp.Lines = append(resp.Lines, ToCancellationResponse(c))
 	}
+	return resp
+}
+
+// FromRequest validates incoming DTO to a command booking for userID.
+func FromRequest(userID uuid.UUID, req dto.BookingRequest) (CreateCommand, error) {
 	roomTypeID, err := uuid.Parse(req.RoomTypeID)
 	if err != nil {
 		return CreateCommand{}, pkgErrors.New("bad_request", "invalid room type id")
@@ -69,3 +212,126 @@ func FromRequest(req dto.BookingRequest) (CreateCommand, error) {
 		Guests:     guests,
 	}, nil
 }
+
+// FromModifyRequest validates an amendment DTO to command.
+func FromModifyRequest(req dto.ModifyBookingRequest) (ModifyCommand, error) {
+	var cmd ModifyCommand
+	if req.RoomTypeID != "" {
+		roomTypeID, err := uuid.Parse(req.RoomTypeID)
+		if err != nil {
+			return ModifyCommand{}, pkgErrors.New("bad_request", "invalid room type id")
+		}
+		cmd.RoomTypeID = roomTypeID
+	}
+	if req.Guests < 0 {
+		return ModifyCommand{}, pkgErrors.New("bad_request", "guests must be positive")
+	}
+	cmd.CheckIn = req.CheckIn.Time
+	cmd.CheckOut = req.CheckOut.Time
+	cmd.Guests = req.Guests
+	return cmd, nil
+}
+
+// FromReservationRequest validates a multi-room reservation DTO to a command reserving for userID.
+func FromReservationRequest(userID uuid.UUID, req dto.ReservationRequest) (ReservationCommand, error) {
+	cmd := ReservationCommand{UserID: userID, Lines: make([]ReservationLine, 0, len(req.Lines))}
+	for _, line := range req.Lines {
+		single, err := FromRequest(userID, dto.BookingRequest{
+			RoomTypeID: line.RoomTypeID,
+			CheckIn:    line.CheckIn,
+			CheckOut:   line.CheckOut,
+			Guests:     line.Guests,
+		})
+		if err != nil {
+			return ReservationCommand{}, err
+		}
+		cmd.Lines = append(cmd.Lines, ReservationLine{
+			RoomTypeID: single.RoomTypeID,
+			CheckIn:    single.CheckIn,
+			CheckOut:   single.CheckOut,
+			Guests:     single.Guests,
+		})
+	}
+	return cmd, nil
+}
+
+// FromSearchQuery validates booking list filters to a domain filter.
+func FromSearchQuery(q dto.BookingSearchQuery) (domain.Filter, error) {
+	f := domain.Filter{Page: query.Options{Limit: q.Limit, Offset: q.Offset}}
+
+	ids := []struct {
+		raw  string
+		dst  *uuid.UUID
+		name string
+	}{
+		{q.UserID, &f.UserID, "user id"},
+		{q.HotelID, &f.HotelID, "hotel id"},
+		{q.RoomTypeID, &f.RoomTypeID, "room type id"},
+	}
+	for _, id := range ids {
+		if id.raw == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(id.raw)
+		if err != nil {
+			return domain.Filter{}, pkgErrors.New("bad_request", "invalid "+id.name)
+		}
+		*id.dst = parsed
+	}
+
+	if q.Status != "" {
+		for _, status := range strings.Split(q.Status, ",") {
+			status = strings.TrimSpace(status)
+			if _, err := valueobject.ValidateBookingStatus(status); err != nil {
+				return domain.Filter{}, pkgErrors.New("bad_request", "invalid status "+status)
+			}
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+
+	dates := []struct {
+		raw  string
+		dst  *time.Time
+		name string
+	}{
+		{q.CheckInFrom, &f.CheckInFrom, "check_in_from"},
+		{q.CheckInTo, &f.CheckInTo, "check_in_to"},
+		{q.CheckOutFrom, &f.CheckOutFrom, "check_out_from"},
+		{q.CheckOutTo, &f.CheckOutTo, "check_out_to"},
+		{q.CreatedFrom, &f.CreatedFrom, "created_from"},
+		{q.CreatedTo, &f.CreatedTo, "created_to"},
+	}
+	for _, date := range dates {
+		if date.raw == "" {
+			continue
+		}
+		var d dto.Date
+		if err := d.UnmarshalJSON([]byte(date.raw)); err != nil {
+			return domain.Filter{}, pkgErrors.New("bad_request", "invalid "+date.name)
+		}
+		*date.dst = d.Time
+	}
+
+	sorts, err := query.ParseSort(q.Sort, domain.SortableFields...)
+	if err != nil {
+		return domain.Filter{}, err
+	}
+	f.Sort = sorts
+	return f, nil
+}
+
+// ToOutboxResponse maps an outbox message to DTO.
+func ToOutboxResponse(m domain.OutboxMessage) dto.OutboxMessageResponse {
+	return dto.OutboxMessageResponse{
+		ID:            m.ID.String(),
+		AggregateID:   m.AggregateID.String(),
+		EventType:     m.EventType,
+		Payload:       m.Payload,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+		DeliveredAt:   m.DeliveredAt,
+	}
+}
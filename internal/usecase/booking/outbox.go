@@ -0,0 +1,82 @@
+package booking
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking/assembler"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+)
+
+// outboxLease keeps a claimed message away from other relays while it is delivered.
+const outboxLease = time.Minute
+
+// RelayOutbox delivers up to limit due outbox messages: refund requests to
+// the payment service and every other event to the notification service.
+// Failed deliveries are retried with exponential backoff and parked as failed
+// after domain.MaxOutboxAttempts.
+func (s *Service) RelayOutbox(ctx context.Context, limit int) (assembler.RelayReport, error) {
+	report := assembler.RelayReport{Errors: map[uuid.UUID]error{}}
+	now := time.Now()
+	messages, err := s.repo.ClaimOutbox(ctx, now, outboxLease, limit)
+	if err != nil {
+		return report, err
+	}
+
+	for _, m := range messages {
+		if err := s.deliver(ctx, m); err != nil {
+			report.Errors[m.ID] = err
+			attempts := m.Attempts + 1
+			giveUp := attempts >= domain.MaxOutboxAttempts
+			if err := s.repo.MarkAttemptFailed(ctx, m.ID, err.Error(), time.Now().Add(domain.OutboxRetryDelay(attempts)), giveUp); err != nil {
+				return report, err
+			}
+			if giveUp {
+				report.Failed++
+			} else {
+				report.Retrying++
+			}
+			continue
+		}
+		if err := s.repo.MarkDelivered(ctx, m.ID, time.Now()); err != nil {
+			return report, err
+		}
+		report.Delivered++
+	}
+	return report, nil
+}
+
+// deliver hands one outbox message to its consumer.
+func (s *Service) deliver(ctx context.Context, m domain.OutboxMessage) error {
+	if m.EventType != domain.EventTypeRefundRequested {
+		return s.notifier.Notify(ctx, m.EventType, json.RawMessage(m.Payload))
+	}
+	var refund domain.RefundRequested
+	if err := json.Unmarshal(m.Payload, &refund); err != nil {
+		return err
+	}
+	_, err := s.payments.Refund(ctx, refund.PaymentBookingID, refund.Amount, refund.Reason)
+	return err
+}
+
+// ListOutbox returns outbox messages, optionally narrowed to one delivery status.
+func (s *Service) ListOutbox(ctx context.Context, status string, opts query.Options) ([]domain.OutboxMessage, error) {
+	return s.repo.ListOutbox(ctx, status, opts.Normalize(50))
+}
+
+// GetOutboxMessage returns a single outbox message.
+func (s *Service) GetOutboxMessage(ctx context.Context, id uuid.UUID) (domain.OutboxMessage, error) {
+	return s.repo.FindOutbox(ctx, id)
+}
+
+// ReplayOutbox schedules a stuck or failed message for immediate redelivery.
+func (s *Service) ReplayOutbox(ctx context.Context, id uuid.UUID) (domain.OutboxMessage, error) {
+	if err := s.repo.ReplayOutbox(ctx, id); err != nil {
+		return domain.OutboxMessage{}, err
+	}
+	return s.repo.FindOutbox(ctx, id)
+}
@@ -0,0 +1,160 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking/assembler"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// CreateReservation books several rooms together. Every line is priced on its
+// own, availability is checked for all lines in one locked transaction and a
+// single payment covering the combined price is opened on the lead line. Like
+// a single booking, creation runs as a saga that cancels every line when the
+// payment cannot be opened.
+func (s *Service) CreateReservation(ctx context.Context, cmd assembler.ReservationCommand) (domain.Reservation, domain.PaymentResult, error) {
+	lines := make([]domain.Booking, 0, len(cmd.Lines))
+	for _, item := range cmd.Lines {
+		stay, err := valueobject.NewDateRange(item.CheckIn, item.CheckOut)
+		if err != nil {
+			return domain.Reservation{}, domain.PaymentResult{}, err
+		}
+		price, err := s.quote(ctx, item.RoomTypeID, stay, item.Guests)
+		if err != nil {
+			return domain.Reservation{}, domain.PaymentResult{}, err
+		}
+
+		line := domain.Booking{
+			ID:          uuid.New(),
+			RoomTypeID:  item.RoomTypeID,
+			CheckIn:     item.CheckIn,
+			CheckOut:    item.CheckOut,
+			Status:      string(valueobject.StatusPendingPayment),
+			Guests:      item.Guests,
+			TotalPrice:  price,
+			TotalNights: stay.Nights(),
+			CreatedAt:   time.Now(),
+		}
+		lines = append(lines, line)
+	}
+
+	reservation, err := domain.NewReservation(cmd.UserID, lines)
+	if err != nil {
+		return domain.Reservation{}, domain.PaymentResult{}, err
+	}
+
+	saga := domain.NewCreationSaga(reservation.LeadBookingID, reservation.ID, reservation.TotalPrice())
+	if err := s.repo.SaveSaga(ctx, saga); err != nil {
+		return domain.Reservation{}, domain.PaymentResult{}, err
+	}
+
+	if err := s.repo.ReserveGroup(ctx, reservation, s.groupAvailabilityCheck(reservation.Lines)); err != nil {
+		return domain.Reservation{}, domain.PaymentResult{}, s.abortCreation(ctx, &saga, err)
+	}
+
+	paymentResult, err := s.runCreation(ctx, &saga)
+	if err != nil {
+		return domain.Reservation{}, domain.PaymentResult{}, err
+	}
+
+	return reservation, paymentResult, nil
+}
+
+// groupAvailabilityCheck checks every line against the existing bookings of
+// its room type plus the earlier lines of the same reservation, so several
+// lines cannot claim the last free room together.
+func (s *Service) groupAvailabilityCheck(lines []domain.Booking) domain.ReservationCheck {
+	return func(ctx context.Context, inv domain.Inventory) error {
+		availability := domain.NewAvailabilityService()
+		for i, line := range lines {
+			stay := line.Stay()
+			rooms, err := inv.CountBookableRooms(ctx, line.RoomTypeID)
+			if err != nil {
+				return err
+			}
+			held, err := inv.FindOverlapping(ctx, line.RoomTypeID, stay)
+			if err != nil {
+				return err
+			}
+			for _, earlier := range lines[:i] {
+				if earlier.RoomTypeID == line.RoomTypeID {
+					held = append(held, earlier)
+				}
+			}
+			if err := availability.EnsureAvailable(rooms, stay, held); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// GetReservation returns a reservation with all of its lines.
+func (s *Service) GetReservation(ctx context.Context, id uuid.UUID) (domain.Reservation, error) {
+	return s.repo.FindReservation(ctx, id)
+}
+
+// CancelReservation cancels every line of a reservation that still holds
+// inventory, each settled under its own cancellation policy. Nothing is
+// cancelled when any line is already checked in or completed.
+func (s *Service) CancelReservation(ctx context.Context, id uuid.UUID) ([]assembler.Cancellation, error) {
+	reservation, err := s.repo.FindReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range reservation.Lines {
+		if line.Status == domain.StatusCheckedIn || line.Status == domain.StatusCompleted {
+			return nil, errors.New("bad_request", "cannot cancel booking after check-in")
+		}
+	}
+
+	results := make([]assembler.Cancellation, 0, len(reservation.Lines))
+	for _, line := range reservation.Lines {
+		if !line.HoldsInventory() {
+			continue
+		}
+		result, err := s.cancel(ctx, line, "user_requested")
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// paymentKey returns the booking the payment of b is recorded under: the lead
+// line for reservation members, b itself otherwise.
+func (s *Service) paymentKey(ctx context.Context, b domain.Booking) uuid.UUID {
+	if b.ReservationID == uuid.Nil {
+		return b.ID
+	}
+	reservation, err := s.repo.FindReservation(ctx, b.ReservationID)
+	if err != nil {
+		return b.ID
+	}
+	return reservation.LeadBookingID
+}
+
+// siblings returns the pending lines of the reservation b belongs to, b first.
+// A settled payment of the lead line confirms or cancels all of them together.
+func (s *Service) siblings(ctx context.Context, b domain.Booking) []domain.Booking {
+	lines := []domain.Booking{b}
+	if b.ReservationID == uuid.Nil || b.Status != domain.StatusPendingPayment {
+		return lines
+	}
+	reservation, err := s.repo.FindReservation(ctx, b.ReservationID)
+	if err != nil {
+		return lines
+	}
+	for _, line := range reservation.Lines {
+		if line.ID != b.ID && line.Status == domain.StatusPendingPayment {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
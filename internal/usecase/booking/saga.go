@@ -0,0 +1,182 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+const (
+	// sagaStaleAfter is how long a saga may go without progress before
+	// recovery takes it over; it comfortably exceeds a payment call.
+	sagaStaleAfter = 2 * time.Minute
+	sagaBatchSize  = 50
+)
+
+var errInterrupted = errors.New("internal_error", "booking creation was interrupted")
+
+// runCreation drives a creation saga whose booking lines were just reserved:
+// it opens the payment and announces the new booking. Any failure before the
+// payment is open is compensated and returned.
+func (s *Service) runCreation(ctx context.Context, saga *domain.Saga) (domain.PaymentResult, error) {
+	saga.Advance(domain.SagaStepReserved)
+	if err := s.repo.SaveSaga(ctx, *saga); err != nil {
+		return domain.PaymentResult{}, s.compensate(ctx, saga, err)
+	}
+
+	payment, err := s.payments.Initiate(ctx, saga.BookingID, saga.Amount)
+	if err != nil {
+		return domain.PaymentResult{}, s.compensate(ctx, saga, err)
+	}
+	saga.Advance(domain.SagaStepPaymentInitiated)
+	if err := s.repo.SaveSaga(ctx, *saga); err != nil {
+		return domain.PaymentResult{}, s.compensate(ctx, saga, err)
+	}
+
+	// The booking stands once paid for; if announcing it fails, the failure
+	// is recorded on the saga and recovery retries the announcement later.
+	if err := s.announce(ctx, saga); err != nil {
+		saga.RecordFailure(err.Error())
+		_ = s.repo.SaveSaga(ctx, *saga)
+	}
+	return payment, nil
+}
+
+// abortCreation closes a saga whose reservation was rejected; nothing was held.
+func (s *Service) abortCreation(ctx context.Context, saga *domain.Saga, cause error) error {
+	saga.Compensate(cause.Error())
+	saga.Compensated()
+	_ = s.repo.SaveSaga(ctx, *saga)
+	return cause
+}
+
+// announce publishes the creation events of the saga's bookings together
+// with its completion. The saga only advances once both are stored.
+func (s *Service) announce(ctx context.Context, saga *domain.Saga) error {
+	var lines []domain.Booking
+	if saga.ReservationID == uuid.Nil {
+		bk, err := s.repo.FindByID(ctx, saga.BookingID)
+		if err != nil {
+			return err
+		}
+		bk.RecordEvent(domain.NewBookingCreated(bk.ID, bk.UserID, bk.RoomTypeID, bk.TotalPrice, bk.Guests))
+		lines = []domain.Booking{bk}
+	} else {
+		reservation, err := s.repo.FindReservation(ctx, saga.ReservationID)
+		if err != nil {
+			return err
+		}
+		lines = reservation.Lines
+		for i := range lines {
+			lines[i].RecordEvent(domain.NewBookingCreated(lines[i].ID, reservation.UserID, lines[i].RoomTypeID, lines[i].TotalPrice, lines[i].Guests))
+		}
+		lines[0].RecordEvent(domain.NewReservationCreated(reservation.ID, reservation.UserID, len(lines), reservation.TotalPrice()))
+	}
+
+	done := *saga
+	done.Advance(domain.SagaStepNotified)
+	if err := s.repo.SaveSaga(ctx, done, lines...); err != nil {
+		return err
+	}
+	*saga = done
+	return nil
+}
+
+// compensate cancels the bookings created by the saga and expires any
+// payment it may have opened. The cause is returned so callers can surface
+// it; a saga left compensating is finished by recovery.
+func (s *Service) compensate(ctx context.Context, saga *domain.Saga, cause error) error {
+	saga.Compensate(cause.Error())
+	_ = s.repo.SaveSaga(ctx, *saga)
+
+	lines, err := s.sagaLines(ctx, *saga)
+	if err != nil {
+		return cause
+	}
+	for _, line := range lines {
+		if line.Status != domain.StatusPendingPayment {
+			continue
+		}
+		if err := line.Cancel("booking_failed", domain.CancellationQuote{}); err != nil {
+			return cause
+		}
+		// The guest was never told about the booking, so its cancellation is not announced.
+		line.ClearEvents()
+		if err := s.repo.Save(ctx, line); err != nil {
+			return cause
+		}
+	}
+	if len(lines) > 0 && saga.Reached(domain.SagaStepReserved) {
+		// Usually no payment was opened at all; one that was must not stay
+		// payable, so a failed expiry leaves the saga for recovery to retry.
+		if err := s.payments.Expire(ctx, saga.BookingID); err != nil {
+			switch errors.FromError(err).Code {
+			case "not_found", "conflict":
+			default:
+				saga.RecordFailure(err.Error())
+				_ = s.repo.SaveSaga(ctx, *saga)
+				return cause
+			}
+		}
+	}
+
+	saga.Compensated()
+	_ = s.repo.SaveSaga(ctx, *saga)
+	return cause
+}
+
+// sagaLines loads the bookings a saga created; none when its reservation
+// never committed.
+func (s *Service) sagaLines(ctx context.Context, saga domain.Saga) ([]domain.Booking, error) {
+	if saga.ReservationID == uuid.Nil {
+		bk, err := s.repo.FindByID(ctx, saga.BookingID)
+		if errors.FromError(err).Code == "not_found" {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []domain.Booking{bk}, nil
+	}
+	reservation, err := s.repo.FindReservation(ctx, saga.ReservationID)
+	if errors.FromError(err).Code == "not_found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reservation.Lines, nil
+}
+
+// RecoverSagas resumes creation sagas that stopped making progress, e.g.
+// after a crash. Sagas that had opened a payment are completed; the rest are
+// compensated since their caller never received a payment link.
+func (s *Service) RecoverSagas(ctx context.Context, now time.Time) (int, error) {
+	stale, err := s.repo.ClaimStaleSagas(ctx, now.Add(-sagaStaleAfter), now, sagaBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for i := range stale {
+		saga := &stale[i]
+		if saga.Status == domain.SagaRunning && saga.Reached(domain.SagaStepPaymentInitiated) {
+			if err := s.announce(ctx, saga); err != nil {
+				saga.RecordFailure(err.Error())
+				_ = s.repo.SaveSaga(ctx, *saga)
+				continue
+			}
+		} else {
+			_ = s.compensate(ctx, saga, errInterrupted)
+			if !saga.Finished() {
+				continue
+			}
+		}
+		recovered++
+	}
+	return recovered, nil
+}
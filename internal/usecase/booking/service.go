@@ -3,17 +3,14 @@ package booking
 import (
 	"context"
 	"database/sql"
-	"time" // Added time import
+	"time"
 
 	"github.com/google/uuid"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/booking"
 	hdomain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking/assembler"
-	pkgDomain "github.com/ftryyln/hotel-booking-microservices/pkg/domain"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
-
-
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
@@ -30,6 +27,9 @@ func NewService(repo domain.Repository, hotels hdomain.Repository, payments doma
 	return &Service{repo: repo, hotels: hotels, payments: payments, notifier: notifier}
 }
 
+// CreateBooking reserves inventory for a new booking and opens its payment.
+// The steps run as a saga: when the payment cannot be opened the booking is
+// cancelled again instead of lingering as pending_payment.
 func (s *Service) CreateBooking(ctx context.Context, cmd assembler.CreateCommand) (domain.Booking, domain.PaymentResult, error) {
 	// Use value objects
 	dateRange, err := valueobject.NewDateRange(cmd.CheckIn, cmd.CheckOut)
@@ -37,16 +37,11 @@ func (s *Service) CreateBooking(ctx context.Context, cmd assembler.CreateCommand
 		return domain.Booking{}, domain.PaymentResult{}, err
 	}
 
-	rt, err := s.hotels.GetRoomType(ctx, cmd.RoomTypeID)
+	totalPrice, err := s.quote(ctx, cmd.RoomTypeID, dateRange, cmd.Guests)
 	if err != nil {
-		return domain.Booking{}, domain.PaymentResult{}, errors.New("not_found", "room type not found")
+		return domain.Booking{}, domain.PaymentResult{}, err
 	}
 
-	// Use domain service for pricing
-	pricingService := domain.NewPricingService()
-	baseTotal := pricingService.CalculateTotalPrice(rt.BasePrice, dateRange.Nights(), cmd.Guests)
-	totalPrice := pricingService.ApplyDiscount(baseTotal, dateRange.Nights())
-
 	booking := domain.Booking{
 		ID:          uuid.New(),
 		UserID:      cmd.UserID,
@@ -60,18 +55,19 @@ func (s *Service) CreateBooking(ctx context.Context, cmd assembler.CreateCommand
 		CreatedAt:   time.Now(),
 	}
 
-	// Record creation event
-	booking.RecordEvent(domain.NewBookingCreated(booking.ID, booking.UserID, booking.RoomTypeID, booking.TotalPrice, booking.Guests))
-
-	if err := s.repo.Create(ctx, booking); err != nil {
+	// Track creation as a saga so a failed payment cancels the booking
+	saga := domain.NewCreationSaga(booking.ID, uuid.Nil, booking.TotalPrice)
+	if err := s.repo.SaveSaga(ctx, saga); err != nil {
 		return domain.Booking{}, domain.PaymentResult{}, err
 	}
 
-	// Publish domain events
-	s.publishEvents(ctx, booking.Events())
-	booking.ClearEvents()
+	// Reserve inventory: the availability check and insert share one locked transaction
+	if err := s.repo.Reserve(ctx, booking, s.availabilityCheck(booking.RoomTypeID, dateRange, uuid.Nil)); err != nil {
+		return domain.Booking{}, domain.PaymentResult{}, s.abortCreation(ctx, &saga, err)
+	}
 
-	paymentResult, err := s.payments.Initiate(ctx, booking.ID, booking.TotalPrice)
+	// Payment and the booking.created announcement run as the remaining saga steps
+	paymentResult, err := s.runCreation(ctx, &saga)
 	if err != nil {
 		return domain.Booking{}, domain.PaymentResult{}, err
 	}
@@ -79,23 +75,167 @@ func (s *Service) CreateBooking(ctx context.Context, cmd assembler.CreateCommand
 	return booking, paymentResult, nil
 }
 
-func (s *Service) CancelBooking(ctx context.Context, id uuid.UUID) error {
-	booking, err := s.repo.FindByID(ctx, id)
+// quote prices a stay of the room type through the pricing domain service.
+func (s *Service) quote(ctx context.Context, roomTypeID uuid.UUID, stay valueobject.DateRange, guests int) (float64, error) {
+	rt, err := s.hotels.GetRoomType(ctx, roomTypeID)
+	if err != nil {
+		return 0, errors.New("not_found", "room type not found")
+	}
+
+	// Use domain service for pricing
+	pricingService := domain.NewPricingService()
+	baseTotal := pricingService.CalculateTotalPrice(rt.BasePrice, stay.Nights(), guests)
+	return pricingService.ApplyDiscount(baseTotal, stay.Nights()), nil
+}
+
+// availabilityCheck ensures at least one bookable room stays free on every night of the stay.
+// The booking identified by excluding is ignored so an amendment does not compete with itself.
+func (s *Service) availabilityCheck(roomTypeID uuid.UUID, stay valueobject.DateRange, excluding uuid.UUID) domain.ReservationCheck {
+	return func(ctx context.Context, inv domain.Inventory) error {
+		rooms, err := inv.CountBookableRooms(ctx, roomTypeID)
+		if err != nil {
+			return err
+		}
+		overlapping, err := inv.FindOverlapping(ctx, roomTypeID, stay)
+		if err != nil {
+			return err
+		}
+		existing := make([]domain.Booking, 0, len(overlapping))
+		for _, b := range overlapping {
+			if b.ID != excluding {
+				existing = append(existing, b)
+			}
+		}
+		return domain.NewAvailabilityService().EnsureAvailable(rooms, stay, existing)
+	}
+}
+
+// ModifyBooking amends the dates, guests or room type of a confirmed booking.
+// The new terms are repriced and re-checked against inventory; a higher price
+// opens an adjustment payment while a lower one refunds the difference.
+func (s *Service) ModifyBooking(ctx context.Context, id uuid.UUID, cmd assembler.ModifyCommand) (assembler.Modification, error) {
+	bk, err := s.GetBooking(ctx, id)
+	if err != nil {
+		return assembler.Modification{}, err
+	}
+
+	after := bk.Terms()
+	if cmd.RoomTypeID != uuid.Nil {
+		after.RoomTypeID = cmd.RoomTypeID
+	}
+	if !cmd.CheckIn.IsZero() {
+		after.CheckIn = cmd.CheckIn
+	}
+	if !cmd.CheckOut.IsZero() {
+		after.CheckOut = cmd.CheckOut
+	}
+	if cmd.Guests > 0 {
+		after.Guests = cmd.Guests
+	}
+
+	stay, err := valueobject.NewDateRange(after.CheckIn, after.CheckOut)
 	if err != nil {
+		return assembler.Modification{}, err
+	}
+	if after.TotalPrice, err = s.quote(ctx, after.RoomTypeID, stay, after.Guests); err != nil {
+		return assembler.Modification{}, err
+	}
+	after.TotalNights = stay.Nights()
+
+	before := bk.Terms()
+	if err := bk.Modify(after); err != nil {
+		return assembler.Modification{}, err
+	}
+
+	if err := s.repo.Amend(ctx, bk, s.availabilityCheck(after.RoomTypeID, stay, bk.ID)); err != nil {
+		return assembler.Modification{}, err
+	}
+
+	bk.ClearEvents()
+
+	result := assembler.Modification{Booking: bk, PriceDelta: bk.TotalPrice - before.TotalPrice}
+	switch {
+	case result.PriceDelta > 0:
+		result.Payment, err = s.payments.RequestAdditional(ctx, s.paymentKey(ctx, bk), result.PriceDelta)
+	case result.PriceDelta < 0:
+		result.Refund, err = s.payments.Refund(ctx, s.paymentKey(ctx, bk), -result.PriceDelta, "booking_modified")
+	}
+	if err != nil {
+		// Without the price adjustment the new terms must not stand.
+		if err := s.revertModification(ctx, bk, before); err != nil {
+			return result, errors.New("bad_gateway", "booking modified but payment adjustment failed")
+		}
+		return assembler.Modification{}, errors.New("bad_gateway", "payment adjustment failed; booking left unchanged")
+	}
+	return result, nil
+}
+
+// revertModification amends a booking back to the terms it had before a
+// modification whose payment adjustment failed.
+func (s *Service) revertModification(ctx context.Context, bk domain.Booking, before domain.Terms) error {
+	if err := bk.Modify(before); err != nil {
 		return err
 	}
+	return s.repo.Amend(ctx, bk, s.availabilityCheck(before.RoomTypeID, before.Stay(), bk.ID))
+}
+
+// CancelBooking cancels a booking. Confirmed bookings are settled under the
+// cancellation policy of their rate; the refund of the refundable share is
+// stored in the outbox with the cancellation and performed by the relay.
+func (s *Service) CancelBooking(ctx context.Context, id uuid.UUID) (assembler.Cancellation, error) {
+	booking, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return assembler.Cancellation{}, err
+	}
+	// An unpaid reservation shares one invoice, so it is cancelled as a whole.
+	if booking.ReservationID != uuid.Nil && booking.Status == domain.StatusPendingPayment {
+		return assembler.Cancellation{}, errors.New("bad_request", "cancel the whole reservation until it is paid")
+	}
+	return s.cancel(ctx, booking, "user_requested")
+}
+
+func (s *Service) cancel(ctx context.Context, booking domain.Booking, reason string) (assembler.Cancellation, error) {
+	// A price increase still awaiting payment is voided, not refunded, and the
+	// cancellation is settled on what was actually collected. Reservation
+	// lines share the lead line's payments, so there only the refund cap of
+	// the payment service applies.
+	settled := booking
+	if booking.Status == domain.StatusConfirmed && booking.ReservationID == uuid.Nil {
+		voided, err := s.payments.VoidAdjustments(ctx, booking.ID)
+		if err != nil {
+			return assembler.Cancellation{}, errors.New("bad_gateway", "could not void pending payment adjustments")
+		}
+		settled.TotalPrice -= voided
+	}
+
+	policy := s.cancellationPolicy(ctx, booking.RoomTypeID)
+	quote := domain.NewCancellationService().Quote(policy, settled, time.Now())
 
 	// Use domain method
-	if err := booking.Cancel("user_requested"); err != nil {
-		return err
+	if err := booking.Cancel(reason, quote); err != nil {
+		return assembler.Cancellation{}, err
+	}
+	if quote.Refund > 0 {
+		booking.RecordEvent(domain.NewRefundRequested(booking.ID, s.paymentKey(ctx, booking), quote.Refund, reason))
 	}
 
 	if err := s.repo.Save(ctx, booking); err != nil {
-		return err
+		return assembler.Cancellation{}, err
 	}
 
-	s.publishEvents(ctx, booking.Events())
-	return nil
+	booking.ClearEvents()
+	return assembler.Cancellation{Booking: booking, Quote: quote}, nil
+}
+
+// cancellationPolicy resolves the policy of a room type, falling back to its
+// hotel and then to the platform default.
+func (s *Service) cancellationPolicy(ctx context.Context, roomTypeID uuid.UUID) valueobject.CancellationPolicy {
+	rt, err := s.hotels.GetRoomType(ctx, roomTypeID)
+	if err != nil {
+		return valueobject.DefaultCancellationPolicy
+	}
+	h, _ := s.hotels.GetHotel(ctx, rt.HotelID)
+	return rt.EffectiveCancellationPolicy(h)
 }
 
 func (s *Service) ApplyStatus(ctx context.Context, id uuid.UUID, status string) error {
@@ -105,36 +245,61 @@ func (s *Service) ApplyStatus(ctx context.Context, id uuid.UUID, status string)
 	if err != nil {
 		return err
 	}
+	// Repeated deliveries of the same payment outcome are no-ops.
+	if booking.Status == status {
+		return nil
+	}
+
+	// The lead line's payment settles every pending line of a reservation.
+	if status == domain.StatusConfirmed || status == domain.StatusCancelled {
+		for _, line := range s.siblings(ctx, booking)[1:] {
+			if err := s.applyStatus(ctx, line, status); err != nil {
+				return err
+			}
+		}
+	}
+	return s.applyStatus(ctx, booking, status)
+}
 
+func (s *Service) applyStatus(ctx context.Context, booking domain.Booking, status string) error {
 	var updateErr error
 	switch status {
 	case domain.StatusConfirmed:
 		updateErr = booking.Confirm()
 	case domain.StatusCancelled:
-		updateErr = booking.Cancel("admin_requested")
+		// Expired, no-show and cancelled bookings have nothing left to cancel,
+		// so a repeated failed-payment webhook settles as a no-op.
+		if !booking.HoldsInventory() {
+			return nil
+		}
+		_, err := s.cancel(ctx, booking, "admin_requested")
+		return err
 	case domain.StatusCheckedIn:
 		updateErr = booking.GuestCheckIn()
 
 	case domain.StatusCompleted:
 		updateErr = booking.Complete()
+	case domain.StatusExpired:
+		updateErr = booking.Expire()
 	default:
 		// Fallback for direct status update (legacy)
-		return s.repo.UpdateStatus(ctx, id, status)
+		return s.repo.UpdateStatus(ctx, booking.ID, status)
 	}
 
 	if updateErr != nil {
 		return updateErr
 	}
 
-	if err := s.repo.Save(ctx, booking); err != nil {
+	if err := s.persist(ctx, booking, ""); err != nil {
 		return err
 	}
 
-	s.publishEvents(ctx, booking.Events())
 	return nil
 }
 
-func (s *Service) Checkpoint(ctx context.Context, id uuid.UUID, action string) error {
+// Checkpoint applies a front-desk action. On check_in the guest gets
+// roomNumber, or the first free room of the booked type when it is empty.
+func (s *Service) Checkpoint(ctx context.Context, id uuid.UUID, action, roomNumber string) error {
 	bk, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -158,14 +323,32 @@ func (s *Service) Checkpoint(ctx context.Context, id uuid.UUID, action string) e
 		return updateErr
 	}
 
-	if err := s.repo.Save(ctx, bk); err != nil {
+	if err := s.persist(ctx, bk, roomNumber); err != nil {
 		return err
 	}
 
-	s.publishEvents(ctx, bk.Events())
 	return nil
 }
 
+// persist saves a booking after a transition, assigning a room on check-in
+// and releasing it on completion.
+func (s *Service) persist(ctx context.Context, b domain.Booking, roomNumber string) error {
+	switch b.Status {
+	case domain.StatusCheckedIn:
+		_, err := s.repo.AssignRoom(ctx, b, domain.PickRoom(roomNumber))
+		return err
+	case domain.StatusCompleted:
+		return s.repo.ReleaseRoom(ctx, b)
+	default:
+		return s.repo.Save(ctx, b)
+	}
+}
+
+// GetRoomAssignment returns the room assigned to a booking at check-in.
+func (s *Service) GetRoomAssignment(ctx context.Context, bookingID uuid.UUID) (domain.RoomAssignment, error) {
+	return s.repo.FindAssignment(ctx, bookingID)
+}
+
 func (s *Service) GetBooking(ctx context.Context, id uuid.UUID) (domain.Booking, error) {
 	b, err := s.repo.FindByID(ctx, id)
 	if err != nil {
@@ -177,6 +360,24 @@ func (s *Service) GetBooking(ctx context.Context, id uuid.UUID) (domain.Booking,
 	return b, nil
 }
 
+// UserBookings returns every booking of a user, for personal data exports.
+func (s *Service) UserBookings(ctx context.Context, userID uuid.UUID) ([]domain.Booking, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// BookingHotel returns the hotel a booking is at, for permission checks.
+func (s *Service) BookingHotel(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	b, err := s.GetBooking(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	rt, err := s.hotels.GetRoomType(ctx, b.RoomTypeID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return rt.HotelID, nil
+}
+
 func (s *Service) ListBookings(ctx context.Context, opts query.Options) ([]domain.Booking, error) {
 	bks, err := s.repo.List(ctx, opts.Normalize(50))
 	if err != nil {
@@ -185,48 +386,142 @@ func (s *Service) ListBookings(ctx context.Context, opts query.Options) ([]domai
 	return bks, nil
 }
 
-func (s *Service) publishEvents(ctx context.Context, events []pkgDomain.DomainEvent) {
-	for _, event := range events {
-		_ = s.notifier.Notify(ctx, event.EventType(), event)
-	}
+// SearchBookings returns one page of bookings matching f with the total match count.
+func (s *Service) SearchBookings(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	f.Page = f.Page.Normalize(50)
+	return s.repo.Search(ctx, f)
 }
 
-// AutoCheckout processes bookings that should be automatically checked out.
-// It finds all bookings with checkout_date = today and status = checked_in,
-// then completes them automatically.
+// checkoutBatchSize bounds how many bookings one checkout query loads.
+const checkoutBatchSize = 100
+
+// AutoCheckout completes checked_in bookings whose check-out date is today or
+// earlier and returns how many it completed.
 func (s *Service) AutoCheckout(ctx context.Context) (int, error) {
-	today := time.Now().Truncate(24 * time.Hour)
-	
-	// Find bookings that need auto-checkout
-	// We'll use the repository's List method with a filter
-	// For simplicity, we'll get all bookings and filter in memory
-	// In production, you'd want to add a specific repository method
-	allBookings, err := s.repo.List(ctx, query.Options{Limit: 1000})
-	if err != nil {
-		return 0, err
-	}
-
-	count := 0
-	for _, booking := range allBookings {
-		// Check if booking should be auto-checked-out
-		checkoutDate := booking.CheckOut.Truncate(24 * time.Hour)
-		if checkoutDate.Equal(today) && booking.Status == string(valueobject.StatusCheckedIn) {
-			// Complete the booking
+	report, err := s.CheckoutDue(ctx, time.Now())
+	return report.Completed, err
+}
+
+// CheckoutDue completes checked_in bookings whose check-out date is on or
+// before day, in batches. Overdue bookings missed by an earlier run are
+// picked up too. A booking already completed by a concurrent run is skipped,
+// and other per-booking failures are reported without stopping the run.
+func (s *Service) CheckoutDue(ctx context.Context, day time.Time) (assembler.CheckoutReport, error) {
+	report := assembler.CheckoutReport{Failed: map[uuid.UUID]error{}}
+	before := day.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	after := uuid.Nil
+	for {
+		batch, err := s.repo.FindDueForCheckout(ctx, before, after, checkoutBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, booking := range batch {
 			if err := booking.Complete(); err != nil {
-				// Log error but continue with other bookings
+				report.Failed[booking.ID] = err
 				continue
 			}
 
-			if err := s.repo.Save(ctx, booking); err != nil {
-				// Log error but continue
+			if err := s.persist(ctx, booking, ""); err != nil {
+				if errors.FromError(err).Code == "conflict" {
+					report.Skipped++
+				} else {
+					report.Failed[booking.ID] = err
+				}
 				continue
 			}
 
-			s.publishEvents(ctx, booking.Events())
 			booking.ClearEvents()
-			count++
+			report.Completed++
+		}
+		if len(batch) < checkoutBatchSize {
+			return report, nil
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// ExpireUnpaid releases pending_payment bookings created before cutoff.
+// The related payment is failed first so a late invoice settlement cannot
+// confirm a booking whose inventory was already released; bookings whose
+// payment was settled meanwhile are skipped, and other failures are reported
+// and retried on the next run.
+func (s *Service) ExpireUnpaid(ctx context.Context, cutoff time.Time) (assembler.BatchReport, error) {
+	report := assembler.BatchReport{Failed: map[uuid.UUID]error{}}
+	pending, err := s.repo.FindPendingBefore(ctx, cutoff)
+	if err != nil {
+		return report, err
+	}
+
+	for _, booking := range pending {
+		if err := s.payments.Expire(ctx, s.paymentKey(ctx, booking)); err != nil {
+			switch errors.FromError(err).Code {
+			case "not_found":
+			case "conflict":
+				report.Skipped++
+				continue
+			default:
+				report.Failed[booking.ID] = err
+				continue
+			}
 		}
+
+		if err := booking.Expire(); err != nil {
+			report.Failed[booking.ID] = err
+			continue
+		}
+
+		if err := s.repo.Transition(ctx, booking, domain.StatusPendingPayment); err != nil {
+			if errors.FromError(err).Code == "conflict" {
+				report.Skipped++
+			} else {
+				report.Failed[booking.ID] = err
+			}
+			continue
+		}
+
+		booking.ClearEvents()
+		report.Processed++
+	}
+
+	return report, nil
+}
+
+// MarkNoShows closes confirmed bookings whose check-in date is before cutoff
+// without the guest arriving. Their inventory is released, the policy charge
+// is kept and the remaining nights are refunded through the payment service.
+// The status change is conditional, so of two racing runs only one marks a
+// booking, and its refund request is stored in the outbox with the change:
+// the relay performs it and retries it until the payment service accepts.
+func (s *Service) MarkNoShows(ctx context.Context, cutoff time.Time, policy domain.NoShowPolicy) (assembler.BatchReport, error) {
+	report := assembler.BatchReport{Failed: map[uuid.UUID]error{}}
+	missed, err := s.repo.FindNoShows(ctx, cutoff)
+	if err != nil {
+		return report, err
+	}
+
+	for _, booking := range missed {
+		charge := policy.Quote(booking)
+		if err := booking.MarkNoShow(charge); err != nil {
+			report.Failed[booking.ID] = err
+			continue
+		}
+		if charge.Refund > 0 {
+			booking.RecordEvent(domain.NewRefundRequested(booking.ID, s.paymentKey(ctx, booking), charge.Refund, "no_show"))
+		}
+
+		if err := s.repo.Transition(ctx, booking, domain.StatusConfirmed); err != nil {
+			if errors.FromError(err).Code == "conflict" {
+				report.Skipped++
+			} else {
+				report.Failed[booking.ID] = err
+			}
+			continue
+		}
+
+		booking.ClearEvents()
+		report.Processed++
 	}
 
-	return count, nil
+	return report, nil
 }
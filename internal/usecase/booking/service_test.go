@@ -2,7 +2,9 @@ package booking_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
@@ -14,13 +16,14 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/booking/assembler"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestCreateBooking(t *testing.T) {
 	roomTypeID := uuid.New()
-	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
 	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 500000}}
 	payment := &paymentGatewayStub{}
 	notifier := &notificationGatewayStub{}
@@ -34,7 +37,6 @@ func TestCreateBooking(t *testing.T) {
 		{
 			name: "happy path",
 			req: dto.BookingRequest{
-				UserID:     uuid.New().String(),
 				RoomTypeID: roomTypeID.String(),
 				CheckIn:    dto.Date{Time: time.Now().Add(24 * time.Hour)},
 				CheckOut:   dto.Date{Time: time.Now().Add(48 * time.Hour)},
@@ -43,7 +45,6 @@ func TestCreateBooking(t *testing.T) {
 		{
 			name: "invalid dates",
 			req: dto.BookingRequest{
-				UserID:     uuid.New().String(),
 				RoomTypeID: roomTypeID.String(),
 				CheckIn:    dto.Date{Time: time.Now().Add(48 * time.Hour)},
 				CheckOut:   dto.Date{Time: time.Now().Add(24 * time.Hour)},
@@ -53,7 +54,6 @@ func TestCreateBooking(t *testing.T) {
 		{
 			name: "missing room type",
 			req: dto.BookingRequest{
-				UserID:     uuid.New().String(),
 				RoomTypeID: uuid.New().String(),
 				CheckIn:    dto.Date{Time: time.Now().Add(24 * time.Hour)},
 				CheckOut:   dto.Date{Time: time.Now().Add(48 * time.Hour)},
@@ -70,7 +70,7 @@ func TestCreateBooking(t *testing.T) {
 				hotelRepo.err = nil
 			}
 
-			cmd, err := assembler.FromRequest(tt.req)
+			cmd, err := assembler.FromRequest(uuid.New(), tt.req)
 			if err == nil {
 				_, _, err = service.CreateBooking(context.Background(), cmd)
 			}
@@ -83,6 +83,53 @@ func TestCreateBooking(t *testing.T) {
 	}
 }
 
+func TestCreateBookingRejectsOverbooking(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 500000}}
+	service := booking.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC)
+	cmd := assembler.CreateCommand{
+		UserID:     uuid.New(),
+		RoomTypeID: roomTypeID,
+		CheckIn:    checkIn,
+		CheckOut:   checkIn.AddDate(0, 0, 3),
+		Guests:     2,
+	}
+
+	_, _, err := service.CreateBooking(context.Background(), cmd)
+	require.NoError(t, err)
+
+	// overlapping stay on the only room is rejected
+	overlap := cmd
+	overlap.CheckIn = checkIn.AddDate(0, 0, 2)
+	overlap.CheckOut = checkIn.AddDate(0, 0, 4)
+	_, _, err = service.CreateBooking(context.Background(), overlap)
+	require.Error(t, err)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+
+	// back-to-back stay starting on checkout day is fine
+	next := cmd
+	next.CheckIn = cmd.CheckOut
+	next.CheckOut = cmd.CheckOut.AddDate(0, 0, 1)
+	_, _, err = service.CreateBooking(context.Background(), next)
+	require.NoError(t, err)
+
+	// cancelled bookings release their nights
+	for id, bk := range repo.store {
+		if bk.CheckIn.Equal(checkIn) {
+			bk.Status = domain.StatusCancelled
+			repo.store[id] = bk
+		}
+	}
+	_, _, err = service.CreateBooking(context.Background(), overlap)
+	require.Error(t, err) // still collides with the back-to-back stay on night 4
+	overlap.CheckOut = checkIn.AddDate(0, 0, 3)
+	_, _, err = service.CreateBooking(context.Background(), overlap)
+	require.NoError(t, err)
+}
+
 func TestApplyStatusInvalidTransition(t *testing.T) {
 	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
 	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
@@ -97,21 +144,71 @@ func TestApplyStatusInvalidTransition(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestApplyStatusCancelledIsNoOpForReleasedBookings(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	service := booking.NewService(repo, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	for _, status := range valueobject.ReleasedBookingStatuses {
+		id := uuid.New()
+		repo.store[id] = domain.Booking{ID: id, Status: status}
+		require.NoError(t, service.ApplyStatus(context.Background(), id, domain.StatusCancelled))
+		require.Equal(t, status, repo.store[id].Status)
+	}
+	require.Empty(t, repo.events())
+}
+
 // stubs
 
 type bookingRepoStub struct {
-	store map[uuid.UUID]domain.Booking
+	store       map[uuid.UUID]domain.Booking
+	rooms       int
+	free        []domain.RoomCandidate
+	assignments map[uuid.UUID]domain.RoomAssignment
+	groups      map[uuid.UUID]domain.Reservation
+	outbox      []domain.OutboxMessage
+	sagas       map[uuid.UUID]domain.Saga
+	// announceErr fails saves that publish a saga's creation events.
+	announceErr error
+	// beforeTransition simulates a concurrent writer racing Transition.
+	beforeTransition func()
 }
 
-func (b *bookingRepoStub) Create(ctx context.Context, bk domain.Booking) error {
+// save stores bk and its pending events, like the GORM repository's outbox.
+func (b *bookingRepoStub) save(bk domain.Booking) {
+	b.publish(bk)
 	b.store[bk.ID] = bk
+}
+
+func (b *bookingRepoStub) publish(bk domain.Booking) {
+	for _, event := range bk.Events() {
+		payload, _ := json.Marshal(event)
+		b.outbox = append(b.outbox, domain.OutboxMessage{
+			ID:          uuid.New(),
+			AggregateID: event.AggregateID(),
+			EventType:   event.EventType(),
+			Payload:     payload,
+			Status:      domain.OutboxPending,
+		})
+	}
+}
+
+func (b *bookingRepoStub) events() []string {
+	var types []string
+	for _, m := range b.outbox {
+		types = append(types, m.EventType)
+	}
+	return types
+}
+
+func (b *bookingRepoStub) Create(ctx context.Context, bk domain.Booking) error {
+	b.save(bk)
 	return nil
 }
 
 func (b *bookingRepoStub) FindByID(ctx context.Context, id uuid.UUID) (domain.Booking, error) {
 	bk, ok := b.store[id]
 	if !ok {
-		return domain.Booking{}, errors.New("not found")
+		return domain.Booking{}, pkgErrors.New("not_found", "booking not found")
 	}
 	return bk, nil
 }
@@ -141,11 +238,263 @@ func (b *bookingRepoStub) List(ctx context.Context, _ query.Options) ([]domain.B
 	}
 	return out, nil
 }
+func (b *bookingRepoStub) FindPendingBefore(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusPendingPayment && v.CreatedAt.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) FindNoShows(ctx context.Context, cutoff time.Time) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusConfirmed && v.CheckIn.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+func (b *bookingRepoStub) FindAssignment(ctx context.Context, bookingID uuid.UUID) (domain.RoomAssignment, error) {
+	a, ok := b.assignments[bookingID]
+	if !ok {
+		return domain.RoomAssignment{}, pkgErrors.New("not_found", "room assignment not found")
+	}
+	return a, nil
+}
+
+func (b *bookingRepoStub) AssignRoom(ctx context.Context, bk domain.Booking, pick domain.RoomPicker) (domain.RoomAssignment, error) {
+	room, err := pick(b.free)
+	if err != nil {
+		return domain.RoomAssignment{}, err
+	}
+	if b.assignments == nil {
+		b.assignments = map[uuid.UUID]domain.RoomAssignment{}
+	}
+	a := domain.RoomAssignment{ID: uuid.New(), BookingID: bk.ID, RoomID: room.ID, RoomNumber: room.Number, CheckedInAt: time.Now()}
+	b.assignments[bk.ID] = a
+	b.save(bk)
+	return a, nil
+}
+
+func (b *bookingRepoStub) ReleaseRoom(ctx context.Context, bk domain.Booking) error {
+	if b.store[bk.ID].Status != domain.StatusCheckedIn {
+		return pkgErrors.New("conflict", "booking already checked out")
+	}
+	if a, ok := b.assignments[bk.ID]; ok {
+		now := time.Now()
+		a.CheckedOutAt = &now
+		b.assignments[bk.ID] = a
+	}
+	b.save(bk)
+	return nil
+}
+
 func (b *bookingRepoStub) Save(ctx context.Context, bk domain.Booking) error {
-	b.store[bk.ID] = bk
+	b.save(bk)
+	return nil
+}
+
+func (b *bookingRepoStub) Transition(ctx context.Context, bk domain.Booking, from string) error {
+	if b.beforeTransition != nil {
+		b.beforeTransition()
+	}
+	if b.store[bk.ID].Status != from {
+		return pkgErrors.New("conflict", "booking is no longer "+from)
+	}
+	b.save(bk)
+	return nil
+}
+
+func (b *bookingRepoStub) Reserve(ctx context.Context, bk domain.Booking, check domain.ReservationCheck) error {
+	if err := check(ctx, b); err != nil {
+		return err
+	}
+	b.save(bk)
+	return nil
+}
+
+func (b *bookingRepoStub) Amend(ctx context.Context, bk domain.Booking, check domain.ReservationCheck) error {
+	if err := check(ctx, b); err != nil {
+		return err
+	}
+	b.save(bk)
 	return nil
 }
 
+func (b *bookingRepoStub) ReserveGroup(ctx context.Context, r domain.Reservation, check domain.ReservationCheck) error {
+	if err := check(ctx, b); err != nil {
+		return err
+	}
+	if b.groups == nil {
+		b.groups = map[uuid.UUID]domain.Reservation{}
+	}
+	b.groups[r.ID] = r
+	for _, line := range r.Lines {
+		b.save(line)
+	}
+	return nil
+}
+
+func (b *bookingRepoStub) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if f.UserID == uuid.Nil || v.UserID == f.UserID {
+			out = append(out, v)
+		}
+	}
+	return domain.SearchResult{Bookings: out, Total: int64(len(out))}, nil
+}
+
+func (b *bookingRepoStub) FindDueForCheckout(ctx context.Context, before time.Time, after uuid.UUID, limit int) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.Status == domain.StatusCheckedIn && v.CheckOut.Before(before) && (after == uuid.Nil || v.ID.String() > after.String()) {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID.String() < out[j].ID.String() })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (b *bookingRepoStub) FindReservation(ctx context.Context, id uuid.UUID) (domain.Reservation, error) {
+	r, ok := b.groups[id]
+	if !ok {
+		return domain.Reservation{}, pkgErrors.New("not_found", "reservation not found")
+	}
+	lines := make([]domain.Booking, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		lines = append(lines, b.store[line.ID])
+	}
+	r.Lines = lines
+	return r, nil
+}
+
+func (b *bookingRepoStub) ClaimOutbox(_ context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxMessage, error) {
+	var out []domain.OutboxMessage
+	for i, m := range b.outbox {
+		if len(out) == limit {
+			break
+		}
+		if m.Status == domain.OutboxPending && !m.NextAttemptAt.After(now) {
+			b.outbox[i].NextAttemptAt = now.Add(lease)
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (b *bookingRepoStub) MarkDelivered(_ context.Context, id uuid.UUID, at time.Time) error {
+	for i := range b.outbox {
+		if b.outbox[i].ID == id {
+			b.outbox[i].Status = domain.OutboxDelivered
+			b.outbox[i].DeliveredAt = &at
+		}
+	}
+	return nil
+}
+
+func (b *bookingRepoStub) MarkAttemptFailed(_ context.Context, id uuid.UUID, reason string, next time.Time, giveUp bool) error {
+	for i := range b.outbox {
+		if b.outbox[i].ID == id {
+			b.outbox[i].Attempts++
+			b.outbox[i].LastError = reason
+			b.outbox[i].NextAttemptAt = next
+			if giveUp {
+				b.outbox[i].Status = domain.OutboxFailed
+			}
+		}
+	}
+	return nil
+}
+
+func (b *bookingRepoStub) ListOutbox(_ context.Context, status string, _ query.Options) ([]domain.OutboxMessage, error) {
+	var out []domain.OutboxMessage
+	for _, m := range b.outbox {
+		if status == "" || m.Status == status {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (b *bookingRepoStub) FindOutbox(_ context.Context, id uuid.UUID) (domain.OutboxMessage, error) {
+	for _, m := range b.outbox {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return domain.OutboxMessage{}, pkgErrors.New("not_found", "outbox message not found")
+}
+
+func (b *bookingRepoStub) ReplayOutbox(_ context.Context, id uuid.UUID) error {
+	for i := range b.outbox {
+		if b.outbox[i].ID == id {
+			b.outbox[i].Status = domain.OutboxPending
+			b.outbox[i].Attempts = 0
+			b.outbox[i].NextAttemptAt = time.Now()
+			return nil
+		}
+	}
+	return pkgErrors.New("not_found", "outbox message not found")
+}
+
+func (b *bookingRepoStub) SaveSaga(_ context.Context, saga domain.Saga, publish ...domain.Booking) error {
+	if b.sagas == nil {
+		b.sagas = map[uuid.UUID]domain.Saga{}
+	}
+	if len(publish) > 0 && b.announceErr != nil {
+		return b.announceErr
+	}
+	saga.UpdatedAt = time.Now()
+	b.sagas[saga.ID] = saga
+	for _, bk := range publish {
+		b.publish(bk)
+	}
+	return nil
+}
+
+func (b *bookingRepoStub) FindSaga(_ context.Context, id uuid.UUID) (domain.Saga, error) {
+	saga, ok := b.sagas[id]
+	if !ok {
+		return domain.Saga{}, pkgErrors.New("not_found", "saga not found")
+	}
+	return saga, nil
+}
+
+func (b *bookingRepoStub) ClaimStaleSagas(_ context.Context, before, now time.Time, limit int) ([]domain.Saga, error) {
+	var out []domain.Saga
+	for id, saga := range b.sagas {
+		if len(out) == limit {
+			break
+		}
+		if !saga.Finished() && saga.UpdatedAt.Before(before) {
+			saga.UpdatedAt = now
+			b.sagas[id] = saga
+			out = append(out, saga)
+		}
+	}
+	return out, nil
+}
+
+func (b *bookingRepoStub) CountBookableRooms(context.Context, uuid.UUID) (int, error) {
+	return b.rooms, nil
+}
+
+func (b *bookingRepoStub) FindOverlapping(ctx context.Context, roomTypeID uuid.UUID, stay valueobject.DateRange) ([]domain.Booking, error) {
+	var out []domain.Booking
+	for _, v := range b.store {
+		if v.RoomTypeID == roomTypeID && v.Stay().Overlaps(stay) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
 type hotelRepoStub struct {
 	roomType hdomain.RoomType
 	err      error
@@ -182,10 +531,29 @@ func (h *hotelRepoStub) GetRoom(context.Context, uuid.UUID) (hdomain.Room, error
 }
 func (h *hotelRepoStub) UpdateRoom(context.Context, uuid.UUID, hdomain.Room) error { return nil }
 func (h *hotelRepoStub) DeleteRoom(context.Context, uuid.UUID) error               { return nil }
+func (h *hotelRepoStub) NightlyInventory(context.Context, []uuid.UUID, valueobject.DateRange) ([]hdomain.NightlyInventory, error) {
+	return nil, nil
+}
 
-type paymentGatewayStub struct{}
+type paymentGatewayStub struct {
+	initiated   []float64
+	initiateErr error
+	expired     []uuid.UUID
+	expireErr   error
+	charged     []float64
+	refunded    []float64
+	refundFor   []uuid.UUID
+	chargeErr   error
+	refundErr   error
+	// unpaid is the total of adjustments awaiting payment, voided on cancel.
+	unpaid float64
+}
 
-func (p *paymentGatewayStub) Initiate(context.Context, uuid.UUID, float64) (domain.PaymentResult, error) {
+func (p *paymentGatewayStub) Initiate(_ context.Context, _ uuid.UUID, amount float64) (domain.PaymentResult, error) {
+	if p.initiateErr != nil {
+		return domain.PaymentResult{}, p.initiateErr
+	}
+	p.initiated = append(p.initiated, amount)
 	return domain.PaymentResult{
 		ID:         uuid.New(),
 		Status:     "pending",
@@ -194,9 +562,47 @@ func (p *paymentGatewayStub) Initiate(context.Context, uuid.UUID, float64) (doma
 	}, nil
 }
 
-type notificationGatewayStub struct{}
+func (p *paymentGatewayStub) Expire(_ context.Context, bookingID uuid.UUID) error {
+	if p.expireErr != nil {
+		return p.expireErr
+	}
+	p.expired = append(p.expired, bookingID)
+	return nil
+}
+
+func (p *paymentGatewayStub) RequestAdditional(_ context.Context, _ uuid.UUID, amount float64) (domain.PaymentResult, error) {
+	if p.chargeErr != nil {
+		return domain.PaymentResult{}, p.chargeErr
+	}
+	p.charged = append(p.charged, amount)
+	return domain.PaymentResult{ID: uuid.New(), Status: "pending", Provider: "mock", PaymentURL: "http://mock"}, nil
+}
+
+func (p *paymentGatewayStub) Refund(_ context.Context, bookingID uuid.UUID, amount float64, _ string) (domain.RefundResult, error) {
+	if p.refundErr != nil {
+		return domain.RefundResult{}, p.refundErr
+	}
+	p.refunded = append(p.refunded, amount)
+	p.refundFor = append(p.refundFor, bookingID)
+	return domain.RefundResult{PaymentID: uuid.New(), Amount: amount, Status: "refunded"}, nil
+}
+
+func (p *paymentGatewayStub) VoidAdjustments(context.Context, uuid.UUID) (float64, error) {
+	voided := p.unpaid
+	p.unpaid = 0
+	return voided, nil
+}
+
+type notificationGatewayStub struct {
+	events []string
+	err    error
+}
 
-func (n *notificationGatewayStub) Notify(context.Context, string, any) error {
+func (n *notificationGatewayStub) Notify(_ context.Context, event string, _ any) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.events = append(n.events, event)
 	return nil
 }
 
@@ -275,3 +681,635 @@ func TestAutoCheckoutNoBookings(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, count)
 }
+
+func TestCheckoutDueProcessesEveryBatch(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := booking.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	today := time.Now().Truncate(24 * time.Hour)
+	for i := 0; i < 250; i++ {
+		b := domain.Booking{ID: uuid.New(), CheckIn: today.AddDate(0, 0, -3), CheckOut: today.AddDate(0, 0, -(i % 3)), Status: domain.StatusCheckedIn}
+		repo.store[b.ID] = b
+	}
+
+	report, err := service.CheckoutDue(context.Background(), today)
+	require.NoError(t, err)
+	require.Equal(t, 250, report.Completed)
+	require.Empty(t, report.Failed)
+	for _, b := range repo.store {
+		require.Equal(t, domain.StatusCompleted, b.Status)
+	}
+
+	// a second run finds nothing left to do
+	report, err = service.CheckoutDue(context.Background(), today)
+	require.NoError(t, err)
+	require.Zero(t, report.Completed)
+}
+
+func TestExpireUnpaid(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	payment := &paymentGatewayStub{}
+	notifier := &notificationGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, notifier)
+
+	now := time.Now()
+	stale := domain.Booking{ID: uuid.New(), Status: domain.StatusPendingPayment, CreatedAt: now.Add(-time.Hour)}
+	fresh := domain.Booking{ID: uuid.New(), Status: domain.StatusPendingPayment, CreatedAt: now}
+	paid := domain.Booking{ID: uuid.New(), Status: domain.StatusConfirmed, CreatedAt: now.Add(-time.Hour)}
+	for _, b := range []domain.Booking{stale, fresh, paid} {
+		repo.store[b.ID] = b
+	}
+
+	report, err := service.ExpireUnpaid(context.Background(), now.Add(-15*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Processed)
+	require.Equal(t, domain.StatusExpired, repo.store[stale.ID].Status)
+	require.Equal(t, domain.StatusPendingPayment, repo.store[fresh.ID].Status)
+	require.Equal(t, domain.StatusConfirmed, repo.store[paid.ID].Status)
+	require.Equal(t, []uuid.UUID{stale.ID}, payment.expired)
+	require.Contains(t, repo.events(), domain.EventTypeBookingExpired)
+}
+
+func TestExpireUnpaidSkipsSettledPayment(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	payment := &paymentGatewayStub{expireErr: pkgErrors.New("conflict", "payment already settled")}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	stale := domain.Booking{ID: uuid.New(), Status: domain.StatusPendingPayment, CreatedAt: time.Now().Add(-time.Hour)}
+	repo.store[stale.ID] = stale
+
+	report, err := service.ExpireUnpaid(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, report.Processed)
+	require.Equal(t, 1, report.Skipped)
+	require.Equal(t, domain.StatusPendingPayment, repo.store[stale.ID].Status)
+
+	// other payment failures are reported and retried on the next run
+	payment.expireErr = errors.New("payment service down")
+	report, err = service.ExpireUnpaid(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.EqualError(t, report.Failed[stale.ID], "payment service down")
+	require.Equal(t, domain.StatusPendingPayment, repo.store[stale.ID].Status)
+}
+
+func TestCheckpointAssignsAndReleasesRoom(t *testing.T) {
+	room := domain.RoomCandidate{ID: uuid.New(), Number: "201"}
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, free: []domain.RoomCandidate{{ID: uuid.New(), Number: "101"}, room}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 500000}}
+	service := booking.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	id := uuid.New()
+	repo.store[id] = domain.Booking{ID: id, Status: domain.StatusConfirmed}
+
+	err := service.Checkpoint(context.Background(), id, "check_in", "999")
+	require.Error(t, err)
+	require.Equal(t, domain.StatusConfirmed, repo.store[id].Status)
+
+	require.NoError(t, service.Checkpoint(context.Background(), id, "check_in", "201"))
+	assignment, err := service.GetRoomAssignment(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, room.ID, assignment.RoomID)
+	require.True(t, assignment.Active())
+
+	require.NoError(t, service.Checkpoint(context.Background(), id, "complete", ""))
+	assignment, err = service.GetRoomAssignment(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, assignment.Active())
+}
+
+func TestModifyBooking(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	notifier := &notificationGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, notifier)
+
+	checkIn := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	repo.store[id] = domain.Booking{
+		ID: id, RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1),
+		Status: domain.StatusConfirmed, Guests: 2, TotalNights: 1, TotalPrice: 100000,
+	}
+
+	// extending the stay does not conflict with the booking's own nights
+	res, err := service.ModifyBooking(context.Background(), id, assembler.ModifyCommand{CheckOut: checkIn.AddDate(0, 0, 2)})
+	require.NoError(t, err)
+	require.Equal(t, 200000.0, res.Booking.TotalPrice)
+	require.Equal(t, 100000.0, res.PriceDelta)
+	require.NotEqual(t, uuid.Nil, res.Payment.ID)
+	require.Equal(t, []float64{100000}, payment.charged)
+	require.Contains(t, repo.events(), domain.EventTypeBookingModified)
+
+	// shortening it again refunds the difference
+	res, err = service.ModifyBooking(context.Background(), id, assembler.ModifyCommand{CheckOut: checkIn.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	require.Equal(t, -100000.0, res.PriceDelta)
+	require.Equal(t, []float64{100000}, payment.refunded)
+	require.Equal(t, 1, repo.store[id].TotalNights)
+
+	_, err = service.ModifyBooking(context.Background(), id, assembler.ModifyCommand{CheckOut: checkIn.AddDate(0, 0, 1)})
+	require.Error(t, err)
+	require.Equal(t, "bad_request", pkgErrors.FromError(err).Code)
+
+	// a failed charge leaves the booking on its previous terms
+	payment.chargeErr = errors.New("payment service down")
+	_, err = service.ModifyBooking(context.Background(), id, assembler.ModifyCommand{CheckOut: checkIn.AddDate(0, 0, 3)})
+	require.Equal(t, "bad_gateway", pkgErrors.FromError(err).Code)
+	require.Equal(t, 1, repo.store[id].TotalNights)
+	require.Equal(t, 100000.0, repo.store[id].TotalPrice)
+	require.True(t, repo.store[id].CheckOut.Equal(checkIn.AddDate(0, 0, 1)))
+}
+
+func TestModifyBookingRejectsUnavailableDates(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	id, other := uuid.New(), uuid.New()
+	original := domain.Booking{
+		ID: id, RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1),
+		Status: domain.StatusConfirmed, Guests: 2, TotalNights: 1, TotalPrice: 100000,
+	}
+	repo.store[id] = original
+	repo.store[other] = domain.Booking{
+		ID: other, RoomTypeID: roomTypeID, CheckIn: checkIn.AddDate(0, 0, 1), CheckOut: checkIn.AddDate(0, 0, 2),
+		Status: domain.StatusConfirmed, Guests: 1, TotalNights: 1, TotalPrice: 100000,
+	}
+
+	_, err := service.ModifyBooking(context.Background(), id, assembler.ModifyCommand{CheckOut: checkIn.AddDate(0, 0, 2)})
+	require.Error(t, err)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+	require.Equal(t, original.CheckOut, repo.store[id].CheckOut)
+	require.Empty(t, payment.charged)
+
+	pending := uuid.New()
+	repo.store[pending] = domain.Booking{ID: pending, RoomTypeID: roomTypeID, CheckIn: checkIn.AddDate(0, 0, 5), CheckOut: checkIn.AddDate(0, 0, 6), Status: domain.StatusPendingPayment, Guests: 1}
+	_, err = service.ModifyBooking(context.Background(), pending, assembler.ModifyCommand{Guests: 3})
+	require.Error(t, err)
+}
+
+func TestCancelBookingAppliesCancellationPolicy(t *testing.T) {
+	roomTypeID := uuid.New()
+	policy := valueobject.CancellationPolicy{FreeCancellationDays: 7, PenaltyPercent: 50}
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000, CancellationPolicy: &policy}}
+	payment := &paymentGatewayStub{}
+	notifier := &notificationGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, notifier)
+
+	now := time.Now()
+	late := domain.Booking{ID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: now.AddDate(0, 0, 2), CheckOut: now.AddDate(0, 0, 4), Status: domain.StatusConfirmed, TotalPrice: 200000}
+	early := domain.Booking{ID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: now.AddDate(0, 0, 30), CheckOut: now.AddDate(0, 0, 32), Status: domain.StatusConfirmed, TotalPrice: 200000}
+	unpaid := domain.Booking{ID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: now.AddDate(0, 0, 1), CheckOut: now.AddDate(0, 0, 2), Status: domain.StatusPendingPayment, TotalPrice: 100000}
+	staying := domain.Booking{ID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: now, CheckOut: now.AddDate(0, 0, 1), Status: domain.StatusCheckedIn, TotalPrice: 100000}
+	upgraded := domain.Booking{ID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: now.AddDate(0, 0, 30), CheckOut: now.AddDate(0, 0, 33), Status: domain.StatusConfirmed, TotalPrice: 300000}
+	for _, b := range []domain.Booking{late, early, unpaid, staying, upgraded} {
+		repo.store[b.ID] = b
+	}
+
+	res, err := service.CancelBooking(context.Background(), late.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusCancelled, res.Booking.Status)
+	require.Equal(t, 100000.0, res.Quote.Penalty)
+	require.Equal(t, 100000.0, res.Quote.Refund)
+
+	res, err = service.CancelBooking(context.Background(), early.ID)
+	require.NoError(t, err)
+	require.Zero(t, res.Quote.Penalty)
+	require.Equal(t, 200000.0, res.Quote.Refund)
+
+	// the unpaid part of a modification is voided instead of refunded
+	payment.unpaid = 100000
+	res, err = service.CancelBooking(context.Background(), upgraded.ID)
+	require.NoError(t, err)
+	require.Equal(t, 200000.0, res.Quote.Refund)
+	require.Zero(t, payment.unpaid)
+
+	res, err = service.CancelBooking(context.Background(), unpaid.ID)
+	require.NoError(t, err)
+	require.Zero(t, res.Quote.Refund)
+	require.Contains(t, repo.events(), domain.EventTypeBookingCancelled)
+
+	// refunds are queued with the cancellation and retried by the relay until they succeed
+	require.Empty(t, payment.refunded)
+	payment.refundErr = errors.New("payment service down")
+	report, err := service.RelayOutbox(context.Background(), 50)
+	require.NoError(t, err)
+	require.Len(t, report.Errors, 3)
+	for _, msg := range repo.outbox {
+		if msg.EventType == domain.EventTypeRefundRequested {
+			_, err := service.ReplayOutbox(context.Background(), msg.ID)
+			require.NoError(t, err)
+		}
+	}
+	payment.refundErr = nil
+	_, err = service.RelayOutbox(context.Background(), 50)
+	require.NoError(t, err)
+	require.Equal(t, []float64{100000, 200000, 200000}, payment.refunded)
+	require.Equal(t, []uuid.UUID{late.ID, early.ID, upgraded.ID}, payment.refundFor)
+
+	_, err = service.CancelBooking(context.Background(), staying.ID)
+	require.Error(t, err)
+	require.Equal(t, domain.StatusCheckedIn, repo.store[staying.ID].Status)
+}
+
+func TestCancellationServiceNonRefundable(t *testing.T) {
+	b := domain.Booking{CheckIn: time.Now().AddDate(0, 0, 60), Status: domain.StatusConfirmed, TotalPrice: 500000}
+	quote := domain.NewCancellationService().Quote(valueobject.CancellationPolicy{NonRefundable: true, PenaltyPercent: 100}, b, time.Now())
+	require.Equal(t, domain.CancellationQuote{Penalty: 500000}, quote)
+
+	quote = domain.NewCancellationService().Quote(valueobject.DefaultCancellationPolicy, b, b.CheckIn)
+	require.Equal(t, domain.CancellationQuote{Penalty: 500000, Refund: 0}, quote)
+}
+
+func TestMarkNoShows(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: uuid.New(), BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	notifier := &notificationGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, notifier)
+
+	today := time.Now().Truncate(24 * time.Hour)
+	missed := domain.Booking{ID: uuid.New(), CheckIn: today.AddDate(0, 0, -1), CheckOut: today.AddDate(0, 0, 2), Status: domain.StatusConfirmed, TotalNights: 3, TotalPrice: 300000}
+	arriving := domain.Booking{ID: uuid.New(), CheckIn: today, CheckOut: today.AddDate(0, 0, 1), Status: domain.StatusConfirmed, TotalNights: 1, TotalPrice: 100000}
+	staying := domain.Booking{ID: uuid.New(), CheckIn: today.AddDate(0, 0, -1), CheckOut: today.AddDate(0, 0, 1), Status: domain.StatusCheckedIn, TotalNights: 2, TotalPrice: 200000}
+	for _, b := range []domain.Booking{missed, arriving, staying} {
+		repo.store[b.ID] = b
+	}
+
+	report, err := service.MarkNoShows(context.Background(), today, domain.NoShowPolicy{ChargeNights: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Processed)
+	require.Equal(t, domain.StatusNoShow, repo.store[missed.ID].Status)
+	require.False(t, repo.store[missed.ID].HoldsInventory())
+	require.Equal(t, domain.StatusConfirmed, repo.store[arriving.ID].Status)
+	require.Equal(t, domain.StatusCheckedIn, repo.store[staying.ID].Status)
+	require.Equal(t, []string{domain.EventTypeBookingNoShow, domain.EventTypeRefundRequested}, repo.events())
+
+	// the refund is queued with the status change and retried by the relay
+	payment.refundErr = errors.New("payment service down")
+	relay, err := service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, relay.Retrying)
+	require.Empty(t, payment.refunded)
+
+	payment.refundErr = nil
+	for i := range repo.outbox {
+		repo.outbox[i].NextAttemptAt = time.Now()
+	}
+	relay, err = service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, relay.Delivered)
+	require.Equal(t, []float64{200000}, payment.refunded)
+	require.Equal(t, []uuid.UUID{missed.ID}, payment.refundFor)
+}
+
+func TestMarkNoShowsSkipsBookingsMarkedByAnotherRun(t *testing.T) {
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}}
+	service := booking.NewService(repo, &hotelRepoStub{}, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	today := time.Now().Truncate(24 * time.Hour)
+	missed := domain.Booking{ID: uuid.New(), CheckIn: today.AddDate(0, 0, -1), CheckOut: today.AddDate(0, 0, 1), Status: domain.StatusConfirmed, TotalNights: 2, TotalPrice: 200000}
+	repo.store[missed.ID] = missed
+	// another replica marks the booking after this run loaded it
+	repo.beforeTransition = func() {
+		marked := missed
+		marked.Status = domain.StatusNoShow
+		repo.store[missed.ID] = marked
+	}
+
+	report, err := service.MarkNoShows(context.Background(), today, domain.NoShowPolicy{ChargeNights: 1})
+	require.NoError(t, err)
+	require.Zero(t, report.Processed)
+	require.Equal(t, 1, report.Skipped)
+	require.Empty(t, repo.events())
+}
+
+func TestNoShowPolicyQuote(t *testing.T) {
+	b := domain.Booking{TotalNights: 4, TotalPrice: 400000}
+	require.Equal(t, domain.CancellationQuote{Penalty: 200000, Refund: 200000}, domain.NoShowPolicy{ChargeNights: 2}.Quote(b))
+	require.Equal(t, domain.CancellationQuote{Penalty: 400000}, domain.NoShowPolicy{ChargeNights: -1}.Quote(b))
+	require.Equal(t, domain.CancellationQuote{Penalty: 400000}, domain.NoShowPolicy{ChargeNights: 10}.Quote(b))
+	require.Equal(t, domain.CancellationQuote{Refund: 400000}, domain.NoShowPolicy{}.Quote(b))
+}
+
+func TestCreateReservation(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 3}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	notifier := &notificationGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, notifier)
+
+	checkIn := time.Now().AddDate(0, 0, 30).Truncate(24 * time.Hour)
+	cmd, err := assembler.FromReservationRequest(uuid.New(), dto.ReservationRequest{
+		Lines: []dto.ReservationLine{
+			{RoomTypeID: roomTypeID.String(), CheckIn: dto.Date{Time: checkIn}, CheckOut: dto.Date{Time: checkIn.AddDate(0, 0, 2)}, Guests: 2},
+			{RoomTypeID: roomTypeID.String(), CheckIn: dto.Date{Time: checkIn}, CheckOut: dto.Date{Time: checkIn.AddDate(0, 0, 1)}, Guests: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	res, pay, err := service.CreateReservation(context.Background(), cmd)
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, pay.ID)
+	require.Len(t, res.Lines, 2)
+	require.Equal(t, res.Lines[0].ID, res.LeadBookingID)
+	require.Equal(t, 300000.0, res.TotalPrice())
+	require.Equal(t, []float64{300000}, payment.initiated)
+	require.Contains(t, repo.events(), domain.EventTypeReservationCreated)
+
+	// the lead payment settles every line
+	require.NoError(t, service.ApplyStatus(context.Background(), res.LeadBookingID, domain.StatusConfirmed))
+	for _, line := range res.Lines {
+		require.Equal(t, domain.StatusConfirmed, repo.store[line.ID].Status)
+		require.Equal(t, res.ID, repo.store[line.ID].ReservationID)
+	}
+
+	// a single room is cancelled and refunded against the combined payment
+	single, err := service.CancelBooking(context.Background(), res.Lines[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, 100000.0, single.Quote.Refund)
+	_, err = service.RelayOutbox(context.Background(), 50)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{res.LeadBookingID}, payment.refundFor)
+
+	group, err := service.CancelReservation(context.Background(), res.ID)
+	require.NoError(t, err)
+	require.Len(t, group, 1)
+	require.Equal(t, res.LeadBookingID, group[0].Booking.ID)
+	_, err = service.RelayOutbox(context.Background(), 50)
+	require.NoError(t, err)
+	require.Equal(t, []float64{100000, 200000}, payment.refunded)
+	require.Equal(t, domain.StatusCancelled, repo.store[res.LeadBookingID].Status)
+}
+
+func TestCreateReservationIsAtomic(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)
+	line := assembler.ReservationLine{RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Guests: 2}
+	_, _, err := service.CreateReservation(context.Background(), assembler.ReservationCommand{
+		UserID: uuid.New(),
+		Lines:  []assembler.ReservationLine{line, line},
+	})
+	require.Error(t, err)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+	require.Empty(t, repo.store)
+	require.Empty(t, payment.initiated)
+
+	res, _, err := service.CreateReservation(context.Background(), assembler.ReservationCommand{
+		UserID: uuid.New(),
+		Lines:  []assembler.ReservationLine{line},
+	})
+	require.NoError(t, err)
+
+	// unpaid lines share one invoice and cannot be cancelled one by one
+	_, err = service.CancelBooking(context.Background(), res.LeadBookingID)
+	require.Error(t, err)
+	require.Equal(t, "bad_request", pkgErrors.FromError(err).Code)
+
+	// a failed payment cancels the whole reservation
+	require.NoError(t, service.ApplyStatus(context.Background(), res.LeadBookingID, domain.StatusCancelled))
+	require.Equal(t, domain.StatusCancelled, repo.store[res.LeadBookingID].Status)
+}
+
+func TestRelayOutbox(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	notifier := &notificationGatewayStub{err: errors.New("notification service down")}
+	service := booking.NewService(repo, hotelRepo, &paymentGatewayStub{}, notifier)
+
+	checkIn := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err := service.CreateBooking(context.Background(), assembler.CreateCommand{
+		UserID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Guests: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{domain.EventTypeBookingCreated}, repo.events())
+	require.Empty(t, notifier.events)
+
+	// delivery failures are retried later with backoff
+	report, err := service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Retrying)
+	msg := repo.outbox[0]
+	require.Equal(t, domain.OutboxPending, msg.Status)
+	require.Equal(t, 1, msg.Attempts)
+	require.Equal(t, "notification service down", msg.LastError)
+	require.True(t, msg.NextAttemptAt.After(time.Now()))
+
+	report, err = service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Zero(t, report.Delivered+report.Retrying+report.Failed)
+
+	// after the last attempt the message is parked until replayed
+	repo.outbox[0].Attempts = domain.MaxOutboxAttempts - 1
+	repo.outbox[0].NextAttemptAt = time.Now()
+	report, err = service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Failed)
+	failed, err := service.ListOutbox(context.Background(), domain.OutboxFailed, query.Options{})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+
+	notifier.err = nil
+	replayed, err := service.ReplayOutbox(context.Background(), msg.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.OutboxPending, replayed.Status)
+	report, err = service.RelayOutbox(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Delivered)
+	require.Equal(t, []string{domain.EventTypeBookingCreated}, notifier.events)
+	require.Equal(t, domain.OutboxDelivered, repo.outbox[0].Status)
+
+	_, err = service.ReplayOutbox(context.Background(), uuid.New())
+	require.Error(t, err)
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	require.Equal(t, 30*time.Second, domain.OutboxRetryDelay(1))
+	require.Equal(t, 2*time.Minute, domain.OutboxRetryDelay(3))
+	require.Equal(t, time.Hour, domain.OutboxRetryDelay(20))
+}
+
+func TestCreateBookingCompensatesFailedPayment(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{initiateErr: errors.New("payment service down")}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 8, 1, 0, 0, 0, 0, time.UTC)
+	cmd := assembler.CreateCommand{UserID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Guests: 1}
+	_, _, err := service.CreateBooking(context.Background(), cmd)
+	require.EqualError(t, err, "payment service down")
+
+	// the booking is cancelled again, releasing the only room, and never announced
+	require.Len(t, repo.store, 1)
+	for _, bk := range repo.store {
+		require.Equal(t, domain.StatusCancelled, bk.Status)
+		require.Equal(t, []uuid.UUID{bk.ID}, payment.expired)
+	}
+	require.Empty(t, repo.events())
+	require.Len(t, repo.sagas, 1)
+	for _, saga := range repo.sagas {
+		require.Equal(t, domain.SagaCompensated, saga.Status)
+		require.Equal(t, domain.SagaStepReserved, saga.Step)
+		require.Equal(t, "payment service down", saga.LastError)
+	}
+
+	payment.initiateErr = nil
+	created, _, err := service.CreateBooking(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Equal(t, []string{domain.EventTypeBookingCreated}, repo.events())
+	saga := findSaga(t, repo, created.ID)
+	require.Equal(t, domain.SagaCompleted, saga.Status)
+	require.Equal(t, domain.SagaStepNotified, saga.Step)
+}
+
+func TestCreateBookingRetriesFailedPaymentExpiry(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{initiateErr: errors.New("payment timeout"), expireErr: errors.New("payment service down")}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 8, 10, 0, 0, 0, 0, time.UTC)
+	_, _, err := service.CreateBooking(context.Background(), assembler.CreateCommand{
+		UserID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Guests: 1,
+	})
+	require.EqualError(t, err, "payment timeout")
+
+	// the booking is cancelled, but the payment the timed-out call may have opened is still open
+	require.Len(t, repo.store, 1)
+	var bookingID uuid.UUID
+	for id, bk := range repo.store {
+		bookingID = id
+		require.Equal(t, domain.StatusCancelled, bk.Status)
+	}
+	saga := findSaga(t, repo, bookingID)
+	require.Equal(t, domain.SagaCompensating, saga.Status)
+	require.Equal(t, "payment service down", saga.LastError)
+
+	// recovery expires it once the saga goes stale
+	payment.expireErr = nil
+	saga.UpdatedAt = time.Now().Add(-time.Hour)
+	repo.sagas[saga.ID] = saga
+	count, err := service.RecoverSagas(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []uuid.UUID{bookingID}, payment.expired)
+	require.Equal(t, domain.SagaCompensated, findSaga(t, repo, bookingID).Status)
+}
+
+func TestRecoverSagas(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 5}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	payment := &paymentGatewayStub{}
+	service := booking.NewService(repo, hotelRepo, payment, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 9, 1, 0, 0, 0, 0, time.UTC)
+	create := func() domain.Booking {
+		bk, _, err := service.CreateBooking(context.Background(), assembler.CreateCommand{
+			UserID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Guests: 1,
+		})
+		require.NoError(t, err)
+		return bk
+	}
+	// rewind simulates a crash right after the given step was recorded
+	rewind := func(bk domain.Booking, step string) {
+		saga := findSaga(t, repo, bk.ID)
+		saga.Step, saga.Status = step, domain.SagaRunning
+		saga.UpdatedAt = time.Now().Add(-time.Hour)
+		repo.sagas[saga.ID] = saga
+	}
+	beforePayment := create()
+	rewind(beforePayment, domain.SagaStepReserved)
+	afterPayment := create()
+	rewind(afterPayment, domain.SagaStepPaymentInitiated)
+	orphan := domain.NewCreationSaga(uuid.New(), uuid.Nil, 100000)
+	orphan.UpdatedAt = time.Now().Add(-time.Hour)
+	repo.sagas[orphan.ID] = orphan
+	fresh := create()
+	rewind(fresh, domain.SagaStepReserved)
+	saga := findSaga(t, repo, fresh.ID)
+	saga.UpdatedAt = time.Now()
+	repo.sagas[saga.ID] = saga
+	repo.outbox = nil
+
+	count, err := service.RecoverSagas(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// interrupted before the payment was recorded: compensated
+	require.Equal(t, domain.StatusCancelled, repo.store[beforePayment.ID].Status)
+	require.Equal(t, domain.SagaCompensated, findSaga(t, repo, beforePayment.ID).Status)
+	require.Equal(t, []uuid.UUID{beforePayment.ID}, payment.expired)
+	// interrupted after the payment: completed and announced
+	require.Equal(t, domain.StatusPendingPayment, repo.store[afterPayment.ID].Status)
+	require.Equal(t, domain.SagaCompleted, findSaga(t, repo, afterPayment.ID).Status)
+	require.Equal(t, []string{domain.EventTypeBookingCreated}, repo.events())
+	// never reserved: nothing to undo
+	require.Equal(t, domain.SagaCompensated, repo.sagas[orphan.ID].Status)
+	// still in progress elsewhere: left alone
+	require.Equal(t, domain.SagaRunning, findSaga(t, repo, fresh.ID).Status)
+
+	count, err = service.RecoverSagas(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestCreateBookingRetriesFailedAnnouncement(t *testing.T) {
+	roomTypeID := uuid.New()
+	repo := &bookingRepoStub{store: map[uuid.UUID]domain.Booking{}, rooms: 1, announceErr: errors.New("outbox unavailable")}
+	hotelRepo := &hotelRepoStub{roomType: hdomain.RoomType{ID: roomTypeID, BasePrice: 100000}}
+	service := booking.NewService(repo, hotelRepo, &paymentGatewayStub{}, &notificationGatewayStub{})
+
+	checkIn := time.Date(2030, 10, 1, 0, 0, 0, 0, time.UTC)
+	created, _, err := service.CreateBooking(context.Background(), assembler.CreateCommand{
+		UserID: uuid.New(), RoomTypeID: roomTypeID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 1), Guests: 1,
+	})
+	require.NoError(t, err)
+
+	// the booking stands, and the saga waits at the payment step with the failure recorded
+	require.Equal(t, domain.StatusPendingPayment, repo.store[created.ID].Status)
+	require.Empty(t, repo.events())
+	saga := findSaga(t, repo, created.ID)
+	require.Equal(t, domain.SagaRunning, saga.Status)
+	require.Equal(t, domain.SagaStepPaymentInitiated, saga.Step)
+	require.Equal(t, "outbox unavailable", saga.LastError)
+
+	// recovery announces the booking once the saga goes stale
+	repo.announceErr = nil
+	saga.UpdatedAt = time.Now().Add(-time.Hour)
+	repo.sagas[saga.ID] = saga
+	count, err := service.RecoverSagas(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []string{domain.EventTypeBookingCreated}, repo.events())
+	require.Equal(t, domain.SagaCompleted, findSaga(t, repo, created.ID).Status)
+}
+
+func findSaga(t *testing.T, repo *bookingRepoStub, bookingID uuid.UUID) domain.Saga {
+	t.Helper()
+	for _, saga := range repo.sagas {
+		if saga.BookingID == bookingID {
+			return saga
+		}
+	}
+	t.Fatalf("no saga for booking %s", bookingID)
+	return domain.Saga{}
+}
@@ -3,6 +3,7 @@ package assembler
 import (
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/hotel"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 // HotelAggregate represents hotel with its room types.
@@ -29,6 +30,8 @@ func ToHotelResponse(agg HotelAggregate) dto.HotelResponse {
 		Address:     agg.Hotel.Address,
 		CreatedAt:   agg.Hotel.CreatedAt,
 		RoomTypes:   summaries,
+
+		CancellationPolicy: ToCancellationPolicyDTO(agg.Hotel.CancellationPolicy),
 	}
 }
 
@@ -52,11 +55,37 @@ func RoomTypesToDTO(rts []domain.RoomType) []dto.RoomTypeResponse {
 			Capacity:  rt.Capacity,
 			BasePrice: rt.BasePrice,
 			Amenities: rt.Amenities,
+
+			CancellationPolicy: ToCancellationPolicyDTO(rt.CancellationPolicy),
 		})
 	}
 	return out
 }
 
+// FromCancellationPolicyDTO validates an optional policy; nil stays nil.
+func FromCancellationPolicyDTO(req *dto.CancellationPolicy) (*valueobject.CancellationPolicy, error) {
+	if req == nil {
+		return nil, nil
+	}
+	policy, err := valueobject.NewCancellationPolicy(req.FreeCancellationDays, req.PenaltyPercent, req.NonRefundable)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ToCancellationPolicyDTO maps an optional policy to DTO.
+func ToCancellationPolicyDTO(p *valueobject.CancellationPolicy) *dto.CancellationPolicy {
+	if p == nil {
+		return nil
+	}
+	return &dto.CancellationPolicy{
+		FreeCancellationDays: p.FreeCancellationDays,
+		PenaltyPercent:       p.PenaltyPercent,
+		NonRefundable:        p.NonRefundable,
+	}
+}
+
 // RoomResponses maps rooms to DTOs.
 func RoomResponses(rooms []domain.Room) []dto.RoomResponse {
 	out := make([]dto.RoomResponse, 0, len(rooms))
@@ -75,3 +104,30 @@ func RoomResponse(r domain.Room) dto.RoomResponse {
 		Status:     r.Status,
 	}
 }
+
+// RoomTypeAvailability pairs a room type with its per-night inventory.
+type RoomTypeAvailability struct {
+	RoomType domain.RoomType
+	Nights   []domain.NightlyInventory
+}
+
+// ToAvailabilityResponse maps a room type calendar to DTO.
+func ToAvailabilityResponse(a RoomTypeAvailability) dto.RoomTypeAvailabilityResponse {
+	nights := make([]dto.NightAvailability, 0, len(a.Nights))
+	for _, n := range a.Nights {
+		nights = append(nights, dto.NightAvailability{
+			Date:           n.Night.Format("2006-01-02"),
+			TotalRooms:     n.TotalRooms,
+			BookedRooms:    n.BookedRooms,
+			OutOfOrder:     n.OutOfOrder,
+			RemainingRooms: n.Remaining(),
+			NightlyPrice:   a.RoomType.BasePrice,
+		})
+	}
+	return dto.RoomTypeAvailabilityResponse{
+		RoomTypeID: a.RoomType.ID.String(),
+		HotelID:    a.RoomType.HotelID.String(),
+		Name:       a.RoomType.Name,
+		Nights:     nights,
+	}
+}
@@ -28,7 +28,11 @@ func (s *Service) CreateHotel(ctx context.Context, req dto.HotelRequest) (uuid.U
 	if err != nil {
 		return uuid.Nil, err
 	}
-	h := domain.Hotel{ID: uuid.New(), Name: name, Description: req.Description, Address: addr}
+	policy, err := assembler.FromCancellationPolicyDTO(req.CancellationPolicy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	h := domain.Hotel{ID: uuid.New(), Name: name, Description: req.Description, Address: addr, CancellationPolicy: policy}
 	return h.ID, s.repo.CreateHotel(ctx, h)
 }
 
@@ -52,6 +56,10 @@ func (s *Service) CreateRoomType(ctx context.Context, req dto.RoomTypeRequest) (
 	if err := valueobject.RoomTypeSpec(req.Capacity, req.BasePrice); err != nil {
 		return uuid.Nil, err
 	}
+	policy, err := assembler.FromCancellationPolicyDTO(req.CancellationPolicy)
+	if err != nil {
+		return uuid.Nil, err
+	}
 	rt := domain.RoomType{
 		ID:        uuid.New(),
 		HotelID:   uuid.MustParse(req.HotelID),
@@ -59,6 +67,8 @@ func (s *Service) CreateRoomType(ctx context.Context, req dto.RoomTypeRequest) (
 		Capacity:  req.Capacity,
 		BasePrice: req.BasePrice,
 		Amenities: req.Amenities,
+
+		CancellationPolicy: policy,
 	}
 	return rt.ID, s.repo.CreateRoomType(ctx, rt)
 }
@@ -110,10 +120,16 @@ func (s *Service) UpdateHotel(ctx context.Context, id uuid.UUID, req dto.HotelUp
 	if err != nil {
 		return err
 	}
+	policy, err := assembler.FromCancellationPolicyDTO(req.CancellationPolicy)
+	if err != nil {
+		return err
+	}
 	h := domain.Hotel{
 		Name:        name,
 		Description: req.Description,
 		Address:     addr,
+
+		CancellationPolicy: policy,
 	}
 	return s.repo.UpdateHotel(ctx, id, h)
 }
@@ -158,3 +174,81 @@ func (s *Service) UpdateRoom(ctx context.Context, id uuid.UUID, req dto.RoomUpda
 func (s *Service) DeleteRoom(ctx context.Context, id uuid.UUID) error {
 	return s.repo.DeleteRoom(ctx, id)
 }
+
+// RoomTypeHotel returns the hotel a room type belongs to, for permission checks.
+func (s *Service) RoomTypeHotel(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	rt, err := s.repo.GetRoomType(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, errors.New("not_found", "room type not found")
+		}
+		return uuid.Nil, err
+	}
+	return rt.HotelID, nil
+}
+
+// RoomHotel returns the hotel a room belongs to, for permission checks.
+func (s *Service) RoomHotel(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	room, err := s.GetRoom(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return s.RoomTypeHotel(ctx, room.RoomTypeID)
+}
+
+// maxAvailabilityNights bounds the calendar window served in one request.
+const maxAvailabilityNights = 90
+
+// RoomTypeAvailability returns the per-night calendar of a single room type.
+func (s *Service) RoomTypeAvailability(ctx context.Context, id uuid.UUID, stay valueobject.DateRange) (assembler.RoomTypeAvailability, error) {
+	if err := validateAvailabilityWindow(stay); err != nil {
+		return assembler.RoomTypeAvailability{}, err
+	}
+	rt, err := s.repo.GetRoomType(ctx, id)
+	if err != nil {
+		return assembler.RoomTypeAvailability{}, err
+	}
+	nights, err := s.repo.NightlyInventory(ctx, []uuid.UUID{rt.ID}, stay)
+	if err != nil {
+		return assembler.RoomTypeAvailability{}, err
+	}
+	return assembler.RoomTypeAvailability{RoomType: rt, Nights: nights}, nil
+}
+
+// HotelAvailability returns the per-night calendar of every room type in a hotel.
+func (s *Service) HotelAvailability(ctx context.Context, hotelID uuid.UUID, stay valueobject.DateRange) ([]assembler.RoomTypeAvailability, error) {
+	if err := validateAvailabilityWindow(stay); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetHotel(ctx, hotelID); err != nil {
+		return nil, err
+	}
+	roomTypes, err := s.repo.ListRoomTypes(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(roomTypes))
+	for _, rt := range roomTypes {
+		ids = append(ids, rt.ID)
+	}
+	nights, err := s.repo.NightlyInventory(ctx, ids, stay)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[uuid.UUID][]domain.NightlyInventory, len(roomTypes))
+	for _, n := range nights {
+		byType[n.RoomTypeID] = append(byType[n.RoomTypeID], n)
+	}
+	out := make([]assembler.RoomTypeAvailability, 0, len(roomTypes))
+	for _, rt := range roomTypes {
+		out = append(out, assembler.RoomTypeAvailability{RoomType: rt, Nights: byType[rt.ID]})
+	}
+	return out, nil
+}
+
+func validateAvailabilityWindow(stay valueobject.DateRange) error {
+	if stay.Nights() > maxAvailabilityNights {
+		return errors.New("bad_request", "availability window exceeds 90 nights")
+	}
+	return nil
+}
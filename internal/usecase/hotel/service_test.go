@@ -13,6 +13,7 @@ import (
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/hotel"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
 func TestCreateHotelValidates(t *testing.T) {
@@ -83,6 +84,7 @@ type hotelRepoStub struct {
 	hotels    []domain.Hotel
 	roomTypes []domain.RoomType
 	rooms     []domain.Room
+	booked    map[uuid.UUID]int
 }
 
 func (h *hotelRepoStub) CreateHotel(ctx context.Context, v domain.Hotel) error {
@@ -184,6 +186,22 @@ func (h *hotelRepoStub) DeleteRoom(ctx context.Context, id uuid.UUID) error {
 	return stdErrors.New("not found")
 }
 
+func (h *hotelRepoStub) NightlyInventory(ctx context.Context, ids []uuid.UUID, stay valueobject.DateRange) ([]domain.NightlyInventory, error) {
+	var out []domain.NightlyInventory
+	for _, id := range ids {
+		total := 0
+		for _, r := range h.rooms {
+			if r.RoomTypeID == id {
+				total++
+			}
+		}
+		for night := stay.Start; night.Before(stay.End); night = night.AddDate(0, 0, 1) {
+			out = append(out, domain.NightlyInventory{RoomTypeID: id, Night: night, TotalRooms: total, BookedRooms: h.booked[id]})
+		}
+	}
+	return out, nil
+}
+
 func TestUpdateHotel(t *testing.T) {
 	repo := &hotelRepoStub{}
 	svc := hotel.NewService(repo)
@@ -361,3 +379,36 @@ func TestDeleteRoom(t *testing.T) {
 	_, err = svc.GetRoom(context.Background(), roomID)
 	require.Error(t, err)
 }
+
+func TestHotelAvailability(t *testing.T) {
+	repo := &hotelRepoStub{}
+	hID := uuid.New()
+	rtID := uuid.New()
+	repo.hotels = append(repo.hotels, domain.Hotel{ID: hID, Name: "H", Address: "Addr"})
+	repo.roomTypes = append(repo.roomTypes, domain.RoomType{ID: rtID, HotelID: hID, Name: "Deluxe", Capacity: 2, BasePrice: 1000})
+	repo.rooms = append(repo.rooms,
+		domain.Room{ID: uuid.New(), RoomTypeID: rtID, Number: "101", Status: "available"},
+		domain.Room{ID: uuid.New(), RoomTypeID: rtID, Number: "102", Status: "available"},
+	)
+	repo.booked = map[uuid.UUID]int{rtID: 3}
+	svc := hotel.NewService(repo)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stay, err := valueobject.NewDateRange(start, start.AddDate(0, 0, 3))
+	require.NoError(t, err)
+
+	calendars, err := svc.HotelAvailability(context.Background(), hID, stay)
+	require.NoError(t, err)
+	require.Len(t, calendars, 1)
+	require.Len(t, calendars[0].Nights, 3)
+	require.Equal(t, 2, calendars[0].Nights[0].TotalRooms)
+	require.Equal(t, 0, calendars[0].Nights[0].Remaining())
+
+	_, err = svc.HotelAvailability(context.Background(), uuid.New(), stay)
+	require.Error(t, err)
+
+	long, err := valueobject.NewDateRange(start, start.AddDate(0, 0, 120))
+	require.NoError(t, err)
+	_, err = svc.RoomTypeAvailability(context.Background(), rtID, long)
+	require.Error(t, err)
+}
@@ -1,11 +1,13 @@
 package assembler
 
 import (
+	"slices"
 	"time"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
 // Command represents inbound send request.
@@ -26,26 +28,74 @@ func FromRequest(req dto.NotificationRequest) (Command, error) {
 	return Command{Type: req.Type, Target: req.Target, Message: req.Message}, nil
 }
 
-// ToDomain builds domain Notification with generated metadata.
+// ToDomain builds a queued domain Notification, due immediately, with
+// generated metadata.
 func ToDomain(cmd Command, id string, createdAt time.Time) domain.Notification {
 	return domain.Notification{
-		ID:        id,
-		Type:      cmd.Type,
-		Target:    cmd.Target,
-		Message:   cmd.Message,
-		CreatedAt: createdAt,
+		ID:            id,
+		Type:          cmd.Type,
+		Target:        cmd.Target,
+		Message:       cmd.Message,
+		Status:        domain.StatusQueued,
+		NextAttemptAt: createdAt,
+		CreatedAt:     createdAt,
+		UpdatedAt:     createdAt,
 	}
 }
 
+// FromSearchQuery validates notification list filters to a domain filter.
+func FromSearchQuery(q dto.NotificationSearchQuery) (domain.Filter, error) {
+	f := domain.Filter{
+		Type:   q.Type,
+		Target: q.Target,
+		Page:   query.Options{Limit: q.Limit, Offset: q.Offset},
+	}
+	if q.Status != "" {
+		if !slices.Contains(domain.Statuses, q.Status) {
+			return domain.Filter{}, errors.New("bad_request", "invalid status "+q.Status)
+		}
+		f.Status = q.Status
+	}
+	dates := []struct {
+		raw  string
+		dst  *time.Time
+		name string
+	}{
+		{q.CreatedFrom, &f.CreatedFrom, "created_from"},
+		{q.CreatedTo, &f.CreatedTo, "created_to"},
+	}
+	for _, date := range dates {
+		if date.raw == "" {
+			continue
+		}
+		var d dto.Date
+		if err := d.UnmarshalJSON([]byte(date.raw)); err != nil {
+			return domain.Filter{}, errors.New("bad_request", "invalid "+date.name)
+		}
+		*date.dst = d.Time
+	}
+	return f, nil
+}
+
 // ToResponse maps domain Notification to DTO.
 func ToResponse(n domain.Notification) dto.NotificationResponse {
-	return dto.NotificationResponse{
+	resp := dto.NotificationResponse{
 		ID:        n.ID,
 		Type:      n.Type,
 		Target:    n.Target,
 		Message:   n.Message,
+		Status:    n.Status,
+		Attempts:  n.Attempts,
+		LastError: n.LastError,
 		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+		SentAt:    n.SentAt,
+	}
+	if n.Status == domain.StatusFailed {
+		next := n.NextAttemptAt
+		resp.NextAttemptAt = &next
 	}
+	return resp
 }
 
 // ToResponses maps slice to DTOs.
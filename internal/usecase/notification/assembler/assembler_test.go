@@ -21,3 +21,16 @@ func TestFromRequest(t *testing.T) {
 	require.Error(t, err)
 }
 
+
+func TestFromSearchQuery(t *testing.T) {
+	f, err := FromSearchQuery(dto.NotificationSearchQuery{Status: "failed", CreatedFrom: "2025-01-01", Limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, "failed", f.Status)
+	require.Equal(t, 2025, f.CreatedFrom.Year())
+	require.Equal(t, 5, f.Page.Limit)
+
+	_, err = FromSearchQuery(dto.NotificationSearchQuery{Status: "delivered"})
+	require.EqualError(t, err, "invalid status delivered")
+	_, err = FromSearchQuery(dto.NotificationSearchQuery{CreatedTo: "yesterday"})
+	require.EqualError(t, err, "invalid created_to")
+}
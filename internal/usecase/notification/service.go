@@ -2,72 +2,100 @@ package notification
 
 import (
 	"context"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification/assembler"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
-// Service wraps dispatcher implementation.
+// deliveryLease keeps a claimed notification away from other workers while it is dispatched.
+const deliveryLease = time.Minute
+
+// Service queues notifications and delivers them through the dispatcher.
 type Service struct {
+	repo       domain.Repository
 	dispatcher domain.Dispatcher
-	mu         sync.Mutex
-	store      []domain.Notification
 }
 
-func NewService(dispatcher domain.Dispatcher) *Service {
-	return &Service{dispatcher: dispatcher}
+func NewService(repo domain.Repository, dispatcher domain.Dispatcher) *Service {
+	return &Service{repo: repo, dispatcher: dispatcher}
 }
 
+// Send stores the notification as queued for the delivery workers.
 func (s *Service) Send(ctx context.Context, cmd assembler.Command) (domain.Notification, error) {
-	if err := s.dispatcher.Dispatch(ctx, cmd.Target, cmd.Message); err != nil {
+	record := assembler.ToDomain(cmd, uuid.New().String(), time.Now().UTC())
+	if err := s.repo.Create(ctx, record); err != nil {
 		return domain.Notification{}, err
 	}
-	return s.persist(cmd), nil
+	return record, nil
+}
+
+// ClaimDue leases up to limit notifications that are due for delivery.
+func (s *Service) ClaimDue(ctx context.Context, limit int) ([]domain.Notification, error) {
+	return s.repo.ClaimDue(ctx, time.Now().UTC(), deliveryLease, limit)
 }
 
-func (s *Service) persist(cmd assembler.Command) domain.Notification {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	record := domain.Notification{
-		ID:        uuid.New().String(),
-		Target:    cmd.Target,
-		Type:      cmd.Type,
-		Message:   cmd.Message,
-		CreatedAt: time.Now().UTC(),
+// Deliver dispatches a claimed notification once and records the outcome.
+// Failures are retried with exponential backoff and dead-lettered after
+// domain.MaxAttempts.
+func (s *Service) Deliver(ctx context.Context, n domain.Notification) (domain.Notification, error) {
+	if err := s.dispatcher.Dispatch(ctx, n.Target, n.Message); err != nil {
+		n.MarkFailed(err, time.Now().UTC())
+	} else {
+		n.MarkSent(time.Now().UTC())
+	}
+	if err := s.repo.UpdateDelivery(ctx, n); err != nil {
+		return domain.Notification{}, err
 	}
-	s.store = append(s.store, record)
-	return record
+	return n, nil
 }
 
-func (s *Service) List(_ context.Context, opts query.Options) []domain.Notification {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	norm := opts.Normalize(50)
-	start := norm.Offset
-	if start > len(s.store) {
-		start = len(s.store)
+// Retry puts a dead-lettered notification back in the queue.
+func (s *Service) Retry(ctx context.Context, id string) (domain.Notification, error) {
+	n, err := s.Get(ctx, id)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+	if n.Status != domain.StatusDeadLetter {
+		return domain.Notification{}, errors.New("conflict", "only dead-lettered notifications can be retried")
 	}
-	end := start + norm.Limit
-	if end > len(s.store) {
-		end = len(s.store)
+	if n.Target == domain.Erased {
+		return domain.Notification{}, errors.New("conflict", "recipient has been erased")
+	}
+	n.Requeue(time.Now().UTC())
+	if err := s.repo.UpdateDelivery(ctx, n); err != nil {
+		return domain.Notification{}, err
 	}
-	out := make([]domain.Notification, end-start)
-	copy(out, s.store[start:end])
-	return out
+	return n, nil
 }
 
-func (s *Service) Get(_ context.Context, id string) (domain.Notification, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, n := range s.store {
-		if n.ID == id {
-			return n, true
-		}
+// Search pages through notifications matching f, newest first.
+func (s *Service) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	return s.repo.Search(ctx, f)
+}
+
+// DeadLetters pages through notifications that ran out of delivery attempts.
+func (s *Service) DeadLetters(ctx context.Context, opts query.Options) (domain.SearchResult, error) {
+	return s.repo.Search(ctx, domain.Filter{Status: domain.StatusDeadLetter, Page: opts})
+}
+
+func (s *Service) Get(ctx context.Context, id string) (domain.Notification, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return domain.Notification{}, errors.New("not_found", "notification not found")
 	}
-	return domain.Notification{}, false
+	n, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+	return n, nil
+}
+
+// EraseTarget redacts the target and message of every notification sent to
+// target and returns how many were erased.
+func (s *Service) EraseTarget(ctx context.Context, target string) (int64, error) {
+	return s.repo.EraseTarget(ctx, target)
 }
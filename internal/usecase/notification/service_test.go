@@ -4,44 +4,185 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/notification"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/notification/assembler"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/dto"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/query"
 )
 
-func TestSendAndList(t *testing.T) {
+func TestSendQueuesAndDeliverRecords(t *testing.T) {
 	dispatcher := &dispatcherStub{}
-	svc := notification.NewService(dispatcher)
+	svc := notification.NewService(&repoStub{}, dispatcher)
+	ctx := context.Background()
 
 	cmd, _ := assembler.FromRequest(dto.NotificationRequest{Type: "email", Target: "user@example.com", Message: "hello"})
-	resp, err := svc.Send(context.Background(), cmd)
+	resp, err := svc.Send(ctx, cmd)
 	require.NoError(t, err)
 	require.NotEmpty(t, resp.ID)
+	require.Equal(t, domain.StatusQueued, resp.Status)
+	require.Empty(t, dispatcher.sent, "send only queues")
 
-	list := svc.List(context.Background(), query.Options{Limit: 10})
-	require.Len(t, list, 1)
+	claimed, err := svc.ClaimDue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	delivered, err := svc.Deliver(ctx, claimed[0])
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusSent, delivered.Status)
+	require.Equal(t, 1, delivered.Attempts)
+	require.NotNil(t, delivered.SentAt)
+	require.Equal(t, []string{"user@example.com"}, dispatcher.sent)
 
-	found, ok := svc.Get(context.Background(), resp.ID)
-	require.True(t, ok)
-	require.Equal(t, resp.ID, found.ID)
+	found, err := svc.Get(ctx, resp.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusSent, found.Status)
+	_, err = svc.Get(ctx, "nope")
+	require.Equal(t, "not_found", pkgErrors.FromError(err).Code)
+	claimed, err = svc.ClaimDue(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
 }
 
-func TestSendDispatchError(t *testing.T) {
-	dispatcher := &dispatcherStub{err: errors.New("fail")}
-	svc := notification.NewService(dispatcher)
+func TestDeliverRetriesThenDeadLetters(t *testing.T) {
+	dispatcher := &dispatcherStub{err: errors.New("smtp timeout")}
+	svc := notification.NewService(&repoStub{}, dispatcher)
+	ctx := context.Background()
 	cmd, _ := assembler.FromRequest(dto.NotificationRequest{Type: "email", Target: "x", Message: "y"})
-	_, err := svc.Send(context.Background(), cmd)
-	require.Error(t, err)
+	n, err := svc.Send(ctx, cmd)
+	require.NoError(t, err)
+
+	for attempt := 1; attempt < domain.MaxAttempts; attempt++ {
+		before := time.Now().UTC()
+		n, err = svc.Deliver(ctx, n)
+		require.NoError(t, err)
+		require.Equal(t, domain.StatusFailed, n.Status)
+		require.Equal(t, attempt, n.Attempts)
+		require.Equal(t, "smtp timeout", n.LastError)
+		require.False(t, n.NextAttemptAt.Before(before.Add(domain.RetryDelay(attempt))))
+	}
+	n, err = svc.Deliver(ctx, n)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusDeadLetter, n.Status)
+
+	dead, err := svc.DeadLetters(ctx, query.Options{})
+	require.NoError(t, err)
+	require.Len(t, dead.Notifications, 1)
+
+	retried, err := svc.Retry(ctx, n.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusQueued, retried.Status)
+	require.Zero(t, retried.Attempts)
+	require.Equal(t, "smtp timeout", retried.LastError)
+	_, err = svc.Retry(ctx, n.ID)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+	claimed, err := svc.ClaimDue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 10*time.Second, domain.RetryDelay(1))
+	require.Equal(t, 40*time.Second, domain.RetryDelay(3))
+	require.Equal(t, 30*time.Minute, domain.RetryDelay(20))
+}
+
+func TestEraseTargetRedactsOnlyThatRecipient(t *testing.T) {
+	svc := notification.NewService(&repoStub{}, &dispatcherStub{})
+	ctx := context.Background()
+	for _, target := range []string{"user@example.com", "other@example.com", "user@example.com"} {
+		cmd, _ := assembler.FromRequest(dto.NotificationRequest{Type: "email", Target: target, Message: "hello"})
+		_, err := svc.Send(ctx, cmd)
+		require.NoError(t, err)
+	}
+
+	erased, err := svc.EraseTarget(ctx, "user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), erased)
+	result, err := svc.Search(ctx, domain.Filter{Target: "user@example.com"})
+	require.NoError(t, err)
+	require.Empty(t, result.Notifications)
+	result, err = svc.Search(ctx, domain.Filter{Target: "other@example.com"})
+	require.NoError(t, err)
+	require.Len(t, result.Notifications, 1)
 }
 
 type dispatcherStub struct {
-	err error
+	err  error
+	sent []string
 }
 
 func (d *dispatcherStub) Dispatch(ctx context.Context, target, message string) error {
-	return d.err
+	if d.err != nil {
+		return d.err
+	}
+	d.sent = append(d.sent, target)
+	return nil
+}
+
+// repoStub keeps notifications in insertion order and filters by target and status.
+type repoStub struct {
+	items []domain.Notification
+}
+
+func (r *repoStub) Create(ctx context.Context, n domain.Notification) error {
+	r.items = append(r.items, n)
+	return nil
+}
+
+func (r *repoStub) UpdateDelivery(ctx context.Context, n domain.Notification) error {
+	for i := range r.items {
+		if r.items[i].ID == n.ID {
+			r.items[i] = n
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (r *repoStub) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.Notification, error) {
+	var claimed []domain.Notification
+	for i, n := range r.items {
+		due := n.Status == domain.StatusQueued || n.Status == domain.StatusFailed
+		if due && !n.NextAttemptAt.After(now) && len(claimed) < limit {
+			r.items[i].NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, r.items[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (r *repoStub) FindByID(ctx context.Context, id string) (domain.Notification, error) {
+	for _, n := range r.items {
+		if n.ID == id {
+			return n, nil
+		}
+	}
+	return domain.Notification{}, pkgErrors.New("not_found", "notification not found")
+}
+
+func (r *repoStub) Search(ctx context.Context, f domain.Filter) (domain.SearchResult, error) {
+	var out []domain.Notification
+	for _, n := range r.items {
+		if (f.Target == "" || n.Target == f.Target) && (f.Status == "" || n.Status == f.Status) {
+			out = append(out, n)
+		}
+	}
+	return domain.SearchResult{Notifications: out, Total: int64(len(out))}, nil
+}
+
+func (r *repoStub) EraseTarget(ctx context.Context, target string) (int64, error) {
+	var erased int64
+	for i := range r.items {
+		if r.items[i].Target == target {
+			r.items[i].Target = domain.Erased
+			r.items[i].Message = domain.Erased
+			erased++
+		}
+	}
+	return erased, nil
 }
@@ -15,6 +15,7 @@ import (
 type InitiateCommand struct {
 	BookingID uuid.UUID
 	Money     valueobject.Money
+	Kind      string
 }
 
 // WebhookCommand represents inbound webhook update.
@@ -28,12 +29,22 @@ type WebhookCommand struct {
 // RefundCommand represents refund intent.
 type RefundCommand struct {
 	PaymentID uuid.UUID
+	Amount    float64
+	Reason    string
+}
+
+// BookingRefundCommand returns money collected for a booking, across its
+// booking payment and paid adjustments.
+type BookingRefundCommand struct {
+	BookingID uuid.UUID
+	Amount    float64
 	Reason    string
 }
 
 // RefundResult represents refund outcome for handler mapping.
 type RefundResult struct {
 	PaymentID uuid.UUID
+	Amount    float64
 	Status    string
 	Reference string
 }
@@ -48,7 +59,15 @@ func FromPaymentRequest(req dto.PaymentRequest) (InitiateCommand, error) {
 	if err != nil {
 		return InitiateCommand{}, err
 	}
-	return InitiateCommand{BookingID: bookingID, Money: money}, nil
+	kind := req.Kind
+	switch kind {
+	case "":
+		kind = domain.KindBooking
+	case domain.KindBooking, domain.KindAdjustment:
+	default:
+		return InitiateCommand{}, errors.New("bad_request", "invalid payment kind")
+	}
+	return InitiateCommand{BookingID: bookingID, Money: money, Kind: kind}, nil
 }
 
 // FromWebhook builds webhook command.
@@ -74,18 +93,32 @@ func FromRefundRequest(req dto.RefundRequest) (RefundCommand, error) {
 	if err != nil {
 		return RefundCommand{}, errors.New("bad_request", "invalid payment id")
 	}
-	return RefundCommand{PaymentID: paymentID, Reason: req.Reason}, nil
+	if req.Amount < 0 {
+		return RefundCommand{}, errors.New("bad_request", "refund amount cannot be negative")
+	}
+	return RefundCommand{PaymentID: paymentID, Amount: req.Amount, Reason: req.Reason}, nil
+}
+
+// FromBookingRefundRequest builds a booking refund command.
+func FromBookingRefundRequest(bookingID uuid.UUID, req dto.BookingRefundRequest) (BookingRefundCommand, error) {
+	if req.Amount <= 0 {
+		return BookingRefundCommand{}, errors.New("bad_request", "refund amount must be positive")
+	}
+	return BookingRefundCommand{BookingID: bookingID, Amount: req.Amount, Reason: req.Reason}, nil
 }
 
 // ToRefundResult maps provider response to result.
-func ToRefundResult(paymentID uuid.UUID, ref string) RefundResult {
-	return RefundResult{PaymentID: paymentID, Status: "refunded", Reference: ref}
+func ToRefundResult(paymentID uuid.UUID, amount float64, ref string) RefundResult {
+	return RefundResult{PaymentID: paymentID, Amount: amount, Status: "refunded", Reference: ref}
 }
 
 // ToResponse maps domain Payment to DTO.
 func ToResponse(p domain.Payment) dto.PaymentResponse {
 	return dto.PaymentResponse{
 		ID:         p.ID.String(),
+		Amount:     p.Amount,
+		Kind:       p.Kind,
+		Refunded:   p.Refunded,
 		Status:     p.Status,
 		Provider:   p.Provider,
 		PaymentURL: p.PaymentURL,
@@ -96,6 +129,7 @@ func ToResponse(p domain.Payment) dto.PaymentResponse {
 func ToRefundResponse(res RefundResult) dto.RefundResponse {
 	return dto.RefundResponse{
 		ID:        res.PaymentID.String(),
+		Amount:    res.Amount,
 		Status:    res.Status,
         Reference: res.Reference,
 	}
@@ -3,6 +3,8 @@ package payment
 import (
 	"context"
 	"errors"
+	"math"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -25,10 +27,20 @@ func NewService(repo domain.Repository, provider domain.Provider, updater domain
 }
 
 // Initiate creates a new payment from a validated command.
+// A booking has a single booking payment; adjustment payments may be added
+// once it exists to collect price increases.
 func (s *Service) Initiate(ctx context.Context, cmd assembler.InitiateCommand) (domain.Payment, error) {
-	if existing, err := s.repo.FindByBookingID(ctx, cmd.BookingID); err == nil {
+	kind := cmd.Kind
+	if kind == "" {
+		kind = domain.KindBooking
+	}
+	existing, err := s.repo.FindByBookingID(ctx, cmd.BookingID)
+	if kind == domain.KindBooking && err == nil {
 		return existing, pkgErrors.New("conflict", "payment already exists for booking")
 	}
+	if kind == domain.KindAdjustment && err != nil {
+		return domain.Payment{}, pkgErrors.New("not_found", "booking payment not found")
+	}
 
 	payment := domain.Payment{
 		ID:        uuid.New(),
@@ -36,6 +48,7 @@ func (s *Service) Initiate(ctx context.Context, cmd assembler.InitiateCommand) (
 		Amount:    cmd.Money.Amount,
 		Currency:  cmd.Money.Currency,
 		Status:    string(valueobject.PaymentPending),
+		Kind:      kind,
 		Provider:  "xendit-mock",
 	}
 
@@ -56,7 +69,9 @@ func (s *Service) Initiate(ctx context.Context, cmd assembler.InitiateCommand) (
 	return initiated, nil
 }
 
-// HandleWebhook applies status update from provider webhook.
+// HandleWebhook applies status update from provider webhook. A repeated
+// delivery of the status already recorded only re-syncs the booking, so a
+// provider retrying after a failed booking update completes the sync.
 func (s *Service) HandleWebhook(ctx context.Context, cmd assembler.WebhookCommand) error {
 	payment, err := s.repo.FindByID(ctx, cmd.PaymentID)
 	if err != nil {
@@ -72,8 +87,11 @@ func (s *Service) HandleWebhook(ctx context.Context, cmd assembler.WebhookComman
 	if err != nil {
 		return err
 	}
-	if err := currentStatus.CanTransition(targetStatus); err != nil {
-		return err
+	duplicate := currentStatus == targetStatus
+	if !duplicate {
+		if err := currentStatus.CanTransition(targetStatus); err != nil {
+			return err
+		}
 	}
 
 	canonical := assembler.CanonicalPayload(cmd)
@@ -81,20 +99,26 @@ func (s *Service) HandleWebhook(ctx context.Context, cmd assembler.WebhookComman
 		return pkgErrors.New("forbidden", "invalid signature")
 	}
 
-	if err := s.repo.UpdateStatus(ctx, payment.ID, string(targetStatus), payment.PaymentURL, cmd.RawPayload, cmd.Signature); err != nil {
-		return err
+	if !duplicate {
+		if err := s.repo.UpdateStatus(ctx, payment.ID, string(targetStatus), payment.PaymentURL, cmd.RawPayload, cmd.Signature); err != nil {
+			return err
+		}
 	}
 
-	if s.bookingUpdater != nil {
+	// Only the booking payment drives the booking lifecycle.
+	if s.bookingUpdater != nil && payment.Kind != domain.KindAdjustment {
 		var bookingStatus string
-		switch cmd.Status {
-		case domain.StatusPaid:
+		switch targetStatus {
+		case valueobject.PaymentPaid:
 			bookingStatus = "confirmed"
-		case domain.StatusFailed:
+		case valueobject.PaymentFailed:
 			bookingStatus = "cancelled"
 		}
 		if bookingStatus != "" {
-			_ = s.bookingUpdater.Update(ctx, payment.BookingID, bookingStatus)
+			// Surface the failure so the provider retries the webhook.
+			if err := s.bookingUpdater.Update(ctx, payment.BookingID, bookingStatus); err != nil {
+				return pkgErrors.New("bad_gateway", "payment recorded but booking status update failed")
+			}
 		}
 	}
 
@@ -114,19 +138,150 @@ func mapProviderStatus(status string) (valueobject.PaymentStatus, error) {
 	}
 }
 
-// Refund requests refund via provider.
+// Refund requests refund via provider. A zero amount refunds what is left of
+// the payment; a smaller amount issues a partial refund. Only paid payments
+// are refunded, and never beyond the amount collected.
 func (s *Service) Refund(ctx context.Context, cmd assembler.RefundCommand) (assembler.RefundResult, error) {
 	payment, err := s.repo.FindByID(ctx, cmd.PaymentID)
 	if err != nil {
 		return assembler.RefundResult{}, err
 	}
+	if payment.Status != domain.StatusPaid {
+		return assembler.RefundResult{}, pkgErrors.New("bad_request", "only paid payments can be refunded")
+	}
+
+	amount := cmd.Amount
+	if amount == 0 {
+		amount = payment.Refundable()
+	}
+	if amount == 0 {
+		return assembler.RefundResult{}, pkgErrors.New("bad_request", "payment already fully refunded")
+	}
+	if amount > payment.Refundable() {
+		return assembler.RefundResult{}, pkgErrors.New("bad_request", "refund amount exceeds refundable amount")
+	}
+	return s.refund(ctx, payment, amount, cmd.Reason)
+}
+
+// RefundByBooking returns up to cmd.Amount of what a booking has paid,
+// drawing on the booking payment first and then on paid adjustments. Unpaid
+// payments contribute nothing, so the refunded amount in the result may be
+// lower than requested.
+func (s *Service) RefundByBooking(ctx context.Context, cmd assembler.BookingRefundCommand) (assembler.RefundResult, error) {
+	payments, err := s.repo.ListByBooking(ctx, cmd.BookingID)
+	if err != nil {
+		return assembler.RefundResult{}, err
+	}
+	if len(payments) == 0 {
+		return assembler.RefundResult{}, pkgErrors.New("not_found", "payment not found")
+	}
+
+	result := assembler.ToRefundResult(payments[0].ID, 0, "")
+	references := make([]string, 0, len(payments))
+	remaining := cmd.Amount
+	for _, p := range payments {
+		amount := math.Min(remaining, p.Refundable())
+		if amount <= 0 {
+			continue
+		}
+		refund, err := s.refund(ctx, p, amount, cmd.Reason)
+		if err != nil {
+			return result, err
+		}
+		result.Amount += refund.Amount
+		references = append(references, refund.Reference)
+		remaining -= amount
+	}
+	result.Reference = strings.Join(references, ",")
+	return result, nil
+}
+
+// refund reserves amount on the payment's refunded total before calling the
+// provider, so concurrent refunds cannot overdraw it, and releases the
+// reservation when the provider rejects the refund.
+func (s *Service) refund(ctx context.Context, payment domain.Payment, amount float64, reason string) (assembler.RefundResult, error) {
+	reserved, err := s.repo.AddRefunded(ctx, payment.ID, amount)
+	if err != nil {
+		return assembler.RefundResult{}, err
+	}
+	if !reserved {
+		return assembler.RefundResult{}, pkgErrors.New("conflict", "refund amount exceeds refundable amount")
+	}
 
-	ref, err := s.provider.Refund(ctx, payment, cmd.Reason)
+	refunded := payment
+	refunded.Amount = amount
+	ref, err := s.provider.Refund(ctx, refunded, reason)
 	if err != nil {
+		_, _ = s.repo.AddRefunded(ctx, payment.ID, -amount)
 		return assembler.RefundResult{}, err
 	}
 
-	return assembler.ToRefundResult(payment.ID, ref), nil
+	return assembler.ToRefundResult(payment.ID, amount, ref), nil
+}
+
+// VoidAdjustments fails the pending adjustment payments of a booking, e.g.
+// when it is cancelled before a price increase was paid, and returns them.
+func (s *Service) VoidAdjustments(ctx context.Context, bookingID uuid.UUID) ([]domain.Payment, error) {
+	payments, err := s.repo.ListByBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	voided := make([]domain.Payment, 0, len(payments))
+	for _, p := range payments {
+		if p.Kind != domain.KindAdjustment || p.Status != domain.StatusPending {
+			continue
+		}
+		ok, err := s.repo.TransitionStatus(ctx, p.ID, domain.StatusPending, domain.StatusFailed)
+		if err != nil {
+			return voided, err
+		}
+		// Paid in the meantime: the adjustment stands and is refundable.
+		if !ok {
+			continue
+		}
+		p.Status = domain.StatusFailed
+		voided = append(voided, p)
+	}
+	return voided, nil
+}
+
+// ExpireByBooking fails the pending payment of a booking whose hold lapsed.
+// Already failed payments are returned unchanged so retries are harmless.
+// The transition is conditional, so a payment settled concurrently is never
+// overwritten.
+func (s *Service) ExpireByBooking(ctx context.Context, bookingID uuid.UUID) (domain.Payment, error) {
+	payment, err := s.repo.FindByBookingID(ctx, bookingID)
+	if err != nil {
+		return domain.Payment{}, pkgErrors.New("not_found", "payment not found")
+	}
+	if payment.Status == domain.StatusFailed {
+		return payment, nil
+	}
+
+	currentStatus, err := valueobject.ValidatePaymentStatus(payment.Status)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	if err := currentStatus.CanTransition(valueobject.PaymentFailed); err != nil {
+		return domain.Payment{}, pkgErrors.New("conflict", "payment already settled")
+	}
+
+	ok, err := s.repo.TransitionStatus(ctx, payment.ID, domain.StatusPending, domain.StatusFailed)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	if !ok {
+		current, err := s.repo.FindByID(ctx, payment.ID)
+		if err != nil {
+			return domain.Payment{}, err
+		}
+		if current.Status == domain.StatusFailed {
+			return current, nil
+		}
+		return domain.Payment{}, pkgErrors.New("conflict", "payment already settled")
+	}
+	payment.Status = domain.StatusFailed
+	return payment, nil
 }
 
 // GetPayment fetches payment by ID.
@@ -11,6 +11,7 @@ import (
 	domain "github.com/ftryyln/hotel-booking-microservices/internal/domain/payment"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/payment"
 	"github.com/ftryyln/hotel-booking-microservices/internal/usecase/payment/assembler"
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
 )
 
@@ -29,7 +30,8 @@ func TestHandleWebhook(t *testing.T) {
 		wantBookingLen int
 	}{
 		{"valid paid", domain.StatusPaid, true, false, 1},
-		{"expired maps to failed", "EXPIRED", true, false, 0},
+		{"provider casing", "PAID", true, false, 1},
+		{"expired maps to failed", "EXPIRED", true, false, 1},
 		{"invalid signature", domain.StatusPaid, false, true, 0},
 	}
 
@@ -47,6 +49,9 @@ func TestHandleWebhook(t *testing.T) {
 			}
 			require.NoError(t, err)
 			require.Len(t, updater.statuses, tt.wantBookingLen)
+			if tt.status == "EXPIRED" {
+				require.Equal(t, []string{"cancelled"}, updater.statuses)
+			}
 			p := repo.store[paymentID]
 			require.Equal(t, "payload", p.WebhookPayload)
 			require.Equal(t, "sig", p.WebhookSignature)
@@ -54,44 +59,156 @@ func TestHandleWebhook(t *testing.T) {
 	}
 }
 
-func TestRefund(t *testing.T) {
+func TestHandleWebhookSurfacesBookingSyncFailure(t *testing.T) {
 	paymentID := uuid.New()
 	repo := &paymentRepoStub{store: map[uuid.UUID]domain.Payment{
-		paymentID: {ID: paymentID},
+		paymentID: {ID: paymentID, BookingID: uuid.New(), Status: string(valueobject.PaymentPending)},
+	}}
+	updater := &bookingUpdaterStub{err: errors.New("booking service down")}
+	service := payment.NewService(repo, &providerStub{signatureValid: true}, updater)
+	cmd := assembler.WebhookCommand{PaymentID: paymentID, Status: domain.StatusPaid, Signature: "sig"}
+
+	err := service.HandleWebhook(context.Background(), cmd)
+	require.Error(t, err)
+	require.Equal(t, "bad_gateway", pkgErrors.FromError(err).Code)
+	require.Equal(t, string(valueobject.PaymentPaid), repo.store[paymentID].Status)
+
+	// the provider's retry of the same webhook re-syncs the booking
+	updater.err = nil
+	require.NoError(t, service.HandleWebhook(context.Background(), cmd))
+	require.Equal(t, []string{"confirmed", "confirmed"}, updater.statuses)
+
+	// a conflicting outcome for a settled payment is still rejected
+	cmd.Status = domain.StatusFailed
+	require.Error(t, service.HandleWebhook(context.Background(), cmd))
+}
+
+func TestRefund(t *testing.T) {
+	paymentID, pendingID := uuid.New(), uuid.New()
+	repo := &paymentRepoStub{store: map[uuid.UUID]domain.Payment{
+		paymentID: {ID: paymentID, Amount: 100, Status: domain.StatusPaid},
+		pendingID: {ID: pendingID, Amount: 100, Status: domain.StatusPending},
 	}}
 	provider := &providerStub{signatureValid: true}
 	service := payment.NewService(repo, provider, nil)
+	cmd := assembler.RefundCommand{PaymentID: paymentID, Reason: "test"}
 
-	tests := []struct {
-		name       string
-		shouldFail bool
-	}{
-		{"success", false},
-		{"provider failure", true},
-	}
+	_, err := service.Refund(context.Background(), assembler.RefundCommand{PaymentID: pendingID})
+	require.EqualError(t, err, "only paid payments can be refunded")
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if tt.shouldFail {
-				provider.refundErr = errors.New("fail")
-			} else {
-				provider.refundErr = nil
-			}
-			cmd := assembler.RefundCommand{PaymentID: paymentID, Reason: "test"}
-			_, err := service.Refund(context.Background(), cmd)
-			if tt.shouldFail {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-		})
-	}
+	// a rejected refund does not count towards the refunded total
+	provider.refundErr = errors.New("fail")
+	_, err = service.Refund(context.Background(), cmd)
+	require.Error(t, err)
+	require.Zero(t, repo.store[paymentID].Refunded)
+
+	provider.refundErr = nil
+	res, err := service.Refund(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Equal(t, 100.0, res.Amount)
+	require.Equal(t, 100.0, repo.store[paymentID].Refunded)
+
+	_, err = service.Refund(context.Background(), cmd)
+	require.EqualError(t, err, "payment already fully refunded")
+}
+
+func TestInitiateAdjustmentAndPartialRefund(t *testing.T) {
+	bookingID := uuid.New()
+	repo := &paymentRepoStub{store: map[uuid.UUID]domain.Payment{}}
+	updater := &bookingUpdaterStub{}
+	service := payment.NewService(repo, &providerStub{signatureValid: true}, updater)
+	money := valueobject.Money{Amount: 300000, Currency: "IDR"}
+
+	_, err := service.Initiate(context.Background(), assembler.InitiateCommand{BookingID: bookingID, Money: money, Kind: domain.KindAdjustment})
+	require.Error(t, err) // adjustments need an existing booking payment
+
+	original, err := service.Initiate(context.Background(), assembler.InitiateCommand{BookingID: bookingID, Money: money})
+	require.NoError(t, err)
+	require.Equal(t, domain.KindBooking, original.Kind)
+
+	_, err = service.Initiate(context.Background(), assembler.InitiateCommand{BookingID: bookingID, Money: money})
+	require.Error(t, err)
+
+	adjustment, err := service.Initiate(context.Background(), assembler.InitiateCommand{BookingID: bookingID, Money: valueobject.Money{Amount: 50000, Currency: "IDR"}, Kind: domain.KindAdjustment})
+	require.NoError(t, err)
+
+	// settling an adjustment does not touch the booking status
+	require.NoError(t, service.HandleWebhook(context.Background(), assembler.WebhookCommand{PaymentID: adjustment.ID, Status: domain.StatusPaid, Signature: "sig"}))
+	require.Empty(t, updater.statuses)
+	require.NoError(t, service.HandleWebhook(context.Background(), assembler.WebhookCommand{PaymentID: original.ID, Status: domain.StatusPaid, Signature: "sig"}))
+
+	res, err := service.Refund(context.Background(), assembler.RefundCommand{PaymentID: original.ID, Amount: 100000})
+	require.NoError(t, err)
+	require.Equal(t, 100000.0, res.Amount)
+
+	// partial refunds add up and never exceed what was paid
+	_, err = service.Refund(context.Background(), assembler.RefundCommand{PaymentID: original.ID, Amount: 250000})
+	require.EqualError(t, err, "refund amount exceeds refundable amount")
+
+	// an unpaid adjustment is voided and never refunded
+	unpaid, err := service.Initiate(context.Background(), assembler.InitiateCommand{BookingID: bookingID, Money: valueobject.Money{Amount: 70000, Currency: "IDR"}, Kind: domain.KindAdjustment})
+	require.NoError(t, err)
+	voided, err := service.VoidAdjustments(context.Background(), bookingID)
+	require.NoError(t, err)
+	require.Len(t, voided, 1)
+	require.Equal(t, unpaid.ID, voided[0].ID)
+	require.Equal(t, domain.StatusFailed, repo.store[unpaid.ID].Status)
+
+	// booking refunds draw on the booking payment, then paid adjustments
+	res, err = service.RefundByBooking(context.Background(), assembler.BookingRefundCommand{BookingID: bookingID, Amount: 500000})
+	require.NoError(t, err)
+	require.Equal(t, 250000.0, res.Amount)
+	require.Equal(t, 300000.0, repo.store[original.ID].Refunded)
+	require.Equal(t, 50000.0, repo.store[adjustment.ID].Refunded)
+
+	res, err = service.RefundByBooking(context.Background(), assembler.BookingRefundCommand{BookingID: bookingID, Amount: 1000})
+	require.NoError(t, err)
+	require.Zero(t, res.Amount)
 }
 
 // stubs
 
+func TestExpireByBooking(t *testing.T) {
+	pendingBooking, paidBooking := uuid.New(), uuid.New()
+	pendingID, paidID := uuid.New(), uuid.New()
+	repo := &paymentRepoStub{store: map[uuid.UUID]domain.Payment{
+		pendingID: {ID: pendingID, BookingID: pendingBooking, Status: domain.StatusPending},
+		paidID:    {ID: paidID, BookingID: paidBooking, Status: domain.StatusPaid},
+	}}
+	service := payment.NewService(repo, &providerStub{}, &bookingUpdaterStub{})
+
+	pay, err := service.ExpireByBooking(context.Background(), pendingBooking)
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusFailed, pay.Status)
+	require.Equal(t, domain.StatusFailed, repo.store[pendingID].Status)
+
+	_, err = service.ExpireByBooking(context.Background(), pendingBooking)
+	require.NoError(t, err)
+
+	_, err = service.ExpireByBooking(context.Background(), paidBooking)
+	require.Error(t, err)
+	require.Equal(t, domain.StatusPaid, repo.store[paidID].Status)
+
+	_, err = service.ExpireByBooking(context.Background(), uuid.New())
+	require.Error(t, err)
+
+	// a payment settled between the read and the write stays paid
+	racedBooking, racedID := uuid.New(), uuid.New()
+	repo.store[racedID] = domain.Payment{ID: racedID, BookingID: racedBooking, Status: domain.StatusPending}
+	repo.beforeTransition = func() {
+		pay := repo.store[racedID]
+		pay.Status = domain.StatusPaid
+		repo.store[racedID] = pay
+	}
+	_, err = service.ExpireByBooking(context.Background(), racedBooking)
+	require.Equal(t, "conflict", pkgErrors.FromError(err).Code)
+	require.Equal(t, domain.StatusPaid, repo.store[racedID].Status)
+}
+
 type paymentRepoStub struct {
 	store map[uuid.UUID]domain.Payment
+	// beforeTransition simulates a concurrent writer racing TransitionStatus.
+	beforeTransition func()
 }
 
 func (p *paymentRepoStub) Create(ctx context.Context, pay domain.Payment) error {
@@ -109,7 +226,7 @@ func (p *paymentRepoStub) FindByID(ctx context.Context, id uuid.UUID) (domain.Pa
 
 func (p *paymentRepoStub) FindByBookingID(ctx context.Context, bookingID uuid.UUID) (domain.Payment, error) {
 	for _, pay := range p.store {
-		if pay.BookingID == bookingID {
+		if pay.BookingID == bookingID && pay.Kind != domain.KindAdjustment {
 			return pay, nil
 		}
 	}
@@ -127,6 +244,45 @@ func (p *paymentRepoStub) UpdateStatus(ctx context.Context, id uuid.UUID, status
 	return errors.New("not found")
 }
 
+// ListByBooking lists the booking payment before its adjustments.
+func (p *paymentRepoStub) ListByBooking(ctx context.Context, bookingID uuid.UUID) ([]domain.Payment, error) {
+	var lead, adjustments []domain.Payment
+	for _, pay := range p.store {
+		switch {
+		case pay.BookingID != bookingID:
+		case pay.Kind == domain.KindAdjustment:
+			adjustments = append(adjustments, pay)
+		default:
+			lead = append(lead, pay)
+		}
+	}
+	return append(lead, adjustments...), nil
+}
+
+func (p *paymentRepoStub) TransitionStatus(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	if p.beforeTransition != nil {
+		p.beforeTransition()
+		p.beforeTransition = nil
+	}
+	pay, ok := p.store[id]
+	if !ok || pay.Status != from {
+		return false, nil
+	}
+	pay.Status = to
+	p.store[id] = pay
+	return true, nil
+}
+
+func (p *paymentRepoStub) AddRefunded(ctx context.Context, id uuid.UUID, amount float64) (bool, error) {
+	pay, ok := p.store[id]
+	if !ok || pay.Status != domain.StatusPaid || pay.Refunded+amount > pay.Amount || pay.Refunded+amount < 0 {
+		return false, nil
+	}
+	pay.Refunded += amount
+	p.store[id] = pay
+	return true, nil
+}
+
 func (p *paymentRepoStub) Initiate(context.Context, uuid.UUID, float64) (string, error) {
 	return "", nil
 }
@@ -150,9 +306,10 @@ func (p *providerStub) Refund(ctx context.Context, payment domain.Payment, reaso
 
 type bookingUpdaterStub struct {
 	statuses []string
+	err      error
 }
 
 func (b *bookingUpdaterStub) Update(ctx context.Context, bookingID uuid.UUID, status string) error {
 	b.statuses = append(b.statuses, status)
-	return nil
+	return b.err
 }
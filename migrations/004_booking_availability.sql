@@ -0,0 +1,4 @@
+-- Speed up availability checks that look for overlapping stays per room type
+-- Migration: 004_booking_availability.sql
+
+CREATE INDEX IF NOT EXISTS idx_bookings_room_type_stay ON bookings(room_type_id, check_in, check_out);
@@ -0,0 +1,10 @@
+-- Record which physical room a booking occupies after check-in
+-- Migration: 005_room_assignment.sql
+
+ALTER TABLE checkins
+ADD COLUMN IF NOT EXISTS room_id UUID REFERENCES rooms(id);
+
+CREATE INDEX IF NOT EXISTS idx_checkins_room ON checkins(room_id);
+
+-- A room can only hold one active (not yet checked-out) assignment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_checkins_active_room ON checkins(room_id) WHERE check_out_at IS NULL;
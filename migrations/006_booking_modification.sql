@@ -0,0 +1,12 @@
+-- Allow adjustment payments alongside the original booking payment
+-- Migration: 006_booking_modification.sql
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_booking_id_key;
+
+ALTER TABLE payments
+ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'booking';
+
+CREATE INDEX IF NOT EXISTS idx_payments_booking ON payments(booking_id);
+
+-- Each booking still has exactly one booking payment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_booking_kind ON payments(booking_id) WHERE kind = 'booking';
@@ -0,0 +1,12 @@
+-- Cancellation policies per hotel with optional room type overrides
+-- Migration: 007_cancellation_policies.sql
+
+ALTER TABLE hotels
+ADD COLUMN IF NOT EXISTS cancellation_free_days INT,
+ADD COLUMN IF NOT EXISTS cancellation_penalty_percent NUMERIC CHECK (cancellation_penalty_percent BETWEEN 0 AND 100),
+ADD COLUMN IF NOT EXISTS cancellation_non_refundable BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE room_types
+ADD COLUMN IF NOT EXISTS cancellation_free_days INT,
+ADD COLUMN IF NOT EXISTS cancellation_penalty_percent NUMERIC CHECK (cancellation_penalty_percent BETWEEN 0 AND 100),
+ADD COLUMN IF NOT EXISTS cancellation_non_refundable BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,16 @@
+-- Group several booking lines under one reservation and one payment
+-- Migration: 008_reservations.sql
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    lead_booking_id UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_user ON reservations(user_id);
+
+ALTER TABLE bookings
+ADD COLUMN IF NOT EXISTS reservation_id UUID REFERENCES reservations(id);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_reservation ON bookings(reservation_id);
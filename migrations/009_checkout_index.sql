@@ -0,0 +1,4 @@
+-- Index checked_in bookings by check-out date for the auto-checkout job
+-- Migration: 009_checkout_index.sql
+
+CREATE INDEX IF NOT EXISTS idx_bookings_status_check_out ON bookings(status, check_out);
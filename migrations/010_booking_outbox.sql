@@ -0,0 +1,18 @@
+-- Store booking domain events with the booking change that raised them
+-- Migration: 010_booking_outbox.sql
+
+CREATE TABLE IF NOT EXISTS booking_outbox (
+    id UUID PRIMARY KEY,
+    aggregate_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_outbox_aggregate ON booking_outbox(aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_booking_outbox_due ON booking_outbox(status, next_attempt_at);
@@ -0,0 +1,17 @@
+-- Track booking creation sagas (no foreign keys: a saga starts before its booking exists)
+-- Migration: 011_booking_sagas.sql
+
+CREATE TABLE IF NOT EXISTS booking_sagas (
+    id UUID PRIMARY KEY,
+    booking_id UUID NOT NULL,
+    reservation_id UUID,
+    amount NUMERIC NOT NULL,
+    step TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_sagas_booking_id ON booking_sagas(booking_id);
+CREATE INDEX IF NOT EXISTS idx_booking_sagas_stale ON booking_sagas(status, updated_at);
@@ -0,0 +1,10 @@
+-- Track access tokens revoked before their expiry
+-- Migration: 014_revoked_tokens.sql
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id UUID PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
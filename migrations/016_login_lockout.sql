@@ -0,0 +1,17 @@
+-- Failed-login tracking, temporary account lockout and auth audit events
+-- Migration: 016_login_lockout.sql
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS auth_audit_events (
+    id UUID PRIMARY KEY,
+    aggregate_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_events_aggregate ON auth_audit_events(aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_events_type ON auth_audit_events(event_type);
@@ -0,0 +1,13 @@
+-- Hotel-scoped staff roles
+-- Migration: 018_hotel_roles.sql
+
+CREATE TABLE IF NOT EXISTS user_hotel_roles (
+    user_id UUID NOT NULL REFERENCES users(id),
+    hotel_id UUID NOT NULL,
+    role TEXT NOT NULL,
+    granted_by UUID,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (user_id, hotel_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_hotel_roles_hotel ON user_hotel_roles(hotel_id);
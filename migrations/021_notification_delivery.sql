@@ -0,0 +1,6 @@
+-- Asynchronous notification delivery with retries and dead-letter state
+-- Migration: 021_notification_delivery.sql
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(status, next_attempt_at);
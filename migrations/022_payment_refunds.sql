@@ -0,0 +1,4 @@
+-- Running refund total per payment so refunds never exceed what was collected
+-- Migration: 022_payment_refunds.sql
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded NUMERIC NOT NULL DEFAULT 0;
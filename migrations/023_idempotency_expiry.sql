@@ -0,0 +1,6 @@
+-- Expire idempotency records so the table does not grow without bound
+-- Migration: 023_idempotency_expiry.sql
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NOT NULL DEFAULT now() + INTERVAL '24 hours';
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
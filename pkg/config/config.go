@@ -26,6 +26,8 @@ type Config struct {
 	XenditSuccessURL   string
 	XenditFailureURL   string
 	XenditInvoiceDuration time.Duration
+	BookingHoldWindow     time.Duration
+	NoShowChargeNights    int
 	SMTPHost           string
 	SMTPPort           int
 	SMTPUsername       string
@@ -40,11 +42,27 @@ type Config struct {
 	CircuitWindow      time.Duration
 	CircuitThreshold   float64
 	CircuitCooldown    time.Duration
+	RevocationRefreshInterval time.Duration
+	JWTSigningKeyFile  string
+	JWTVerifyKeyFiles  []string
+	ServiceSigningKeyFile string
+	ServiceVerifyKeys  []string
+	JWKSRefreshInterval time.Duration
+	AppURL             string
+	LoginMaxAttempts   int
+	LoginLockoutDuration time.Duration
+	LoginBaseDelay     time.Duration
+	LoginMaxDelay      time.Duration
+	LoginIPMaxAttempts int
+	TrustedProxies     []string
+	NotificationWorkers int
+	NotificationPollInterval time.Duration
 }
 
 // Load reads env vars with defaults.
 func Load() Config {
 	limit := intEnv("RATE_LIMIT_PER_MINUTE", 60)
+	invoiceDuration := durationEnv("XENDIT_INVOICE_DURATION", 15*time.Minute)
 
 	cfg := Config{
 		ServiceName:        getEnv("SERVICE_NAME", "hotel-service"),
@@ -62,7 +80,9 @@ func Load() Config {
 		XenditBaseURL:      getEnv("XENDIT_BASE_URL", "https://api.xendit.co"),
 		XenditSuccessURL:   getEnv("XENDIT_SUCCESS_URL", ""),
 		XenditFailureURL:   getEnv("XENDIT_FAILURE_URL", ""),
-		XenditInvoiceDuration: durationEnv("XENDIT_INVOICE_DURATION", 15*time.Minute),
+		XenditInvoiceDuration: invoiceDuration,
+		BookingHoldWindow:     durationEnv("BOOKING_HOLD_WINDOW", invoiceDuration),
+		NoShowChargeNights:    intEnv("NO_SHOW_CHARGE_NIGHTS", 1),
 		SMTPHost:           getEnv("SMTP_HOST", ""),
 		SMTPPort:           intEnv("SMTP_PORT", 587),
 		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
@@ -77,6 +97,21 @@ func Load() Config {
 		CircuitWindow:      durationEnv("CIRCUIT_BREAKER_WINDOW", 30*time.Second),
 		CircuitThreshold:   floatEnv("CIRCUIT_BREAKER_THRESHOLD", 0.5),
 		CircuitCooldown:    durationEnv("CIRCUIT_BREAKER_COOLDOWN", 15*time.Second),
+		RevocationRefreshInterval: durationEnv("REVOCATION_REFRESH_INTERVAL", 30*time.Second),
+		JWTSigningKeyFile:  getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerifyKeyFiles:  listEnv("JWT_VERIFY_KEY_FILES"),
+		ServiceSigningKeyFile: getEnv("SERVICE_SIGNING_KEY_FILE", ""),
+		ServiceVerifyKeys:  listEnv("SERVICE_VERIFY_KEYS"),
+		JWKSRefreshInterval: durationEnv("JWKS_REFRESH_INTERVAL", 5*time.Minute),
+		AppURL:             getEnv("APP_URL", "http://localhost:3000"),
+		LoginMaxAttempts:   intEnv("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockoutDuration: durationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginBaseDelay:     durationEnv("LOGIN_BASE_DELAY", time.Second),
+		LoginMaxDelay:      durationEnv("LOGIN_MAX_DELAY", 30*time.Second),
+		LoginIPMaxAttempts: intEnv("LOGIN_IP_MAX_ATTEMPTS", 20),
+		TrustedProxies:     listEnv("TRUSTED_PROXIES"),
+		NotificationWorkers: intEnv("NOTIFICATION_WORKERS", 4),
+		NotificationPollInterval: durationEnv("NOTIFICATION_POLL_INTERVAL", time.Second),
 	}
 
 	if cfg.ServiceName == "" {
@@ -119,3 +154,13 @@ func floatEnv(key string, fallback float64) float64 {
 	}
 	return fallback
 }
+
+func listEnv(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/idempotency"
+)
+
+type idempotencyKeyModel struct {
+	UserID      string `gorm:"primaryKey"`
+	Key         string `gorm:"column:idempotency_key;primaryKey"`
+	RequestHash string
+	State       string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index"`
+}
+
+func (idempotencyKeyModel) TableName() string { return "idempotency_keys" }
+
+// IdempotencyStore keeps idempotency records in postgres so replays survive
+// restarts and are shared between replicas. Records expire after
+// idempotency.Retention and are purged by StartCleanup.
+type IdempotencyStore struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyStore builds a GORM backed idempotency.Store.
+func NewIdempotencyStore(db *gorm.DB) *IdempotencyStore { return &IdempotencyStore{db: db} }
+
+// MigrateIdempotency ensures the idempotency_keys table exists.
+func MigrateIdempotency(db *gorm.DB) error {
+	return db.AutoMigrate(&idempotencyKeyModel{})
+}
+
+func (s *IdempotencyStore) Begin(ctx context.Context, userID, key, hash string, staleBefore time.Time) (idempotency.Record, bool, error) {
+	now := time.Now()
+	model := idempotencyKeyModel{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: hash,
+		State:       idempotency.InProgress,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(idempotency.Retention),
+	}
+	res := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+	if res.Error != nil {
+		return idempotency.Record{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return model.toRecord(), true, nil
+	}
+
+	// An expired record not yet purged no longer holds the key.
+	res = s.db.WithContext(ctx).Model(&idempotencyKeyModel{}).
+		Where("user_id = ? AND idempotency_key = ? AND expires_at < ?", userID, key, now).
+		Updates(map[string]any{
+			"request_hash": hash,
+			"state":        idempotency.InProgress,
+			"status_code":  0,
+			"content_type": "",
+			"body":         nil,
+			"created_at":   now,
+			"updated_at":   now,
+			"expires_at":   model.ExpiresAt,
+		})
+	if res.Error != nil {
+		return idempotency.Record{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return model.toRecord(), true, nil
+	}
+
+	// Take over a key whose holder never finished, as long as it was for the same request.
+	res = s.db.WithContext(ctx).Model(&idempotencyKeyModel{}).
+		Where("user_id = ? AND idempotency_key = ? AND request_hash = ? AND state = ? AND updated_at < ?",
+			userID, key, hash, idempotency.InProgress, staleBefore).
+		Update("updated_at", now)
+	if res.Error != nil {
+		return idempotency.Record{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return model.toRecord(), true, nil
+	}
+
+	var existing idempotencyKeyModel
+	if err := s.db.WithContext(ctx).Take(&existing, "user_id = ? AND idempotency_key = ?", userID, key).Error; err != nil {
+		return idempotency.Record{}, false, err
+	}
+	return existing.toRecord(), false, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, userID, key string, status int, contentType string, body []byte) error {
+	return s.db.WithContext(ctx).Model(&idempotencyKeyModel{}).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		Updates(map[string]any{
+			"state":        idempotency.Completed,
+			"status_code":  status,
+			"content_type": contentType,
+			"body":         body,
+			"updated_at":   time.Now(),
+		}).Error
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, userID, key string) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ? AND state = ?", userID, key, idempotency.InProgress).
+		Delete(&idempotencyKeyModel{}).Error
+}
+
+// DeleteExpired removes records that expired before now and returns how many.
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&idempotencyKeyModel{})
+	return res.RowsAffected, res.Error
+}
+
+// StartCleanup purges expired records every interval until ctx is done.
+func (s *IdempotencyStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			purgeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			_, _ = s.DeleteExpired(purgeCtx, time.Now())
+			cancel()
+		}
+	}()
+}
+
+func (m idempotencyKeyModel) toRecord() idempotency.Record {
+	return idempotency.Record{
+		UserID:      m.UserID,
+		Key:         m.Key,
+		RequestHash: m.RequestHash,
+		State:       m.State,
+		StatusCode:  m.StatusCode,
+		ContentType: m.ContentType,
+		Body:        m.Body,
+		CreatedAt:   m.CreatedAt,
+		ExpiresAt:   m.ExpiresAt,
+	}
+}
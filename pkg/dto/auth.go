@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // RegisterRequest for creating new users.
 type RegisterRequest struct {
 	Email    string `json:"email"`
@@ -13,18 +18,135 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RefreshRequest exchanges a refresh token for new tokens.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest optionally names the refresh token to revoke along with the access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// ForgotPasswordRequest asks for a password reset link to be mailed.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password with a mailed reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest confirms an email address with a mailed verification token.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// RevokedTokenResponse is an access token ID revoked until it expires.
+type RevokedTokenResponse struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RevocationListResponse lists revoked, unexpired access tokens.
+type RevocationListResponse struct {
+	Tokens []RevokedTokenResponse `json:"tokens"`
+}
+
 // AuthResponse returns JWT tokens plus user info.
 type AuthResponse struct {
 	ID           string `json:"id"`
 	Email        string `json:"email"`
 	Role         string `json:"role"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// MFARequired replaces the tokens when the password was right but the
+	// account needs a second factor; MFAToken is exchanged for them.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+// MFAChallengeRequest completes a login with a TOTP or recovery code.
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// MFASetupResponse carries the secret to add to an authenticator app.
+type MFASetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFAVerifyRequest confirms enrollment with a code from the authenticator.
+type MFAVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerifyResponse lists recovery codes; they are shown only once.
+type MFAVerifyResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 // ProfileResponse shows user data.
 type ProfileResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID            string            `json:"id"`
+	Email         string            `json:"email"`
+	Role          string            `json:"role"`
+	Name          string            `json:"name,omitempty"`
+	Phone         string            `json:"phone,omitempty"`
+	Locale        string            `json:"locale,omitempty"`
+	Preferences   map[string]string `json:"preferences,omitempty"`
+	EmailVerified bool              `json:"email_verified"`
+	LockedUntil   *time.Time        `json:"locked_until,omitempty"`
+	MFAEnabled    bool              `json:"mfa_enabled"`
+	ErasedAt      *time.Time        `json:"erased_at,omitempty"`
+}
+
+// UpdateProfileRequest changes the fields present. An empty string clears a
+// field; preferences, when present, replace the stored set.
+type UpdateProfileRequest struct {
+	Name        *string           `json:"name,omitempty"`
+	Phone       *string           `json:"phone,omitempty"`
+	Locale      *string           `json:"locale,omitempty"`
+	Preferences map[string]string `json:"preferences,omitempty"`
+}
+
+// ChangePasswordRequest sets a new password for the signed-in user.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// EraseAccountRequest confirms account erasure with the user's password.
+type EraseAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DataExportResponse is everything the platform holds about a user. Records
+// from other services keep those services' representation.
+type DataExportResponse struct {
+	Profile       ProfileResponse     `json:"profile"`
+	HotelRoles    []HotelRoleResponse `json:"hotel_roles"`
+	Bookings      []json.RawMessage   `json:"bookings"`
+	Payments      []json.RawMessage   `json:"payments"`
+	Notifications []json.RawMessage   `json:"notifications"`
+	ExportedAt    time.Time           `json:"exported_at"`
+}
+
+// HotelRoleRequest grants a staff role at a hotel.
+type HotelRoleRequest struct {
+	HotelID string `json:"hotel_id"`
+	Role    string `json:"role"`
+}
+
+// HotelRoleResponse shows a staff role grant.
+type HotelRoleResponse struct {
+	UserID    string    `json:"user_id"`
+	HotelID   string    `json:"hotel_id"`
+	Role      string    `json:"role"`
+	GrantedBy string    `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -35,9 +36,9 @@ func (d Date) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf(`"%s"`, d.Time.Format("2006-01-02"))), nil
 }
 
-// BookingRequest is used for booking creation.
+// BookingRequest is used for booking creation; the booking belongs to the
+// authenticated user.
 type BookingRequest struct {
-	UserID     string `json:"user_id"`
 	RoomTypeID string `json:"room_type_id"`
 	CheckIn    Date   `json:"check_in"`
 	CheckOut   Date   `json:"check_out"`
@@ -54,6 +55,9 @@ type BookingResponse struct {
 	CheckIn     time.Time        `json:"check_in"`
 	CheckOut    time.Time        `json:"check_out"`
 	Payment     *PaymentResponse `json:"payment,omitempty"`
+	Room        *RoomAssignment  `json:"room,omitempty"`
+
+	ReservationID string `json:"reservation_id,omitempty"`
 }
 
 // BookingAggregateResponse merges booking+payment.
@@ -63,6 +67,100 @@ type BookingAggregateResponse struct {
 }
 
 // CheckpointRequest handles lifecycle updates.
+// RoomNumber optionally picks the room on check_in; the first free room is used otherwise.
 type CheckpointRequest struct {
-	Action string `json:"action"`
+	Action     string `json:"action"`
+	RoomNumber string `json:"room_number,omitempty"`
+}
+
+// RoomAssignment shows the physical room held by a checked-in booking.
+type RoomAssignment struct {
+	RoomID       string     `json:"room_id"`
+	RoomNumber   string     `json:"room_number"`
+	CheckedInAt  time.Time  `json:"checked_in_at"`
+	CheckedOutAt *time.Time `json:"checked_out_at,omitempty"`
+}
+
+// ModifyBookingRequest amends a confirmed booking; omitted fields keep their current value.
+type ModifyBookingRequest struct {
+	RoomTypeID string `json:"room_type_id,omitempty"`
+	CheckIn    Date   `json:"check_in"`
+	CheckOut   Date   `json:"check_out"`
+	Guests     int    `json:"guests,omitempty"`
+}
+
+// BookingModificationResponse returns the amended booking with its price settlement.
+// A positive price_delta carries the adjustment payment, a negative one the refund.
+type BookingModificationResponse struct {
+	Booking    BookingResponse  `json:"booking"`
+	PriceDelta float64          `json:"price_delta"`
+	Payment    *PaymentResponse `json:"payment,omitempty"`
+	Refund     *RefundResponse  `json:"refund,omitempty"`
+}
+
+// BookingCancellationResponse returns the cancelled booking with the policy
+// settlement; the refund amount is paid back asynchronously.
+type BookingCancellationResponse struct {
+	Booking      BookingResponse `json:"booking"`
+	Penalty      float64         `json:"penalty"`
+	RefundAmount float64         `json:"refund_amount"`
+}
+
+// BookingSearchQuery carries the raw filters of the booking list endpoint.
+// Dates accept YYYY-MM-DD or RFC3339; *_from bounds are inclusive and *_to bounds exclusive.
+type BookingSearchQuery struct {
+	UserID       string
+	Status       string // comma separated
+	HotelID      string
+	RoomTypeID   string
+	CheckInFrom  string
+	CheckInTo    string
+	CheckOutFrom string
+	CheckOutTo   string
+	CreatedFrom  string
+	CreatedTo    string
+	Sort         string // comma separated, "-" prefix for descending
+	Limit        int
+	Offset       int
+}
+
+// ReservationLine is one room of a multi-room reservation.
+type ReservationLine struct {
+	RoomTypeID string `json:"room_type_id"`
+	CheckIn    Date   `json:"check_in"`
+	CheckOut   Date   `json:"check_out"`
+	Guests     int    `json:"guests"`
+}
+
+// ReservationRequest books several rooms together under one payment.
+type ReservationRequest struct {
+	Lines []ReservationLine `json:"lines"`
+}
+
+// ReservationResponse returns a reservation with its booking lines and combined payment.
+type ReservationResponse struct {
+	ID         string            `json:"id"`
+	TotalPrice float64           `json:"total_price"`
+	Lines      []BookingResponse `json:"lines"`
+	Payment    *PaymentResponse  `json:"payment,omitempty"`
+}
+
+// ReservationCancellationResponse returns the settlement of every cancelled line.
+type ReservationCancellationResponse struct {
+	ID    string                        `json:"id"`
+	Lines []BookingCancellationResponse `json:"lines"`
+}
+
+// OutboxMessageResponse shows a booking event waiting for or past delivery.
+type OutboxMessageResponse struct {
+	ID            string          `json:"id"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
 }
@@ -4,9 +4,17 @@ import "time"
 
 // HotelRequest defines admin input.
 type HotelRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Address     string `json:"address"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description"`
+	Address            string              `json:"address"`
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
+}
+
+// CancellationPolicy configures refunds for cancelled confirmed bookings.
+type CancellationPolicy struct {
+	FreeCancellationDays int     `json:"free_cancellation_days"`
+	PenaltyPercent       float64 `json:"penalty_percent"`
+	NonRefundable        bool    `json:"non_refundable"`
 }
 
 // RoomTypeRequest configures hotel room types.
@@ -16,6 +24,8 @@ type RoomTypeRequest struct {
 	Capacity  int     `json:"capacity"`
 	BasePrice float64 `json:"base_price"`
 	Amenities string  `json:"amenities"`
+
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
 }
 
 // RoomTypeResponse exposes room type details.
@@ -26,6 +36,8 @@ type RoomTypeResponse struct {
 	Capacity  int     `json:"capacity"`
 	BasePrice float64 `json:"base_price"`
 	Amenities string  `json:"amenities"`
+
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
 }
 
 // RoomRequest describes a physical room.
@@ -51,6 +63,8 @@ type HotelResponse struct {
 	Address     string            `json:"address"`
 	CreatedAt   time.Time         `json:"created_at"`
 	RoomTypes   []RoomTypeSummary `json:"room_types"`
+
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
 }
 
 // RoomTypeSummary short view.
@@ -62,10 +76,12 @@ type RoomTypeSummary struct {
 }
 
 // HotelUpdateRequest for updating hotel details.
+// An omitted cancellation_policy keeps the current one.
 type HotelUpdateRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Address     string `json:"address"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description"`
+	Address            string              `json:"address"`
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty"`
 }
 
 // RoomUpdateRequest for updating room details.
@@ -73,3 +89,21 @@ type RoomUpdateRequest struct {
 	Number string `json:"number,omitempty"`
 	Status string `json:"status,omitempty"`
 }
+
+// RoomTypeAvailabilityResponse is the per-night calendar of a room type.
+type RoomTypeAvailabilityResponse struct {
+	RoomTypeID string              `json:"room_type_id"`
+	HotelID    string              `json:"hotel_id"`
+	Name       string              `json:"name"`
+	Nights     []NightAvailability `json:"nights"`
+}
+
+// NightAvailability shows inventory for one night.
+type NightAvailability struct {
+	Date           string  `json:"date"`
+	TotalRooms     int     `json:"total_rooms"`
+	BookedRooms    int     `json:"booked_rooms"`
+	OutOfOrder     int     `json:"out_of_order_rooms"`
+	RemainingRooms int     `json:"remaining_rooms"`
+	NightlyPrice   float64 `json:"nightly_price"`
+}
@@ -9,11 +9,34 @@ type NotificationRequest struct {
 	Message string `json:"message"`
 }
 
-// NotificationResponse captures stored notification metadata.
+// NotificationResponse captures stored notification metadata and delivery state.
 type NotificationResponse struct {
-	ID        string    `json:"id"`
-	Type      string    `json:"type"`
-	Target    string    `json:"target"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string     `json:"id"`
+	Type          string     `json:"type"`
+	Target        string     `json:"target"`
+	Message       string     `json:"message"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // set while a failed delivery waits for its retry
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// NotificationSearchQuery carries the raw filters of the notification list endpoint.
+// Dates accept YYYY-MM-DD or RFC3339; created_from is inclusive and created_to exclusive.
+type NotificationSearchQuery struct {
+	Type        string
+	Target      string
+	Status      string
+	CreatedFrom string
+	CreatedTo   string
+	Limit       int
+	Offset      int
+}
+
+// EraseNotificationsRequest names the recipient whose notifications are erased.
+type EraseNotificationsRequest struct {
+	Target string `json:"target"`
 }
@@ -1,18 +1,23 @@
 package dto
 
 // PaymentRequest triggers payment provider.
+// Kind defaults to "booking"; "adjustment" requests a supplementary charge.
 type PaymentRequest struct {
 	BookingID string  `json:"booking_id"`
 	Amount    float64 `json:"amount"`
 	Currency  string  `json:"currency"`
+	Kind      string  `json:"kind,omitempty"`
 }
 
 // PaymentResponse describes created payment.
 type PaymentResponse struct {
-	ID         string `json:"id"`
-	Status     string `json:"status"`
-	Provider   string `json:"provider"`
-	PaymentURL string `json:"payment_url"`
+	ID         string  `json:"id"`
+	Amount     float64 `json:"amount,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Refunded   float64 `json:"refunded,omitempty"`
+	Status     string  `json:"status"`
+	Provider   string  `json:"provider"`
+	PaymentURL string  `json:"payment_url"`
 }
 
 // WebhookRequest is provider callback payload.
@@ -29,9 +34,16 @@ type RefundRequest struct {
 	Reason    string  `json:"reason"`
 }
 
+// BookingRefundRequest returns up to Amount of what a booking has paid.
+type BookingRefundRequest struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
 // RefundResponse describes refund status.
 type RefundResponse struct {
-	ID        string `json:"id"`
-	Status    string `json:"status"`
-	Reference string `json:"reference"`
+	ID        string  `json:"id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
+	Reference string  `json:"reference"`
 }
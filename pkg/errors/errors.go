@@ -40,6 +40,10 @@ func StatusCode(err APIError) int {
 		return http.StatusNotFound
 	case "conflict":
 		return http.StatusConflict
+	case "unprocessable_entity":
+		return http.StatusUnprocessableEntity
+	case "too_many_requests":
+		return http.StatusTooManyRequests
 	case "bad_gateway", "upstream_error":
 		return http.StatusBadGateway
 	default:
@@ -0,0 +1,44 @@
+// Package idempotency defines the records kept for requests made with an
+// idempotency key. It sits below both the HTTP middleware that enforces keys
+// and the database package that stores them, so neither depends on the other.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Record states.
+const (
+	InProgress = "in_progress"
+	Completed  = "completed"
+)
+
+// Retention is how long a record is kept; a key may be reused afterwards.
+const Retention = 24 * time.Hour
+
+// Record is the stored outcome of a request made with an idempotency key.
+type Record struct {
+	UserID      string
+	Key         string
+	RequestHash string
+	State       string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Store persists idempotency records per user and key.
+type Store interface {
+	// Begin claims the key for a request with the given hash. When the key is
+	// already held it returns the existing record and false; an in-progress
+	// record last claimed before staleBefore is taken over instead, and so is
+	// any record past its expiry.
+	Begin(ctx context.Context, userID, key, hash string, staleBefore time.Time) (Record, bool, error)
+	// Complete stores the response of the request holding the key.
+	Complete(ctx context.Context, userID, key string, status int, contentType string, body []byte) error
+	// Release frees the key so the request can be retried.
+	Release(ctx context.Context, userID, key string) error
+}
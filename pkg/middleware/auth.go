@@ -18,15 +18,16 @@ type contextKey string
 const AuthContextKey contextKey = "auth_claims"
 const AuthTokenKey contextKey = "auth_token"
 
-// Claims extends JWT with extra info.
-type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
+// JWT middleware validates Authorization header. Only service tokens signed
+// with secret are accepted; user tokens need Authenticate with a key source.
+func JWT(secret string, roles ...string) func(http.Handler) http.Handler {
+	return Authenticate(NewVerifier(secret, nil), nil, roles...)
 }
 
-// JWT middleware validates Authorization header.
-func JWT(secret string, roles ...string) func(http.Handler) http.Handler {
+// Authenticate validates the Authorization header with verifier and rejects
+// tokens whose jti is on the revocation list. When roles are given, admin
+// tokens only pass if they were issued after a second factor.
+func Authenticate(verifier *Verifier, revoked RevocationChecker, roles ...string) func(http.Handler) http.Handler {
 	allowed := map[string]struct{}{}
 	for _, role := range roles {
 		allowed[role] = struct{}{}
@@ -40,20 +41,25 @@ func JWT(secret string, roles ...string) func(http.Handler) http.Handler {
 				return
 			}
 
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(secret), nil
-			})
-			if err != nil || !token.Valid {
+			claims, err := verifier.ParseAccess(r.Context(), tokenString)
+			if err != nil {
 				writeError(w, errors.New("unauthorized", "invalid token"))
 				return
 			}
+			if revoked != nil && revoked.Revoked(claims.ID) {
+				writeError(w, errors.New("unauthorized", "token revoked"))
+				return
+			}
 
 			if len(allowed) > 0 {
 				if _, ok := allowed[claims.Role]; !ok {
 					writeError(w, errors.New("forbidden", "insufficient role"))
 					return
 				}
+				if claims.Role == RoleAdmin && !claims.MFA() {
+					writeError(w, errors.New("forbidden", "admin access requires multi-factor authentication"))
+					return
+				}
 			}
 
 			ctx := context.WithValue(r.Context(), AuthContextKey, claims)
@@ -63,6 +69,19 @@ func JWT(secret string, roles ...string) func(http.Handler) http.Handler {
 	}
 }
 
+// RoleService marks tokens minted for service-to-service calls.
+const RoleService = "service"
+
+// RoleAdmin is the role of platform administrators.
+const RoleAdmin = "admin"
+
+// IssueServiceToken signs a short-lived token identifying a calling service
+// with the shared secret; see ServiceSigner for per-service keys.
+func IssueServiceToken(secret, service string, ttl time.Duration) (string, error) {
+	claims := NewClaims(TokenTypeAccess, service, RoleService, "", time.Now(), ttl)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
 func extractToken(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
 	parts := strings.Split(auth, " ")
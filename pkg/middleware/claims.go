@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+const (
+	// ClaimsIssuer is the iss of every token accepted by the platform.
+	ClaimsIssuer = "hotel-booking-auth"
+	// ClaimsAudience is the aud of every token accepted by the platform.
+	ClaimsAudience = "hotel-booking-api"
+
+	// TokenTypeAccess marks tokens that authorize API calls.
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh marks tokens that can only be exchanged at /auth/refresh.
+	TokenTypeRefresh = "refresh"
+	// TokenTypeMFA marks login challenges that can only be exchanged, with a
+	// second factor, at /auth/mfa/challenge.
+	TokenTypeMFA = "mfa"
+
+	// AMRPassword is the amr value of a password login (RFC 8176).
+	AMRPassword = "pwd"
+	// AMROTP is the amr value of a login confirmed with a one-time code.
+	AMROTP = "otp"
+)
+
+// Claims is the JWT contract shared by the auth-service issuer and every
+// validator. Subject and UserID both carry the user ID, or the calling
+// service's name for service tokens.
+type Claims struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+	Email  string `json:"email,omitempty"`
+	// EmailVerified is set on access tokens of users who confirmed Email.
+	EmailVerified bool `json:"email_verified,omitempty"`
+	// AMR lists the authentication methods of the login the token descends from.
+	AMR []string `json:"amr,omitempty"`
+	// HotelRoles scopes staff roles to the hotels they were granted for.
+	HotelRoles []HotelRole `json:"hotel_roles,omitempty"`
+	Type       string      `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// HotelRole is a staff role granted for one hotel.
+type HotelRole struct {
+	HotelID string `json:"hotel_id"`
+	Role    string `json:"role"`
+}
+
+// MFA reports whether the token was issued after a second factor.
+func (c *Claims) MFA() bool {
+	for _, method := range c.AMR {
+		if method == AMROTP {
+			return true
+		}
+	}
+	return false
+}
+
+// Admin reports whether the token grants admin rights: the admin role is
+// only honoured on tokens issued after a second factor.
+func (c *Claims) Admin() bool {
+	return c.Role == RoleAdmin && c.MFA()
+}
+
+// NewClaims builds claims of tokenType for subject, valid for ttl from now
+// and identified by a fresh jti.
+func NewClaims(tokenType, subject, role, email string, now time.Time, ttl time.Duration) Claims {
+	return Claims{
+		UserID: subject,
+		Role:   role,
+		Email:  email,
+		Type:   tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    ClaimsIssuer,
+			Audience:  jwt.ClaimStrings{ClaimsAudience},
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+// Verifier checks tokens against the platform's keys. User tokens are signed
+// by auth-service with an asymmetric key named by the kid header. Service
+// tokens are signed with the calling service's own key, which auth-service
+// publishes bound to that service, so a service cannot speak for another.
+// The shared secret, when set, is a fallback for deployments without service
+// keys and is honoured only for tokens with the service role, so a service
+// holding it cannot mint user or admin tokens.
+type Verifier struct {
+	serviceSecret []byte
+	keys          KeySource
+}
+
+func NewVerifier(serviceSecret string, keys KeySource) *Verifier {
+	return &Verifier{serviceSecret: []byte(serviceSecret), keys: keys}
+}
+
+// Parse verifies the signature, issuer, audience and expiry of token.
+func (v *Verifier) Parse(ctx context.Context, token string) (*Claims, error) {
+	invalid := errors.New("unauthorized", "invalid token")
+	claims := &Claims{}
+	var key VerificationKey
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			if len(v.serviceSecret) == 0 {
+				return nil, invalid
+			}
+			return v.serviceSecret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		if v.keys == nil || kid == "" {
+			return nil, invalid
+		}
+		var err error
+		if key, err = v.keys.Key(ctx, kid); err != nil {
+			return nil, err
+		}
+		return key.Public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(ClaimsIssuer),
+		jwt.WithAudience(ClaimsAudience),
+	)
+	if err != nil || !parsed.Valid || claims.ExpiresAt == nil {
+		return nil, invalid
+	}
+	if _, shared := parsed.Method.(*jwt.SigningMethodHMAC); shared && claims.Role != RoleService {
+		return nil, invalid
+	}
+	// A service key only vouches for its own service.
+	if key.Service != "" && (claims.Role != RoleService || claims.Subject != key.Service || claims.UserID != key.Service) {
+		return nil, invalid
+	}
+	return claims, nil
+}
+
+// ParseAccess is Parse restricted to access tokens, so refresh tokens cannot
+// authorize API calls.
+func (v *Verifier) ParseAccess(ctx context.Context, token string) (*Claims, error) {
+	claims, err := v.Parse(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeAccess {
+		return nil, errors.New("unauthorized", "invalid token")
+	}
+	return claims, nil
+}
+
+// ClaimsFromContext returns the claims JWT stored for the request.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(AuthContextKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// UserIDFromContext returns the ID of the user the request's token was issued to.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, errors.New("unauthorized", "missing claims")
+	}
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, errors.New("unauthorized", "token is not issued to a user")
+	}
+	return id, nil
+}
+
+// RequireVerifiedEmail rejects requests whose token belongs to a user who
+// has not verified their email address. It must run after Authenticate.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			writeError(w, errors.New("unauthorized", "missing claims"))
+			return
+		}
+		if !claims.EmailVerified {
+			writeError(w, errors.New("forbidden", "email address not verified"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,210 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+func TestVerifierValidatesClaims(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := middleware.NewVerifier("secret", keys)
+	claims := middleware.NewClaims(middleware.TokenTypeAccess, uuid.NewString(), "admin", "", time.Now(), time.Minute)
+	_, err := verifier.ParseAccess(context.Background(), keys.sign(t, claims))
+	require.NoError(t, err)
+
+	foreign := claims
+	foreign.Issuer = "someone-else"
+	_, err = verifier.ParseAccess(context.Background(), keys.sign(t, foreign))
+	require.Error(t, err)
+
+	foreign = claims
+	foreign.Audience = jwt.ClaimStrings{"another-api"}
+	_, err = verifier.ParseAccess(context.Background(), keys.sign(t, foreign))
+	require.Error(t, err)
+
+	foreign = claims
+	foreign.ExpiresAt = nil
+	_, err = verifier.ParseAccess(context.Background(), keys.sign(t, foreign))
+	require.Error(t, err)
+
+	// the shared secret cannot mint user or admin tokens, only service tokens
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = verifier.ParseAccess(context.Background(), forged)
+	require.Error(t, err)
+
+	service, err := middleware.IssueServiceToken("secret", "booking-service", time.Minute)
+	require.NoError(t, err)
+	got, err := verifier.ParseAccess(context.Background(), service)
+	require.NoError(t, err)
+	require.Equal(t, middleware.RoleService, got.Role)
+}
+
+func TestAuthenticateRequiresMFAForAdmins(t *testing.T) {
+	keys := newTestKeys(t)
+	handler := middleware.Authenticate(middleware.NewVerifier("", keys), nil, "admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(amr ...string) int {
+		claims := middleware.NewClaims(middleware.TokenTypeAccess, uuid.NewString(), "admin", "", time.Now(), time.Minute)
+		claims.AMR = amr
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+keys.sign(t, claims))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusForbidden, call())
+	require.Equal(t, http.StatusForbidden, call(middleware.AMRPassword))
+	require.Equal(t, http.StatusOK, call(middleware.AMRPassword, middleware.AMROTP))
+}
+
+func TestJWKSCacheFetchesPublishedKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	fetches := 0
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		jwk, err := middleware.NewJWK(keys.private.Public())
+		require.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(middleware.JWKSet{Keys: []middleware.JWK{jwk}})
+	}))
+	defer auth.Close()
+
+	cache := middleware.NewJWKSCache(auth.URL+middleware.JWKSPath, time.Hour)
+	verifier := middleware.NewVerifier("", cache)
+	claims := middleware.NewClaims(middleware.TokenTypeAccess, uuid.NewString(), "customer", "", time.Now(), time.Minute)
+
+	// an unknown kid triggers the first fetch
+	_, err := verifier.ParseAccess(context.Background(), keys.sign(t, claims))
+	require.NoError(t, err)
+	_, err = verifier.ParseAccess(context.Background(), keys.sign(t, claims))
+	require.NoError(t, err)
+	require.Equal(t, 1, fetches)
+
+	// unknown kids do not refetch more than once per interval
+	other := newTestKeys(t)
+	_, err = verifier.ParseAccess(context.Background(), other.sign(t, claims))
+	require.Error(t, err)
+	require.Equal(t, 1, fetches)
+}
+
+func TestServiceKeysOnlyVouchForTheirService(t *testing.T) {
+	keys := newTestKeys(t)
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwk, err := middleware.NewJWK(keys.private.Public())
+		require.NoError(t, err)
+		jwk.Service = "booking-service"
+		_ = json.NewEncoder(w).Encode(middleware.JWKSet{Keys: []middleware.JWK{jwk}})
+	}))
+	defer auth.Close()
+
+	cache := middleware.NewJWKSCache(auth.URL+middleware.JWKSPath, time.Hour)
+	verifier := middleware.NewVerifier("", cache)
+	signer, err := middleware.NewServiceSigner("booking-service", keys.private)
+	require.NoError(t, err)
+	token, err := signer.Token(time.Minute)
+	require.NoError(t, err)
+	claims, err := verifier.ParseAccess(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, "booking-service", claims.Subject)
+
+	// the key cannot speak for another service
+	impostor, err := middleware.NewServiceSigner("payment-service", keys.private)
+	require.NoError(t, err)
+	token, err = impostor.Token(time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.ParseAccess(context.Background(), token)
+	require.Error(t, err)
+
+	// nor mint user or admin tokens
+	admin := middleware.NewClaims(middleware.TokenTypeAccess, uuid.NewString(), "admin", "", time.Now(), time.Minute)
+	_, err = verifier.ParseAccess(context.Background(), keys.sign(t, admin))
+	require.Error(t, err)
+}
+
+func TestAllowServicesRejectsOtherServices(t *testing.T) {
+	keys := newTestKeys(t)
+	handler := middleware.Authenticate(middleware.NewVerifier("secret", keys), nil, "admin", middleware.RoleService)(
+		middleware.AllowServices("booking-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	call := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	booking, err := middleware.NewSharedSecretSigner("booking-service", "secret").Token(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, call(booking))
+
+	payment, err := middleware.NewSharedSecretSigner("payment-service", "secret").Token(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, call(payment))
+
+	// other roles are left to Authenticate
+	admin := middleware.NewClaims(middleware.TokenTypeAccess, uuid.NewString(), "admin", "", time.Now(), time.Minute)
+	admin.AMR = []string{middleware.AMRPassword, middleware.AMROTP}
+	require.Equal(t, http.StatusOK, call(keys.sign(t, admin)))
+}
+
+func TestUserIDFromContext(t *testing.T) {
+	_, err := middleware.UserIDFromContext(context.Background())
+	require.Error(t, err)
+
+	userID := uuid.New()
+	ctx := context.WithValue(context.Background(), middleware.AuthContextKey, &middleware.Claims{UserID: userID.String()})
+	got, err := middleware.UserIDFromContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, userID, got)
+
+	ctx = context.WithValue(context.Background(), middleware.AuthContextKey, &middleware.Claims{UserID: "booking-service"})
+	_, err = middleware.UserIDFromContext(ctx)
+	require.Error(t, err)
+}
+
+// testKeys signs tokens with one Ed25519 key and resolves it by kid.
+type testKeys struct {
+	kid     string
+	private ed25519.PrivateKey
+	service string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwk, err := middleware.NewJWK(private.Public())
+	require.NoError(t, err)
+	return &testKeys{kid: jwk.Kid, private: private}
+}
+
+func (k *testKeys) Key(_ context.Context, kid string) (middleware.VerificationKey, error) {
+	if kid != k.kid {
+		return middleware.VerificationKey{}, pkgErrors.New("unauthorized", "unknown signing key")
+	}
+	return middleware.VerificationKey{Public: k.private.Public(), Service: k.service}, nil
+}
+
+func (k *testKeys) sign(t *testing.T, claims middleware.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	require.NoError(t, err)
+	return signed
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/idempotency"
+)
+
+// IdempotencyHeader carries the client chosen key identifying one logical request.
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyReplayedHeader marks responses replayed from a stored record.
+const IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyLockTimeout is how long a request may hold its key before a
+// retry may take it over, e.g. after the service crashed mid-request.
+const idempotencyLockTimeout = time.Minute
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to
+// retry. The first request with a key runs and its response is stored; a
+// retry with the same payload gets the stored response replayed, while
+// reuse of the key for a different payload is rejected. Keys are scoped to
+// the authenticated user, so it must run after JWT. Server errors release
+// the key so the request can be retried.
+func Idempotency(store idempotency.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				writeError(w, errors.New("bad_request", "idempotency key too long"))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, errors.New("bad_request", "invalid body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			userID := ""
+			if claims, ok := ClaimsFromContext(r.Context()); ok {
+				userID = claims.UserID
+			}
+			hash := requestHash(r, body)
+
+			record, acquired, err := store.Begin(r.Context(), userID, key, hash, time.Now().Add(-idempotencyLockTimeout))
+			if err != nil {
+				writeError(w, errors.New("internal_error", "idempotency store unavailable"))
+				return
+			}
+			if !acquired {
+				switch {
+				case record.RequestHash != hash:
+					writeError(w, errors.New("unprocessable_entity", "idempotency key reused with a different request"))
+				case record.State != idempotency.Completed:
+					writeError(w, errors.New("conflict", "request with this idempotency key is still in progress"))
+				default:
+					if record.ContentType != "" {
+						w.Header().Set("Content-Type", record.ContentType)
+					}
+					w.Header().Set(IdempotencyReplayedHeader, "true")
+					w.WriteHeader(record.StatusCode)
+					_, _ = w.Write(record.Body)
+				}
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				// Use a fresh context: the outcome must be recorded even when the client went away.
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if p := recover(); p != nil {
+					_ = store.Release(ctx, userID, key)
+					panic(p)
+				}
+				if rec.status >= http.StatusInternalServerError {
+					_ = store.Release(ctx, userID, key)
+					return
+				}
+				_ = store.Complete(ctx, userID, key, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes())
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// requestHash fingerprints the method, path and body of a request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
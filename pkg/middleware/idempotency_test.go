@@ -0,0 +1,138 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	sqlite "github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/database"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/idempotency"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+func TestIdempotency(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.MigrateIdempotency(db))
+
+	calls := 0
+	status := http.StatusCreated
+	handler := middleware.Idempotency(database.NewIdempotencyStore(db))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+	send := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyHeader, key)
+		}
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, &middleware.Claims{UserID: userID}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("user-1", "key-1", `{"guests":2}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Equal(t, `{"call":1}`, first.Body.String())
+
+	// a retry replays the stored response without running the handler again
+	retry := send("user-1", "key-1", `{"guests":2}`)
+	require.Equal(t, 1, calls)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, `{"call":1}`, retry.Body.String())
+	require.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	require.Equal(t, "true", retry.Header().Get(middleware.IdempotencyReplayedHeader))
+
+	// reusing the key for another payload is rejected
+	require.Equal(t, http.StatusUnprocessableEntity, send("user-1", "key-1", `{"guests":3}`).Code)
+	require.Equal(t, 1, calls)
+
+	// keys are scoped per user, and requests without a key are not tracked
+	require.Equal(t, http.StatusCreated, send("user-2", "key-1", `{"guests":2}`).Code)
+	require.Equal(t, http.StatusCreated, send("user-1", "", `{"guests":2}`).Code)
+	require.Equal(t, 3, calls)
+
+	// server errors free the key for another attempt
+	status = http.StatusBadGateway
+	require.Equal(t, http.StatusBadGateway, send("user-1", "key-2", `{}`).Code)
+	status = http.StatusCreated
+	require.Equal(t, http.StatusCreated, send("user-1", "key-2", `{}`).Code)
+	require.Equal(t, 5, calls)
+}
+
+func TestIdempotencyStoreLocks(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.MigrateIdempotency(db))
+	store := database.NewIdempotencyStore(db)
+	ctx := context.Background()
+
+	_, acquired, err := store.Begin(ctx, "user-1", "lock", "hash", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// a concurrent retry sees the request in progress
+	record, acquired, err := store.Begin(ctx, "user-1", "lock", "hash", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.False(t, acquired)
+	require.Equal(t, idempotency.InProgress, record.State)
+
+	// an abandoned request is taken over once its lock is stale
+	_, acquired, err = store.Begin(ctx, "user-1", "lock", "hash", time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	require.NoError(t, store.Complete(ctx, "user-1", "lock", http.StatusOK, "application/json", []byte(`{}`)))
+	record, acquired, err = store.Begin(ctx, "user-1", "lock", "hash", time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.False(t, acquired)
+	require.Equal(t, idempotency.Completed, record.State)
+	require.Equal(t, http.StatusOK, record.StatusCode)
+	require.Equal(t, []byte(`{}`), record.Body)
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.MigrateIdempotency(db))
+	store := database.NewIdempotencyStore(db)
+	ctx := context.Background()
+
+	record, acquired, err := store.Begin(ctx, "user-exp", "old", "hash", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.WithinDuration(t, time.Now().Add(idempotency.Retention), record.ExpiresAt, time.Minute)
+	require.NoError(t, store.Complete(ctx, "user-exp", "old", http.StatusCreated, "application/json", []byte(`{}`)))
+	_, _, err = store.Begin(ctx, "user-exp", "fresh", "hash", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	// once expired, a completed key is free for a new request
+	require.NoError(t, db.Table("idempotency_keys").
+		Where("user_id = ? AND idempotency_key = ?", "user-exp", "old").
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	record, acquired, err = store.Begin(ctx, "user-exp", "old", "other", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.Equal(t, idempotency.InProgress, record.State)
+
+	// the cleanup only purges expired records
+	deleted, err := store.DeleteExpired(ctx, time.Now().Add(idempotency.Retention+time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(2))
+	var remaining int64
+	require.NoError(t, db.Table("idempotency_keys").Where("user_id = ?", "user-exp").Count(&remaining).Error)
+	require.Zero(t, remaining)
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// JWKSPath is where auth-service publishes its verification keys.
+const JWKSPath = "/auth/.well-known/jwks.json"
+
+// jwksMinRefetch bounds how often an unknown kid triggers a JWKS fetch.
+const jwksMinRefetch = 10 * time.Second
+
+// JWK is a public key in JSON Web Key form (RFC 7517). Only RSA and Ed25519
+// keys are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	// Service names the service a key belongs to; such a key only verifies
+	// that service's own service tokens.
+	Service string `json:"service,omitempty"`
+}
+
+// JWKSet is the document served at JWKSPath.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK encodes pub as a signing JWK identified by its RFC 7638 thumbprint.
+func NewJWK(pub crypto.PublicKey) (JWK, error) {
+	var k JWK
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		k = JWK{Kty: "OKP", Alg: "EdDSA", Crv: "Ed25519", X: b64(key)}
+	case *rsa.PublicKey:
+		k = JWK{Kty: "RSA", Alg: "RS256", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", pub)
+	}
+	k.Use = "sig"
+	k.Kid = k.thumbprint()
+	return k, nil
+}
+
+// thumbprint hashes the required members in lexicographic order (RFC 7638).
+func (k JWK) thumbprint() string {
+	var canonical string
+	switch k.Kty {
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Crv, k.X)
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return b64(sum[:])
+}
+
+// PublicKey decodes the key material.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %s", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			return nil, fmt.Errorf("invalid RSA key %s", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// VerificationKey is a public key tokens are verified with. Service is set
+// for keys published for one service.
+type VerificationKey struct {
+	Public  crypto.PublicKey
+	Service string
+}
+
+// KeySource resolves the verification key for a token's kid header.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (VerificationKey, error)
+}
+
+// JWKSCache keeps the auth service's published keys in memory, refreshing
+// them on an interval. A kid it has not seen triggers an early refresh, so
+// tokens signed with a newly rotated key verify without waiting.
+type JWKSCache struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]VerificationKey
+	fetchedAt time.Time
+}
+
+func NewJWKSCache(url string, interval time.Duration) *JWKSCache {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &JWKSCache{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		keys:     map[string]VerificationKey{},
+	}
+}
+
+func (c *JWKSCache) Key(ctx context.Context, kid string) (VerificationKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) >= jwksMinRefetch
+	c.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if stale {
+		if err := c.Refresh(ctx); err != nil {
+			return VerificationKey{}, err
+		}
+		c.mu.RLock()
+		key, ok = c.keys[kid]
+		c.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return VerificationKey{}, errors.New("unauthorized", "unknown signing key")
+}
+
+// Refresh replaces the cached keys with the published set.
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	c.mu.Lock()
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to fetch jwks: %d", resp.StatusCode)
+	}
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+	keys := make(map[string]VerificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if pub, err := k.PublicKey(); err == nil {
+			keys[k.Kid] = VerificationKey{Public: pub, Service: k.Service}
+		}
+	}
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+	return nil
+}
+
+// Start fetches the keys immediately and then on every interval until ctx is done.
+func (c *JWKSCache) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			_ = c.Refresh(fetchCtx)
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+// Can reports whether the token grants perm on the hotel. Admins hold every
+// permission on every hotel; staff only through a role granted for it.
+func (c *Claims) Can(perm valueobject.Permission, hotelID uuid.UUID) bool {
+	if c.Admin() {
+		return true
+	}
+	if hotelID == uuid.Nil {
+		return false
+	}
+	for _, grant := range c.HotelRoles {
+		if grant.HotelID == hotelID.String() && valueobject.Role(grant.Role).Grants(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// canSomewhere reports whether the token grants perm on any hotel.
+func (c *Claims) canSomewhere(perm valueobject.Permission) bool {
+	if c.Admin() {
+		return true
+	}
+	for _, grant := range c.HotelRoles {
+		if valueobject.Role(grant.Role).Grants(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// HotelResolver returns the hotel a request's resource belongs to.
+type HotelResolver func(ctx context.Context) (uuid.UUID, error)
+
+// ForHotel resolves to a hotel already known to the caller.
+func ForHotel(id uuid.UUID) HotelResolver {
+	return func(context.Context) (uuid.UUID, error) { return id, nil }
+}
+
+// Authorize checks that the caller may perform perm on the hotel hotelOf
+// resolves to. hotelOf only runs for callers holding perm at some hotel, so
+// everyone else is refused without looking the resource up.
+func Authorize(ctx context.Context, perm valueobject.Permission, hotelOf HotelResolver) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("unauthorized", "missing claims")
+	}
+	if !claims.canSomewhere(perm) {
+		return errors.New("forbidden", "missing permission "+string(perm))
+	}
+	if claims.Admin() {
+		return nil
+	}
+	hotelID, err := hotelOf(ctx)
+	if err != nil {
+		return err
+	}
+	if !claims.Can(perm, hotelID) {
+		return errors.New("forbidden", "missing permission "+string(perm)+" for this hotel")
+	}
+	return nil
+}
+
+// RequirePermission is Authorize as route middleware, for routes whose
+// resource hotel can be resolved from the request. It must run after
+// Authenticate.
+func RequirePermission(perm valueobject.Permission, hotelOf func(r *http.Request) (uuid.UUID, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := Authorize(r.Context(), perm, func(context.Context) (uuid.UUID, error) { return hotelOf(r) })
+			if err != nil {
+				writeError(w, errors.FromError(err))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+	"github.com/ftryyln/hotel-booking-microservices/pkg/valueobject"
+)
+
+func TestClaimsCanIsScopedToHotel(t *testing.T) {
+	mine, other := uuid.New(), uuid.New()
+	clerk := &middleware.Claims{Role: "customer", HotelRoles: []middleware.HotelRole{{HotelID: mine.String(), Role: "front_desk"}}}
+	require.True(t, clerk.Can(valueobject.PermBookingCheckIn, mine))
+	require.False(t, clerk.Can(valueobject.PermBookingCheckIn, other))
+	require.False(t, clerk.Can(valueobject.PermRoomDelete, mine))
+	require.False(t, clerk.Can(valueobject.PermBookingRead, uuid.Nil))
+
+	admin := &middleware.Claims{Role: "admin", AMR: []string{middleware.AMRPassword, middleware.AMROTP}}
+	require.True(t, admin.Can(valueobject.PermRoomDelete, other))
+	require.True(t, admin.Can(valueobject.PermBookingRead, uuid.Nil))
+}
+
+func TestRequirePermissionResolvesResourceHotel(t *testing.T) {
+	mine, other := uuid.New(), uuid.New()
+	rooms := map[string]uuid.UUID{"a": mine, "b": other}
+	lookups := 0
+	r := chi.NewRouter()
+	r.With(middleware.RequirePermission(valueobject.PermRoomUpdate, func(r *http.Request) (uuid.UUID, error) {
+		lookups++
+		hotelID, ok := rooms[chi.URLParam(r, "id")]
+		if !ok {
+			return uuid.Nil, pkgErrors.New("not_found", "room not found")
+		}
+		return hotelID, nil
+	})).Put("/rooms/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	call := func(claims *middleware.Claims, room string) int {
+		req := httptest.NewRequest(http.MethodPut, "/rooms/"+room, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.AuthContextKey, claims))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	clerk := &middleware.Claims{HotelRoles: []middleware.HotelRole{{HotelID: mine.String(), Role: "front_desk"}}}
+	require.Equal(t, http.StatusOK, call(clerk, "a"))
+	require.Equal(t, http.StatusForbidden, call(clerk, "b"))
+	require.Equal(t, http.StatusNotFound, call(clerk, "c"))
+	require.Equal(t, 3, lookups)
+
+	// callers without the permission anywhere are refused before the lookup
+	require.Equal(t, http.StatusForbidden, call(&middleware.Claims{Role: "customer"}, "a"))
+	require.Equal(t, 3, lookups)
+}
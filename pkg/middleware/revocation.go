@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RevocationChecker reports whether the token with the given jti was revoked.
+type RevocationChecker interface {
+	Revoked(tokenID string) bool
+}
+
+// RevocationSource fetches the currently revoked token IDs mapped to the
+// expiry of each token.
+type RevocationSource func(ctx context.Context) (map[string]time.Time, error)
+
+// RevocationList keeps an in-memory copy of revoked token IDs, pulled from a
+// source on an interval, so that checking a token is a map lookup rather than
+// a call to the auth service. A revocation takes effect once the next pull
+// lands; when a pull fails the previous copy is kept.
+type RevocationList struct {
+	source   RevocationSource
+	interval time.Duration
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewRevocationList(source RevocationSource, interval time.Duration) *RevocationList {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &RevocationList{source: source, interval: interval, revoked: map[string]time.Time{}}
+}
+
+// Revoked is safe to call on a nil list, which revokes nothing.
+func (l *RevocationList) Revoked(tokenID string) bool {
+	if l == nil || tokenID == "" {
+		return false
+	}
+	l.mu.RLock()
+	expiresAt, ok := l.revoked[tokenID]
+	l.mu.RUnlock()
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Add records a revocation ahead of the next pull.
+func (l *RevocationList) Add(tokenID string, expiresAt time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.revoked[tokenID] = expiresAt
+	l.mu.Unlock()
+}
+
+// Refresh replaces the list with the source's current contents.
+func (l *RevocationList) Refresh(ctx context.Context) error {
+	revoked, err := l.source(ctx)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.revoked = revoked
+	l.mu.Unlock()
+	return nil
+}
+
+// Start pulls the list immediately and then on every interval until ctx is done.
+func (l *RevocationList) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			pullCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			_ = l.Refresh(pullCtx)
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// HTTPRevocationSource pulls revoked token IDs from the auth service's
+// revocation endpoint, authenticating with tokens from signer.
+func HTTPRevocationSource(authURL string, signer *ServiceSigner) RevocationSource {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(ctx context.Context) (map[string]time.Time, error) {
+		token, err := signer.Token(time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, authURL+"/auth/revocations", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("failed to fetch revoked tokens: %d", resp.StatusCode)
+		}
+
+		var envelope struct {
+			Data struct {
+				Attributes struct {
+					Tokens []struct {
+						ID        string    `json:"id"`
+						ExpiresAt time.Time `json:"expires_at"`
+					} `json:"tokens"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			return nil, err
+		}
+		revoked := make(map[string]time.Time, len(envelope.Data.Attributes.Tokens))
+		for _, t := range envelope.Data.Attributes.Tokens {
+			revoked[t.ID] = t.ExpiresAt
+		}
+		return revoked, nil
+	}
+}
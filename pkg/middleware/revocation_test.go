@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/middleware"
+)
+
+func TestAuthenticateRejectsRevokedTokens(t *testing.T) {
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/auth/revocations", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"attributes":{"tokens":[{"id":"revoked-jti","expires_at":"` +
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}]}}}`))
+	}))
+	defer auth.Close()
+
+	list := middleware.NewRevocationList(middleware.HTTPRevocationSource(auth.URL, middleware.NewSharedSecretSigner("test-service", "secret")), time.Minute)
+	require.NoError(t, list.Refresh(context.Background()))
+
+	keys := newTestKeys(t)
+	handler := middleware.Authenticate(middleware.NewVerifier("", keys), list)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(jti string) int {
+		claims := middleware.NewClaims(middleware.TokenTypeAccess, "user", "customer", "", time.Now(), time.Minute)
+		claims.ID = jti
+		token := keys.sign(t, claims)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, call("revoked-jti"))
+	require.Equal(t, http.StatusOK, call("other-jti"))
+
+	list.Add("other-jti", time.Now().Add(time.Minute))
+	require.Equal(t, http.StatusUnauthorized, call("other-jti"))
+
+	// expired revocations no longer matter
+	list.Add("stale-jti", time.Now().Add(-time.Minute))
+	require.False(t, list.Revoked("stale-jti"))
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// ServiceSigner mints the service tokens one service calls others with.
+// Tokens are signed with the service's own key, whose public half
+// auth-service publishes bound to the service's name; without a key it falls
+// back to the shared secret.
+type ServiceSigner struct {
+	service string
+	signer  crypto.Signer
+	method  jwt.SigningMethod
+	kid     string
+	secret  string
+}
+
+// NewServiceSigner signs service's tokens with key, an Ed25519 or RSA private key.
+func NewServiceSigner(service string, key crypto.Signer) (*ServiceSigner, error) {
+	method, err := SigningMethodFor(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := NewJWK(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceSigner{service: service, signer: key, method: method, kid: jwk.Kid}, nil
+}
+
+// NewSharedSecretSigner signs service's tokens with the shared secret. Any
+// holder of the secret can mint them, so it only suits development.
+func NewSharedSecretSigner(service, secret string) *ServiceSigner {
+	return &ServiceSigner{service: service, secret: secret}
+}
+
+// LoadServiceSigner signs with the private key in keyFile, or with secret
+// when no key file is configured.
+func LoadServiceSigner(service, keyFile, secret string) (*ServiceSigner, error) {
+	if keyFile == "" {
+		return NewSharedSecretSigner(service, secret), nil
+	}
+	key, err := ReadPEMKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: service signing key must be a private key", keyFile)
+	}
+	return NewServiceSigner(service, signer)
+}
+
+// Token returns a service token valid for ttl.
+func (s *ServiceSigner) Token(ttl time.Duration) (string, error) {
+	if s.signer == nil {
+		return IssueServiceToken(s.secret, s.service, ttl)
+	}
+	claims := NewClaims(TokenTypeAccess, s.service, RoleService, "", time.Now(), ttl)
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.signer)
+}
+
+// SigningMethodFor returns the JWT algorithm used with pub.
+func SigningMethodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, fmt.Errorf("unsupported signing key type %T", pub)
+}
+
+// ReadPEMKey reads a PKCS#8 or PKCS#1 private key or a PKIX public key.
+func ReadPEMKey(file string) (any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", file)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+}
+
+// AllowServices restricts a route's service callers to the named services.
+// Other roles are left to Authenticate's role list. It must run after
+// Authenticate.
+func AllowServices(services ...string) func(http.Handler) http.Handler {
+	allowed := map[string]struct{}{}
+	for _, service := range services {
+		allowed[service] = struct{}{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				writeError(w, errors.New("unauthorized", "missing claims"))
+				return
+			}
+			if claims.Role == RoleService {
+				if _, ok := allowed[claims.Subject]; !ok {
+					writeError(w, errors.New("forbidden", "service not allowed"))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -13,3 +13,17 @@ func TestOptionsNormalize(t *testing.T) {
 		t.Fatalf("expected offset clamped to 0, got %+v", opts)
 	}
 }
+
+func TestParseSort(t *testing.T) {
+	sorts, err := ParseSort("-check_in, created_at", "check_in", "created_at")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sorts) != 2 || !sorts[0].Desc || sorts[0].Clause() != "check_in DESC" || sorts[1].Clause() != "created_at ASC" {
+		t.Fatalf("unexpected sorts %+v", sorts)
+	}
+
+	if _, err := ParseSort("password", "check_in"); err == nil {
+		t.Fatalf("expected unknown field to be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// Sort orders results by one field.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a comma separated sort expression such as "-check_in,created_at",
+// where a leading "-" sorts descending. Fields outside allowed are rejected.
+func ParseSort(raw string, allowed ...string) ([]Sort, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	permitted := map[string]struct{}{}
+	for _, field := range allowed {
+		permitted[field] = struct{}{}
+	}
+
+	var sorts []Sort
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		s := Sort{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := permitted[s.Field]; !ok {
+			return nil, errors.New("bad_request", "cannot sort by "+s.Field)
+		}
+		sorts = append(sorts, s)
+	}
+	return sorts, nil
+}
+
+// Clause renders the sort as an ORDER BY fragment.
+func (s Sort) Clause() string {
+	if s.Desc {
+		return s.Field + " DESC"
+	}
+	return s.Field + " ASC"
+}
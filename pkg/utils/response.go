@@ -18,6 +18,7 @@ type Meta struct {
 	Message   string `json:"message,omitempty"`
 	RequestID string `json:"requestId"`
 	Count     int    `json:"count,omitempty"`
+	Total     int64  `json:"total,omitempty"`
 }
 
 // Resource represents a single resource item.
@@ -72,6 +73,23 @@ func RespondWithCount(w http.ResponseWriter, status int, message string, data an
 	_ = json.NewEncoder(w).Encode(env)
 }
 
+// RespondWithTotal writes a list envelope with the page item count and the
+// total number of matches across all pages.
+func RespondWithTotal(w http.ResponseWriter, status int, message string, data any, count int, total int64) {
+	env := Envelope{
+		Data: data,
+		Meta: Meta{
+			Message:   message,
+			RequestID: requestIDFrom(message),
+			Count:     count,
+			Total:     total,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(env)
+}
+
 // requestIDFrom generates a UUID; message used only to vary seed (timestamp added).
 func requestIDFrom(_ string) string {
 	return uuid.New().String()
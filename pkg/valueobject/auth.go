@@ -1,26 +1,83 @@
 package valueobject
 
 import (
+	"net/mail"
+	"regexp"
 	"strings"
 
 	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
 )
 
-// NormalizeEmail trims and lowercases basic email form.
+// NormalizeEmail trims and lowercases a bare email address. Display names,
+// comments and domains without a dot are rejected.
 func NormalizeEmail(raw string) (string, error) {
+	invalid := pkgErrors.New("bad_request", "invalid email")
 	email := strings.ToLower(strings.TrimSpace(raw))
-	if email == "" || !strings.Contains(email, "@") {
-		return "", pkgErrors.New("bad_request", "invalid email")
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", invalid
+	}
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	if at < 1 || len(email) > 254 || !strings.Contains(domain, ".") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", invalid
 	}
 	return email, nil
 }
 
+var (
+	e164   = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	locale = regexp.MustCompile(`^([a-z]{2,3})(?:-([A-Z]{2}|[0-9]{3}))?$`)
+)
+
+// NormalizePhone strips spaces, dashes, dots and parentheses and requires
+// an E.164 number such as +6281234567890. Empty input stays empty.
+func NormalizePhone(raw string) (string, error) {
+	phone := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, raw)
+	if phone == "" {
+		return "", nil
+	}
+	if !e164.MatchString(phone) {
+		return "", pkgErrors.New("bad_request", "invalid phone; use international format, e.g. +6281234567890")
+	}
+	return phone, nil
+}
+
+// ParseLocale accepts a language tag with an optional region, such as "id"
+// or "en-US", and normalizes case and separator. Empty input stays empty.
+func ParseLocale(raw string) (string, error) {
+	tag := strings.ReplaceAll(strings.TrimSpace(raw), "_", "-")
+	if tag == "" {
+		return "", nil
+	}
+	lang, region, _ := strings.Cut(tag, "-")
+	tag = strings.ToLower(lang)
+	if region != "" {
+		tag += "-" + strings.ToUpper(region)
+	}
+	if !locale.MatchString(tag) {
+		return "", pkgErrors.New("bad_request", "invalid locale")
+	}
+	return tag, nil
+}
+
 // Role represents allowed user roles.
 type Role string
 
 const (
 	RoleCustomer Role = "customer"
 	RoleAdmin    Role = "admin"
+
+	// Hotel staff roles are granted per hotel on top of a user's role.
+	RoleHotelManager Role = "hotel_manager"
+	RoleFrontDesk    Role = "front_desk"
 )
 
 // ParseRole validates and returns a normalized role.
@@ -36,3 +93,44 @@ func ParseRole(raw string) (Role, error) {
 		return "", pkgErrors.New("bad_request", "invalid role")
 	}
 }
+
+// ParseHotelRole validates a hotel staff role. Only staff roles can be
+// scoped to a hotel; admins are global.
+func ParseHotelRole(raw string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(raw)))
+	switch role {
+	case RoleHotelManager, RoleFrontDesk:
+		return role, nil
+	default:
+		return "", pkgErrors.New("bad_request", "invalid hotel role")
+	}
+}
+
+// Permission names an action on a hotel's resources.
+type Permission string
+
+const (
+	PermHotelUpdate    Permission = "hotel:update"
+	PermRoomTypeCreate Permission = "room_type:create"
+	PermRoomCreate     Permission = "room:create"
+	PermRoomUpdate     Permission = "room:update"
+	PermRoomDelete     Permission = "room:delete"
+	PermBookingRead    Permission = "booking:read"
+	PermBookingWrite   Permission = "booking:write"
+	PermBookingCheckIn Permission = "booking:checkin"
+)
+
+var hotelRolePermissions = map[Role][]Permission{
+	RoleHotelManager: {PermHotelUpdate, PermRoomTypeCreate, PermRoomCreate, PermRoomUpdate, PermRoomDelete, PermBookingRead, PermBookingWrite, PermBookingCheckIn},
+	RoleFrontDesk:    {PermRoomUpdate, PermBookingRead, PermBookingCheckIn},
+}
+
+// Grants reports whether a hotel staff role includes perm.
+func (r Role) Grants(perm Permission) bool {
+	for _, granted := range hotelRolePermissions[r] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
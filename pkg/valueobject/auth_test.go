@@ -11,3 +11,39 @@ func TestValidateRole(t *testing.T) {
 		t.Fatalf("expected error for invalid role")
 	}
 }
+
+func TestHotelRolePermissions(t *testing.T) {
+	if _, err := ParseHotelRole("admin"); err == nil {
+		t.Fatalf("expected admin to be rejected as a hotel role")
+	}
+	role, err := ParseHotelRole(" Front_Desk ")
+	if err != nil || role != RoleFrontDesk {
+		t.Fatalf("expected front desk role, got %v err=%v", role, err)
+	}
+	if !role.Grants(PermBookingCheckIn) || role.Grants(PermRoomDelete) {
+		t.Fatalf("unexpected front desk permissions")
+	}
+	if !RoleHotelManager.Grants(PermRoomDelete) || RoleCustomer.Grants(PermBookingRead) {
+		t.Fatalf("unexpected role permissions")
+	}
+}
+
+func TestNormalizePhoneAndLocale(t *testing.T) {
+	phone, err := NormalizePhone("+62 (812) 3456-7890")
+	if err != nil || phone != "+6281234567890" {
+		t.Fatalf("expected normalized phone, got %q err=%v", phone, err)
+	}
+	for _, bad := range []string{"0812345678", "+0123456789", "+62abc"} {
+		if _, err := NormalizePhone(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	for raw, want := range map[string]string{"ID": "id", "en_us": "en-US", "es-419": "es-419", "": ""} {
+		if got, err := ParseLocale(raw); err != nil || got != want {
+			t.Fatalf("ParseLocale(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseLocale("english"); err == nil {
+		t.Fatalf("expected invalid locale to be rejected")
+	}
+}
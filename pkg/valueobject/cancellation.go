@@ -0,0 +1,33 @@
+package valueobject
+
+import (
+	pkgErrors "github.com/ftryyln/hotel-booking-microservices/pkg/errors"
+)
+
+// CancellationPolicy describes what a guest is charged for cancelling a confirmed booking.
+type CancellationPolicy struct {
+	// FreeCancellationDays is how many days before check-in a cancellation is still fully refunded.
+	FreeCancellationDays int
+	// PenaltyPercent is the share of the total price kept for later cancellations.
+	PenaltyPercent float64
+	// NonRefundable keeps the full price regardless of timing.
+	NonRefundable bool
+}
+
+// DefaultCancellationPolicy applies when neither the room type nor the hotel
+// configures one: free until the day before check-in, fully charged after.
+var DefaultCancellationPolicy = CancellationPolicy{FreeCancellationDays: 1, PenaltyPercent: 100}
+
+// NewCancellationPolicy validates the policy terms.
+func NewCancellationPolicy(freeDays int, penaltyPercent float64, nonRefundable bool) (CancellationPolicy, error) {
+	if freeDays < 0 {
+		return CancellationPolicy{}, pkgErrors.New("bad_request", "free cancellation days cannot be negative")
+	}
+	if penaltyPercent < 0 || penaltyPercent > 100 {
+		return CancellationPolicy{}, pkgErrors.New("bad_request", "penalty percent must be between 0 and 100")
+	}
+	if nonRefundable {
+		return CancellationPolicy{PenaltyPercent: 100, NonRefundable: true}, nil
+	}
+	return CancellationPolicy{FreeCancellationDays: freeDays, PenaltyPercent: penaltyPercent}, nil
+}
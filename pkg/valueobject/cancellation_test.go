@@ -0,0 +1,23 @@
+package valueobject
+
+import "testing"
+
+func TestNewCancellationPolicy(t *testing.T) {
+	p, err := NewCancellationPolicy(3, 50, false)
+	if err != nil {
+		t.Fatalf("expected valid policy, got %v", err)
+	}
+	if p.FreeCancellationDays != 3 || p.PenaltyPercent != 50 {
+		t.Fatalf("unexpected policy %+v", p)
+	}
+	if _, err := NewCancellationPolicy(-1, 50, false); err == nil {
+		t.Fatalf("expected error for negative days")
+	}
+	if _, err := NewCancellationPolicy(1, 120, false); err == nil {
+		t.Fatalf("expected error for penalty above 100")
+	}
+	p, err = NewCancellationPolicy(7, 10, true)
+	if err != nil || !p.NonRefundable || p.PenaltyPercent != 100 || p.FreeCancellationDays != 0 {
+		t.Fatalf("expected non-refundable policy, got %+v (%v)", p, err)
+	}
+}
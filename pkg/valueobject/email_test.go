@@ -17,4 +17,9 @@ func TestNormalizeEmail(t *testing.T) {
 	if _, err := NormalizeEmail("   "); err == nil {
 		t.Fatalf("expected error for empty email")
 	}
+	for _, raw := range []string{"@example.com", "user@", "user@localhost", "user@example..com", "a@b@example.com", "User <user@example.com>", "user name@example.com"} {
+		if _, err := NormalizeEmail(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
 }
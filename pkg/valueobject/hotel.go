@@ -37,8 +37,12 @@ const (
 	RoomAvailable   RoomStatus = "available"
 	RoomUnavailable RoomStatus = "unavailable"
 	RoomMaintenance RoomStatus = "maintenance"
+	RoomOccupied    RoomStatus = "occupied"
 )
 
+// OutOfOrderRoomStatuses lists room states that cannot be sold.
+var OutOfOrderRoomStatuses = []string{string(RoomMaintenance), string(RoomUnavailable)}
+
 // NormalizeRoomStatus validates or defaults to available.
 func NormalizeRoomStatus(raw string) (RoomStatus, error) {
 	status := strings.ToLower(strings.TrimSpace(raw))
@@ -46,7 +50,7 @@ func NormalizeRoomStatus(raw string) (RoomStatus, error) {
 		return RoomAvailable, nil
 	}
 	switch RoomStatus(status) {
-	case RoomAvailable, RoomUnavailable, RoomMaintenance:
+	case RoomAvailable, RoomUnavailable, RoomMaintenance, RoomOccupied:
 		return RoomStatus(status), nil
 	default:
 		return "", pkgErrors.New("bad_request", "invalid room status")
@@ -11,12 +11,17 @@ const (
 	StatusCancelled      BookingStatus = "cancelled"
 	StatusCheckedIn      BookingStatus = "checked_in"
 	StatusCompleted      BookingStatus = "completed"
+	StatusExpired        BookingStatus = "expired"
+	StatusNoShow         BookingStatus = "no_show"
 )
 
+// ReleasedBookingStatuses lists booking states that no longer hold room inventory.
+var ReleasedBookingStatuses = []string{string(StatusCancelled), string(StatusExpired), string(StatusNoShow)}
+
 // ValidateBookingStatus ensures status is known.
 func ValidateBookingStatus(status string) (BookingStatus, error) {
 	switch BookingStatus(status) {
-	case StatusPendingPayment, StatusConfirmed, StatusCancelled, StatusCheckedIn, StatusCompleted:
+	case StatusPendingPayment, StatusConfirmed, StatusCancelled, StatusCheckedIn, StatusCompleted, StatusExpired, StatusNoShow:
 		return BookingStatus(status), nil
 	default:
 		return "", pkgErrors.New("bad_request", "invalid booking status")
@@ -27,11 +32,11 @@ func ValidateBookingStatus(status string) (BookingStatus, error) {
 func (s BookingStatus) CanTransition(target BookingStatus) error {
 	switch s {
 	case StatusPendingPayment:
-		if target == StatusConfirmed || target == StatusCancelled {
+		if target == StatusConfirmed || target == StatusCancelled || target == StatusExpired {
 			return nil
 		}
 	case StatusConfirmed:
-		if target == StatusCheckedIn || target == StatusCancelled {
+		if target == StatusCheckedIn || target == StatusCancelled || target == StatusNoShow {
 			return nil
 		}
 	case StatusCheckedIn:
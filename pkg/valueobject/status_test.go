@@ -24,6 +24,22 @@ func TestBookingStatusTransition(t *testing.T) {
 	if err := next.CanTransition(completed); err == nil {
 		t.Fatalf("expected invalid transition to fail")
 	}
+
+	expired, _ := ValidateBookingStatus("expired")
+	if err := curr.CanTransition(expired); err != nil {
+		t.Fatalf("expected pending_payment -> expired ok, got %v", err)
+	}
+	if err := next.CanTransition(expired); err == nil {
+		t.Fatalf("expected confirmed -> expired to fail")
+	}
+
+	noShow, _ := ValidateBookingStatus("no_show")
+	if err := next.CanTransition(noShow); err != nil {
+		t.Fatalf("expected confirmed -> no_show ok, got %v", err)
+	}
+	if err := curr.CanTransition(noShow); err == nil {
+		t.Fatalf("expected pending_payment -> no_show to fail")
+	}
 }
 
 func TestValidatePaymentStatus(t *testing.T) {